@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/internal/types"
+	"github.com/mockzilla/connexions/v2/pkg/har"
+	"github.com/mockzilla/connexions/v2/pkg/static"
+)
+
+// HARImportResult describes the static services written by ImportHAR.
+type HARImportResult struct {
+	// Services maps service names to the number of responses written.
+	Services map[string]int
+
+	// Duplicates is the number of entries dropped because a response
+	// for the same method and path was already imported.
+	Duplicates int
+
+	// Skipped is the number of entries without a usable response,
+	// e.g. failed requests, empty bodies or binary content.
+	Skipped int
+}
+
+// harResponse is a HAR entry reduced to what the static layout keeps.
+type harResponse struct {
+	service     string
+	method      string
+	path        string
+	contentType string
+	content     []byte
+	meta        *static.Meta
+}
+
+// ImportHAR turns the entries of a HAR document into static service directories
+// under outDir, one per host, in the layout GenerateSpecFromStaticDir expects.
+// If serviceName is set, all entries go into that single service.
+//
+// Volatile headers such as Date or Set-Cookie are dropped.
+// Only one response is kept per method and path: a successful one wins over errors,
+// otherwise the first recorded response is used.
+func ImportHAR(data []byte, outDir, serviceName string) (*HARImportResult, error) {
+	doc, err := har.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &HARImportResult{Services: make(map[string]int)}
+
+	var keys []string
+	responses := make(map[string]*harResponse)
+	for _, entry := range doc.Log.Entries {
+		resp := harEntryResponse(entry, serviceName)
+		if resp == nil {
+			res.Skipped++
+			continue
+		}
+
+		key := resp.service + " " + resp.method + " " + resp.path
+		existing, ok := responses[key]
+		if !ok {
+			keys = append(keys, key)
+			responses[key] = resp
+			continue
+		}
+
+		res.Duplicates++
+		if !isSuccessStatus(existing.meta.Status) && isSuccessStatus(resp.meta.Status) {
+			responses[key] = resp
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no importable entries found in HAR")
+	}
+
+	for _, key := range keys {
+		resp := responses[key]
+		serviceDir := filepath.Join(outDir, resp.service)
+		if err := static.WriteResponse(serviceDir, resp.method, resp.path, resp.contentType, resp.content, resp.meta); err != nil {
+			return nil, fmt.Errorf("writing %s %s: %w", resp.method, resp.path, err)
+		}
+		res.Services[resp.service]++
+	}
+
+	return res, nil
+}
+
+// harEntryResponse extracts the static response of an entry.
+// Returns nil if the entry can't be represented in the static layout.
+func harEntryResponse(entry *har.Entry, serviceName string) *harResponse {
+	if entry == nil || entry.Request == nil || entry.Response == nil || entry.Response.Status <= 0 {
+		return nil
+	}
+
+	u, err := url.Parse(entry.Request.URL)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "." || segment == ".." || strings.Contains(segment, `\`) {
+			return nil
+		}
+	}
+
+	content, err := entry.Response.Content.Bytes()
+	if err != nil {
+		return nil
+	}
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return nil
+	}
+
+	contentType := har.Header(entry.Response.Headers, "Content-Type")
+	if contentType == "" && entry.Response.Content != nil {
+		contentType = entry.Response.Content.MimeType
+	}
+	if static.Extension(contentType) == "" {
+		return nil
+	}
+
+	name := serviceName
+	if name == "" {
+		name = HostServiceName(u.Host)
+	}
+
+	meta := &static.Meta{Status: entry.Response.Status}
+	for _, h := range har.StableHeaders(entry.Response.Headers) {
+		if strings.EqualFold(h.Name, "Content-Type") {
+			continue
+		}
+		if meta.Headers == nil {
+			meta.Headers = make(map[string]string)
+		}
+		meta.Headers[http.CanonicalHeaderKey(h.Name)] = h.Value
+	}
+
+	return &harResponse{
+		service:     name,
+		method:      strings.ToUpper(entry.Request.Method),
+		path:        path,
+		contentType: contentType,
+		content:     content,
+		meta:        meta,
+	}
+}
+
+// HostServiceName derives a service name from a host, dropping the port and "www." prefix.
+// Example: "api.example.com:8443" -> "api_example_com"
+func HostServiceName(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	return types.ToSnakeCase(host)
+}
+
+func isSuccessStatus(status int) bool {
+	return status >= 200 && status < 300
+}
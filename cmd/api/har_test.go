@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mockzilla/connexions/v2/pkg/har"
+	"github.com/mockzilla/connexions/v2/pkg/static"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+)
+
+func harEntry(method, rawURL string, status int, contentType, body string, headers ...*har.NameValue) *har.Entry {
+	headers = append(headers, &har.NameValue{Name: "Content-Type", Value: contentType})
+	return &har.Entry{
+		Request: &har.Request{Method: method, URL: rawURL},
+		Response: &har.Response{
+			Status:  status,
+			Headers: headers,
+			Content: &har.Content{MimeType: contentType, Text: body},
+		},
+	}
+}
+
+func marshalHAR(t *testing.T, entries ...*har.Entry) []byte {
+	t.Helper()
+	data, err := json.Marshal(&har.HAR{Log: &har.Log{Version: har.Version, Entries: entries}})
+	require.NoError(t, err)
+	return data
+}
+
+func TestImportHAR(t *testing.T) {
+	t.Run("writes static services per host", func(t *testing.T) {
+		dir := t.TempDir()
+		data := marshalHAR(t,
+			harEntry("GET", "https://api.example.com/users?page=1", 200, "application/json; charset=utf-8", `[{"id":1}]`,
+				&har.NameValue{Name: "date", Value: "Mon, 01 Jan 2024 00:00:00 GMT"},
+				&har.NameValue{Name: "x-rate-limit", Value: "100"}),
+			harEntry("GET", "https://api.example.com/users?page=2", 200, "application/json", `[{"id":2}]`),
+			harEntry("POST", "https://api.example.com/users", 201, "application/json", `{"id":3}`),
+			harEntry("GET", "https://www.cdn.example.com:8443/", 200, "text/html", `<html></html>`),
+			harEntry("GET", "https://api.example.com/logo.png", 200, "image/png", "png"),
+			harEntry("DELETE", "https://api.example.com/users/1", 204, "application/json", ""),
+		)
+
+		res, err := ImportHAR(data, dir, "")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"api_example_com": 2, "cdn_example_com": 1}, res.Services)
+		assert.Equal(t, 1, res.Duplicates)
+		assert.Equal(t, 2, res.Skipped)
+
+		content, err := os.ReadFile(filepath.Join(dir, "api_example_com", "get", "users", "index.json"))
+		require.NoError(t, err)
+		assert.Equal(t, `[{"id":1}]`, string(content))
+
+		var meta static.Meta
+		metaBytes, err := os.ReadFile(filepath.Join(dir, "api_example_com", "get", "users", "index"+static.MetaSuffix))
+		require.NoError(t, err)
+		require.NoError(t, yaml.Unmarshal(metaBytes, &meta))
+		assert.Equal(t, map[string]string{"X-Rate-Limit": "100"}, meta.Headers)
+
+		metaBytes, err = os.ReadFile(filepath.Join(dir, "api_example_com", "post", "users", "index"+static.MetaSuffix))
+		require.NoError(t, err)
+		require.NoError(t, yaml.Unmarshal(metaBytes, &meta))
+		assert.Equal(t, 201, meta.Status)
+
+		assert.FileExists(t, filepath.Join(dir, "cdn_example_com", "get", "index.html"))
+	})
+
+	t.Run("successful response wins over errors", func(t *testing.T) {
+		dir := t.TempDir()
+		data := marshalHAR(t,
+			harEntry("GET", "https://api.example.com/me", 500, "application/json", `{"error":"boom"}`),
+			harEntry("GET", "https://api.example.com/me", 200, "application/json", `{"id":1}`),
+			harEntry("GET", "https://api.example.com/me", 200, "application/json", `{"id":2}`),
+		)
+
+		res, err := ImportHAR(data, dir, "me")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"me": 1}, res.Services)
+		assert.Equal(t, 2, res.Duplicates)
+
+		content, err := os.ReadFile(filepath.Join(dir, "me", "get", "me", "index.json"))
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":1}`, string(content))
+		assert.NoFileExists(t, filepath.Join(dir, "me", "get", "me", "index"+static.MetaSuffix))
+	})
+
+	t.Run("no importable entries", func(t *testing.T) {
+		_, err := ImportHAR(marshalHAR(t), t.TempDir(), "")
+		assert.ErrorContains(t, err, "no importable entries")
+	})
+
+	t.Run("invalid HAR", func(t *testing.T) {
+		_, err := ImportHAR([]byte("{"), t.TempDir(), "")
+		assert.Error(t, err)
+	})
+}
+
+func TestHostServiceName(t *testing.T) {
+	assert.Equal(t, "api_example_com", HostServiceName("api.example.com:8443"))
+	assert.Equal(t, "example_com", HostServiceName("WWW.Example.com"))
+}
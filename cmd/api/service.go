@@ -157,6 +157,11 @@ func GenerateService(opts ServiceOptions) error {
 		return fmt.Errorf("reading spec file: %w", err)
 	}
 
+	specContents, err = typedef.ConvertSwagger2(specContents)
+	if err != nil {
+		return fmt.Errorf("converting Swagger 2.0 spec: %w", err)
+	}
+
 	// If spec was fetched from URL, save it locally for //go:embed to work
 	if files.IsURL(specFile) {
 		specExt := ".yml"
@@ -616,6 +621,17 @@ func handleSetupSourceSpec(opts ServiceOptions, setupDir, serviceName, serviceTy
 		return fmt.Errorf("reading spec file from %s: %w", opts.SpecPath, err)
 	}
 
+	// Swagger 2.0 specs are stored converted, the generated service only handles OpenAPI 3
+	if typedef.IsSwagger2(specContents) {
+		specContents, err = typedef.ConvertSwagger2(specContents)
+		if err != nil {
+			return fmt.Errorf("converting Swagger 2.0 spec from %s: %w", opts.SpecPath, err)
+		}
+		if !opts.Quiet {
+			fmt.Printf("Converted Swagger 2.0 spec to OpenAPI 3: %s\n", opts.SpecPath)
+		}
+	}
+
 	specExt := ".yml"
 	if files.IsJsonType(specContents) {
 		specExt = ".json"
@@ -18,6 +18,7 @@ import (
 	"github.com/lmittmann/tint"
 	"github.com/mockzilla/connexions/v2/internal/portable"
 	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/certs"
 	"github.com/mockzilla/connexions/v2/pkg/config"
 	"github.com/mockzilla/connexions/v2/pkg/loader"
 
@@ -40,6 +41,16 @@ func main() {
 		return
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(portable.RunImport(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(portable.RunExport(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "infer" {
+		os.Exit(portable.RunInfer(os.Args[2:]))
+	}
+
 	for {
 		exitCode := runServer()
 		if exitCode == exitCodeRestart {
@@ -135,6 +146,12 @@ func runServer() int {
 	_ = api.CreateHomeRoutes(router)
 	_ = api.CreateServiceRoutes(router)
 	_ = api.CreateHistoryRoutes(router)
+	_ = api.CreateDriftRoutes(router)
+	_ = api.CreateAdminRoutes(router)
+	_ = api.CreateVerifyRoutes(router)
+	if err := api.CreateOIDCRoutes(router); err != nil {
+		slog.Error("Failed to start OIDC provider", "error", err)
+	}
 
 	// Auto-discover and register all services
 	// Services are automatically registered via their init() functions
@@ -153,18 +170,33 @@ func runServer() int {
 	port := getEnv("PORT", "2200")
 	addr := fmt.Sprintf(":%s", port)
 
+	appCfg := router.Config()
+	tlsCfg, err := certs.ServerConfig(appCfg.TLS, appCfg.Paths.Data)
+	if err != nil {
+		log.Printf("Failed to set up TLS: %v", err)
+		return exitCodeError
+	}
+
 	server := &http.Server{
 		Addr:         addr,
 		Handler:      router,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		TLSConfig:    tlsCfg,
 	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting Connexions Server on %s", addr)
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if tlsCfg == nil {
+			log.Printf("Starting Connexions Server on %s", addr)
+			err = server.ListenAndServe()
+		} else {
+			log.Printf("Starting Connexions Server on %s with TLS", addr)
+			err = server.ListenAndServeTLS("", "")
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
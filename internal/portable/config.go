@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/mockzilla/connexions/v2/pkg/api"
 	"github.com/mockzilla/connexions/v2/pkg/config"
 	"go.yaml.in/yaml/v4"
 )
@@ -14,6 +15,9 @@ import (
 type portableConfig struct {
 	App      *config.AppConfig                `yaml:"app"`
 	Services map[string]*config.ServiceConfig `yaml:"services"`
+
+	// path is the file the config was read from, empty for defaults.
+	path string
 }
 
 // loadPortableConfig reads and parses the unified config file.
@@ -37,7 +41,7 @@ func loadPortableConfig(path, baseDir string) (*portableConfig, error) {
 		return nil, fmt.Errorf("parsing config: %w", err)
 	}
 
-	cfg := &portableConfig{}
+	cfg := &portableConfig{path: path}
 
 	// Parse app section: start with defaults, then overlay YAML values.
 	if raw.App.Kind != 0 {
@@ -51,7 +55,13 @@ func loadPortableConfig(path, baseDir string) (*portableConfig, error) {
 	// Apply WithDefaults on each service config.
 	if len(raw.Services) > 0 {
 		cfg.Services = raw.Services
-		for _, svc := range cfg.Services {
+		for name, svc := range cfg.Services {
+			if svc == nil {
+				continue
+			}
+			if err := svc.ParseRules(); err != nil {
+				return nil, fmt.Errorf("parsing service %s config: %w", name, err)
+			}
 			svc.WithDefaults()
 		}
 	}
@@ -59,6 +69,15 @@ func loadPortableConfig(path, baseDir string) (*portableConfig, error) {
 	return cfg, nil
 }
 
+// serviceOptions returns the handler options of a service:
+// runtime config changes are written to its section of the config file.
+func (c *portableConfig) serviceOptions(name string) []api.HandlerOption {
+	if c.path == "" {
+		return nil
+	}
+	return []api.HandlerOption{api.WithConfigFile(c.path, "services", name)}
+}
+
 // loadContexts reads a per-service context file and returns each service's
 // context as YAML bytes suitable for factory.WithServiceContext.
 // If path is empty, returns nil.
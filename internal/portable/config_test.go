@@ -17,6 +17,7 @@ func TestLoadPortableConfig(t *testing.T) {
 		require.NoError(t, err)
 		assert.Nil(t, cfg.App)
 		assert.Nil(t, cfg.Services)
+		assert.Empty(t, cfg.serviceOptions("petstore"))
 	})
 
 	t.Run("full config", func(t *testing.T) {
@@ -37,6 +38,8 @@ services:
 
 		cfg, err := loadPortableConfig(path, baseDir)
 		require.NoError(t, err)
+		assert.Equal(t, path, cfg.path)
+		assert.Len(t, cfg.serviceOptions("petstore"), 1)
 
 		require.NotNil(t, cfg.App)
 		assert.Equal(t, 3000, cfg.App.Port)
@@ -114,6 +117,25 @@ services:
 		_, err := loadPortableConfig(path, baseDir)
 		assert.Error(t, err)
 	})
+
+	t.Run("invalid rule returns error", func(t *testing.T) {
+		content := `
+services:
+  petstore:
+    rules:
+      - name: search
+        query:
+          q:
+            regex: "("
+        response:
+          status: 200
+`
+		path := filepath.Join(baseDir, "rules.yml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+		_, err := loadPortableConfig(path, baseDir)
+		assert.ErrorContains(t, err, "parsing service petstore config: rules[0]: rule search: q: regex")
+	})
 }
 
 func TestLoadContexts(t *testing.T) {
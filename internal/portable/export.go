@@ -0,0 +1,79 @@
+package portable
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+)
+
+const exportUsage = "Usage: connexions export har <service> [-server url] [-history-url path] [-output file]"
+
+// RunExport runs the export command: `export har <service>`.
+// It downloads the history of a service from a running server as HAR 1.2,
+// writing it to -output or stdout.
+func RunExport(args []string) int {
+	if len(args) == 0 || args[0] != "har" {
+		log.Println(exportUsage)
+		return exitCodeError
+	}
+
+	fs := flag.NewFlagSet("export har", flag.ContinueOnError)
+	server := fs.String("server", "http://localhost:2200", "Base URL of the running server")
+	historyURL := fs.String("history-url", config.DefaultHistoryURL, "History URL of the running server")
+	output := fs.String("output", "", "File to write the HAR to (default: stdout)")
+
+	fl, positional := splitArgs(args[1:])
+	if err := fs.Parse(fl); err != nil {
+		return exitCodeError
+	}
+	if len(positional) != 1 {
+		log.Println(exportUsage)
+		return exitCodeError
+	}
+
+	data, err := fetchHistory(*server, *historyURL, positional[0], "har")
+	if err != nil {
+		log.Printf("Failed to export history: %v", err)
+		return exitCodeError
+	}
+
+	if *output == "" {
+		_, _ = os.Stdout.Write(data)
+		return exitCodeShutdown
+	}
+
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		log.Printf("Failed to write %s: %v", *output, err)
+		return exitCodeError
+	}
+	fmt.Printf("Exported history of %s to %s\n", positional[0], *output)
+	return exitCodeShutdown
+}
+
+// fetchHistory requests the history of a service in the given format, e.g. har or openapi.
+func fetchHistory(server, historyURL, service, format string) ([]byte, error) {
+	u := strings.TrimRight(server, "/") + "/" + strings.Trim(historyURL, "/") + "/" + url.PathEscape(service) +
+		"?format=" + url.QueryEscape(format)
+
+	resp, err := http.Get(u) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("fetching: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d from %s: %s", resp.StatusCode, u, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,44 @@
+package portable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.history/petstore" || r.URL.Query().Get("format") != "har" {
+			http.Error(w, "Service not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"log":{"version":"1.2","entries":[]}}`))
+	}))
+	defer srv.Close()
+
+	t.Run("writes HAR to file", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "petstore.har")
+		assert.Equal(t, exitCodeShutdown, RunExport([]string{"har", "petstore", "-server", srv.URL, "-output", out}))
+
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"log":{"version":"1.2","entries":[]}}`, string(data))
+	})
+
+	t.Run("fails for unknown service", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "missing.har")
+		assert.Equal(t, exitCodeError, RunExport([]string{"har", "missing", "-server", srv.URL, "-output", out}))
+		assert.NoFileExists(t, out)
+	})
+
+	t.Run("requires har format and service", func(t *testing.T) {
+		assert.Equal(t, exitCodeError, RunExport(nil))
+		assert.Equal(t, exitCodeError, RunExport([]string{"json", "petstore"}))
+		assert.Equal(t, exitCodeError, RunExport([]string{"har"}))
+	})
+}
@@ -0,0 +1,62 @@
+package portable
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/grpc"
+)
+
+// grpcRegistry registers gRPC services to be served.
+type grpcRegistry interface {
+	Register(svc *grpc.Service, cfg *config.ServiceConfig, serviceDB db.DB)
+}
+
+// grpcListener serves gRPC services on their own port.
+// It starts listening when the first service is registered, so the port is only taken
+// when there's something to serve, also when the first descriptor set is added while running.
+// Listen and serve errors are sent to errs.
+type grpcListener struct {
+	server *grpc.Server
+	port   int
+	errs   chan<- error
+	once   sync.Once
+}
+
+func newGRPCListener(port int, errs chan<- error) *grpcListener {
+	return &grpcListener{
+		server: grpc.NewServer(),
+		port:   port,
+		errs:   errs,
+	}
+}
+
+// Register adds or replaces a service and starts listening if it's the first one.
+func (l *grpcListener) Register(svc *grpc.Service, cfg *config.ServiceConfig, serviceDB db.DB) {
+	l.server.Register(svc, cfg, serviceDB)
+	l.once.Do(l.listen)
+}
+
+func (l *grpcListener) listen() {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", l.port))
+	if err != nil {
+		l.errs <- fmt.Errorf("listening for gRPC: %w", err)
+		return
+	}
+
+	log.Printf("gRPC services on localhost:%d", l.port)
+	go func() {
+		if err := l.server.Serve(lis); err != nil {
+			l.errs <- fmt.Errorf("gRPC server failed: %w", err)
+		}
+	}()
+}
+
+// GracefulStop stops serving and waits for pending calls to finish.
+func (l *grpcListener) GracefulStop() {
+	l.server.GracefulStop()
+}
@@ -0,0 +1,80 @@
+package portable
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := lis.Addr().(*net.TCPAddr).Port
+	require.NoError(t, lis.Close())
+	return port
+}
+
+func TestGRPCListener(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "pets.protoset")
+	require.NoError(t, os.WriteFile(specPath, loadTestSpec(t, "pets.protoset"), 0644))
+
+	t.Run("listens once a service is added", func(t *testing.T) {
+		port := freePort(t)
+		errs := make(chan error, 2)
+		listener := newGRPCListener(port, errs)
+		t.Cleanup(listener.GracefulStop)
+
+		// Nothing to serve yet, so the port is free
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		require.NoError(t, err)
+		require.NoError(t, lis.Close())
+
+		router := testRouter(t)
+		handlers := make(map[string]*swappableHandler)
+		reloadSpec(specPath, router, &portableConfig{}, nil, handlers, listener)
+		require.Contains(t, handlers, "pets")
+
+		conn, err := grpclib.NewClient(fmt.Sprintf("127.0.0.1:%d", port), grpclib.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		md, ok := handlers["pets"].handler.(*grpc.Service).Method("/pets.v1.PetService/GetPet")
+		require.True(t, ok)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, conn.Invoke(ctx, "/pets.v1.PetService/GetPet", dynamicpb.NewMessage(md.Input()), dynamicpb.NewMessage(md.Output())))
+		assert.Empty(t, errs)
+	})
+
+	t.Run("reports a taken port", func(t *testing.T) {
+		lis, err := net.Listen("tcp", ":0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = lis.Close() })
+
+		errs := make(chan error, 2)
+		listener := newGRPCListener(lis.Addr().(*net.TCPAddr).Port, errs)
+		t.Cleanup(listener.GracefulStop)
+
+		err = registerService(testRouter(t), specPath, nil, nil, make(map[string]*swappableHandler), listener)
+		require.NoError(t, err)
+
+		select {
+		case err := <-errs:
+			assert.ErrorContains(t, err, "listening for gRPC")
+		default:
+			t.Fatal("expected a listen error")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package portable
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,14 +12,26 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
 	"github.com/mockzilla/connexions/v2/pkg/factory"
+	"github.com/mockzilla/connexions/v2/pkg/graphql"
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+	"github.com/mockzilla/connexions/v2/pkg/security"
 )
 
 // handler implements the api.Handler interface using a factory.Factory
 // to generate mock responses directly from an OpenAPI spec - no codegen needed.
+// validate enables request and generated response validation, nil disables both.
+// security enforces the spec's security requirements, nil disables enforcement.
+// Both request checks run in the middleware chain through CheckRequest.
+// claimsKeys verifies bearer JWTs before their claims are used, nil decodes them unverified.
 type handler struct {
-	factory *factory.Factory
-	routes  api.RouteDescriptions
+	factory    *factory.Factory
+	routes     api.RouteDescriptions
+	validate   *config.ValidateConfig
+	security   *security.Enforcer
+	claimsKeys oidc.KeySet
 }
 
 // newHandler creates a handler from raw OpenAPI spec bytes.
@@ -80,11 +93,16 @@ func (h *handler) Generate(w http.ResponseWriter, r *http.Request) {
 	api.NewJSONResponse(w).Send(res)
 }
 
+// ServeHTTP serves mock API responses, see handleRequest.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handleRequest(w, r)
+}
+
 // handleRequest serves mock API responses for incoming HTTP requests.
 // The endpoint path is extracted from chi's wildcard parameter, which gives us
 // the path relative to the service mount point (prefix already stripped).
 func (h *handler) handleRequest(w http.ResponseWriter, r *http.Request) {
-	ctx := api.ExtractContextFromRequest(r)
+	ctx := api.WithClaims(api.ExtractContextFromRequest(r), api.ExtractClaimsFromRequest(r, h.claimsKeys))
 
 	// chi's "*" param gives us the path within the mounted sub-router,
 	// with the service prefix already stripped by chi's Route().
@@ -97,6 +115,8 @@ func (h *handler) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	op := h.factory.FindOperation(specPath, r.Method)
+
 	resp, err := h.factory.Response(specPath, r.Method, ctx)
 	if err != nil {
 		slog.Debug("Failed to generate response", "method", r.Method, "path", specPath, "error", err)
@@ -104,6 +124,26 @@ func (h *handler) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Determine status code from the spec
+	statusCode := http.StatusOK
+	if op != nil && op.Response != nil && op.Response.SuccessCode > 0 {
+		statusCode = op.Response.SuccessCode
+	}
+
+	contentType := resp.Headers.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	if h.validate != nil && h.validate.Response && op != nil {
+		if errs := schema.ValidateResponse(op.Response, statusCode, contentType, resp.Body); len(errs) > 0 {
+			slog.Warn("Generated response does not match the spec", "method", r.Method, "path", specPath, "errors", len(errs))
+			api.SendProblem(w, http.StatusInternalServerError, "generated response does not match the spec", errs)
+			return
+		}
+		w.Header().Set("X-Validated", "true")
+	}
+
 	// Set response headers from the generated response
 	for key, values := range resp.Headers {
 		for _, v := range values {
@@ -113,13 +153,11 @@ func (h *handler) handleRequest(w http.ResponseWriter, r *http.Request) {
 
 	// Set content-type if not already set by response headers
 	if w.Header().Get("Content-Type") == "" {
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Type", contentType)
 	}
 
-	// Determine status code from the spec
-	op := h.factory.FindOperation(specPath, r.Method)
-	if op != nil && op.Response != nil && op.Response.SuccessCode > 0 {
-		w.WriteHeader(op.Response.SuccessCode)
+	if statusCode != http.StatusOK {
+		w.WriteHeader(statusCode)
 	}
 
 	if resp.Body != nil {
@@ -127,10 +165,90 @@ func (h *handler) handleRequest(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CheckRequest enforces the security requirements and validates the request when enabled.
+// It runs in the middleware chain, so the checks apply to every response of the service,
+// not only to generated ones. Requests matching no operation are left to the handler.
+func (h *handler) CheckRequest(w http.ResponseWriter, r *http.Request, path string) bool {
+	specPath, ok := h.factory.MatchPath(path, r.Method)
+	if !ok {
+		return true
+	}
+
+	if failure := h.security.Check(r, specPath, path); failure != nil {
+		slog.Debug("Security requirements not met", "method", r.Method, "path", specPath, "status", failure.StatusCode)
+		for _, challenge := range failure.Challenges {
+			w.Header().Add("WWW-Authenticate", challenge)
+		}
+		api.SendProblem(w, failure.StatusCode, failure.Message, nil)
+		return false
+	}
+
+	if h.validate == nil || !h.validate.Request {
+		return true
+	}
+	op := h.factory.FindOperation(specPath, r.Method)
+	if op == nil {
+		return true
+	}
+	if errs := h.validateRequest(r, op, path, specPath); len(errs) > 0 {
+		slog.Debug("Request validation failed", "method", r.Method, "path", specPath, "errors", len(errs))
+		api.SendProblem(w, http.StatusBadRequest, "request does not match the spec", errs)
+		return false
+	}
+	return true
+}
+
+// validateRequest validates the path, query, headers and body of a request against the operation.
+// The body is restored after reading, so the response generation can still use it.
+func (h *handler) validateRequest(r *http.Request, op *schema.Operation, endpointPath, specPath string) []*schema.ValidationError {
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	pathValues := config.ExtractPathValues(endpointPath, specPath)
+	return schema.ValidateRequest(op, pathValues, r.URL.Query(), r.Header, body)
+}
+
+// ValidateResponse validates an upstream response against the responses of the matching operation.
+func (h *handler) ValidateResponse(method, path string, statusCode int, contentType string, body []byte) (string, []*schema.ValidationError) {
+	specPath, ok := h.factory.MatchPath(path, method)
+	if !ok {
+		return "", nil
+	}
+	op := h.factory.FindOperation(specPath, method)
+	if op == nil {
+		return "", nil
+	}
+	return specPath, schema.ValidateResponse(op.Response, statusCode, contentType, body)
+}
+
+// serviceHandler is a service backend that can be hot-swapped.
+// ServeHTTP receives every request under the service prefix,
+// with chi's "*" param holding the path relative to the service root.
+type serviceHandler interface {
+	api.Handler
+	http.Handler
+}
+
+// graphqlHandler adapts a graphql.Handler to the catch-all route of swappableHandler,
+// serving only the GraphQL endpoint.
+type graphqlHandler struct {
+	*graphql.Handler
+}
+
+func (h graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if "/"+chi.URLParam(r, "*") != graphql.Path {
+		http.Error(w, fmt.Sprintf("no matching operation: %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		return
+	}
+	h.Handler.ServeHTTP(w, r)
+}
+
 // swappableHandler wraps a handler with a mutex for hot-swapping.
 type swappableHandler struct {
 	mu      sync.RWMutex
-	handler *handler
+	handler serviceHandler
 }
 
 func (s *swappableHandler) Routes() api.RouteDescriptions {
@@ -150,14 +268,45 @@ func (s *swappableHandler) Generate(w http.ResponseWriter, r *http.Request) {
 	s.handler.Generate(w, r)
 }
 
-// handleRequest delegates to the current handler's handleRequest.
+// WriteError delegates simulated errors to the current handler
+// if it writes them in its own protocol.
+func (s *swappableHandler) WriteError(w http.ResponseWriter, r *http.Request, statusCode int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if ew, ok := s.handler.(api.ErrorWriter); ok {
+		return ew.WriteError(w, r, statusCode)
+	}
+	return false
+}
+
+// ValidateResponse delegates to the current handler if it validates responses.
+func (s *swappableHandler) ValidateResponse(method, path string, statusCode int, contentType string, body []byte) (string, []*schema.ValidationError) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if rv, ok := s.handler.(api.ResponseValidator); ok {
+		return rv.ValidateResponse(method, path, statusCode, contentType, body)
+	}
+	return "", nil
+}
+
+// CheckRequest delegates to the current handler if it checks requests.
+func (s *swappableHandler) CheckRequest(w http.ResponseWriter, r *http.Request, path string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if rc, ok := s.handler.(api.RequestChecker); ok {
+		return rc.CheckRequest(w, r, path)
+	}
+	return true
+}
+
+// handleRequest delegates to the current handler.
 func (s *swappableHandler) handleRequest(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	s.handler.handleRequest(w, r)
+	s.handler.ServeHTTP(w, r)
 }
 
-func (s *swappableHandler) swap(h *handler) {
+func (s *swappableHandler) swap(h serviceHandler) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.handler = h
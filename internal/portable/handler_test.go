@@ -2,8 +2,10 @@ package portable
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -13,9 +15,17 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/mockzilla/connexions/v2/pkg/api"
 	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/grpc"
+	"github.com/mockzilla/connexions/v2/pkg/middleware"
 	"github.com/mockzilla/connexions/v2/pkg/schema"
+	"github.com/mockzilla/connexions/v2/pkg/security"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 //go:embed testdata/**
@@ -67,6 +77,17 @@ func TestHandler_RegisterRoutes(t *testing.T) {
 	assert.NotEqual(t, http.StatusMethodNotAllowed, w.Code)
 }
 
+// newCheckedRouter mounts the handler behind the request check middleware, as a service router does.
+func newCheckedRouter(h *handler) chi.Router {
+	params := middleware.NewParams(config.NewServiceConfig(), nil, nil)
+	params.SetRequestChecker(h.CheckRequest)
+
+	r := chi.NewRouter()
+	r.Use(middleware.CreateRequestCheckMiddleware(params))
+	h.RegisterRoutes(r)
+	return r
+}
+
 func TestHandler_handleRequest(t *testing.T) {
 	specBytes := loadTestSpec(t, "petstore.yml")
 	h, err := newHandler(specBytes)
@@ -114,6 +135,135 @@ func TestHandler_handleRequest(t *testing.T) {
 	})
 }
 
+func TestHandler_Validate(t *testing.T) {
+	specBytes := loadTestSpec(t, "petstore.yml")
+	h, err := newHandler(specBytes)
+	require.NoError(t, err)
+	h.validate = &config.ValidateConfig{Request: true, Response: true}
+
+	r := newCheckedRouter(h)
+
+	t.Run("rejects invalid path params with problem details", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/pets/abc", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+		var problem api.ProblemDetails
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "Bad Request", problem.Title)
+		assert.Equal(t, []*schema.ValidationError{
+			{In: "path", Field: "petId", Message: "expected integer, got string"},
+		}, problem.Errors)
+	})
+
+	t.Run("rejects invalid body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pets", bytes.NewBufferString(`{"id":"1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var problem api.ProblemDetails
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.ElementsMatch(t, []*schema.ValidationError{
+			{In: "body", Field: "$.id", Message: "expected integer, got string"},
+			{In: "body", Field: "$.name", Message: "required property is missing"},
+		}, problem.Errors)
+	})
+
+	t.Run("serves valid requests with validated responses", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pets", bytes.NewBufferString(`{"id":1,"name":"Rex"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "true", w.Header().Get("X-Validated"))
+	})
+
+	t.Run("accepts anything when disabled", func(t *testing.T) {
+		plain, err := newHandler(specBytes)
+		require.NoError(t, err)
+
+		router := chi.NewRouter()
+		plain.RegisterRoutes(router)
+
+		req := httptest.NewRequest(http.MethodGet, "/pets/abc", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-Validated"))
+	})
+}
+
+func TestHandler_Security(t *testing.T) {
+	specBytes := []byte(`
+openapi: 3.0.0
+info:
+  title: Secured
+  version: "1.0"
+security:
+  - bearerAuth: []
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: string
+components:
+  securitySchemes:
+    bearerAuth:
+      type: http
+      scheme: bearer
+`)
+	h, err := newHandler(specBytes)
+	require.NoError(t, err)
+	h.security, err = security.New(specBytes, &config.SecurityConfig{Enforce: true, Tokens: []string{"secret"}}, "pets")
+	require.NoError(t, err)
+
+	r := newCheckedRouter(h)
+
+	t.Run("returns 401 without credentials", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Bearer realm="pets"`, w.Header().Get("WWW-Authenticate"))
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("returns 403 for unknown token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+		req.Header.Set("Authorization", "Bearer other")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("serves requests with accepted token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
 func TestHandler_MountedUnderServicePrefix(t *testing.T) {
 	specBytes := loadTestSpec(t, "petstore.yml")
 	h, err := newHandler(specBytes)
@@ -222,7 +372,7 @@ func TestRegisterService(t *testing.T) {
 	router := testRouter(t)
 	handlers := make(map[string]*swappableHandler)
 
-	err := registerService(router, specPath, nil, nil, handlers)
+	err := registerService(router, specPath, nil, nil, handlers, nil)
 	require.NoError(t, err)
 
 	assert.Contains(t, handlers, "petstore")
@@ -236,6 +386,83 @@ func TestRegisterService(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestRegisterService_RequestChecks(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "pets.yml")
+	require.NoError(t, os.WriteFile(specPath, []byte(`
+openapi: 3.0.0
+info:
+  title: Secured
+  version: "1.0"
+security:
+  - bearerAuth: []
+paths:
+  /pets:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+      responses:
+        "201":
+          description: created
+components:
+  securitySchemes:
+    bearerAuth:
+      type: http
+      scheme: bearer
+`), 0644))
+
+	svcCfg, err := config.NewServiceConfigFromBytes([]byte(`
+security:
+  enforce: true
+  tokens: [secret]
+validate:
+  request: true
+rules:
+  - method: POST
+    path: /pets
+    response:
+      status: 202
+      body: '{"queued":true}'
+`))
+	require.NoError(t, err)
+
+	router := testRouter(t)
+	require.NoError(t, registerService(router, specPath, svcCfg, nil, make(map[string]*swappableHandler), nil))
+
+	send := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/pets/pets", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("rejects unauthenticated requests before rules answer", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, send("", `{"name":"Rex"}`).Code)
+		assert.Equal(t, http.StatusForbidden, send("other", `{"name":"Rex"}`).Code)
+	})
+
+	t.Run("rejects invalid requests before rules answer", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("secret", `{}`).Code)
+	})
+
+	t.Run("lets rules answer checked requests", func(t *testing.T) {
+		w := send("secret", `{"name":"Rex"}`)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.JSONEq(t, `{"queued":true}`, w.Body.String())
+	})
+}
+
 func TestBuildHandler(t *testing.T) {
 	specBytes := loadTestSpec(t, "petstore.yml")
 
@@ -244,13 +471,13 @@ func TestBuildHandler(t *testing.T) {
 	require.NoError(t, os.WriteFile(specPath, specBytes, 0644))
 
 	t.Run("builds from valid spec file", func(t *testing.T) {
-		h, err := buildHandler(specPath, nil)
+		h, err := buildHandler(specPath, nil, nil)
 		require.NoError(t, err)
 		assert.NotEmpty(t, h.Routes())
 	})
 
 	t.Run("returns error for missing file", func(t *testing.T) {
-		_, err := buildHandler("/nonexistent/spec.yml", nil)
+		_, err := buildHandler("/nonexistent/spec.yml", nil, nil)
 		assert.Error(t, err)
 	})
 }
@@ -317,7 +544,7 @@ func TestIntegration_EndToEnd(t *testing.T) {
 	_ = api.CreateHistoryRoutes(router)
 	handlers := make(map[string]*swappableHandler)
 
-	err := registerService(router, specPath, nil, nil, handlers)
+	err := registerService(router, specPath, nil, nil, handlers, nil)
 	require.NoError(t, err)
 
 	ts := httptest.NewServer(router)
@@ -408,3 +635,157 @@ func TestIntegration_EndToEnd(t *testing.T) {
 		assert.Equal(t, "/pets", gen.Path)
 	})
 }
+
+func TestRegisterService_GraphQL(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "pets.graphql")
+	require.NoError(t, os.WriteFile(specPath, loadTestSpec(t, "pets.graphql"), 0644))
+
+	router := testRouter(t)
+	handlers := make(map[string]*swappableHandler)
+
+	svcCfg := &config.ServiceConfig{
+		Generation: &config.GenerationConfig{ListSize: &config.ListSize{Min: 2, Max: 2}},
+	}
+	err := registerService(router, specPath, svcCfg, nil, handlers, nil)
+	require.NoError(t, err)
+	require.Contains(t, handlers, "pets")
+
+	t.Run("serves the graphql endpoint", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pets/graphql", bytes.NewReader([]byte(`{"query":"{ pets { __typename id } }"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Data struct {
+				Pets []map[string]any `json:"pets"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Len(t, res.Data.Pets, 2)
+		assert.Equal(t, "Pet", res.Data.Pets[0]["__typename"])
+	})
+
+	t.Run("404 for other paths", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pets/other", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("hot-swaps the schema", func(t *testing.T) {
+		sdl := []byte("type Query { version: Int! }")
+		require.NoError(t, os.WriteFile(specPath, sdl, 0644))
+
+		h, err := buildHandler(specPath, svcCfg, nil)
+		require.NoError(t, err)
+		handlers["pets"].swap(h)
+
+		req := httptest.NewRequest(http.MethodPost, "/pets/graphql", bytes.NewReader([]byte(`{"query":"{ version }"}`)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"version"`)
+	})
+}
+
+func TestRegisterService_GRPC(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "pets.protoset")
+	require.NoError(t, os.WriteFile(specPath, loadTestSpec(t, "pets.protoset"), 0644))
+
+	t.Run("requires a gRPC server", func(t *testing.T) {
+		err := registerService(testRouter(t), specPath, nil, nil, make(map[string]*swappableHandler), nil)
+		assert.Error(t, err)
+	})
+
+	router := testRouter(t)
+	handlers := make(map[string]*swappableHandler)
+	grpcServer := grpc.NewServer()
+
+	err := registerService(router, specPath, nil, nil, handlers, grpcServer)
+	require.NoError(t, err)
+	require.Contains(t, handlers, "pets")
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = grpcServer.Serve(lis) }()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpclib.NewClient(lis.Addr().String(), grpclib.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	md, ok := handlers["pets"].handler.(*grpc.Service).Method("/pets.v1.PetService/GetPet")
+	require.True(t, ok)
+	invoke := func(method string) error {
+		return conn.Invoke(context.Background(), method, dynamicpb.NewMessage(md.Input()), dynamicpb.NewMessage(md.Output()))
+	}
+
+	t.Run("serves methods over gRPC", func(t *testing.T) {
+		assert.NoError(t, invoke("/pets.v1.PetService/GetPet"))
+		assert.Equal(t, codes.Unimplemented, status.Code(invoke("/pets.v1.PetService/Missing")))
+	})
+
+	t.Run("serves methods as JSON over HTTP", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pets/pets.v1.PetService/GetPet", bytes.NewReader([]byte(`{"id": "1"}`)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"name"`)
+	})
+
+	t.Run("hot-swap re-registers the service", func(t *testing.T) {
+		before := handlers["pets"].handler
+		reloadSpec(specPath, router, &portableConfig{}, nil, handlers, grpcServer)
+
+		assert.NotSame(t, before, handlers["pets"].handler)
+		assert.NoError(t, invoke("/pets.v1.PetService/GetPet"))
+	})
+}
+
+func TestRegisterService_SOAP(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "calculator.wsdl")
+	require.NoError(t, os.WriteFile(specPath, loadTestSpec(t, "calculator.wsdl"), 0644))
+
+	addRequest := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+<soap:Body><Add xmlns="urn:example:calculator"><a>1</a><b>2</b></Add></soap:Body></soap:Envelope>`
+
+	t.Run("serves operations", func(t *testing.T) {
+		router := testRouter(t)
+		handlers := make(map[string]*swappableHandler)
+		require.NoError(t, registerService(router, specPath, nil, nil, handlers, nil))
+		require.Contains(t, handlers, "calculator")
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/calculator", bytes.NewReader([]byte(addRequest)))
+		req.Header.Set("Content-Type", "text/xml")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "AddResponse")
+	})
+
+	t.Run("simulated errors are SOAP Faults", func(t *testing.T) {
+		svcCfg, err := config.NewServiceConfigFromBytes([]byte("errors:\n  p100: 500\n"))
+		require.NoError(t, err)
+
+		router := testRouter(t)
+		require.NoError(t, registerService(router, specPath, svcCfg, nil, make(map[string]*swappableHandler), nil))
+
+		req := httptest.NewRequest(http.MethodPost, "/calculator/calculator", bytes.NewReader([]byte(addRequest)))
+		req.Header.Set("Content-Type", "text/xml")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "<faultcode>soap:Server</faultcode>")
+	})
+}
@@ -0,0 +1,73 @@
+package portable
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	cmdapi "github.com/mockzilla/connexions/v2/cmd/api"
+)
+
+// RunImport runs the import command: `import har <file> [-output dir] [-name service]`.
+// The HAR entries are written as static services to <output>/static/<service>,
+// so the output directory can be served with `connexions <output>`.
+func RunImport(args []string) int {
+	if len(args) == 0 || args[0] != "har" {
+		log.Println("Usage: connexions import har <file> [-output dir] [-name service]")
+		return exitCodeError
+	}
+
+	fs := flag.NewFlagSet("import har", flag.ContinueOnError)
+	output := fs.String("output", ".", "Directory to write the static/ services to")
+	name := fs.String("name", "", "Service name for all entries (default: derived from each host)")
+
+	fl, positional := splitArgs(args[1:])
+	if err := fs.Parse(fl); err != nil {
+		return exitCodeError
+	}
+	if len(positional) != 1 {
+		log.Println("Usage: connexions import har <file> [-output dir] [-name service]")
+		return exitCodeError
+	}
+
+	res, err := importHAR(positional[0], filepath.Join(*output, "static"), *name)
+	if err != nil {
+		log.Printf("Failed to import %s: %v", positional[0], err)
+		return exitCodeError
+	}
+
+	for _, svc := range slices.Sorted(maps.Keys(res.Services)) {
+		fmt.Printf("Imported %d response(s) into %s\n", res.Services[svc], filepath.Join(*output, "static", svc))
+	}
+	fmt.Printf("Dropped %d duplicate(s), skipped %d entry(ies)\n", res.Duplicates, res.Skipped)
+	return exitCodeShutdown
+}
+
+// importHAR imports a HAR file into static service directories under staticDir.
+func importHAR(path, staticDir, serviceName string) (*cmdapi.HARImportResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading HAR: %w", err)
+	}
+	return cmdapi.ImportHAR(data, staticDir, serviceName)
+}
+
+// resolveHARSpecs imports a HAR file given with --har into static services under dir
+// and returns the specs generated from them.
+// The specs embed the responses, so dir can be removed once they're generated.
+func resolveHARSpecs(path, dir string) []string {
+	res, err := importHAR(path, filepath.Join(dir, "static"), "")
+	if err != nil {
+		slog.Error("Failed to import HAR", "path", path, "error", err)
+		return nil
+	}
+	slog.Info("Imported HAR", "path", path, "services", len(res.Services),
+		"duplicates", res.Duplicates, "skipped", res.Skipped)
+
+	return resolveStaticSpecs(dir, nil)
+}
@@ -0,0 +1,70 @@
+package portable
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHAR = `{"log": {"version": "1.2", "entries": [
+	{"request": {"method": "GET", "url": "https://api.example.com/users/1"},
+	 "response": {"status": 200, "headers": [{"name": "Content-Type", "value": "application/json"}, {"name": "Date", "value": "today"}],
+	              "content": {"mimeType": "application/json", "text": "{\"id\": 1, \"name\": \"Jane\"}"}}}
+]}}`
+
+func TestRunImport(t *testing.T) {
+	harPath := filepath.Join(t.TempDir(), "session.har")
+	require.NoError(t, os.WriteFile(harPath, []byte(testHAR), 0o644))
+
+	t.Run("imports into static layout", func(t *testing.T) {
+		out := t.TempDir()
+		assert.Equal(t, exitCodeShutdown, RunImport([]string{"har", harPath, "-output", out}))
+		assert.FileExists(t, filepath.Join(out, "static", "api_example_com", "get", "users", "1", "index.json"))
+		assert.True(t, IsPortableMode([]string{out}))
+	})
+
+	t.Run("uses service name", func(t *testing.T) {
+		out := t.TempDir()
+		assert.Equal(t, exitCodeShutdown, RunImport([]string{"har", "-name", "users", "-output", out, harPath}))
+		assert.DirExists(t, filepath.Join(out, "static", "users"))
+	})
+
+	t.Run("requires har format and file", func(t *testing.T) {
+		assert.Equal(t, exitCodeError, RunImport(nil))
+		assert.Equal(t, exitCodeError, RunImport([]string{"curl", harPath}))
+		assert.Equal(t, exitCodeError, RunImport([]string{"har"}))
+	})
+
+	t.Run("fails for missing file", func(t *testing.T) {
+		assert.Equal(t, exitCodeError, RunImport([]string{"har", filepath.Join(t.TempDir(), "missing.har")}))
+	})
+}
+
+func TestResolveHARSpecs(t *testing.T) {
+	t.Run("generates a spec per host", func(t *testing.T) {
+		harPath := filepath.Join(t.TempDir(), "session.har")
+		require.NoError(t, os.WriteFile(harPath, []byte(testHAR), 0o644))
+
+		dir := t.TempDir()
+		specs := resolveHARSpecs(harPath, dir)
+		require.Len(t, specs, 1)
+		assert.Equal(t, "api_example_com.yml", filepath.Base(specs[0]))
+		assert.DirExists(t, filepath.Join(dir, "static", "api_example_com"))
+
+		// The specs don't need the imported files
+		require.NoError(t, os.RemoveAll(dir))
+
+		spec, err := os.ReadFile(specs[0])
+		require.NoError(t, err)
+		assert.Contains(t, string(spec), "/users/1:")
+	})
+
+	t.Run("returns nil for invalid HAR", func(t *testing.T) {
+		harPath := filepath.Join(t.TempDir(), "broken.har")
+		require.NoError(t, os.WriteFile(harPath, []byte("{"), 0o644))
+		assert.Nil(t, resolveHARSpecs(harPath, t.TempDir()))
+	})
+}
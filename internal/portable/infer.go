@@ -0,0 +1,91 @@
+package portable
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/har"
+	"github.com/mockzilla/connexions/v2/pkg/infer"
+)
+
+const inferUsage = "Usage: connexions infer har <file> [-title name] [-base-path path] [-output file]\n" +
+	"       connexions infer history <service> [-server url] [-history-url path] [-output file]"
+
+// RunInfer runs the infer command, writing an OpenAPI 3.1 spec inferred from observed traffic
+// to -output or stdout:
+//   - `infer har <file>` reads the requests of a HAR capture
+//   - `infer history <service>` asks a running server to infer the spec from the history of a service
+//
+// The spec can be served with `connexions <file>`.
+func RunInfer(args []string) int {
+	if len(args) == 0 || (args[0] != "har" && args[0] != "history") {
+		log.Println(inferUsage)
+		return exitCodeError
+	}
+	source := args[0]
+
+	fs := flag.NewFlagSet("infer "+source, flag.ContinueOnError)
+	output := fs.String("output", "", "File to write the spec to (default: stdout)")
+	title := fs.String("title", "", "Title of the spec (default: derived from the input)")
+	basePath := fs.String("base-path", "", "Path prefix to strip from request paths")
+	server := fs.String("server", "http://localhost:2200", "Base URL of the running server")
+	historyURL := fs.String("history-url", config.DefaultHistoryURL, "History URL of the running server")
+
+	fl, positional := splitArgs(args[1:])
+	if err := fs.Parse(fl); err != nil {
+		return exitCodeError
+	}
+	if len(positional) != 1 {
+		log.Println(inferUsage)
+		return exitCodeError
+	}
+
+	var (
+		spec []byte
+		err  error
+	)
+	switch source {
+	case "har":
+		name := *title
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(positional[0]), filepath.Ext(positional[0]))
+		}
+		spec, err = inferFromHAR(positional[0], infer.Options{Title: name, BasePath: *basePath})
+	case "history":
+		spec, err = fetchHistory(*server, *historyURL, positional[0], "openapi")
+	}
+	if err != nil {
+		log.Printf("Failed to infer spec: %v", err)
+		return exitCodeError
+	}
+
+	if *output == "" {
+		_, _ = os.Stdout.Write(spec)
+		return exitCodeShutdown
+	}
+
+	if err := os.WriteFile(*output, spec, 0o644); err != nil {
+		log.Printf("Failed to write %s: %v", *output, err)
+		return exitCodeError
+	}
+	fmt.Printf("Inferred spec written to %s\n", *output)
+	return exitCodeShutdown
+}
+
+// inferFromHAR infers a spec from the entries of a HAR file.
+func inferFromHAR(path string, opts infer.Options) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading HAR: %w", err)
+	}
+	doc, err := har.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return infer.FromHAR(doc, opts)
+}
@@ -0,0 +1,55 @@
+package portable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunInfer(t *testing.T) {
+	harPath := filepath.Join(t.TempDir(), "session.har")
+	require.NoError(t, os.WriteFile(harPath, []byte(testHAR), 0o644))
+
+	t.Run("infers spec from HAR", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "users.yml")
+		assert.Equal(t, exitCodeShutdown, RunInfer([]string{"har", harPath, "-output", out}))
+
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "openapi: 3.1.0")
+		assert.Contains(t, string(data), "title: session")
+		assert.Contains(t, string(data), "/users/{id}:")
+	})
+
+	t.Run("infers spec from server history", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/.history/petstore" || r.URL.Query().Get("format") != "openapi" {
+				http.Error(w, "Service not found", http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte("openapi: 3.1.0\n"))
+		}))
+		defer srv.Close()
+
+		out := filepath.Join(t.TempDir(), "petstore.yml")
+		assert.Equal(t, exitCodeShutdown, RunInfer([]string{"history", "petstore", "-server", srv.URL, "-output", out}))
+
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, "openapi: 3.1.0\n", string(data))
+
+		assert.Equal(t, exitCodeError, RunInfer([]string{"history", "missing", "-server", srv.URL, "-output", out}))
+	})
+
+	t.Run("requires source and input", func(t *testing.T) {
+		assert.Equal(t, exitCodeError, RunInfer(nil))
+		assert.Equal(t, exitCodeError, RunInfer([]string{"curl", harPath}))
+		assert.Equal(t, exitCodeError, RunInfer([]string{"har"}))
+		assert.Equal(t, exitCodeError, RunInfer([]string{"har", filepath.Join(t.TempDir(), "missing.har")}))
+	})
+}
@@ -12,19 +12,37 @@ import (
 	"strings"
 
 	cmdapi "github.com/mockzilla/connexions/v2/cmd/api"
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
 )
 
 // flags holds the parsed CLI flags for portable mode.
 type flags struct {
-	port    int
-	config  string // unified app+services config
-	context string // per-service contexts
+	port     int
+	grpcPort int
+	config   string // unified app+services config
+	context  string // per-service contexts
+	har      string // HAR file imported as static services
+
+	tls           bool // serve HTTPS with a generated certificate
+	tlsCert       string
+	tlsKey        string
+	tlsClientAuth string
+	tlsClientCA   string
+}
+
+// boolFlags are the flags without a value.
+var boolFlags = map[string]bool{
+	"tls": true,
 }
 
 // IsPortableMode determines if the CLI args indicate portable mode.
 // Returns true if any positional arg is a spec file, a URL, or a directory containing spec files.
 func IsPortableMode(args []string) bool {
 	for _, arg := range args {
+		if arg == "--har" || arg == "-har" || strings.HasPrefix(arg, "--har=") || strings.HasPrefix(arg, "-har=") {
+			return true
+		}
 		if strings.HasPrefix(arg, "-") {
 			continue
 		}
@@ -52,15 +70,32 @@ func IsPortableMode(args []string) bool {
 	return false
 }
 
-// isSpecFile checks if a filename is an OpenAPI spec file.
+// isSpecFile checks if a filename is an OpenAPI spec, a GraphQL schema or a protobuf descriptor set file.
 func isSpecFile(name string) bool {
-	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".json")
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".json") ||
+		isGraphQLFile(name) || isGRPCFile(name) || isWSDLFile(name)
+}
+
+// isGraphQLFile checks if a filename is a GraphQL schema (SDL) file.
+func isGraphQLFile(name string) bool {
+	return strings.HasSuffix(name, ".graphql") || strings.HasSuffix(name, ".graphqls") || strings.HasSuffix(name, ".gql")
+}
+
+// isGRPCFile checks if a filename is a serialized protobuf FileDescriptorSet.
+func isGRPCFile(name string) bool {
+	return strings.HasSuffix(name, ".protoset") || strings.HasSuffix(name, ".binpb") ||
+		strings.HasSuffix(name, ".pb") || strings.HasSuffix(name, ".desc")
+}
+
+// isWSDLFile checks if a filename is a WSDL service description.
+func isWSDLFile(name string) bool {
+	return strings.HasSuffix(name, ".wsdl")
 }
 
 // resolveSpecs examines the positional args and returns spec file paths.
 // URL arguments are downloaded to a temp directory and resolved to local paths.
 func resolveSpecs(args []string) []string {
-	var specs []string
+	var specs, dirs []string
 	for _, arg := range args {
 		if strings.HasPrefix(arg, "-") {
 			continue
@@ -79,7 +114,7 @@ func resolveSpecs(args []string) []string {
 			continue
 		}
 		if info.IsDir() {
-			specs = append(specs, resolveStaticSpecs(arg)...)
+			dirs = append(dirs, arg)
 			entries, err := os.ReadDir(arg)
 			if err != nil {
 				continue
@@ -93,6 +128,16 @@ func resolveSpecs(args []string) []string {
 			specs = append(specs, arg)
 		}
 	}
+
+	// Spec files win over static services of the same name,
+	// e.g. a recorded directory next to the spec regenerated from it
+	taken := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		taken[api.NormalizeServiceName(spec)] = true
+	}
+	for _, dir := range dirs {
+		specs = append(specs, resolveStaticSpecs(dir, taken)...)
+	}
 	return specs
 }
 
@@ -101,17 +146,55 @@ func parseFlags(args []string) (flags, []string) {
 	fs := flag.NewFlagSet("portable", flag.ContinueOnError)
 	fl := flags{}
 	fs.IntVar(&fl.port, "port", 0, "Server port (default: from app config or 2200)")
+	fs.IntVar(&fl.grpcPort, "grpc-port", 0, "gRPC server port (default: from app config or 2201)")
 	fs.StringVar(&fl.config, "config", "", "Unified config YAML (app settings + per-service config)")
 	fs.StringVar(&fl.context, "context", "", "Per-service context YAML for value replacements")
+	fs.StringVar(&fl.har, "har", "", "HAR file to serve as static services, one per host")
+	fs.BoolVar(&fl.tls, "tls", false, "Serve HTTPS with HTTP/2, using a generated certificate unless -tls-cert is set")
+	fs.StringVar(&fl.tlsCert, "tls-cert", "", "PEM certificate file for HTTPS")
+	fs.StringVar(&fl.tlsKey, "tls-key", "", "PEM key file of the -tls-cert certificate")
+	fs.StringVar(&fl.tlsClientAuth, "tls-client-auth", "", "Verify client certificates: request or require")
+	fs.StringVar(&fl.tlsClientCA, "tls-client-ca", "", "PEM file of the CAs verifying client certificates (default: the generated CA)")
+
+	flagArgs, positional := splitArgs(args)
+	if err := fs.Parse(flagArgs); err != nil {
+		slog.Warn("Failed to parse flags", "error", err)
+	}
 
-	// Separate positional args from flags
-	var positional []string
-	var flagArgs []string
+	return fl, positional
+}
+
+// applyTLSFlags enables TLS in the app config when the TLS flags are set.
+// The flags win over the app config.
+func applyTLSFlags(appCfg *config.AppConfig, fl flags) {
+	if !fl.tls && fl.tlsCert == "" && fl.tlsClientAuth == "" {
+		return
+	}
+	if appCfg.TLS == nil {
+		appCfg.TLS = &config.TLSConfig{}
+	}
+
+	appCfg.TLS.Enabled = true
+	if fl.tlsCert != "" {
+		appCfg.TLS.CertFile = fl.tlsCert
+		appCfg.TLS.KeyFile = fl.tlsKey
+	}
+	if fl.tlsClientAuth != "" {
+		appCfg.TLS.ClientAuth = fl.tlsClientAuth
+	}
+	if fl.tlsClientCA != "" {
+		appCfg.TLS.ClientCAFile = fl.tlsClientCA
+	}
+}
+
+// splitArgs separates flags and their values from positional args.
+func splitArgs(args []string) (flagArgs, positional []string) {
 	for i := 0; i < len(args); i++ {
 		if strings.HasPrefix(args[i], "-") {
 			flagArgs = append(flagArgs, args[i])
 			// If this flag takes a value (not a boolean), consume next arg too
-			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && !strings.Contains(args[i], "=") {
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && !strings.Contains(args[i], "=") &&
+				!boolFlags[strings.TrimLeft(args[i], "-")] {
 				flagArgs = append(flagArgs, args[i+1])
 				i++
 			}
@@ -119,12 +202,7 @@ func parseFlags(args []string) (flags, []string) {
 			positional = append(positional, args[i])
 		}
 	}
-
-	if err := fs.Parse(flagArgs); err != nil {
-		slog.Warn("Failed to parse flags", "error", err)
-	}
-
-	return fl, positional
+	return flagArgs, positional
 }
 
 // isURL checks if a string is an HTTP or HTTPS URL.
@@ -200,7 +278,8 @@ func hasStaticDir(dir string) bool {
 
 // resolveStaticSpecs looks for a "static" subdirectory within dir,
 // converts each service directory into a temporary OpenAPI spec, and returns the paths.
-func resolveStaticSpecs(dir string) []string {
+// Service directories whose name is taken are skipped.
+func resolveStaticSpecs(dir string, taken map[string]bool) []string {
 	staticDir := filepath.Join(dir, "static")
 	entries, err := os.ReadDir(staticDir)
 	if err != nil {
@@ -220,6 +299,10 @@ func resolveStaticSpecs(dir string) []string {
 		}
 		serviceName := e.Name()
 		serviceDir := filepath.Join(staticDir, serviceName)
+		if taken[api.NormalizeServiceName(serviceName)] {
+			slog.Info("Skipping static service served from a spec file", "service", serviceName, "dir", serviceDir)
+			continue
+		}
 
 		specBytes, err := cmdapi.GenerateSpecFromStaticDir(serviceDir, serviceName)
 		if err != nil {
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/mockzilla/connexions/v2/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -78,6 +79,13 @@ func TestIsSpecFile(t *testing.T) {
 	assert.True(t, isSpecFile("petstore.yaml"))
 	assert.True(t, isSpecFile("petstore.yml"))
 	assert.True(t, isSpecFile("petstore.json"))
+	assert.True(t, isSpecFile("petstore.graphql"))
+	assert.True(t, isSpecFile("petstore.graphqls"))
+	assert.True(t, isSpecFile("petstore.gql"))
+	assert.True(t, isSpecFile("petstore.protoset"))
+	assert.True(t, isSpecFile("petstore.binpb"))
+	assert.True(t, isSpecFile("petstore.pb"))
+	assert.True(t, isSpecFile("petstore.wsdl"))
 	assert.False(t, isSpecFile("petstore.go"))
 	assert.False(t, isSpecFile("petstore.txt"))
 	assert.False(t, isSpecFile("petstore"))
@@ -129,6 +137,11 @@ func TestIsPortableMode(t *testing.T) {
 		require.NoError(t, os.WriteFile(filepath.Join(svcDir, "index.json"), []byte(`{"id":1}`), 0o644))
 		assert.True(t, IsPortableMode([]string{staticDir}))
 	})
+
+	t.Run("detects har flag", func(t *testing.T) {
+		assert.True(t, IsPortableMode([]string{"--har", "session.har"}))
+		assert.True(t, IsPortableMode([]string{"--har=session.har"}))
+	})
 }
 
 func TestResolveSpecs(t *testing.T) {
@@ -230,6 +243,23 @@ func TestResolveSpecs(t *testing.T) {
 		specs := resolveSpecs([]string{rootDir})
 		assert.Len(t, specs, 2)
 	})
+
+	t.Run("spec file wins over static dir of the same service", func(t *testing.T) {
+		rootDir := t.TempDir()
+		specPath := filepath.Join(rootDir, "payments.yml")
+		require.NoError(t, os.WriteFile(specPath, []byte("openapi: 3.0.0\ninfo:\n  title: test\n  version: '1'\npaths: {}"), 0o644))
+
+		for _, name := range []string{"payments", "users"} {
+			svcDir := filepath.Join(rootDir, "static", name, "get", "charges")
+			require.NoError(t, os.MkdirAll(svcDir, 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(svcDir, "index.json"), []byte(`{"id":1}`), 0o644))
+		}
+
+		specs := resolveSpecs([]string{rootDir})
+		require.Len(t, specs, 2)
+		assert.Equal(t, specPath, specs[0])
+		assert.Equal(t, "users.yml", filepath.Base(specs[1]))
+	})
 }
 
 func TestHasStaticDir(t *testing.T) {
@@ -258,7 +288,7 @@ func TestHasStaticDir(t *testing.T) {
 
 func TestResolveStaticSpecs(t *testing.T) {
 	t.Run("returns nil for dir without static subdir", func(t *testing.T) {
-		assert.Nil(t, resolveStaticSpecs(t.TempDir()))
+		assert.Nil(t, resolveStaticSpecs(t.TempDir(), nil))
 	})
 
 	t.Run("skips non-directory entries in static dir", func(t *testing.T) {
@@ -266,14 +296,14 @@ func TestResolveStaticSpecs(t *testing.T) {
 		staticDir := filepath.Join(dir, "static")
 		require.NoError(t, os.MkdirAll(staticDir, 0o755))
 		require.NoError(t, os.WriteFile(filepath.Join(staticDir, "readme.txt"), []byte("hi"), 0o644))
-		specs := resolveStaticSpecs(dir)
+		specs := resolveStaticSpecs(dir, nil)
 		assert.Empty(t, specs)
 	})
 
 	t.Run("skips service dirs with no static files", func(t *testing.T) {
 		dir := t.TempDir()
 		require.NoError(t, os.MkdirAll(filepath.Join(dir, "static", "empty-svc"), 0o755))
-		specs := resolveStaticSpecs(dir)
+		specs := resolveStaticSpecs(dir, nil)
 		assert.Empty(t, specs)
 	})
 }
@@ -283,12 +313,32 @@ func TestParseFlags(t *testing.T) {
 		fl, positional := parseFlags([]string{
 			"petstore.yml",
 			"--port", "3000",
+			"--grpc-port", "3001",
 			"--config", "config.yml",
 			"--context", "ctx.yml",
+			"--har", "session.har",
 		})
 		assert.Equal(t, 3000, fl.port)
+		assert.Equal(t, 3001, fl.grpcPort)
 		assert.Equal(t, "config.yml", fl.config)
 		assert.Equal(t, "ctx.yml", fl.context)
+		assert.Equal(t, "session.har", fl.har)
+		assert.Equal(t, []string{"petstore.yml"}, positional)
+	})
+
+	t.Run("parses TLS flags", func(t *testing.T) {
+		fl, positional := parseFlags([]string{
+			"--tls", "petstore.yml",
+			"--tls-cert", "cert.pem",
+			"--tls-key", "key.pem",
+			"--tls-client-auth", "require",
+			"--tls-client-ca", "ca.pem",
+		})
+		assert.True(t, fl.tls)
+		assert.Equal(t, "cert.pem", fl.tlsCert)
+		assert.Equal(t, "key.pem", fl.tlsKey)
+		assert.Equal(t, "require", fl.tlsClientAuth)
+		assert.Equal(t, "ca.pem", fl.tlsClientCA)
 		assert.Equal(t, []string{"petstore.yml"}, positional)
 	})
 
@@ -309,3 +359,25 @@ func TestParseFlags(t *testing.T) {
 		assert.Equal(t, []string{"petstore.yml", "stripe.yml"}, positional)
 	})
 }
+
+func TestApplyTLSFlags(t *testing.T) {
+	t.Run("no flags keep the app config", func(t *testing.T) {
+		appCfg := config.NewDefaultAppConfig(t.TempDir())
+		applyTLSFlags(appCfg, flags{})
+		assert.Nil(t, appCfg.TLS)
+	})
+
+	t.Run("flags win over the app config", func(t *testing.T) {
+		appCfg := config.NewDefaultAppConfig(t.TempDir())
+		appCfg.TLS = &config.TLSConfig{Hosts: []string{"mock.local"}, ClientAuth: config.ClientAuthRequest}
+
+		applyTLSFlags(appCfg, flags{tlsCert: "cert.pem", tlsKey: "key.pem", tlsClientAuth: "require"})
+		assert.Equal(t, &config.TLSConfig{
+			Enabled:    true,
+			CertFile:   "cert.pem",
+			KeyFile:    "key.pem",
+			Hosts:      []string{"mock.local"},
+			ClientAuth: "require",
+		}, appCfg.TLS)
+	})
+}
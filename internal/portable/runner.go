@@ -16,8 +16,9 @@ import (
 
 	"github.com/lmittmann/tint"
 	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/certs"
 	"github.com/mockzilla/connexions/v2/pkg/config"
-	"github.com/mockzilla/connexions/v2/pkg/factory"
+	"github.com/mockzilla/connexions/v2/pkg/grpc"
 )
 
 const (
@@ -36,6 +37,15 @@ func Run(args []string) int {
 
 	fl, positional := parseFlags(args)
 	specs := resolveSpecs(positional)
+	if fl.har != "" {
+		harDir, err := os.MkdirTemp("", "connexions-portable-har-*")
+		if err != nil {
+			log.Printf("Failed to create temp dir for HAR import: %v", err)
+			return exitCodeError
+		}
+		defer func() { _ = os.RemoveAll(harDir) }()
+		specs = append(specs, resolveHARSpecs(fl.har, harDir)...)
+	}
 	if len(specs) == 0 {
 		log.Println("No OpenAPI spec files found")
 		return exitCodeError
@@ -71,6 +81,19 @@ func Run(args []string) int {
 	if appCfg.Port == 0 {
 		appCfg.Port = 2200
 	}
+	if fl.grpcPort > 0 {
+		appCfg.GRPCPort = fl.grpcPort
+	}
+	if appCfg.GRPCPort == 0 {
+		appCfg.GRPCPort = 2201
+	}
+	applyTLSFlags(appCfg, fl)
+
+	tlsCfg, err := certs.ServerConfig(appCfg.TLS, appCfg.Paths.Data)
+	if err != nil {
+		log.Printf("Failed to set up TLS: %v", err)
+		return exitCodeError
+	}
 
 	// Create router
 	router := api.NewRouter(api.WithConfigOption(appCfg))
@@ -78,17 +101,30 @@ func Run(args []string) int {
 	_ = api.CreateHomeRoutes(router)
 	_ = api.CreateServiceRoutes(router)
 	_ = api.CreateHistoryRoutes(router)
+	_ = api.CreateDriftRoutes(router)
+	_ = api.CreateAdminRoutes(router)
+	_ = api.CreateVerifyRoutes(router)
+	if err := api.CreateOIDCRoutes(router); err != nil {
+		log.Printf("Failed to start OIDC provider: %v", err)
+		return exitCodeError
+	}
 
 	// Track swappable handlers for hot reload
 	handlers := make(map[string]*swappableHandler)
 
+	// Server errors end the run
+	errs := make(chan error, 2)
+
+	// gRPC services are served on their own port
+	grpcServer := newGRPCListener(appCfg.GRPCPort, errs)
+
 	// Register each spec as a service
 	for _, specPath := range specs {
 		name := api.NormalizeServiceName(specPath)
 		svcCfg := cfg.Services[name]
 		ctxBytes := contexts[name]
 
-		if err := registerService(router, specPath, svcCfg, ctxBytes, handlers); err != nil {
+		if err := registerService(router, specPath, svcCfg, ctxBytes, handlers, grpcServer, cfg.serviceOptions(name)...); err != nil {
 			log.Printf("Failed to register %s: %v", specPath, err)
 			continue
 		}
@@ -112,38 +148,55 @@ func Run(args []string) int {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		TLSConfig:    tlsCfg,
 	}
 
 	go func() {
-		log.Printf("Connexions portable mode on http://localhost:%d%s", appCfg.Port, appCfg.HomeURL)
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("Server failed: %v", err)
+		var err error
+		if tlsCfg == nil {
+			log.Printf("Connexions portable mode on http://localhost:%d%s", appCfg.Port, appCfg.HomeURL)
+			err = server.ListenAndServe()
+		} else {
+			log.Printf("Connexions portable mode on https://localhost:%d%s", appCfg.Port, appCfg.HomeURL)
+			err = server.ListenAndServeTLS("", "")
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("server failed: %w", err)
 		}
 	}()
 
 	// Start file watcher
-	go watchSpecs(specs, router, cfg, contexts, handlers)
+	go watchSpecs(specs, router, cfg, contexts, handlers, grpcServer)
 
-	// Wait for shutdown signal
+	// Wait for shutdown signal or a server error
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
 
-	log.Println("Shutting down...")
+	exitCode := exitCodeShutdown
+	select {
+	case <-quit:
+		log.Println("Shutting down...")
+	case err := <-errs:
+		log.Printf("Shutting down: %v", err)
+		exitCode = exitCodeError
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	grpcServer.GracefulStop()
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 		return exitCodeError
 	}
 
 	log.Println("Server exited")
-	return exitCodeShutdown
+	return exitCode
 }
 
 // RunFS extracts an fs.FS to a temp directory and runs portable mode.
-// The FS root should contain OpenAPI spec files (*.yml, *.yaml, *.json),
+// The FS root should contain OpenAPI spec files (*.yml, *.yaml, *.json), GraphQL schemas (*.graphql)
+// or protobuf descriptor sets (*.protoset),
 // and optionally: static/, app.yml, context.yml.
 func RunFS(fsys fs.FS, args []string) int {
 	dir, err := os.MkdirTemp("", "connexions-portable-fs-*")
@@ -213,27 +266,21 @@ func registerService(
 	svcCfg *config.ServiceConfig,
 	contextBytes []byte,
 	handlers map[string]*swappableHandler,
+	grpcServer grpcRegistry,
+	opts ...api.HandlerOption,
 ) error {
-	specBytes, err := os.ReadFile(specPath)
-	if err != nil {
-		return fmt.Errorf("reading spec: %w", err)
-	}
-
 	name := api.NormalizeServiceName(specPath)
 
-	// Build factory options
-	var opts []factory.FactoryOption
-	if contextBytes != nil {
-		opts = append(opts, factory.WithServiceContext(contextBytes))
-	}
-	// Enable lazy loading for large specs
-	opts = append(opts, factory.WithSpecOptions(&config.SpecOptions{LazyLoad: true}))
-
-	h, err := newHandler(specBytes, opts...)
+	h, err := buildHandler(specPath, svcCfg, contextBytes)
 	if err != nil {
 		return fmt.Errorf("creating handler: %w", err)
 	}
 
+	grpcSvc, isGRPC := h.(*grpc.Service)
+	if isGRPC && grpcServer == nil {
+		return fmt.Errorf("no gRPC server to serve %s", name)
+	}
+
 	// Build service config: start with defaults, overlay per-service if provided
 	serviceCfg := config.NewServiceConfig()
 	serviceCfg.Name = name
@@ -246,6 +293,10 @@ func registerService(
 	sw := &swappableHandler{handler: h}
 	handlers[name] = sw
 
-	router.RegisterService(serviceCfg, sw)
+	router.RegisterService(serviceCfg, sw, opts...)
+
+	if isGRPC {
+		grpcServer.Register(grpcSvc, serviceCfg, router.GetDB(name))
+	}
 	return nil
 }
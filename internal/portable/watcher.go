@@ -12,6 +12,12 @@ import (
 	"github.com/mockzilla/connexions/v2/pkg/api"
 	"github.com/mockzilla/connexions/v2/pkg/config"
 	"github.com/mockzilla/connexions/v2/pkg/factory"
+	"github.com/mockzilla/connexions/v2/pkg/graphql"
+	"github.com/mockzilla/connexions/v2/pkg/grpc"
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
+	"github.com/mockzilla/connexions/v2/pkg/security"
+	"github.com/mockzilla/connexions/v2/pkg/soap"
+	"github.com/mockzilla/connexions/v2/pkg/typedef"
 )
 
 // watchSpecs watches spec files for changes, hot-swaps existing handlers
@@ -22,6 +28,7 @@ func watchSpecs(
 	cfg *portableConfig,
 	contexts map[string][]byte,
 	handlers map[string]*swappableHandler,
+	grpcServer grpcRegistry,
 ) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -75,7 +82,7 @@ func watchSpecs(
 				mu.Unlock()
 
 				for _, path := range paths {
-					reloadSpec(path, router, cfg, contexts, handlers)
+					reloadSpec(path, router, cfg, contexts, handlers, grpcServer)
 				}
 			})
 			mu.Unlock()
@@ -97,25 +104,32 @@ func reloadSpec(
 	cfg *portableConfig,
 	contexts map[string][]byte,
 	handlers map[string]*swappableHandler,
+	grpcServer grpcRegistry,
 ) {
 	name := api.NormalizeServiceName(specPath)
 	ctxBytes := contexts[name]
 
+	svcCfg := cfg.Services[name]
+
 	// Existing service - hot-swap the handler
 	if sw, ok := handlers[name]; ok {
-		h, err := buildHandler(specPath, ctxBytes)
+		h, err := buildHandler(specPath, svcCfg, ctxBytes)
 		if err != nil {
 			slog.Error("Failed to reload spec", "path", specPath, "error", err)
 			return
 		}
 		sw.swap(h)
+		if svc, ok := h.(*grpc.Service); ok && grpcServer != nil {
+			if item, ok := router.GetServices()[name]; ok {
+				grpcServer.Register(svc, item.Config, router.GetDB(name))
+			}
+		}
 		slog.Info("Reloaded spec", "service", name, "path", specPath)
 		return
 	}
 
 	// New service - register it
-	svcCfg := cfg.Services[name]
-	if err := registerService(router, specPath, svcCfg, ctxBytes, handlers); err != nil {
+	if err := registerService(router, specPath, svcCfg, ctxBytes, handlers, grpcServer, cfg.serviceOptions(name)...); err != nil {
 		slog.Error("Failed to register new spec", "path", specPath, "error", err)
 		return
 	}
@@ -123,17 +137,91 @@ func reloadSpec(
 }
 
 // buildHandler creates a handler from a spec file path.
-func buildHandler(specPath string, contextBytes []byte) (*handler, error) {
+// GraphQL SDL files get a GraphQL handler, protobuf descriptor sets a gRPC service,
+// WSDL documents a SOAP handler, everything else is treated as an OpenAPI spec.
+// Swagger 2.0 specs are converted to OpenAPI 3 first.
+func buildHandler(specPath string, svcCfg *config.ServiceConfig, contextBytes []byte) (serviceHandler, error) {
 	specBytes, err := os.ReadFile(specPath)
 	if err != nil {
 		return nil, fmt.Errorf("reading spec: %w", err)
 	}
 
+	if isGraphQLFile(specPath) {
+		var opts []graphql.Option
+		if contextBytes != nil {
+			opts = append(opts, graphql.WithServiceContext(contextBytes))
+		}
+		if svcCfg != nil {
+			opts = append(opts, graphql.WithGenerationConfig(svcCfg.Generation))
+		}
+
+		h, err := graphql.NewHandler(specBytes, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return graphqlHandler{h}, nil
+	}
+
+	if isGRPCFile(specPath) {
+		var opts []grpc.Option
+		if contextBytes != nil {
+			opts = append(opts, grpc.WithServiceContext(contextBytes))
+		}
+		if svcCfg != nil {
+			opts = append(opts, grpc.WithGenerationConfig(svcCfg.Generation))
+		}
+
+		return grpc.NewService(specBytes, opts...)
+	}
+
+	if isWSDLFile(specPath) {
+		var opts []soap.Option
+		if contextBytes != nil {
+			opts = append(opts, soap.WithServiceContext(contextBytes))
+		}
+		if svcCfg != nil {
+			opts = append(opts, soap.WithGenerationConfig(svcCfg.Generation))
+		}
+
+		h, err := soap.NewHandler(specBytes, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return h, nil
+	}
+
+	if typedef.IsSwagger2(specBytes) {
+		specBytes, err = typedef.ConvertSwagger2(specBytes)
+		if err != nil {
+			return nil, fmt.Errorf("converting Swagger 2.0 spec: %w", err)
+		}
+		slog.Debug("Converted Swagger 2.0 spec to OpenAPI 3", "path", specPath)
+	}
+
 	var opts []factory.FactoryOption
 	if contextBytes != nil {
 		opts = append(opts, factory.WithServiceContext(contextBytes))
 	}
+	// Enable lazy loading for large specs
 	opts = append(opts, factory.WithSpecOptions(&config.SpecOptions{LazyLoad: true}))
 
-	return newHandler(specBytes, opts...)
+	h, err := newHandler(specBytes, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if svcCfg == nil {
+		return h, nil
+	}
+
+	h.validate = svcCfg.Validate
+	if svcCfg.Security != nil {
+		h.security, err = security.New(specBytes, svcCfg.Security, svcCfg.Name)
+		if err != nil {
+			return nil, fmt.Errorf("reading security requirements: %w", err)
+		}
+	}
+	if svcCfg.Claims != nil && svcCfg.Claims.JWKSURL != "" {
+		h.claimsKeys = oidc.NewRemoteKeySet(svcCfg.Claims.JWKSURL)
+	}
+	return h, nil
 }
@@ -70,6 +70,7 @@ var Replacers = []Replacer{
 	replaceInResponse,
 	replaceInHeaders,
 	replaceInPath,
+	replaceInClaims,
 	replaceFromContext,
 	replaceFromSchemaExample,
 	replaceFromSchemaFormat,
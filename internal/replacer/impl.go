@@ -219,6 +219,29 @@ func replaceInPath(ctx *ReplaceContext) any {
 	return replaceInArea(ctx, "path")
 }
 
+// replaceInClaims is a replacer that replaces response body values with the claims
+// of the request's bearer token, found in the in-claims area.
+// Unlike the other areas, claims are matched against the full name path,
+// so nested fields like data.user.email match the email claim.
+func replaceInClaims(ctx *ReplaceContext) any {
+	if !ctx.state.IsContentReadOnly || ctx.state.IsHeader || ctx.areaPrefix == "" {
+		return nil
+	}
+
+	for _, data := range ctx.data {
+		claims, ok := data[ctx.areaPrefix+"claims"]
+		if !ok {
+			continue
+		}
+
+		if res := replaceValueWithContext(ctx.state.NamePath, claims); res != nil {
+			return res
+		}
+	}
+
+	return nil
+}
+
 func replaceInArea(ctx *ReplaceContext, area string) any {
 	ctxAreaPrefix := ctx.areaPrefix
 	if ctxAreaPrefix == "" {
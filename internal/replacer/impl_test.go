@@ -497,6 +497,51 @@ func TestReplaceInPath(t *testing.T) {
 	})
 }
 
+func TestReplaceInClaims(t *testing.T) {
+	assert := assert2.New(t)
+	fake := faker.New()
+
+	data := []map[string]any{
+		{
+			"in-claims": map[string]any{
+				"sub":       "jane",
+				"email":     "jane@example.com",
+				"tenant_id": "acme",
+			},
+		},
+	}
+
+	t.Run("response-field", func(t *testing.T) {
+		state := NewReplaceState(WithName("tenant_id"), WithReadOnly())
+		res := replaceInClaims(&ReplaceContext{faker: fake, state: state, areaPrefix: "in-", data: data})
+		assert.Equal("acme", res)
+	})
+
+	t.Run("nested-field", func(t *testing.T) {
+		state := NewReplaceState(WithName("data"), WithName("user"), WithName("email"), WithReadOnly())
+		res := replaceInClaims(&ReplaceContext{faker: fake, state: state, areaPrefix: "in-", data: data})
+		assert.Equal("jane@example.com", res)
+	})
+
+	t.Run("skipped-for-requests", func(t *testing.T) {
+		state := NewReplaceState(WithName("email"), WithWriteOnly())
+		res := replaceInClaims(&ReplaceContext{faker: fake, state: state, areaPrefix: "in-", data: data})
+		assert.Nil(res)
+	})
+
+	t.Run("skipped-for-headers", func(t *testing.T) {
+		state := NewReplaceState(WithName("email"), WithHeader(), WithReadOnly())
+		res := replaceInClaims(&ReplaceContext{faker: fake, state: state, areaPrefix: "in-", data: data})
+		assert.Nil(res)
+	})
+
+	t.Run("unknown-claim", func(t *testing.T) {
+		state := NewReplaceState(WithName("name"), WithReadOnly())
+		res := replaceInClaims(&ReplaceContext{faker: fake, state: state, areaPrefix: "in-", data: data})
+		assert.Nil(res)
+	})
+}
+
 func TestReplaceInArea(t *testing.T) {
 	assert := assert2.New(t)
 	fake := faker.New()
@@ -0,0 +1,403 @@
+package api
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/middleware"
+)
+
+// AdminURL is the URL of the runtime admin API.
+const AdminURL = "/.admin"
+
+// CreateAdminRoutes adds the runtime admin routes to the router:
+// GET /.admin/{service}/scenarios lists the scenario states,
+// DELETE /.admin/{service}/scenarios resets all of them,
+// PUT /.admin/{service}/scenarios/{name} jumps a scenario to a step or state,
+// DELETE /.admin/{service}/scenarios/{name} resets a scenario,
+// POST /.admin/{service}/stubs creates a stub, replacing the one with the same ID,
+// GET /.admin/{service}/stubs lists the stubs,
+// DELETE /.admin/{service}/stubs removes all of them,
+// GET /.admin/{service}/stubs/{id} returns a stub,
+// DELETE /.admin/{service}/stubs/{id} removes a stub,
+// GET /.admin/{service}/config returns the config values that can be changed at runtime,
+// PATCH /.admin/{service}/config changes them, also in the config file with ?persist=true,
+// DELETE /.admin/{service}/config undoes the runtime changes.
+// They're meant for test suites, so they're available with the UI disabled.
+func CreateAdminRoutes(router *Router) error {
+	handler := &AdminHandler{
+		router: router,
+	}
+
+	router.Route(AdminURL+"/{service}", func(r chi.Router) {
+		r.Get("/scenarios", handler.listScenarios)
+		r.Delete("/scenarios", handler.clearScenarios)
+		r.Put("/scenarios/{name}", handler.setScenario)
+		r.Delete("/scenarios/{name}", handler.resetScenario)
+
+		r.Post("/stubs", handler.createStub)
+		r.Get("/stubs", handler.listStubs)
+		r.Delete("/stubs", handler.clearStubs)
+		r.Get("/stubs/{id}", handler.getStub)
+		r.Delete("/stubs/{id}", handler.deleteStub)
+
+		r.Get("/config", handler.getConfig)
+		r.Patch("/config", handler.updateConfig)
+		r.Delete("/config", handler.resetConfig)
+	})
+
+	return nil
+}
+
+// AdminHandler handles the runtime admin routes.
+type AdminHandler struct {
+	router *Router
+
+	// mu serializes the writes of config files
+	mu sync.Mutex
+}
+
+// ScenarioListResponse is the response of the scenario state endpoints.
+type ScenarioListResponse struct {
+	Items []*middleware.ScenarioState `json:"items"`
+}
+
+// ScenarioUpdate moves the state of a scenario shared by the requests with Key,
+// empty for the global state: ordered scenarios to Step, starting at 1, state machines to State.
+type ScenarioUpdate struct {
+	Key   string `json:"key"`
+	Step  int    `json:"step,omitempty"`
+	State string `json:"state,omitempty"`
+}
+
+// StubListResponse is the response of the stub list endpoints.
+type StubListResponse struct {
+	Items []*middleware.Stub `json:"items"`
+}
+
+// StubCreate creates a stub answering the requests matching the rule with its response.
+// ID is generated when empty. Times removes the stub after that many uses,
+// TTL, a duration such as 30s, after that time.
+// Responses can't be read from files, only the config can do that.
+type StubCreate struct {
+	ID string `json:"id,omitempty"`
+	config.RuleConfig
+	Times int    `json:"times,omitempty"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+// getService looks up the service named in the URL.
+// Returns the service and its DB or writes an error response and returns nil.
+func (h *AdminHandler) getService(w http.ResponseWriter, r *http.Request) (*ServiceItem, db.DB) {
+	name := chi.URLParam(r, "service")
+	if name == RootServiceName {
+		name = ""
+	}
+
+	svc := h.router.GetServices()[name]
+	database := h.router.GetDB(name)
+	if svc == nil || svc.Config == nil || database == nil {
+		SendProblem(w, http.StatusNotFound, "Service not found", nil)
+		return nil, nil
+	}
+	return svc, database
+}
+
+func (h *AdminHandler) listScenarios(w http.ResponseWriter, r *http.Request) {
+	svc, database := h.getService(w, r)
+	if svc == nil {
+		return
+	}
+
+	items := middleware.GetScenarioStates(r.Context(), database.Table(middleware.ScenarioTable), svc.Config)
+	NewJSONResponse(w).Send(&ScenarioListResponse{Items: items})
+}
+
+func (h *AdminHandler) clearScenarios(w http.ResponseWriter, r *http.Request) {
+	svc, database := h.getService(w, r)
+	if svc == nil {
+		return
+	}
+
+	database.Table(middleware.ScenarioTable).Clear(r.Context())
+	NewJSONResponse(w).Send(&ScenarioListResponse{Items: make([]*middleware.ScenarioState, 0)})
+}
+
+func (h *AdminHandler) setScenario(w http.ResponseWriter, r *http.Request) {
+	svc, database := h.getService(w, r)
+	if svc == nil {
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	sc := svc.Config.Scenarios[name]
+	if sc == nil {
+		SendProblem(w, http.StatusNotFound, fmt.Sprintf("Scenario %s not found", name), nil)
+		return
+	}
+
+	var update ScenarioUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		SendProblem(w, http.StatusBadRequest, "Invalid scenario update: "+err.Error(), nil)
+		return
+	}
+
+	table := database.Table(middleware.ScenarioTable)
+	state := &middleware.ScenarioState{Scenario: name, Key: update.Key}
+	if sc.IsOrdered() {
+		if err := middleware.SetScenarioStep(r.Context(), table, name, sc, update.Key, update.Step); err != nil {
+			SendProblem(w, http.StatusUnprocessableEntity, err.Error(), nil)
+			return
+		}
+		state.Calls, state.Step = sc.StepCalls(update.Step-1), update.Step
+	} else {
+		if err := middleware.SetScenarioState(r.Context(), table, name, sc, update.Key, update.State); err != nil {
+			SendProblem(w, http.StatusUnprocessableEntity, err.Error(), nil)
+			return
+		}
+		state.State = update.State
+	}
+	NewJSONResponse(w).Send(state)
+}
+
+func (h *AdminHandler) resetScenario(w http.ResponseWriter, r *http.Request) {
+	svc, database := h.getService(w, r)
+	if svc == nil {
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	if svc.Config.Scenarios[name] == nil {
+		SendProblem(w, http.StatusNotFound, fmt.Sprintf("Scenario %s not found", name), nil)
+		return
+	}
+
+	table := database.Table(middleware.ScenarioTable)
+	if r.URL.Query().Has("key") {
+		table.Delete(r.Context(), middleware.ScenarioKey(name, r.URL.Query().Get("key")))
+	} else {
+		middleware.ResetScenario(r.Context(), table, name)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) createStub(w http.ResponseWriter, r *http.Request) {
+	_, database := h.getService(w, r)
+	if database == nil {
+		return
+	}
+
+	var create StubCreate
+	if err := json.NewDecoder(r.Body).Decode(&create); err != nil {
+		SendProblem(w, http.StatusBadRequest, "Invalid stub: "+err.Error(), nil)
+		return
+	}
+
+	stub, err := newStub(&create)
+	if err != nil {
+		SendProblem(w, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	middleware.SetStub(r.Context(), database, stub)
+	NewJSONResponse(w).WithStatusCode(http.StatusCreated).Send(stub)
+}
+
+func (h *AdminHandler) listStubs(w http.ResponseWriter, r *http.Request) {
+	_, database := h.getService(w, r)
+	if database == nil {
+		return
+	}
+
+	NewJSONResponse(w).Send(&StubListResponse{Items: middleware.GetStubs(r.Context(), database)})
+}
+
+func (h *AdminHandler) clearStubs(w http.ResponseWriter, r *http.Request) {
+	_, database := h.getService(w, r)
+	if database == nil {
+		return
+	}
+
+	middleware.ResetStubs(r.Context(), database)
+	NewJSONResponse(w).Send(&StubListResponse{Items: make([]*middleware.Stub, 0)})
+}
+
+func (h *AdminHandler) getStub(w http.ResponseWriter, r *http.Request) {
+	_, database := h.getService(w, r)
+	if database == nil {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	stub := middleware.GetStub(r.Context(), database, id)
+	if stub == nil {
+		SendProblem(w, http.StatusNotFound, fmt.Sprintf("Stub %s not found", id), nil)
+		return
+	}
+	NewJSONResponse(w).Send(stub)
+}
+
+func (h *AdminHandler) deleteStub(w http.ResponseWriter, r *http.Request) {
+	_, database := h.getService(w, r)
+	if database == nil {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if !middleware.DeleteStub(r.Context(), database, id) {
+		SendProblem(w, http.StatusNotFound, fmt.Sprintf("Stub %s not found", id), nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) getConfig(w http.ResponseWriter, r *http.Request) {
+	svc := h.getConfigurableService(w, r)
+	if svc == nil {
+		return
+	}
+	h.sendConfig(w, svc.params.ServiceConfig(r.Context()))
+}
+
+func (h *AdminHandler) updateConfig(w http.ResponseWriter, r *http.Request) {
+	svc := h.getConfigurableService(w, r)
+	if svc == nil {
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		SendProblem(w, http.StatusBadRequest, "Invalid config patch: "+err.Error(), nil)
+		return
+	}
+
+	if persist, _ := strconv.ParseBool(r.URL.Query().Get("persist")); persist {
+		if svc.configFile == "" {
+			SendProblem(w, http.StatusUnprocessableEntity, "Service has no config file to write to", nil)
+			return
+		}
+		// Only valid patches are written
+		if _, err := svc.params.ServiceConfig(r.Context()).WithPatch(patch); err != nil {
+			sendConfigPatchProblem(w, err)
+			return
+		}
+		if err := h.writeConfigFile(svc, patch); err != nil {
+			SendProblem(w, http.StatusInternalServerError, "Failed to write config file: "+err.Error(), nil)
+			return
+		}
+	}
+
+	cfg, err := svc.params.UpdateServiceConfig(r.Context(), patch)
+	if err != nil {
+		sendConfigPatchProblem(w, err)
+		return
+	}
+	h.sendConfig(w, cfg)
+}
+
+func (h *AdminHandler) resetConfig(w http.ResponseWriter, r *http.Request) {
+	svc := h.getConfigurableService(w, r)
+	if svc == nil {
+		return
+	}
+	h.sendConfig(w, svc.params.ResetServiceConfig(r.Context()))
+}
+
+// getConfigurableService looks up the service named in the URL if its config can be changed at runtime.
+// Otherwise, it writes an error response and returns nil.
+func (h *AdminHandler) getConfigurableService(w http.ResponseWriter, r *http.Request) *ServiceItem {
+	svc, _ := h.getService(w, r)
+	if svc == nil {
+		return nil
+	}
+	if svc.params == nil {
+		SendProblem(w, http.StatusNotFound, "Service config can't be changed at runtime", nil)
+		return nil
+	}
+	return svc
+}
+
+// sendConfig writes the runtime values of the config.
+func (h *AdminHandler) sendConfig(w http.ResponseWriter, cfg *config.ServiceConfig) {
+	settings, err := cfg.RuntimeSettings()
+	if err != nil {
+		SendProblem(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	NewJSONResponse(w).Send(settings)
+}
+
+// writeConfigFile merges the patch into the config file of the service, keeping its comments.
+func (h *AdminHandler) writeConfigFile(svc *ServiceItem, patch []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	mode := os.FileMode(0o644)
+	src, err := os.ReadFile(svc.configFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if info, err := os.Stat(svc.configFile); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	res, err := config.MergeYAML(src, patch, svc.configPath...)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(svc.configFile, res, mode)
+}
+
+// sendConfigPatchProblem writes 400 for patches that can't be parsed, 422 for invalid values.
+func sendConfigPatchProblem(w http.ResponseWriter, err error) {
+	status := http.StatusUnprocessableEntity
+	if errors.Is(err, config.ErrInvalidConfigPatch) {
+		status = http.StatusBadRequest
+	}
+	SendProblem(w, status, err.Error(), nil)
+}
+
+// newStub validates a stub creation request and returns the stub to store.
+func newStub(create *StubCreate) (*middleware.Stub, error) {
+	if create.Response == nil {
+		return nil, fmt.Errorf("stub has no response")
+	}
+	if create.Response.File != "" {
+		return nil, fmt.Errorf("stub responses can't be read from files")
+	}
+	if create.Times < 0 {
+		return nil, fmt.Errorf("stub times must not be negative")
+	}
+	if err := create.Parse(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	stub := &middleware.Stub{
+		ID:         cmp.Or(create.ID, uuid.NewString()),
+		RuleConfig: create.RuleConfig,
+		Times:      create.Times,
+		CreatedAt:  now,
+	}
+	if create.TTL != "" {
+		ttl, err := time.ParseDuration(create.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid stub ttl %q", create.TTL)
+		}
+		expiresAt := now.Add(ttl)
+		stub.ExpiresAt = &expiresAt
+	}
+	return stub, nil
+}
@@ -0,0 +1,442 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler_Scenarios(t *testing.T) {
+	setup := func(t *testing.T) *Router {
+		t.Helper()
+		cfg, err := config.NewServiceConfigFromBytes([]byte(`
+scenarios:
+  charges:
+    endpoint: POST /charges
+    steps:
+      - status: 201
+        repeat: 2
+      - status: 402
+        body: '{"error":"card_declined"}'
+  order:
+    key: header:X-Session-Id
+    initial: pending
+    states:
+      pending:
+        GET /orders/{id}:
+          status: 202
+          next: done
+      done:
+        GET /orders/{id}:
+          status: 200
+`))
+		require.NoError(t, err)
+
+		router := newTestRouter(t)
+		router.config.DisableUI = true
+		registerTestService(router, &mockService{
+			name:   "test-service",
+			config: cfg,
+			routes: func(r chi.Router) {
+				handler := func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}
+				r.Post("/charges", handler)
+				r.Get("/orders/{id}", handler)
+			},
+		})
+		require.NoError(t, CreateAdminRoutes(router))
+		return router
+	}
+
+	do := func(router *Router, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Session-Id", "a")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	list := func(t *testing.T, router *Router) []*middleware.ScenarioState {
+		t.Helper()
+		w := do(router, http.MethodGet, "/.admin/test-service/scenarios", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var res ScenarioListResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res.Items
+	}
+
+	t.Run("lists states", func(t *testing.T) {
+		router := setup(t)
+		assert.Empty(t, list(t, router))
+
+		do(router, http.MethodPost, "/test-service/charges", "")
+		do(router, http.MethodGet, "/test-service/orders/1", "")
+
+		assert.Equal(t, []*middleware.ScenarioState{
+			{Scenario: "charges", Calls: 1, Step: 1},
+			{Scenario: "order", Key: "a", State: "done"},
+		}, list(t, router))
+	})
+
+	t.Run("jumps to a step", func(t *testing.T) {
+		router := setup(t)
+
+		w := do(router, http.MethodPut, "/.admin/test-service/scenarios/charges", `{"step":2}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"scenario":"charges","key":"","calls":2,"step":2}`, w.Body.String())
+
+		w = do(router, http.MethodPost, "/test-service/charges", "")
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	})
+
+	t.Run("jumps to a state", func(t *testing.T) {
+		router := setup(t)
+
+		w := do(router, http.MethodPut, "/.admin/test-service/scenarios/order", `{"key":"a","state":"done"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = do(router, http.MethodGet, "/test-service/orders/1", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("rejects invalid jumps", func(t *testing.T) {
+		router := setup(t)
+
+		w := do(router, http.MethodPut, "/.admin/test-service/scenarios/charges", `{"step":3}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		w = do(router, http.MethodPut, "/.admin/test-service/scenarios/order", `{"state":"shipped"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		w = do(router, http.MethodPut, "/.admin/test-service/scenarios/order", `{`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do(router, http.MethodPut, "/.admin/test-service/scenarios/missing", `{"step":1}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("resets a scenario", func(t *testing.T) {
+		router := setup(t)
+		do(router, http.MethodPost, "/test-service/charges", "")
+		do(router, http.MethodGet, "/test-service/orders/1", "")
+
+		w := do(router, http.MethodDelete, "/.admin/test-service/scenarios/order?key=b", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Len(t, list(t, router), 2)
+
+		w = do(router, http.MethodDelete, "/.admin/test-service/scenarios/order?key=a", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Len(t, list(t, router), 1)
+
+		w = do(router, http.MethodDelete, "/.admin/test-service/scenarios/charges", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, list(t, router))
+	})
+
+	t.Run("resets all scenarios", func(t *testing.T) {
+		router := setup(t)
+		do(router, http.MethodPost, "/test-service/charges", "")
+
+		w := do(router, http.MethodDelete, "/.admin/test-service/scenarios", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, list(t, router))
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		router := setup(t)
+		w := do(router, http.MethodGet, "/.admin/missing/scenarios", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAdminHandler_Stubs(t *testing.T) {
+	setup := func(t *testing.T) *Router {
+		t.Helper()
+		router := newTestRouter(t)
+		router.config.DisableUI = true
+		registerTestService(router, &mockService{
+			name:   "test-service",
+			config: config.NewServiceConfig(),
+			routes: func(r chi.Router) {
+				r.Post("/charges", func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})
+			},
+		})
+		require.NoError(t, CreateAdminRoutes(router))
+		return router
+	}
+
+	do := func(router *Router, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	charge := func(router *Router, card string) *httptest.ResponseRecorder {
+		return do(router, http.MethodPost, "/test-service/charges", `{"card":{"number":"`+card+`"}}`)
+	}
+
+	create := func(t *testing.T, router *Router, body string) *middleware.Stub {
+		t.Helper()
+		w := do(router, http.MethodPost, "/.admin/test-service/stubs", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var res middleware.Stub
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return &res
+	}
+
+	declined := `{
+		"id": "declined",
+		"method": "POST",
+		"path": "/charges",
+		"body": {"card.number": "4000"},
+		"response": {"status": 402, "body": "{\"error\":\"card_declined\"}"}
+	}`
+
+	t.Run("applies immediately", func(t *testing.T) {
+		router := setup(t)
+		assert.Equal(t, http.StatusOK, charge(router, "4000").Code)
+
+		stub := create(t, router, declined)
+		assert.Equal(t, "declined", stub.ID)
+		assert.False(t, stub.CreatedAt.IsZero())
+
+		w := charge(router, "4000")
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+		assert.JSONEq(t, `{"error":"card_declined"}`, w.Body.String())
+		assert.Equal(t, middleware.ResponseHeaderSourceRule, w.Header().Get(middleware.ResponseHeaderSource))
+
+		assert.Equal(t, http.StatusOK, charge(router, "4242").Code)
+	})
+
+	t.Run("lists and gets stubs", func(t *testing.T) {
+		router := setup(t)
+		generated := create(t, router, `{"priority": 5, "response": {"status": 204}}`)
+		assert.NotEmpty(t, generated.ID)
+		create(t, router, declined)
+		charge(router, "4000")
+
+		w := do(router, http.MethodGet, "/.admin/test-service/stubs", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var res StubListResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Len(t, res.Items, 2)
+		assert.Equal(t, generated.ID, res.Items[0].ID)
+		assert.Equal(t, 1, res.Items[0].Uses)
+		assert.Equal(t, "declined", res.Items[1].ID)
+
+		w = do(router, http.MethodGet, "/.admin/test-service/stubs/declined", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var stub middleware.Stub
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stub))
+		assert.Equal(t, "/charges", stub.Path)
+		require.NotNil(t, stub.Body["card.number"].Equals)
+		assert.Equal(t, "4000", *stub.Body["card.number"].Equals)
+
+		w = do(router, http.MethodGet, "/.admin/test-service/stubs/missing", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("expires after uses", func(t *testing.T) {
+		router := setup(t)
+		create(t, router, `{"id": "once", "times": 1, "response": {"status": 503, "body": "{}"}}`)
+
+		assert.Equal(t, http.StatusServiceUnavailable, charge(router, "4242").Code)
+		assert.Equal(t, http.StatusOK, charge(router, "4242").Code)
+
+		w := do(router, http.MethodGet, "/.admin/test-service/stubs/once", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("expires after ttl", func(t *testing.T) {
+		router := setup(t)
+		stub := create(t, router, `{"ttl": "1h", "response": {"status": 503, "body": "{}"}}`)
+		require.NotNil(t, stub.ExpiresAt)
+		assert.WithinDuration(t, stub.CreatedAt.Add(time.Hour), *stub.ExpiresAt, time.Second)
+	})
+
+	t.Run("deletes stubs", func(t *testing.T) {
+		router := setup(t)
+		create(t, router, declined)
+
+		w := do(router, http.MethodDelete, "/.admin/test-service/stubs/declined", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, http.StatusOK, charge(router, "4000").Code)
+
+		w = do(router, http.MethodDelete, "/.admin/test-service/stubs/declined", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("resets stubs", func(t *testing.T) {
+		router := setup(t)
+		create(t, router, declined)
+
+		w := do(router, http.MethodDelete, "/.admin/test-service/stubs", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"items":[]}`, w.Body.String())
+		assert.Equal(t, http.StatusOK, charge(router, "4000").Code)
+	})
+
+	t.Run("rejects invalid stubs", func(t *testing.T) {
+		router := setup(t)
+		tests := map[string]struct {
+			body string
+			code int
+		}{
+			"bad json":      {`{`, http.StatusBadRequest},
+			"no response":   {`{"path": "/charges"}`, http.StatusUnprocessableEntity},
+			"file response": {`{"response": {"file": "/etc/passwd"}}`, http.StatusUnprocessableEntity},
+			"bad regex":     {`{"query": {"q": {"regex": "("}}, "response": {}}`, http.StatusUnprocessableEntity},
+			"bad template":  {`{"response": {"template": "{{ .Path"}}`, http.StatusUnprocessableEntity},
+			"bad ttl":       {`{"ttl": "soon", "response": {}}`, http.StatusUnprocessableEntity},
+			"bad times":     {`{"times": -1, "response": {}}`, http.StatusUnprocessableEntity},
+		}
+		for name, tc := range tests {
+			w := do(router, http.MethodPost, "/.admin/test-service/stubs", tc.body)
+			assert.Equal(t, tc.code, w.Code, name)
+		}
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		router := setup(t)
+		w := do(router, http.MethodGet, "/.admin/unknown/stubs", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAdminHandler_Config(t *testing.T) {
+	setup := func(t *testing.T, opts ...HandlerOption) *Router {
+		t.Helper()
+		router := newTestRouter(t)
+		router.config.DisableUI = true
+		service := &mockService{
+			name:   "test-service",
+			config: config.NewServiceConfig(),
+			routes: func(r chi.Router) {
+				r.Get("/pets", func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})
+			},
+		}
+		service.config.Name = service.name
+		router.RegisterService(service.config, service, opts...)
+		require.NoError(t, CreateAdminRoutes(router))
+		return router
+	}
+
+	do := func(router *Router, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	pets := func(router *Router) int {
+		return do(router, http.MethodGet, "/test-service/pets", "").Code
+	}
+
+	t.Run("returns runtime settings", func(t *testing.T) {
+		router := setup(t)
+		w := do(router, http.MethodGet, "/.admin/test-service/config", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Contains(t, res, "cache")
+		assert.NotContains(t, res, "name")
+		assert.NotContains(t, res, "validate")
+	})
+
+	t.Run("applies and resets changes", func(t *testing.T) {
+		router := setup(t)
+		assert.Equal(t, http.StatusOK, pets(router))
+
+		w := do(router, http.MethodPatch, "/.admin/test-service/config", `{"errors": {"p100": 503}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"p100": 503}`, mustJSON(t, w.Body.Bytes(), "errors"))
+		assert.Equal(t, http.StatusServiceUnavailable, pets(router))
+
+		w = do(router, http.MethodDelete, "/.admin/test-service/config", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusOK, pets(router))
+	})
+
+	t.Run("rejects invalid changes", func(t *testing.T) {
+		router := setup(t)
+		tests := map[string]struct {
+			body string
+			code int
+		}{
+			"bad syntax":     {`{`, http.StatusBadRequest},
+			"not a mapping":  {`[1]`, http.StatusBadRequest},
+			"not runtime":    {`{"validate": {"request": false}}`, http.StatusUnprocessableEntity},
+			"upstream field": {`{"upstream": {"headers": {"a": "b"}}}`, http.StatusUnprocessableEntity},
+			"bad status":     {`{"errors": {"p10": 42}}`, http.StatusUnprocessableEntity},
+			"bad url":        {`{"upstream": {"url": "ftp://example.com"}}`, http.StatusUnprocessableEntity},
+		}
+		for name, tc := range tests {
+			w := do(router, http.MethodPatch, "/.admin/test-service/config", tc.body)
+			assert.Equal(t, tc.code, w.Code, name)
+		}
+		assert.Equal(t, http.StatusOK, pets(router))
+	})
+
+	t.Run("persist without config file", func(t *testing.T) {
+		router := setup(t)
+		w := do(router, http.MethodPatch, "/.admin/test-service/config?persist=true", `{"latency": "5ms"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("persists to the config file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yml")
+		src := "app:\n  port: 2200\nservices:\n  test-service:\n    # keep me\n    latency: 1ms\n"
+		require.NoError(t, os.WriteFile(path, []byte(src), 0o600))
+
+		router := setup(t, WithConfigFile(path, "services", "test-service"))
+		w := do(router, http.MethodPatch, "/.admin/test-service/config?persist=true", "latency: 5ms\nerrors:\n  p10: 500\n")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "app:\n  port: 2200\nservices:\n  test-service:\n    # keep me\n    latency: 5ms\n    errors:\n      p10: 500\n", string(data))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		// Invalid changes aren't written
+		w = do(router, http.MethodPatch, "/.admin/test-service/config?persist=true", `{"latency": "-5ms"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		after, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, data, after)
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		router := setup(t)
+		w := do(router, http.MethodGet, "/.admin/unknown/config", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// mustJSON returns the JSON of a field of a JSON object.
+func mustJSON(t *testing.T, data []byte, field string) string {
+	t.Helper()
+	var res map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &res))
+	return string(res[field])
+}
@@ -5,13 +5,20 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"log/slog"
+	"maps"
 	"net/http"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
 )
 
 // ContextHeaderName is the header name for passing context replacements via HTTP requests.
 // The value should be base64-encoded JSON.
 const ContextHeaderName = "X-Cxs-Context"
 
+// ClaimsContextArea is the replacement context area holding the claims of the request's bearer token.
+const ClaimsContextArea = "in-claims"
+
 type contextKeyType struct{}
 
 var userContextKey = contextKeyType{}
@@ -51,3 +58,41 @@ func UserContextFromGoContext(ctx context.Context) map[string]any {
 	data, _ := ctx.Value(userContextKey).(map[string]any)
 	return data
 }
+
+// ExtractClaimsFromRequest decodes the claims of the request's bearer JWT.
+// With a key set, only the claims of tokens with a valid signature are returned.
+// Returns nil if the request has no bearer JWT or the token fails verification.
+func ExtractClaimsFromRequest(r *http.Request, keys oidc.KeySet) map[string]any {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !strings.EqualFold(scheme, "bearer") || token == "" {
+		return nil
+	}
+
+	if keys != nil {
+		claims, err := oidc.Verify(token, keys)
+		if err != nil {
+			slog.Debug("Bearer token claims not verified", "error", err)
+			return nil
+		}
+		return claims
+	}
+
+	_, claims, err := oidc.Parse(token)
+	if err != nil {
+		return nil
+	}
+	return claims
+}
+
+// WithClaims returns a copy of the replacement context with the claims in the in-claims area.
+// The context is returned as is when there are no claims.
+func WithClaims(ctx, claims map[string]any) map[string]any {
+	if len(claims) == 0 {
+		return ctx
+	}
+	res := make(map[string]any, len(ctx)+1)
+	maps.Copy(res, ctx)
+	res[ClaimsContextArea] = claims
+	return res
+}
@@ -7,7 +7,9 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
 	assert2 "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExtractContextFromRequest(t *testing.T) {
@@ -98,3 +100,57 @@ func TestUserContextFromGoContext(t *testing.T) {
 		assert.Equal(data, UserContextFromGoContext(ctx))
 	})
 }
+
+func TestExtractClaimsFromRequest(t *testing.T) {
+	assert := assert2.New(t)
+
+	key, err := oidc.GenerateKey()
+	require.NoError(t, err)
+	token, err := key.Sign(map[string]any{"sub": "jane", "tenant_id": "acme"})
+	require.NoError(t, err)
+
+	newRequest := func(authorization string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/me", nil)
+		r.Header.Set("Authorization", authorization)
+		return r
+	}
+
+	t.Run("decodes claims without verification", func(t *testing.T) {
+		claims := ExtractClaimsFromRequest(newRequest("Bearer "+token), nil)
+		assert.Equal("jane", claims["sub"])
+		assert.Equal("acme", claims["tenant_id"])
+	})
+
+	t.Run("verifies claims with a key set", func(t *testing.T) {
+		claims := ExtractClaimsFromRequest(newRequest("bearer "+token), key)
+		assert.Equal("jane", claims["sub"])
+
+		other, err := oidc.GenerateKey()
+		require.NoError(t, err)
+		assert.Nil(ExtractClaimsFromRequest(newRequest("Bearer "+token), other))
+	})
+
+	t.Run("no bearer JWT returns nil", func(t *testing.T) {
+		assert.Nil(ExtractClaimsFromRequest(newRequest(""), nil))
+		assert.Nil(ExtractClaimsFromRequest(newRequest("Basic YTpi"), nil))
+		assert.Nil(ExtractClaimsFromRequest(newRequest("Bearer opaque"), nil))
+	})
+}
+
+func TestWithClaims(t *testing.T) {
+	assert := assert2.New(t)
+
+	t.Run("adds claims to a copy", func(t *testing.T) {
+		ctx := map[string]any{"name": "Jane"}
+		res := WithClaims(ctx, map[string]any{"sub": "jane"})
+
+		assert.Equal(map[string]any{"name": "Jane", "in-claims": map[string]any{"sub": "jane"}}, res)
+		assert.NotContains(ctx, ClaimsContextArea)
+	})
+
+	t.Run("no claims keeps the context", func(t *testing.T) {
+		ctx := map[string]any{"name": "Jane"}
+		assert.Equal(ctx, WithClaims(ctx, nil))
+		assert.Nil(WithClaims(nil, nil))
+	})
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/middleware"
+)
+
+// DriftURL is the URL of the contract drift reports.
+const DriftURL = "/.drift"
+
+// CreateDriftRoutes adds the contract drift report routes to the router:
+// GET /.drift/{service} lists the operations whose upstream responses disagree with the spec,
+// DELETE /.drift/{service} resets the report.
+func CreateDriftRoutes(router *Router) error {
+	if router.Config().DisableUI {
+		return nil
+	}
+
+	handler := &DriftHandler{
+		router: router,
+	}
+
+	router.Route(DriftURL, func(r chi.Router) {
+		r.Get("/{service}", handler.list)
+		r.Delete("/{service}", handler.clear)
+	})
+
+	return nil
+}
+
+// DriftHandler handles contract drift report routes.
+type DriftHandler struct {
+	router *Router
+}
+
+// DriftReportResponse is the response for the drift report endpoint.
+type DriftReportResponse struct {
+	Items []*middleware.DriftOperation `json:"items"`
+}
+
+// getDB looks up the DB of the service named in the URL.
+// Returns the DB or writes an error response and returns nil.
+func (h *DriftHandler) getDB(w http.ResponseWriter, r *http.Request) db.DB {
+	name := chi.URLParam(r, "service")
+	if name == RootServiceName {
+		name = ""
+	}
+
+	if h.router.GetServices()[name] == nil {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return nil
+	}
+
+	database := h.router.GetDB(name)
+	if database == nil {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return nil
+	}
+	return database
+}
+
+func (h *DriftHandler) list(w http.ResponseWriter, r *http.Request) {
+	database := h.getDB(w, r)
+	if database == nil {
+		return
+	}
+
+	items := middleware.GetDriftReport(r.Context(), database.Table(middleware.DriftTable))
+	NewJSONResponse(w).Send(&DriftReportResponse{Items: items})
+}
+
+func (h *DriftHandler) clear(w http.ResponseWriter, r *http.Request) {
+	database := h.getDB(w, r)
+	if database == nil {
+		return
+	}
+
+	database.Table(middleware.DriftTable).Clear(r.Context())
+	NewJSONResponse(w).Send(&DriftReportResponse{Items: make([]*middleware.DriftOperation, 0)})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDriftRoutes(t *testing.T) {
+	t.Run("Returns nil when UI is disabled", func(t *testing.T) {
+		router := newTestRouter(t)
+		router.config.DisableUI = true
+
+		assert.NoError(t, CreateDriftRoutes(router))
+
+		req := httptest.NewRequest(http.MethodGet, "/.drift/test-service", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestDriftHandler(t *testing.T) {
+	setup := func(t *testing.T) *Router {
+		router := newTestRouter(t)
+		registerTestService(router, &mockService{
+			name:   "test-service",
+			config: config.NewServiceConfig(),
+			routes: func(r chi.Router) {},
+		})
+
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		table := router.GetDB("test-service").Table(middleware.DriftTable)
+		for range 3 {
+			middleware.RecordDrift(context.Background(), table, http.MethodGet, "/users/{id}",
+				[]*db.Violation{{Field: "$.id", Message: "expected integer, got string"}}, now)
+		}
+
+		_ = CreateDriftRoutes(router)
+		return router
+	}
+
+	t.Run("Returns drift report", func(t *testing.T) {
+		router := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/.drift/test-service", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res DriftReportResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Len(t, res.Items, 1)
+		assert.Equal(t, "/users/{id}", res.Items[0].Path)
+		assert.Equal(t, 3, res.Items[0].Count)
+		assert.Equal(t, "$.id", res.Items[0].Fields[0].Field)
+	})
+
+	t.Run("Clears drift report", func(t *testing.T) {
+		router := setup(t)
+
+		req := httptest.NewRequest(http.MethodDelete, "/.drift/test-service", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/.drift/test-service", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.JSONEq(t, `{"items":[]}`, w.Body.String())
+	})
+
+	t.Run("Returns 404 for unknown service", func(t *testing.T) {
+		router := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/.drift/nonexistent", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "Service not found")
+	})
+}
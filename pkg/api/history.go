@@ -1,11 +1,18 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/har"
+	"github.com/mockzilla/connexions/v2/pkg/infer"
 )
 
 // CreateHistoryRoutes adds history routes to the router.
@@ -35,8 +42,16 @@ type HistoryHandler struct {
 }
 
 // HistoryListResponse is the response for history list endpoint.
+// NextCursor is set when there are more entries, to be passed as ?cursor= for the next page.
 type HistoryListResponse struct {
-	Items []*db.HistoryEntry `json:"items"`
+	Items      []*db.HistoryEntry `json:"items"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+}
+
+// historyFieldsResponse is the history list response with the entries projected to ?fields=.
+type historyFieldsResponse struct {
+	Items      []map[string]any `json:"items"`
+	NextCursor string           `json:"nextCursor,omitempty"`
 }
 
 // getService looks up the service by name and checks that history is enabled for it.
@@ -78,15 +93,211 @@ func (h *HistoryHandler) list(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Entry not found", http.StatusNotFound)
 			return
 		}
+		if isHARFormat(r) {
+			sendHAR(w, r, []*db.HistoryEntry{entry})
+			return
+		}
 		NewJSONResponse(w).Send(entry)
 		return
 	}
 
-	items := database.History().Data(r.Context())
+	query, err := parseHistoryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := database.History().Query(r.Context(), query)
+	items := page.Items
 	if items == nil {
 		items = make([]*db.HistoryEntry, 0)
 	}
-	NewJSONResponse(w).Send(&HistoryListResponse{Items: items})
+	if isHARFormat(r) {
+		sendHAR(w, r, items)
+		return
+	}
+	if r.URL.Query().Get("format") == "openapi" {
+		sendInferredSpec(w, serviceName, items)
+		return
+	}
+	if fields := r.URL.Query().Get("fields"); fields != "" {
+		projected, err := projectHistory(items, strings.Split(fields, ","))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		NewJSONResponse(w).Send(&historyFieldsResponse{Items: projected, NextCursor: page.NextCursor})
+		return
+	}
+	NewJSONResponse(w).Send(&HistoryListResponse{Items: items, NextCursor: page.NextCursor})
+}
+
+// parseHistoryQuery reads the history filters and pagination from the query string:
+// method, resource, status (404, 4xx or 400-499), source, requestId, since and until (RFC 3339),
+// body (substring of the request or response body), sort (oldest or newest), cursor and limit.
+func parseHistoryQuery(values url.Values) (*db.HistoryQuery, error) {
+	res := &db.HistoryQuery{
+		Method:    values.Get("method"),
+		Resource:  values.Get("resource"),
+		Source:    values.Get("source"),
+		RequestID: values.Get("requestId"),
+		Body:      values.Get("body"),
+		Cursor:    values.Get("cursor"),
+	}
+
+	if status := values.Get("status"); status != "" {
+		minimum, maximum, err := parseStatusRange(status)
+		if err != nil {
+			return nil, err
+		}
+		res.StatusMin, res.StatusMax = minimum, maximum
+	}
+
+	for name, dst := range map[string]*time.Time{"since": &res.Since, "until": &res.Until} {
+		if value := values.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: expected RFC 3339 time", name, value)
+			}
+			*dst = t
+		}
+	}
+
+	switch sortBy := values.Get("sort"); sortBy {
+	case "", "oldest":
+	case "newest":
+		res.Newest = true
+	default:
+		return nil, fmt.Errorf("invalid sort %q: expected oldest or newest", sortBy)
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit %q: expected a positive number", limit)
+		}
+		res.Limit = n
+	}
+
+	if res.Cursor != "" {
+		if _, err := strconv.ParseUint(res.Cursor, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid cursor %q", res.Cursor)
+		}
+	}
+
+	return res, nil
+}
+
+// parseStatusRange parses a status code (404), class (4xx) or range (400-499).
+func parseStatusRange(value string) (int, int, error) {
+	invalid := fmt.Errorf("invalid status %q: expected a code, a class like 4xx or a range like 400-499", value)
+
+	if len(value) == 3 && strings.EqualFold(value[1:], "xx") {
+		class, err := strconv.Atoi(value[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, invalid
+		}
+		return class * 100, class*100 + 99, nil
+	}
+
+	from, to, isRange := strings.Cut(value, "-")
+	minimum, err := strconv.Atoi(from)
+	if err != nil {
+		return 0, 0, invalid
+	}
+	maximum := minimum
+	if isRange {
+		if maximum, err = strconv.Atoi(to); err != nil || maximum < minimum {
+			return 0, 0, invalid
+		}
+	}
+	return minimum, maximum, nil
+}
+
+// projectHistory returns the entries with only the given fields.
+// Fields are dotted paths of the JSON entry, e.g. id or response.statusCode. Missing fields are left out.
+func projectHistory(entries []*db.HistoryEntry, fields []string) ([]map[string]any, error) {
+	res := make([]map[string]any, 0, len(entries))
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		var full map[string]any
+		if err := json.Unmarshal(data, &full); err != nil {
+			return nil, err
+		}
+
+		projected := make(map[string]any)
+		for _, field := range fields {
+			path := strings.Split(strings.TrimSpace(field), ".")
+			if value, ok := lookupField(full, path); ok {
+				setField(projected, path, value)
+			}
+		}
+		res = append(res, projected)
+	}
+	return res, nil
+}
+
+// lookupField returns the value at a path of nested objects.
+func lookupField(obj map[string]any, path []string) (any, bool) {
+	value, ok := obj[path[0]]
+	if !ok || len(path) == 1 {
+		return value, ok
+	}
+	nested, isObj := value.(map[string]any)
+	if !isObj {
+		return nil, false
+	}
+	return lookupField(nested, path[1:])
+}
+
+// setField sets the value at a path of nested objects, creating them as needed.
+func setField(obj map[string]any, path []string, value any) {
+	if len(path) == 1 {
+		obj[path[0]] = value
+		return
+	}
+	nested, ok := obj[path[0]].(map[string]any)
+	if !ok {
+		nested = make(map[string]any)
+		obj[path[0]] = nested
+	}
+	setField(nested, path[1:], value)
+}
+
+// isHARFormat reports whether the history is requested as HAR with ?format=har.
+func isHARFormat(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "har"
+}
+
+// sendHAR writes history entries as a HAR 1.2 document.
+// Request URLs are made absolute with the scheme and host the history was requested with.
+func sendHAR(w http.ResponseWriter, r *http.Request, entries []*db.HistoryEntry) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	doc := har.FromHistory(entries, scheme+"://"+r.Host, &har.Creator{Name: "connexions", Version: "v2"})
+	NewJSONResponse(w).
+		WithHeader("Content-Disposition", `attachment; filename="history.har"`).
+		Send(doc)
+}
+
+// sendInferredSpec writes an OpenAPI 3.1 spec inferred from the history of a service.
+func sendInferredSpec(w http.ResponseWriter, serviceName string, entries []*db.HistoryEntry) {
+	spec, err := infer.FromHistory(entries, infer.Options{Title: serviceName, BasePath: "/" + serviceName})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="openapi.yml"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(spec)
 }
 
 func (h *HistoryHandler) clear(w http.ResponseWriter, r *http.Request) {
@@ -6,10 +6,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/mockzilla/connexions/v2/pkg/config"
 	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/har"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -121,6 +123,106 @@ func TestHistoryHandler_list(t *testing.T) {
 		assert.Equal(t, 200, response.Items[0].Response.StatusCode)
 	})
 
+	t.Run("Returns history as HAR", func(t *testing.T) {
+		router := newTestRouter(t)
+		router.config.History.URL = "/.history"
+
+		service := &mockService{
+			name:   "test-service",
+			config: config.NewServiceConfig(),
+			routes: func(r chi.Router) {},
+		}
+		registerTestService(router, service)
+
+		database := router.GetDB("test-service")
+		database.History().Set(context.Background(), "/users", &db.HistoryRequest{
+			Method: "GET",
+			URL:    "/test-service/users?page=2",
+		}, &db.HistoryResponse{
+			StatusCode:     200,
+			ContentType:    "application/json",
+			Body:           []byte(`{"ok":true}`),
+			IsFromUpstream: true,
+			Duration:       25 * time.Millisecond,
+		})
+
+		_ = CreateHistoryRoutes(router)
+
+		req := httptest.NewRequest(http.MethodGet, "/.history/test-service?format=har", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "history.har")
+
+		doc, err := har.Parse(w.Body.Bytes())
+		assert.NoError(t, err)
+		assert.Equal(t, "1.2", doc.Log.Version)
+		assert.Len(t, doc.Log.Entries, 1)
+
+		entry := doc.Log.Entries[0]
+		assert.Equal(t, "http://example.com/test-service/users?page=2", entry.Request.URL)
+		assert.Equal(t, 200, entry.Response.Status)
+		assert.Equal(t, `{"ok":true}`, entry.Response.Content.Text)
+		assert.Equal(t, 25.0, entry.Time)
+		assert.Equal(t, "upstream", entry.Source)
+	})
+
+	t.Run("Returns spec inferred from history", func(t *testing.T) {
+		router := newTestRouter(t)
+		router.config.History.URL = "/.history"
+
+		service := &mockService{
+			name:   "test-service",
+			config: config.NewServiceConfig(),
+			routes: func(r chi.Router) {},
+		}
+		registerTestService(router, service)
+
+		database := router.GetDB("test-service")
+		for _, id := range []string{"1", "2"} {
+			database.History().Set(context.Background(), "/users/"+id, &db.HistoryRequest{
+				Method: "GET",
+				URL:    "/test-service/users/" + id,
+			}, &db.HistoryResponse{
+				StatusCode:  200,
+				ContentType: "application/json",
+				Body:        []byte(`{"id":` + id + `}`),
+			})
+		}
+
+		_ = CreateHistoryRoutes(router)
+
+		req := httptest.NewRequest(http.MethodGet, "/.history/test-service?format=openapi", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "openapi: 3.1.0")
+		assert.Contains(t, w.Body.String(), "/users/{id}:")
+		assert.Contains(t, w.Body.String(), "title: test-service")
+	})
+
+	t.Run("Returns 422 when inferring from empty history", func(t *testing.T) {
+		router := newTestRouter(t)
+		router.config.History.URL = "/.history"
+
+		service := &mockService{
+			name:   "test-service",
+			config: config.NewServiceConfig(),
+			routes: func(r chi.Router) {},
+		}
+		registerTestService(router, service)
+		_ = CreateHistoryRoutes(router)
+
+		req := httptest.NewRequest(http.MethodGet, "/.history/test-service?format=openapi", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
 	t.Run("Returns 404 for unknown service", func(t *testing.T) {
 		router := newTestRouter(t)
 		router.config.History.URL = "/.history"
@@ -135,6 +237,119 @@ func TestHistoryHandler_list(t *testing.T) {
 	})
 }
 
+func TestHistoryHandler_query(t *testing.T) {
+	router := newTestRouter(t)
+	router.config.History.URL = "/.history"
+
+	service := &mockService{
+		name:   "test-service",
+		config: config.NewServiceConfig(),
+		routes: func(r chi.Router) {},
+	}
+	registerTestService(router, service)
+
+	ctx := context.Background()
+	history := router.GetDB("test-service").History()
+	history.Set(ctx, "/users", &db.HistoryRequest{Method: "GET", URL: "/test-service/users"}, &db.HistoryResponse{
+		StatusCode: 200,
+		Body:       []byte(`[{"name":"jane"}]`),
+		Headers:    []string{"X-Cxs-Source: generated"},
+	})
+	history.Set(ctx, "/users", &db.HistoryRequest{Method: "POST", URL: "/test-service/users", RequestID: "req-2"}, &db.HistoryResponse{
+		StatusCode: 201,
+		Headers:    []string{"X-Cxs-Source: cache"},
+	})
+	history.Set(ctx, "/users/{id}", &db.HistoryRequest{Method: "GET", URL: "/test-service/users/1"}, &db.HistoryResponse{
+		StatusCode: 404,
+		Headers:    []string{"X-Cxs-Source: canned"},
+	})
+
+	_ = CreateHistoryRoutes(router)
+
+	list := func(t *testing.T, query string) (int, *HistoryListResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/.history/test-service?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response HistoryListResponse
+		if w.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w.Code, &response
+	}
+	ids := func(response *HistoryListResponse) []string {
+		res := make([]string, 0, len(response.Items))
+		for _, entry := range response.Items {
+			res = append(res, entry.ID)
+		}
+		return res
+	}
+
+	filters := map[string][]string{
+		"method=post":                        {"2"},
+		"resource=/users/{id}":               {"3"},
+		"status=4xx":                         {"3"},
+		"status=200-299":                     {"1", "2"},
+		"status=201":                         {"2"},
+		"source=cache":                       {"2"},
+		"requestId=req-2":                    {"2"},
+		"body=jane":                          {"1"},
+		"sort=newest":                        {"3", "2", "1"},
+		"until=2000-01-01T00:00:00Z":         {},
+		"since=2000-01-01T00:00:00Z&limit=5": {"1", "2", "3"},
+	}
+	for query, expected := range filters {
+		t.Run("Filters by "+query, func(t *testing.T) {
+			code, response := list(t, query)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, expected, ids(response))
+		})
+	}
+
+	t.Run("Pages with a cursor", func(t *testing.T) {
+		code, response := list(t, "sort=newest&limit=2")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"3", "2"}, ids(response))
+		assert.Equal(t, "2", response.NextCursor)
+
+		_, response = list(t, "sort=newest&limit=2&cursor="+response.NextCursor)
+		assert.Equal(t, []string{"1"}, ids(response))
+		assert.Empty(t, response.NextCursor)
+	})
+
+	t.Run("Projects fields", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/.history/test-service?method=GET&fields=id,request.method,response.statusCode,missing", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"items": [
+			{"id": "1", "request": {"method": "GET"}, "response": {"statusCode": 200}},
+			{"id": "3", "request": {"method": "GET"}, "response": {"statusCode": 404}}
+		]}`, w.Body.String())
+	})
+
+	t.Run("Filters HAR", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/.history/test-service?format=har&status=404", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		doc, err := har.Parse(w.Body.Bytes())
+		assert.NoError(t, err)
+		assert.Len(t, doc.Log.Entries, 1)
+	})
+
+	invalid := []string{"status=abc", "status=6xx", "status=499-400", "since=yesterday", "sort=random", "limit=0", "limit=x", "cursor=abc"}
+	for _, query := range invalid {
+		t.Run("Returns 400 for "+query, func(t *testing.T) {
+			code, _ := list(t, query)
+			assert.Equal(t, http.StatusBadRequest, code)
+		})
+	}
+}
+
 func TestHistoryHandler_getByID(t *testing.T) {
 	t.Run("Returns single entry by ID", func(t *testing.T) {
 		router := newTestRouter(t)
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
+)
+
+// CreateOIDCRoutes mounts the built-in mock OAuth2 / OpenID Connect provider
+// when it is enabled in the app config.
+func CreateOIDCRoutes(router *Router) error {
+	cfg := router.Config().OIDC
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	provider, err := oidc.NewProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	url := "/" + strings.Trim(cfg.URL, "/")
+	router.Route(url, provider.Routes)
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateOIDCRoutes(t *testing.T) {
+	t.Run("Returns nil when disabled", func(t *testing.T) {
+		router := newTestRouter(t)
+
+		assert.NoError(t, CreateOIDCRoutes(router))
+
+		req := httptest.NewRequest(http.MethodGet, "/.oidc/jwks", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Mounts the provider", func(t *testing.T) {
+		router := newTestRouter(t)
+		router.config.OIDC = &config.OIDCConfig{Enabled: true, URL: "/auth/"}
+
+		assert.NoError(t, CreateOIDCRoutes(router))
+
+		req := httptest.NewRequest(http.MethodGet, "/auth/.well-known/openid-configuration", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"issuer":"http://example.com/auth"`)
+	})
+
+	t.Run("Returns error for unreadable key file", func(t *testing.T) {
+		router := newTestRouter(t)
+		router.config.OIDC = &config.OIDCConfig{Enabled: true, KeyFile: t.TempDir()}
+
+		assert.Error(t, CreateOIDCRoutes(router))
+	})
+}
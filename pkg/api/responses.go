@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/schema"
 )
 
 // JSONResponse is a response builder for JSON responses.
@@ -73,6 +75,30 @@ type SimpleResponse struct {
 	Message string `json:"message"`
 }
 
+// ProblemDetails is an RFC 7807 problem details body.
+// Errors lists the individual failures, e.g. each field of a request that failed validation.
+type ProblemDetails struct {
+	Type   string                    `json:"type"`
+	Title  string                    `json:"title"`
+	Status int                       `json:"status"`
+	Detail string                    `json:"detail,omitempty"`
+	Errors []*schema.ValidationError `json:"errors,omitempty"`
+}
+
+// SendProblem sends an RFC 7807 problem details response titled with the status text.
+func SendProblem(w http.ResponseWriter, statusCode int, detail string, errs []*schema.ValidationError) {
+	NewJSONResponse(w).
+		WithStatusCode(statusCode).
+		WithHeader("Content-Type", "application/problem+json").
+		Send(&ProblemDetails{
+			Type:   "about:blank",
+			Title:  http.StatusText(statusCode),
+			Status: statusCode,
+			Detail: detail,
+			Errors: errs,
+		})
+}
+
 // SendHTML is a helper function to send HTML responses.
 func SendHTML(w http.ResponseWriter, statusCode int, data []byte) {
 	if statusCode == 0 {
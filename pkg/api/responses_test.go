@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/mockzilla/connexions/v2/pkg/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -196,6 +197,34 @@ func TestSendHTML(t *testing.T) {
 	})
 }
 
+func TestSendProblem(t *testing.T) {
+	t.Run("Sends problem details with errors", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		SendProblem(w, http.StatusBadRequest, "request does not match the spec", []*schema.ValidationError{
+			{In: "query", Field: "limit", Message: "required parameter is missing"},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "request does not match the spec",
+			"errors": [{"in": "query", "field": "limit", "message": "required parameter is missing"}]
+		}`, w.Body.String())
+	})
+
+	t.Run("Omits empty detail and errors", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		SendProblem(w, http.StatusInternalServerError, "", nil)
+
+		assert.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500}`, w.Body.String())
+	})
+}
+
 func TestSimpleResponse(t *testing.T) {
 	t.Run("Creates success response", func(t *testing.T) {
 		resp := &SimpleResponse{
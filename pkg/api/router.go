@@ -4,6 +4,8 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/mockzilla/connexions/v2/pkg/config"
 	"github.com/mockzilla/connexions/v2/pkg/db"
 	"github.com/mockzilla/connexions/v2/pkg/middleware"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
 	"github.com/mockzilla/connexions/v2/resources"
 )
 
@@ -37,6 +40,28 @@ type Handler interface {
 	Generate(w http.ResponseWriter, r *http.Request)
 }
 
+// ErrorWriter is implemented by handlers that write simulated errors
+// in their own protocol, e.g. as SOAP Faults.
+// WriteError returns false to fall back to the default plain text response.
+type ErrorWriter interface {
+	WriteError(w http.ResponseWriter, r *http.Request, statusCode int) bool
+}
+
+// ResponseValidator is implemented by handlers that validate upstream responses against their spec.
+// path is the request path relative to the service root.
+// ValidateResponse returns the spec path of the matched operation, or "" if no operation matches.
+type ResponseValidator interface {
+	ValidateResponse(method, path string, statusCode int, contentType string, body []byte) (string, []*schema.ValidationError)
+}
+
+// RequestChecker is implemented by handlers that check requests against their spec,
+// e.g. security requirements and request validation, before any response is produced.
+// path is the request path relative to the service root.
+// CheckRequest writes the rejection and returns false when the request must not be served.
+type RequestChecker interface {
+	CheckRequest(w http.ResponseWriter, r *http.Request, path string) bool
+}
+
 // NewRouter creates a new central router with default middleware
 func NewRouter(options ...RouterOption) *Router {
 	r := chi.NewRouter()
@@ -75,6 +100,9 @@ func NewRouter(options ...RouterOption) *Router {
 		databases: make(map[string]db.DB),
 	}
 
+	// Answer preflight requests before they reach the services
+	r.Use(middleware.CORSMiddleware(res.corsConfig))
+
 	for _, opt := range options {
 		opt(res)
 	}
@@ -102,6 +130,7 @@ func (r *Router) RegisterService(
 	// Get service-scoped DB from shared storage
 	serviceDB := r.storage.NewDB(cfg.Name, r.config.History.Duration)
 	mwParams := middleware.NewParams(cfg, r.config.Storage, serviceDB)
+	registerHandlerCapabilities(mwParams, handler)
 
 	// Use cfg.Name as the route prefix (ensure it starts with /)
 	prefix := "/" + cfg.Name
@@ -112,13 +141,20 @@ func (r *Router) RegisterService(
 		// Config override middleware (must be before other middlewares to override config)
 		subRouter.Use(middleware.CreateConfigOverrideMiddleware(mwParams))
 
+		// Request checks (before any middleware that can answer the request)
+		subRouter.Use(middleware.CreateRequestCheckMiddleware(mwParams))
+
 		// Custom middleware
 		for _, createMw := range options.middleware {
 			subRouter.Use(createMw(mwParams))
 		}
 
 		// Standard middleware (always applied)
+		subRouter.Use(middleware.CreateRateLimitMiddleware(mwParams))
 		subRouter.Use(middleware.CreateLatencyAndErrorMiddleware(mwParams))
+		subRouter.Use(middleware.CreateFaultMiddleware(mwParams))
+		subRouter.Use(middleware.CreateRuleMiddleware(mwParams))
+		subRouter.Use(middleware.CreateScenarioMiddleware(mwParams))
 		subRouter.Use(middleware.CreateReplayReadMiddleware(mwParams))
 		subRouter.Use(middleware.CreateReplayWriteMiddleware(mwParams))
 		subRouter.Use(middleware.CreateCacheReadMiddleware(mwParams))
@@ -137,10 +173,14 @@ func (r *Router) RegisterService(
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	configFile, configPath := r.serviceConfigFile(cfg.Name, options)
 	r.services[cfg.Name] = &ServiceItem{
-		Name:    cfg.Name,
-		Handler: handler,
-		Config:  cfg,
+		Name:       cfg.Name,
+		Handler:    handler,
+		Config:     cfg,
+		params:     mwParams,
+		configFile: configFile,
+		configPath: configPath,
 	}
 	r.databases[cfg.Name] = serviceDB
 }
@@ -150,6 +190,8 @@ type HandlerOption func(*handlerOptions)
 
 type handlerOptions struct {
 	middleware []func(*middleware.Params) func(http.Handler) http.Handler
+	configFile string
+	configPath []string
 }
 
 // WithMiddleware prepends middleware before the built-in middleware chain.
@@ -159,6 +201,16 @@ func WithMiddleware(mw []func(*middleware.Params) func(http.Handler) http.Handle
 	}
 }
 
+// WithConfigFile sets the file runtime config changes of the service are written to.
+// keyPath leads to the service config within the file, e.g. services, petstore.
+// Without it, setup/config.yml of the service directory is used if it exists.
+func WithConfigFile(path string, keyPath ...string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.configFile = path
+		o.configPath = keyPath
+	}
+}
+
 // RegisterHTTPHandler registers a Handler as a service.
 // The handlerFactory receives the service DB and returns the handler.
 // The service will be registered at the route "/{cfg.Name}".
@@ -179,6 +231,7 @@ func (r *Router) RegisterHTTPHandler(
 	handler := handlerFactory(serviceDB)
 
 	mwParams := middleware.NewParams(cfg, r.config.Storage, serviceDB)
+	registerHandlerCapabilities(mwParams, handler)
 
 	// Use cfg.Name as the route prefix (ensure it starts with /)
 	prefix := "/" + cfg.Name
@@ -189,13 +242,20 @@ func (r *Router) RegisterHTTPHandler(
 		// Config override middleware (must be before other middlewares to override config)
 		subRouter.Use(middleware.CreateConfigOverrideMiddleware(mwParams))
 
+		// Request checks (before any middleware that can answer the request)
+		subRouter.Use(middleware.CreateRequestCheckMiddleware(mwParams))
+
 		// Custom middleware
 		for _, createMw := range options.middleware {
 			subRouter.Use(createMw(mwParams))
 		}
 
 		// Standard middleware (always applied)
+		subRouter.Use(middleware.CreateRateLimitMiddleware(mwParams))
 		subRouter.Use(middleware.CreateLatencyAndErrorMiddleware(mwParams))
+		subRouter.Use(middleware.CreateFaultMiddleware(mwParams))
+		subRouter.Use(middleware.CreateRuleMiddleware(mwParams))
+		subRouter.Use(middleware.CreateScenarioMiddleware(mwParams))
 		subRouter.Use(middleware.CreateReplayReadMiddleware(mwParams))
 		subRouter.Use(middleware.CreateReplayWriteMiddleware(mwParams))
 		subRouter.Use(middleware.CreateCacheReadMiddleware(mwParams))
@@ -214,14 +274,61 @@ func (r *Router) RegisterHTTPHandler(
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	configFile, configPath := r.serviceConfigFile(cfg.Name, options)
 	r.services[cfg.Name] = &ServiceItem{
-		Name:    cfg.Name,
-		Handler: handler,
-		Config:  cfg,
+		Name:       cfg.Name,
+		Handler:    handler,
+		Config:     cfg,
+		params:     mwParams,
+		configFile: configFile,
+		configPath: configPath,
 	}
 	r.databases[cfg.Name] = serviceDB
 }
 
+// registerHandlerCapabilities hands the optional interfaces a handler implements to the middleware.
+func registerHandlerCapabilities(params *middleware.Params, handler Handler) {
+	if ew, ok := handler.(ErrorWriter); ok {
+		params.SetErrorWriter(ew.WriteError)
+	}
+	if rv, ok := handler.(ResponseValidator); ok {
+		params.SetResponseValidator(rv.ValidateResponse)
+	}
+	if rc, ok := handler.(RequestChecker); ok {
+		params.SetRequestChecker(rc.CheckRequest)
+	}
+}
+
+// serviceConfigFile returns the file and the key path runtime config changes of a service are written to.
+func (r *Router) serviceConfigFile(name string, options *handlerOptions) (string, []string) {
+	if options.configFile != "" {
+		return options.configFile, options.configPath
+	}
+	if name == "" {
+		return "", nil
+	}
+	path := filepath.Join(r.config.Paths.Services, name, "setup", "config.yml")
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+	return path, nil
+}
+
+// corsConfig returns the CORS config of the service a request is for,
+// falling back to the app config for services without one and for app routes.
+func (r *Router) corsConfig(req *http.Request) *config.CORSConfig {
+	name, _, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+
+	r.mu.RLock()
+	svc := r.services[name]
+	r.mu.RUnlock()
+
+	if svc != nil && svc.Config != nil && svc.Config.CORS != nil {
+		return svc.Config.CORS
+	}
+	return r.config.CORS
+}
+
 // Config returns the app configuration
 func (r *Router) Config() *config.AppConfig {
 	return r.config
@@ -44,6 +44,32 @@ func (m *mockService) Generate(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Generate not implemented in mock", http.StatusNotImplemented)
 }
 
+// errorWriterService is a mockService writing simulated errors itself.
+type errorWriterService struct {
+	mockService
+}
+
+func (m *errorWriterService) WriteError(w http.ResponseWriter, _ *http.Request, statusCode int) bool {
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte("custom error"))
+	return true
+}
+
+// requestCheckerService is a mockService rejecting requests without the X-Token header.
+type requestCheckerService struct {
+	mockService
+	paths []string
+}
+
+func (m *requestCheckerService) CheckRequest(w http.ResponseWriter, req *http.Request, path string) bool {
+	m.paths = append(m.paths, path)
+	if req.Header.Get("X-Token") == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
 // newTestRouter creates a router with a temporary directory for testing
 func newTestRouter(t *testing.T) *Router {
 	cfg := config.NewDefaultAppConfig(t.TempDir())
@@ -215,6 +241,137 @@ func TestRouter_RegisterService(t *testing.T) {
 	})
 }
 
+func TestRouter_EndpointConfig(t *testing.T) {
+	router := newTestRouter(t)
+
+	cfg := config.NewServiceConfig()
+	cfg.Endpoints = map[string]*config.EndpointConfigMethods{
+		"/pets/{petId}": {Methods: map[string]*config.EndpointConfig{
+			http.MethodDelete: {Errors: map[string]int{"p100": http.StatusConflict}},
+		}},
+	}
+	cfg.WithDefaults()
+
+	service := &mockService{
+		name:   "test-service",
+		config: cfg,
+		routes: func(r chi.Router) {
+			handler := func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}
+			r.Get("/pets/{id}", handler)
+			r.Delete("/pets/{id}", handler)
+			r.Get("/health", handler)
+		},
+	}
+	registerTestService(router, service)
+
+	for _, tc := range []struct {
+		method, path string
+		expected     int
+	}{
+		{http.MethodDelete, "/test-service/pets/1", http.StatusConflict},
+		{http.MethodGet, "/test-service/pets/1", http.StatusOK},
+		{http.MethodGet, "/test-service/health", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.expected, w.Code, tc.method+" "+tc.path)
+	}
+}
+
+func TestRouter_RequestChecker(t *testing.T) {
+	router := newTestRouter(t)
+
+	cfg := config.NewServiceConfig()
+	cfg.Rules = []*config.RuleConfig{
+		{Method: http.MethodGet, Path: "/pets", Response: &config.RuleResponse{Status: http.StatusAccepted}},
+	}
+	cfg.WithDefaults()
+
+	service := &requestCheckerService{mockService: mockService{
+		name:   "checked",
+		config: cfg,
+		routes: func(r chi.Router) {
+			r.Get("/pets", func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+		},
+	}}
+	service.config.Name = service.name
+	router.RegisterService(service.config, service)
+
+	t.Run("rejects before rules answer", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checked/pets", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, []string{"/pets"}, service.paths)
+	})
+
+	t.Run("lets rules answer checked requests", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/checked/pets", nil)
+		req.Header.Set("X-Token", "secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+}
+
+func TestRouter_CORS(t *testing.T) {
+	appCfg := config.NewDefaultAppConfig(t.TempDir())
+	appCfg.CORS = &config.CORSConfig{Origins: []string{"http://localhost:3000"}}
+	router := NewRouter(WithConfigOption(appCfg))
+
+	for _, name := range []string{"pets", "orders"} {
+		cfg := config.NewServiceConfig()
+		cfg.Name = name
+		if name == "orders" {
+			cfg.CORS = &config.CORSConfig{Origins: []string{"https://shop.test"}}
+		}
+		router.RegisterService(cfg, &mockService{
+			name:   name,
+			config: cfg,
+			routes: func(r chi.Router) {
+				r.Get("/items", func(w http.ResponseWriter, req *http.Request) {
+					_, _ = w.Write([]byte(name))
+				})
+			},
+		})
+	}
+
+	preflight := func(path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("app config applies to services without one", func(t *testing.T) {
+		w := preflight("/pets/items", "http://localhost:3000")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+
+		req := httptest.NewRequest(http.MethodGet, "/pets/items", nil)
+		req.Header.Set("Origin", "http://localhost:3000")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, "pets", rec.Body.String())
+		assert.Equal(t, "http://localhost:3000", rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("service config overrides the app one", func(t *testing.T) {
+		w := preflight("/orders/items", "https://shop.test")
+		assert.Equal(t, "https://shop.test", w.Header().Get("Access-Control-Allow-Origin"))
+
+		w = preflight("/orders/items", "http://localhost:3000")
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
 func TestRouter_MiddlewareOrder(t *testing.T) {
 	t.Run("Middleware executes in correct order", func(t *testing.T) {
 		router := newTestRouter(t)
@@ -277,6 +434,35 @@ func TestRouter_MiddlewareOrder(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 
+	t.Run("Rate limit middleware rejects requests before handler", func(t *testing.T) {
+		router := newTestRouter(t)
+
+		cfg := config.NewServiceConfig()
+		cfg.RateLimit = &config.RateLimitConfig{RateLimit: config.RateLimit{Limit: 1}}
+
+		calls := 0
+		service := &mockService{
+			name:   "test-service",
+			config: cfg,
+			routes: func(r chi.Router) {
+				r.Get("/test", func(w http.ResponseWriter, req *http.Request) {
+					calls++
+					w.WriteHeader(http.StatusOK)
+				})
+			},
+		}
+
+		registerTestService(router, service)
+
+		for _, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test-service/test", nil))
+			assert.Equal(t, expected, w.Code)
+			assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+		}
+		assert.Equal(t, 1, calls)
+	})
+
 	t.Run("Error middleware prevents handler execution", func(t *testing.T) {
 		router := newTestRouter(t)
 
@@ -311,6 +497,34 @@ errors:
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 
+	t.Run("Error middleware uses handler error writer", func(t *testing.T) {
+		router := newTestRouter(t)
+
+		cfg, _ := config.NewServiceConfigFromBytes([]byte(`
+errors:
+  p100: 503
+`))
+		cfg.Name = "test-service"
+
+		service := &errorWriterService{mockService{
+			name:   "test-service",
+			config: cfg,
+			routes: func(r chi.Router) {
+				r.Get("/test", func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})
+			},
+		}}
+		router.RegisterService(cfg, service)
+
+		req := httptest.NewRequest(http.MethodGet, "/test-service/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "custom error", w.Body.String())
+	})
+
 	t.Run("Cache read middleware returns cached response", func(t *testing.T) {
 		router := newTestRouter(t)
 
@@ -11,6 +11,7 @@ import (
 	"github.com/mockzilla/connexions/v2/internal/types"
 	"github.com/mockzilla/connexions/v2/pkg/config"
 	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/middleware"
 )
 
 type RouteType string
@@ -161,6 +162,10 @@ type ServiceItem struct {
 	Name    string                `json:"name"`
 	Handler Handler               `json:"-"`
 	Config  *config.ServiceConfig `json:"-"`
+
+	params     *middleware.Params
+	configFile string
+	configPath []string
 }
 
 type ServiceItemResponse struct {
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/middleware"
+)
+
+// VerifyURL is the URL of the request verification API.
+const VerifyURL = "/.verify"
+
+// CreateVerifyRoutes adds the request verification route to the router:
+// POST /.verify/{service} returns the requests in the history of the service matching a request matcher,
+// with 417 Expectation Failed when their number doesn't meet the expectation.
+// It's meant for test suites, so it's available with the UI disabled.
+func CreateVerifyRoutes(router *Router) error {
+	handler := &VerifyHandler{
+		router: router,
+	}
+
+	router.Route(VerifyURL, func(r chi.Router) {
+		r.Post("/{service}", handler.verify)
+	})
+
+	return nil
+}
+
+// VerifyHandler handles the request verification route.
+type VerifyHandler struct {
+	router *Router
+}
+
+// VerifyRequest selects recorded requests and sets how many of them are expected.
+// Within limits them to the ones made in the last duration, e.g. 30s.
+type VerifyRequest struct {
+	middleware.RequestMatcher
+	Within string        `json:"within,omitempty"`
+	Expect *VerifyExpect `json:"expect,omitempty"`
+}
+
+// VerifyExpect is the expected number of matching requests.
+// In JSON, a number is a shorthand for exactly.
+type VerifyExpect struct {
+	Exactly *int `json:"exactly,omitempty"`
+	AtLeast *int `json:"atLeast,omitempty"`
+	AtMost  *int `json:"atMost,omitempty"`
+}
+
+// VerifyResponse lists the matching requests, oldest first.
+// Message tells why the expectation failed.
+type VerifyResponse struct {
+	Count   int                `json:"count"`
+	Items   []*db.HistoryEntry `json:"items"`
+	Message string             `json:"message,omitempty"`
+}
+
+// UnmarshalJSON reads a number as an exact count, an object as a full expectation.
+func (e *VerifyExpect) UnmarshalJSON(data []byte) error {
+	var count int
+	if err := json.Unmarshal(data, &count); err == nil {
+		e.Exactly = &count
+		return nil
+	}
+
+	type plain VerifyExpect
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+// Check returns an error describing how the count misses the expectation, nil if it meets it.
+func (e *VerifyExpect) Check(count int) error {
+	if e == nil {
+		return nil
+	}
+	if e.Exactly != nil && count != *e.Exactly {
+		return fmt.Errorf("expected exactly %d matching requests, got %d", *e.Exactly, count)
+	}
+	if e.AtLeast != nil && count < *e.AtLeast {
+		return fmt.Errorf("expected at least %d matching requests, got %d", *e.AtLeast, count)
+	}
+	if e.AtMost != nil && count > *e.AtMost {
+		return fmt.Errorf("expected at most %d matching requests, got %d", *e.AtMost, count)
+	}
+	return nil
+}
+
+// validate checks the counts of the expectation aren't negative.
+func (e *VerifyExpect) validate() error {
+	if e == nil {
+		return nil
+	}
+	for _, n := range []*int{e.Exactly, e.AtLeast, e.AtMost} {
+		if n != nil && *n < 0 {
+			return fmt.Errorf("expected counts must not be negative")
+		}
+	}
+	return nil
+}
+
+func (h *VerifyHandler) verify(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "service")
+	if name == RootServiceName {
+		name = ""
+	}
+
+	svc := h.router.GetServices()[name]
+	database := h.router.GetDB(name)
+	if svc == nil || database == nil {
+		SendProblem(w, http.StatusNotFound, "Service not found", nil)
+		return
+	}
+	if svc.Config != nil && !svc.Config.HistoryEnabled() {
+		SendProblem(w, http.StatusNotFound, "History disabled for this service", nil)
+		return
+	}
+
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		SendProblem(w, http.StatusBadRequest, "Invalid verification: "+err.Error(), nil)
+		return
+	}
+	if err := req.Expect.validate(); err != nil {
+		SendProblem(w, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+	if req.Within != "" {
+		within, err := time.ParseDuration(req.Within)
+		if err != nil || within <= 0 {
+			SendProblem(w, http.StatusUnprocessableEntity, fmt.Sprintf("Invalid within %q", req.Within), nil)
+			return
+		}
+		if since := time.Now().Add(-within); req.Since == nil || req.Since.Before(since) {
+			req.Since = &since
+		}
+	}
+
+	items, err := middleware.MatchHistory(database.History().Data(r.Context()), name, &req.RequestMatcher)
+	if err != nil {
+		SendProblem(w, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	res := &VerifyResponse{Count: len(items), Items: items}
+	status := http.StatusOK
+	if err := req.Expect.Check(len(items)); err != nil {
+		res.Message = err.Error()
+		status = http.StatusExpectationFailed
+	}
+	NewJSONResponse(w).WithStatusCode(status).Send(res)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyHandler(t *testing.T) {
+	setup := func(t *testing.T) *Router {
+		t.Helper()
+		router := newTestRouter(t)
+		router.config.DisableUI = true
+		registerTestService(router, &mockService{name: "payments", config: config.NewServiceConfig()})
+
+		history := router.GetDB("payments").History()
+		for _, amount := range []string{"100", "100", "200"} {
+			history.Set(context.Background(), "/charges", &db.HistoryRequest{
+				Method:  http.MethodPost,
+				URL:     "/payments/charges",
+				Body:    []byte(`{"amount": ` + amount + `}`),
+				Headers: []string{"Content-Type: application/json"},
+			}, &db.HistoryResponse{StatusCode: http.StatusOK})
+		}
+
+		require.NoError(t, CreateVerifyRoutes(router))
+		return router
+	}
+
+	verify := func(router *Router, service, body string) (*httptest.ResponseRecorder, *VerifyResponse) {
+		req := httptest.NewRequest(http.MethodPost, VerifyURL+"/"+service, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var res VerifyResponse
+		if w.Code == http.StatusOK || w.Code == http.StatusExpectationFailed {
+			_ = json.Unmarshal(w.Body.Bytes(), &res)
+		}
+		return w, &res
+	}
+
+	t.Run("counts matching requests", func(t *testing.T) {
+		router := setup(t)
+		w, res := verify(router, "payments", `{"method": "POST", "resource": "/charges", "body": {"$.amount": 100}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, 2, res.Count)
+		require.Len(t, res.Items, 2)
+		assert.Equal(t, "/charges", res.Items[0].Resource)
+		assert.Empty(t, res.Message)
+	})
+
+	t.Run("empty matcher matches everything", func(t *testing.T) {
+		router := setup(t)
+		w, res := verify(router, "payments", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 3, res.Count)
+	})
+
+	t.Run("expectations", func(t *testing.T) {
+		router := setup(t)
+		tests := map[string]struct {
+			expect string
+			code   int
+		}{
+			"exactly met":      {`2`, http.StatusOK},
+			"exactly missed":   {`3`, http.StatusExpectationFailed},
+			"at least met":     {`{"atLeast": 1}`, http.StatusOK},
+			"at most missed":   {`{"atMost": 1}`, http.StatusExpectationFailed},
+			"between met":      {`{"atLeast": 1, "atMost": 2}`, http.StatusOK},
+			"exactly object":   {`{"exactly": 2}`, http.StatusOK},
+			"negative count":   {`-1`, http.StatusUnprocessableEntity},
+			"invalid expected": {`"two"`, http.StatusBadRequest},
+		}
+		for name, tc := range tests {
+			w, res := verify(router, "payments", `{"body": {"$.amount": "100"}, "expect": `+tc.expect+`}`)
+			assert.Equal(t, tc.code, w.Code, name)
+			if tc.code == http.StatusExpectationFailed {
+				assert.Equal(t, 2, res.Count, name)
+				assert.Contains(t, res.Message, "got 2", name)
+			}
+		}
+	})
+
+	t.Run("time window", func(t *testing.T) {
+		router := setup(t)
+		w, res := verify(router, "payments", `{"within": "1m", "expect": 3}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 3, res.Count)
+
+		w, res = verify(router, "payments", `{"since": "2999-01-01T00:00:00Z", "expect": 0}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 0, res.Count)
+
+		w, _ = verify(router, "payments", `{"within": "soon"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("invalid matcher", func(t *testing.T) {
+		router := setup(t)
+		w, _ := verify(router, "payments", `{"query": {"q": {"regex": "("}}}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		w, _ = verify(router, "payments", `{`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		router := setup(t)
+		w, _ := verify(router, "unknown", `{}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("history disabled", func(t *testing.T) {
+		router := setup(t)
+		cfg := config.NewServiceConfig()
+		cfg.History = &config.HistoryConfig{Enabled: new(bool)}
+		registerTestService(router, &mockService{name: "quiet", config: cfg})
+
+		w, _ := verify(router, "quiet", `{}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,271 @@
+// Package certs provides the TLS configuration of the server.
+// It loads a configured certificate or generates a local CA and the certificates issued by it,
+// so HTTPS, HTTP/2 and mutual TLS work without any setup.
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+)
+
+// File names of the generated CA and certificates in the tls directory.
+const (
+	CAFile        = "ca.pem"
+	CAKeyFile     = "ca-key.pem"
+	ServerFile    = "server.pem"
+	ServerKeyFile = "server-key.pem"
+	ClientFile    = "client.pem"
+	ClientKeyFile = "client-key.pem"
+)
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 365 * 24 * time.Hour
+
+	// renewBefore is how long before expiry a generated certificate is issued again.
+	renewBefore = 30 * 24 * time.Hour
+)
+
+// defaultHosts are always included in generated server certificates.
+var defaultHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// CA is a certificate authority issuing server and client certificates.
+type CA struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+}
+
+// Dir returns the directory of the generated certificates in the data dir.
+func Dir(dataDir string) string {
+	return filepath.Join(dataDir, "tls")
+}
+
+// ServerConfig returns the TLS configuration of the server, nil if TLS is not enabled.
+// Generated certificates are stored in the tls directory of dataDir.
+// HTTP/2 is negotiated with ALPN.
+func ServerConfig(cfg *config.TLSConfig, dataDir string) (*tls.Config, error) {
+	if !cfg.IsEnabled() {
+		return nil, nil
+	}
+
+	dir := Dir(dataDir)
+	res := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	var ca *CA
+	if cfg.CertFile == "" || (cfg.ClientAuth != "" && cfg.ClientCAFile == "") {
+		var err error
+		if ca, err = LoadOrCreateCA(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading certificate: %w", err)
+		}
+		res.Certificates = []tls.Certificate{cert}
+	} else {
+		hosts := append(slices.Clone(defaultHosts), cfg.Hosts...)
+		cert, err := ca.loadOrIssue(filepath.Join(dir, ServerFile), filepath.Join(dir, ServerKeyFile), "connexions", hosts, x509.ExtKeyUsageServerAuth)
+		if err != nil {
+			return nil, err
+		}
+		res.Certificates = []tls.Certificate{cert}
+	}
+
+	switch cfg.ClientAuth {
+	case "":
+		return res, nil
+	case config.ClientAuthRequest:
+		res.ClientAuth = tls.VerifyClientCertIfGiven
+	case config.ClientAuthRequire:
+		res.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth %q", cfg.ClientAuth)
+	}
+
+	res.ClientCAs = x509.NewCertPool()
+	if cfg.ClientCAFile != "" {
+		data, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA: %w", err)
+		}
+		if !res.ClientCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("reading client CA: no certificates in %s", cfg.ClientCAFile)
+		}
+		return res, nil
+	}
+
+	// Issue a client certificate for trying out mutual TLS right away
+	if _, err := ca.loadOrIssue(filepath.Join(dir, ClientFile), filepath.Join(dir, ClientKeyFile), "client", nil, x509.ExtKeyUsageClientAuth); err != nil {
+		return nil, err
+	}
+	res.ClientCAs.AddCert(ca.Cert)
+	return res, nil
+}
+
+// LoadOrCreateCA loads the CA from dir, generating and storing a new one if it's missing.
+func LoadOrCreateCA(dir string) (*CA, error) {
+	certPath, keyPath := filepath.Join(dir, CAFile), filepath.Join(dir, CAKeyFile)
+
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err == nil {
+		key, ok := pair.PrivateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("loading CA: %T can't sign", pair.PrivateKey)
+		}
+		return &CA{Cert: pair.Leaf, Key: key}, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("loading CA: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating CA key: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: "Connexions Local CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("creating CA: %w", err)
+	}
+	if err := writePair(certPath, keyPath, der, key); err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CA: %w", err)
+	}
+	return &CA{Cert: cert, Key: key}, nil
+}
+
+// Issue issues a certificate for the hosts, DNS names or IPs, with the given extended key usage.
+func (ca *CA) Issue(commonName string, hosts []string, usage x509.ExtKeyUsage) (tls.Certificate, error) {
+	der, key, err := ca.issue(commonName, hosts, usage)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return pairOf(der, key)
+}
+
+func (ca *CA) issue(commonName string, hosts []string, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, key.Public(), ca.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("issuing certificate: %w", err)
+	}
+	return der, key, nil
+}
+
+// loadOrIssue loads a certificate issued by the CA, issuing and storing a new one
+// if it's missing, about to expire, issued by another CA or not covering all hosts.
+func (ca *CA) loadOrIssue(certPath, keyPath, commonName string, hosts []string, usage x509.ExtKeyUsage) (tls.Certificate, error) {
+	if pair, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil && ca.issued(pair.Leaf, hosts) {
+		return pair, nil
+	}
+
+	der, key, err := ca.issue(commonName, hosts, usage)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := writePair(certPath, keyPath, der, key); err != nil {
+		return tls.Certificate{}, err
+	}
+	return pairOf(der, key)
+}
+
+// issued tells whether a stored certificate can be reused.
+func (ca *CA) issued(cert *x509.Certificate, hosts []string) bool {
+	if cert == nil || time.Until(cert.NotAfter) < renewBefore || cert.CheckSignatureFrom(ca.Cert) != nil {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func pairOf(der []byte, key *ecdsa.PrivateKey) (tls.Certificate, error) {
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parsing certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// writePair stores a certificate and its key as PEM files, the key readable only by the owner.
+func writePair(certPath, keyPath string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("encoding key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(certPath), 0o755); err != nil {
+		return fmt.Errorf("creating certificate directory: %w", err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return fmt.Errorf("writing certificate: %w", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return fmt.Errorf("writing key: %w", err)
+	}
+	return nil
+}
+
+func serialNumber() *big.Int {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return serial
+}
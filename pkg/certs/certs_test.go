@@ -0,0 +1,146 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerConfig(t *testing.T) {
+	serve := func(t *testing.T, tlsCfg *tls.Config) *httptest.Server {
+		t.Helper()
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.Proto))
+		}))
+		server.TLS = tlsCfg
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	clientFor := func(t *testing.T, dataDir string, certs ...tls.Certificate) *http.Client {
+		t.Helper()
+		ca, err := LoadOrCreateCA(Dir(dataDir))
+		require.NoError(t, err)
+
+		pool := x509.NewCertPool()
+		pool.AddCert(ca.Cert)
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: pool, Certificates: certs},
+			ForceAttemptHTTP2: true,
+		}}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		tlsCfg, err := ServerConfig(nil, t.TempDir())
+		assert.NoError(t, err)
+		assert.Nil(t, tlsCfg)
+
+		tlsCfg, err = ServerConfig(&config.TLSConfig{}, t.TempDir())
+		assert.NoError(t, err)
+		assert.Nil(t, tlsCfg)
+	})
+
+	t.Run("generates a certificate trusted by the CA and serves HTTP/2", func(t *testing.T) {
+		dataDir := t.TempDir()
+		tlsCfg, err := ServerConfig(&config.TLSConfig{Enabled: true}, dataDir)
+		require.NoError(t, err)
+
+		for _, name := range []string{CAFile, CAKeyFile, ServerFile, ServerKeyFile} {
+			assert.FileExists(t, filepath.Join(Dir(dataDir), name))
+		}
+
+		server := serve(t, tlsCfg)
+		resp, err := clientFor(t, dataDir).Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, 2, resp.ProtoMajor)
+	})
+
+	t.Run("reuses generated certificates", func(t *testing.T) {
+		dataDir := t.TempDir()
+		first, err := ServerConfig(&config.TLSConfig{Enabled: true}, dataDir)
+		require.NoError(t, err)
+
+		second, err := ServerConfig(&config.TLSConfig{Enabled: true}, dataDir)
+		require.NoError(t, err)
+		assert.Equal(t, first.Certificates[0].Certificate, second.Certificates[0].Certificate)
+
+		third, err := ServerConfig(&config.TLSConfig{Enabled: true, Hosts: []string{"mock.local"}}, dataDir)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Certificates[0].Certificate, third.Certificates[0].Certificate)
+		assert.Contains(t, third.Certificates[0].Leaf.DNSNames, "mock.local")
+	})
+
+	t.Run("loads configured certificates", func(t *testing.T) {
+		dir := t.TempDir()
+		ca, err := LoadOrCreateCA(dir)
+		require.NoError(t, err)
+		_, err = ca.loadOrIssue(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), "custom", []string{"custom.local"}, x509.ExtKeyUsageServerAuth)
+		require.NoError(t, err)
+
+		dataDir := t.TempDir()
+		tlsCfg, err := ServerConfig(&config.TLSConfig{
+			CertFile: filepath.Join(dir, "cert.pem"),
+			KeyFile:  filepath.Join(dir, "key.pem"),
+		}, dataDir)
+		require.NoError(t, err)
+		assert.Equal(t, "custom", tlsCfg.Certificates[0].Leaf.Subject.CommonName)
+		assert.NoDirExists(t, Dir(dataDir))
+
+		_, err = ServerConfig(&config.TLSConfig{CertFile: "missing.pem", KeyFile: "missing.pem"}, dataDir)
+		assert.Error(t, err)
+	})
+
+	t.Run("requires client certificates", func(t *testing.T) {
+		dataDir := t.TempDir()
+		tlsCfg, err := ServerConfig(&config.TLSConfig{Enabled: true, ClientAuth: config.ClientAuthRequire}, dataDir)
+		require.NoError(t, err)
+		assert.Equal(t, tls.RequireAndVerifyClientCert, tlsCfg.ClientAuth)
+
+		server := serve(t, tlsCfg)
+
+		_, err = clientFor(t, dataDir).Get(server.URL)
+		assert.Error(t, err)
+
+		clientCert, err := tls.LoadX509KeyPair(filepath.Join(Dir(dataDir), ClientFile), filepath.Join(Dir(dataDir), ClientKeyFile))
+		require.NoError(t, err)
+		resp, err := clientFor(t, dataDir, clientCert).Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("client CA file", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := LoadOrCreateCA(dir)
+		require.NoError(t, err)
+
+		tlsCfg, err := ServerConfig(&config.TLSConfig{
+			Enabled:      true,
+			ClientAuth:   config.ClientAuthRequest,
+			ClientCAFile: filepath.Join(dir, CAFile),
+		}, t.TempDir())
+		require.NoError(t, err)
+		assert.Equal(t, tls.VerifyClientCertIfGiven, tlsCfg.ClientAuth)
+
+		bad := filepath.Join(dir, "bad.pem")
+		require.NoError(t, os.WriteFile(bad, []byte("nope"), 0o600))
+		_, err = ServerConfig(&config.TLSConfig{Enabled: true, ClientAuth: config.ClientAuthRequest, ClientCAFile: bad}, t.TempDir())
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown client auth", func(t *testing.T) {
+		_, err := ServerConfig(&config.TLSConfig{Enabled: true, ClientAuth: "always"}, t.TempDir())
+		assert.Error(t, err)
+	})
+}
@@ -12,6 +12,7 @@ import (
 type AppConfig struct {
 	Title             string            `yaml:"title"`
 	Port              int               `yaml:"port"`
+	GRPCPort          int               `yaml:"grpcPort"`
 	BaseURL           string            `yaml:"baseURL" env:"APP_BASE_URL"`
 	InternalURL       string            `yaml:"internalURL" env:"APP_INTERNAL_URL"`
 	HomeURL           string            `yaml:"homeURL"`
@@ -22,6 +23,9 @@ type AppConfig struct {
 	Editor            *EditorConfig     `yaml:"editor"`
 	History           *AppHistoryConfig `yaml:"history"`
 	Storage           *StorageConfig    `yaml:"storage"`
+	OIDC              *OIDCConfig       `yaml:"oidc"`
+	CORS              *CORSConfig       `yaml:"cors"`
+	TLS               *TLSConfig        `yaml:"tls"`
 	Extra             map[string]any    `yaml:"extra"`
 }
 
@@ -50,6 +54,7 @@ func NewDefaultAppConfig(baseDir string) *AppConfig {
 	return &AppConfig{
 		Title:             "API Explorer",
 		Port:              2200,
+		GRPCPort:          2201,
 		HomeURL:           "/",
 		ServiceURL:        "/.services",
 		ContextAreaPrefix: "in-",
@@ -95,6 +100,118 @@ func NewAppConfigFromBytes(bts []byte, baseDir string) (*AppConfig, error) {
 	return cfg, nil
 }
 
+// Client certificate policies of TLSConfig.ClientAuth.
+const (
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+)
+
+// TLSConfig enables HTTPS with HTTP/2.
+// Without CertFile and KeyFile, a CA and a certificate issued by it are generated
+// in the tls directory of the data dir, and reused on later starts.
+//
+// Example YAML:
+//
+//	tls:
+//	  enabled: true
+//	  hosts: [mock.local]
+//	  clientAuth: require
+type TLSConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// CertFile and KeyFile are the PEM files of the server certificate and its key.
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+
+	// Hosts are the extra DNS names and IPs of the generated certificate,
+	// localhost, 127.0.0.1 and ::1 are always included.
+	Hosts []string `yaml:"hosts"`
+
+	// ClientAuth verifies client certificates: "request" verifies them when sent, "require" rejects connections without one.
+	ClientAuth string `yaml:"clientAuth"`
+
+	// ClientCAFile is the PEM file of the CAs verifying client certificates.
+	// Defaults to the generated CA.
+	ClientCAFile string `yaml:"clientCAFile"`
+}
+
+// IsEnabled tells whether the server should serve HTTPS.
+func (c *TLSConfig) IsEnabled() bool {
+	return c != nil && (c.Enabled || c.CertFile != "")
+}
+
+const (
+	DefaultOIDCURL      = "/.oidc"
+	DefaultOIDCTokenTTL = time.Hour
+)
+
+// OIDCConfig configures the built-in mock OAuth2 / OpenID Connect provider.
+// It issues JWTs signed with a local RSA key, generated at startup unless KeyFile points to one.
+//
+// Example YAML:
+//
+//	oidc:
+//	  enabled: true
+//	  issuer: http://localhost:2200/.oidc
+//	  keyFile: data/oidc-key.pem
+//	  tokenTTL: 1h
+//	  audience: petstore
+//	  claims:
+//	    tenant: acme
+//	  clients:
+//	    my-app: secret
+//	  users:
+//	    jane:
+//	      password: secret
+//	      claims:
+//	        email: jane@example.com
+type OIDCConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// URL is the mount point of the provider, defaults to /.oidc.
+	URL string `yaml:"url"`
+
+	// Issuer is the iss claim of issued tokens.
+	// Defaults to the provider URL on the host of the request.
+	Issuer string `yaml:"issuer"`
+
+	// KeyFile is a PEM file with the RSA signing key.
+	// A missing file is created with a generated key, so tokens survive restarts.
+	KeyFile string `yaml:"keyFile"`
+
+	// TokenTTL is the lifetime of access and ID tokens, defaults to 1h.
+	TokenTTL time.Duration `yaml:"tokenTTL"`
+
+	// Audience is the aud claim of access tokens, defaults to the client ID.
+	Audience string `yaml:"audience"`
+
+	// Claims are added to every issued token.
+	Claims map[string]any `yaml:"claims"`
+
+	// Clients maps accepted client IDs to their secrets, empty accepts any client.
+	Clients map[string]string `yaml:"clients"`
+
+	// Users maps accepted usernames to their passwords and claims, empty accepts any user.
+	Users map[string]*OIDCUser `yaml:"users"`
+}
+
+// OIDCUser is a user of the mock provider.
+type OIDCUser struct {
+	Password string         `yaml:"password"`
+	Claims   map[string]any `yaml:"claims"`
+}
+
+// WithDefaults fills zero-valued fields with defaults.
+func (c *OIDCConfig) WithDefaults() *OIDCConfig {
+	if c.URL == "" {
+		c.URL = DefaultOIDCURL
+	}
+	if c.TokenTTL == 0 {
+		c.TokenTTL = DefaultOIDCTokenTTL
+	}
+	return c
+}
+
 type EditorConfig struct {
 	Theme     string `yaml:"theme"`
 	DarkTheme string `yaml:"darkTheme"`
@@ -17,6 +17,7 @@ func TestNewDefaultAppConfig(t *testing.T) {
 		assert.NotNil(cfg)
 		assert.Equal("API Explorer", cfg.Title)
 		assert.Equal(2200, cfg.Port)
+		assert.Equal(2201, cfg.GRPCPort)
 		assert.Equal("/", cfg.HomeURL)
 		assert.Equal("/.services", cfg.ServiceURL)
 		assert.Equal("in-", cfg.ContextAreaPrefix)
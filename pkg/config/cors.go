@@ -0,0 +1,88 @@
+package config
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CORSConfig enables Cross-Origin Resource Sharing for browser clients.
+// Preflight requests from allowed origins are answered without reaching the service,
+// other responses from allowed origins get the Access-Control-* headers.
+// Empty lists allow any origin, method or header.
+//
+// Example YAML:
+//
+//	cors:
+//	  origins: [http://localhost:3000, https://*.example.com]
+//	  methods: [GET, POST]
+//	  headers: [Authorization, Content-Type]
+//	  expose: [X-Request-Id]
+//	  credentials: true
+//	  max-age: 10m
+type CORSConfig struct {
+	// Origins lists the allowed origins, with * wildcards.
+	Origins []string `yaml:"origins,omitempty"`
+
+	// Methods lists the methods allowed in preflight requests.
+	Methods []string `yaml:"methods,omitempty"`
+
+	// Headers lists the request headers allowed in preflight requests.
+	Headers []string `yaml:"headers,omitempty"`
+
+	// Expose lists the response headers exposed to the browser.
+	Expose []string `yaml:"expose,omitempty"`
+
+	// Credentials allows cookies and authorization headers in cross-origin requests.
+	Credentials bool `yaml:"credentials,omitempty"`
+
+	// MaxAge is how long browsers may cache preflight responses.
+	MaxAge time.Duration `yaml:"max-age,omitempty"`
+}
+
+// AllowsOrigin tells whether the origin matches one of the allowed origins.
+func (c *CORSConfig) AllowsOrigin(origin string) bool {
+	if c == nil || origin == "" {
+		return false
+	}
+	if len(c.Origins) == 0 {
+		return true
+	}
+
+	for _, pattern := range c.Origins {
+		if pattern == "*" || strings.EqualFold(pattern, origin) {
+			return true
+		}
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(origin)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsMethod tells whether the method is allowed in preflight requests.
+func (c *CORSConfig) AllowsMethod(method string) bool {
+	if c == nil {
+		return false
+	}
+	if len(c.Methods) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(c.Methods, func(m string) bool {
+		return m == "*" || strings.EqualFold(m, method)
+	})
+}
+
+// AllowsHeader tells whether the request header is allowed in preflight requests.
+func (c *CORSConfig) AllowsHeader(header string) bool {
+	if c == nil {
+		return false
+	}
+	if len(c.Headers) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(c.Headers, func(h string) bool {
+		return h == "*" || strings.EqualFold(h, header)
+	})
+}
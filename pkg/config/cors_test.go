@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSConfig_AllowsOrigin(t *testing.T) {
+	t.Run("empty list allows any origin", func(t *testing.T) {
+		cfg := &CORSConfig{}
+		assert.True(t, cfg.AllowsOrigin("http://localhost:3000"))
+		assert.False(t, cfg.AllowsOrigin(""))
+	})
+
+	t.Run("exact and wildcard origins", func(t *testing.T) {
+		cfg := &CORSConfig{Origins: []string{"http://localhost:3000", "https://*.example.com"}}
+		assert.True(t, cfg.AllowsOrigin("http://localhost:3000"))
+		assert.True(t, cfg.AllowsOrigin("https://app.Example.com"))
+		assert.False(t, cfg.AllowsOrigin("https://example.com"))
+		assert.False(t, cfg.AllowsOrigin("http://app.example.com"))
+		assert.False(t, cfg.AllowsOrigin("http://localhost:4000"))
+	})
+
+	t.Run("nil config allows nothing", func(t *testing.T) {
+		var cfg *CORSConfig
+		assert.False(t, cfg.AllowsOrigin("http://localhost:3000"))
+		assert.False(t, cfg.AllowsMethod("GET"))
+		assert.False(t, cfg.AllowsHeader("Authorization"))
+	})
+}
+
+func TestCORSConfig_AllowsMethodAndHeader(t *testing.T) {
+	cfg := &CORSConfig{Methods: []string{"GET", "POST"}, Headers: []string{"Authorization"}}
+	assert.True(t, cfg.AllowsMethod("post"))
+	assert.False(t, cfg.AllowsMethod("DELETE"))
+	assert.True(t, cfg.AllowsHeader("authorization"))
+	assert.False(t, cfg.AllowsHeader("X-Custom"))
+
+	cfg = &CORSConfig{}
+	assert.True(t, cfg.AllowsMethod("DELETE"))
+	assert.True(t, cfg.AllowsHeader("X-Custom"))
+}
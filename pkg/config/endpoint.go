@@ -0,0 +1,202 @@
+package config
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// httpMethods are the keys of the per-method form of endpoint settings.
+var httpMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// EndpointConfig overrides service settings for the requests of a single endpoint.
+// Settings left unset are inherited from the service.
+// Latency, Latencies and LatencyDistribution replace all service latency settings,
+// so latency: 0s turns the service latency off.
+// Errors is a map of percentiles to error codes, replacing the service ones.
+// Faults is a map of percentiles to transport faults, replacing the service ones.
+//
+// Example YAML:
+//
+//	endpoints:
+//	  /search:
+//	    latency: 2s
+//	  /orders/{id}:
+//	    DELETE:
+//	      errors:
+//	        p50: 409
+type EndpointConfig struct {
+	Latency             time.Duration            `yaml:"latency,omitempty"`
+	Latencies           map[string]time.Duration `yaml:"latencies,omitempty"`
+	LatencyDistribution *LatencyDistribution     `yaml:"latency-distribution,omitempty"`
+	Errors              map[string]int           `yaml:"errors,omitempty"`
+	Faults              map[string]string        `yaml:"faults,omitempty"`
+
+	latencies []*KeyValue[int, time.Duration]
+	errors    []*KeyValue[int, int]
+	faults    []*KeyValue[int, string]
+
+	// hasLatency tells whether latency is set, so latency: 0s turns the service latency off.
+	hasLatency bool
+}
+
+// UnmarshalYAML reads the settings and remembers whether the latency is set.
+func (e *EndpointConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	type plain EndpointConfig
+	if err := unmarshal((*plain)(e)); err != nil {
+		return err
+	}
+
+	var keys map[string]any
+	if err := unmarshal(&keys); err != nil {
+		return err
+	}
+	_, e.hasLatency = keys["latency"]
+	return nil
+}
+
+// EndpointConfigMethods holds the settings for any method and the settings for single methods.
+type EndpointConfigMethods struct {
+	Any     *EndpointConfig
+	Methods map[string]*EndpointConfig
+}
+
+// UnmarshalYAML reads the per-method form when every key is an HTTP method, the methodless form otherwise.
+func (m *EndpointConfigMethods) UnmarshalYAML(unmarshal func(any) error) error {
+	return unmarshalEndpointMethods(unmarshal, &m.Any, &m.Methods)
+}
+
+// unmarshalEndpointMethods reads the per-method form of endpoint settings when every key
+// is an HTTP method in any case, the methodless form otherwise. Method keys are uppercased.
+func unmarshalEndpointMethods[T any](unmarshal func(any) error, anyMethod **T, methods *map[string]*T) error {
+	var raw map[string]any
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	perMethod := len(raw) > 0
+	for key := range raw {
+		perMethod = perMethod && httpMethods[strings.ToUpper(key)]
+	}
+	if !perMethod {
+		return unmarshal(anyMethod)
+	}
+
+	var byMethod map[string]*T
+	if err := unmarshal(&byMethod); err != nil {
+		return err
+	}
+	*methods = make(map[string]*T, len(byMethod))
+	for method, ep := range byMethod {
+		(*methods)[strings.ToUpper(method)] = ep
+	}
+	return nil
+}
+
+// matchingPatterns returns the endpoint patterns matching a path, most specific first:
+// patterns with more literal segments before those with more parameters,
+// ties in the order of the patterns, so overlapping patterns always resolve the same way.
+func matchingPatterns[T any](endpoints map[string]T, path string) []string {
+	var res []string
+	for pattern := range endpoints {
+		if matchesPattern(path, pattern) {
+			res = append(res, pattern)
+		}
+	}
+	slices.SortFunc(res, func(a, b string) int {
+		return cmp.Or(
+			cmp.Compare(literalSegments(b), literalSegments(a)),
+			cmp.Compare(a, b),
+		)
+	})
+	return res
+}
+
+// literalSegments returns the number of path segments of a pattern that aren't parameters.
+func literalSegments(pattern string) int {
+	res := 0
+	for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			res++
+		}
+	}
+	return res
+}
+
+// GetEndpoint returns the settings for a resource path and method, nil if there are none.
+// The most specific matching pattern wins, see matchingPatterns.
+// Settings for the method take precedence over the methodless ones.
+func (s *ServiceConfig) GetEndpoint(resourcePath, method string) *EndpointConfig {
+	method = strings.ToUpper(method)
+	for _, pattern := range matchingPatterns(s.Endpoints, resourcePath) {
+		methods := s.Endpoints[pattern]
+		if methods == nil {
+			continue
+		}
+		if ep, ok := methods.Methods[method]; ok && ep != nil {
+			return ep
+		}
+		if methods.Any != nil {
+			return methods.Any
+		}
+	}
+	return nil
+}
+
+// ForEndpoint returns the config for the requests of an endpoint:
+// a copy with the settings of the endpoint merged over the service ones,
+// or the service config itself if the endpoint has none.
+func (s *ServiceConfig) ForEndpoint(resourcePath, method string) *ServiceConfig {
+	ep := s.GetEndpoint(resourcePath, method)
+	if ep == nil {
+		return s
+	}
+
+	res := *s
+	if ep.hasLatency || ep.Latency != 0 || ep.Latencies != nil || ep.LatencyDistribution != nil {
+		res.Latency, res.Latencies, res.latencies = ep.Latency, ep.Latencies, ep.latencies
+		res.LatencyDistribution = ep.LatencyDistribution
+	}
+	if ep.Errors != nil {
+		res.Errors, res.errors = ep.Errors, ep.errors
+	}
+	if ep.Faults != nil {
+		res.Faults, res.faults = ep.Faults, ep.faults
+	}
+	return &res
+}
+
+// parse parses the percentiles of the endpoint.
+func (e *EndpointConfig) parse() {
+	e.latencies = parsePercentiles(e.Latencies)
+	e.errors = parsePercentiles(e.Errors)
+	e.faults = parsePercentiles(e.Faults)
+}
+
+// parseEndpoints parses the percentiles of all endpoints.
+func (s *ServiceConfig) parseEndpoints() {
+	for _, methods := range s.Endpoints {
+		if methods == nil {
+			continue
+		}
+		if methods.Any != nil {
+			methods.Any.parse()
+		}
+		for _, ep := range methods.Methods {
+			if ep != nil {
+				ep.parse()
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceConfig_GetEndpoint(t *testing.T) {
+	src := `
+endpoints:
+  /pets/{id}:
+    latency: 1s
+  /orders:
+    POST:
+      latency: 2s
+    DELETE:
+      errors:
+        p100: 409
+`
+	cfg, err := NewServiceConfigFromBytes([]byte(src))
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Second, cfg.GetEndpoint("/pets/{petId}", "GET").Latency)
+	assert.Equal(t, time.Second, cfg.GetEndpoint("/pets/{id}", "DELETE").Latency)
+	assert.Equal(t, 2*time.Second, cfg.GetEndpoint("/orders", "POST").Latency)
+	assert.Equal(t, map[string]int{"p100": 409}, cfg.GetEndpoint("/orders", "DELETE").Errors)
+	assert.Nil(t, cfg.GetEndpoint("/orders", "GET"))
+	assert.Nil(t, cfg.GetEndpoint("/pets", "GET"))
+}
+
+func TestServiceConfig_GetEndpoint_Overlapping(t *testing.T) {
+	cfg, err := NewServiceConfigFromBytes([]byte(`
+endpoints:
+  /pets/{id}:
+    latency: 1s
+  /pets/mine:
+    latency: 2s
+  /{kind}/mine:
+    latency: 3s
+  /orders/{id}:
+    get:
+      latency: 4s
+`))
+	require.NoError(t, err)
+
+	for range 20 {
+		assert.Equal(t, 2*time.Second, cfg.GetEndpoint("/pets/mine", "GET").Latency)
+		assert.Equal(t, time.Second, cfg.GetEndpoint("/pets/{id}", "GET").Latency)
+		assert.Equal(t, 3*time.Second, cfg.GetEndpoint("/toys/mine", "GET").Latency)
+	}
+	assert.Equal(t, 4*time.Second, cfg.GetEndpoint("/orders/{id}", "GET").Latency)
+}
+
+func TestServiceConfig_ForEndpoint(t *testing.T) {
+	src := `
+latencies:
+  p100: 10ms
+errors:
+  p100: 500
+faults:
+  p100: reset
+endpoints:
+  /search:
+    latency: 2s
+  /health:
+    GET:
+      errors: {}
+      faults: {}
+  /ping:
+    latency: 0s
+  /reports:
+    latency-distribution:
+      type: uniform
+      min: 4s
+  /orders:
+    latencies:
+      p100: 3s
+    errors:
+      p100: 409
+    faults:
+      p100: slow-headers:1s
+`
+	cfg, err := NewServiceConfigFromBytes([]byte(src))
+	require.NoError(t, err)
+
+	t.Run("returns the service config without endpoint settings", func(t *testing.T) {
+		assert.Same(t, cfg, cfg.ForEndpoint("/pets", "GET"))
+	})
+
+	t.Run("latency replaces the service latencies", func(t *testing.T) {
+		res := cfg.ForEndpoint("/search", "GET")
+		assert.Equal(t, 2*time.Second, res.GetLatency())
+		assert.Equal(t, 500, res.GetError())
+		assert.Equal(t, FaultReset, res.GetFault())
+		assert.Equal(t, 10*time.Millisecond, cfg.GetLatency())
+	})
+
+	t.Run("zero latency turns the service latencies off", func(t *testing.T) {
+		res := cfg.ForEndpoint("/ping", "GET")
+		for range 10 {
+			assert.Zero(t, res.GetLatency())
+		}
+		assert.Equal(t, 500, res.GetError())
+		assert.Equal(t, 10*time.Millisecond, cfg.GetLatency())
+	})
+
+	t.Run("latency distribution replaces the service latencies", func(t *testing.T) {
+		res := cfg.ForEndpoint("/reports", "GET")
+		assert.Equal(t, 4*time.Second, res.GetLatency())
+		assert.Nil(t, cfg.LatencyDistribution)
+	})
+
+	t.Run("empty maps turn service settings off", func(t *testing.T) {
+		res := cfg.ForEndpoint("/health", "GET")
+		assert.Equal(t, 10*time.Millisecond, res.GetLatency())
+		assert.Zero(t, res.GetError())
+		assert.Empty(t, res.GetFault())
+	})
+
+	t.Run("percentiles replace the service ones", func(t *testing.T) {
+		res := cfg.ForEndpoint("/orders", "POST")
+		assert.Equal(t, 3*time.Second, res.GetLatency())
+		assert.Equal(t, 409, res.GetError())
+		assert.Equal(t, "slow-headers:1s", res.GetFault())
+	})
+
+	t.Run("endpoints set with OverwriteWith are parsed", func(t *testing.T) {
+		base := NewServiceConfig().WithDefaults()
+		base.OverwriteWith(cfg)
+		assert.Equal(t, 409, base.ForEndpoint("/orders", "GET").GetError())
+	})
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Transport faults, injected instead of or into a response.
+const (
+	// FaultReset resets the connection without a response.
+	FaultReset = "reset"
+
+	// FaultEmpty closes the connection without a response.
+	FaultEmpty = "empty"
+
+	// FaultTruncate closes the connection halfway through the response body.
+	FaultTruncate = "truncate"
+
+	// FaultMalformed cuts the response body in half, so it's no longer valid JSON.
+	FaultMalformed = "malformed"
+
+	// FaultHang never responds, until the client gives up or the duration passes.
+	FaultHang = "hang"
+
+	// FaultSlowHeaders trickles the response headers over the duration.
+	FaultSlowHeaders = "slow-headers"
+)
+
+// DefaultSlowHeadersDelay is how long slow-headers takes to send the headers without a duration.
+const DefaultSlowHeadersDelay = 5 * time.Second
+
+var faults = map[string]bool{
+	FaultReset:       true,
+	FaultEmpty:       true,
+	FaultTruncate:    true,
+	FaultMalformed:   true,
+	FaultHang:        true,
+	FaultSlowHeaders: true,
+}
+
+// ParseFault splits a fault into its name and optional duration, e.g. slow-headers:10s.
+func ParseFault(fault string) (string, time.Duration, error) {
+	name, rawDuration, hasDuration := strings.Cut(strings.TrimSpace(fault), ":")
+	if !faults[name] {
+		return "", 0, fmt.Errorf("unknown fault %q", name)
+	}
+	if !hasDuration {
+		return name, 0, nil
+	}
+
+	duration, err := time.ParseDuration(rawDuration)
+	if err != nil {
+		return "", 0, fmt.Errorf("fault %s: %w", name, err)
+	}
+	return name, duration, nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFault(t *testing.T) {
+	t.Run("name only", func(t *testing.T) {
+		name, duration, err := ParseFault(" reset ")
+		assert.NoError(t, err)
+		assert.Equal(t, FaultReset, name)
+		assert.Zero(t, duration)
+	})
+
+	t.Run("with duration", func(t *testing.T) {
+		name, duration, err := ParseFault("slow-headers:10s")
+		assert.NoError(t, err)
+		assert.Equal(t, FaultSlowHeaders, name)
+		assert.Equal(t, 10*time.Second, duration)
+	})
+
+	t.Run("unknown fault", func(t *testing.T) {
+		_, _, err := ParseFault("explode")
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		_, _, err := ParseFault("hang:forever")
+		assert.Error(t, err)
+	})
+}
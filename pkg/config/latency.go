@@ -0,0 +1,107 @@
+package config
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Latency distribution types.
+const (
+	LatencyNormal      = "normal"
+	LatencyLogNormal   = "log-normal"
+	LatencyExponential = "exponential"
+	LatencyUniform     = "uniform"
+
+	// LatencyEmpirical replays the durations recorded for upstream responses in the history.
+	LatencyEmpirical = "empirical"
+)
+
+// DefaultLatencySamples is the number of recent upstream durations an empirical distribution draws from.
+const DefaultLatencySamples = 1000
+
+// LatencyDistribution draws the latency of every request from a statistical distribution.
+// Type is normal, log-normal, exponential, uniform or empirical.
+// Mean is the mean of normal, log-normal and exponential, StdDev the standard deviation of normal and log-normal.
+// Min and Max are the bounds of uniform and clamp the other distributions, Max is unbounded when unset.
+// Jitter adds a uniformly distributed offset between -Jitter and +Jitter to every latency.
+// Samples is the number of recent upstream durations empirical draws from, DefaultLatencySamples by default.
+//
+// Example YAML:
+//
+//	latency-distribution:
+//	  type: log-normal
+//	  mean: 120ms
+//	  stddev: 80ms
+//	  max: 2s
+//	  jitter: 5ms
+type LatencyDistribution struct {
+	Type    string        `yaml:"type"`
+	Mean    time.Duration `yaml:"mean,omitempty"`
+	StdDev  time.Duration `yaml:"stddev,omitempty"`
+	Min     time.Duration `yaml:"min,omitempty"`
+	Max     time.Duration `yaml:"max,omitempty"`
+	Jitter  time.Duration `yaml:"jitter,omitempty"`
+	Samples int           `yaml:"samples,omitempty"`
+}
+
+// IsEmpirical returns whether latencies are drawn from recorded upstream durations.
+func (d *LatencyDistribution) IsEmpirical() bool {
+	return d != nil && d.Type == LatencyEmpirical
+}
+
+// GetSamples returns the number of recent upstream durations empirical draws from.
+func (d *LatencyDistribution) GetSamples() int {
+	if d == nil || d.Samples <= 0 {
+		return DefaultLatencySamples
+	}
+	return d.Samples
+}
+
+// Sample draws a latency with jitter applied, clamped between Min and Max.
+// Empirical distributions draw from observed and return 0 without observations,
+// the other distributions ignore it. Unknown types return 0.
+func (d *LatencyDistribution) Sample(observed []time.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+
+	var res float64
+	switch d.Type {
+	case LatencyNormal:
+		res = rand.NormFloat64()*float64(d.StdDev) + float64(d.Mean)
+	case LatencyLogNormal:
+		if d.Mean <= 0 {
+			return 0
+		}
+		// Derive the parameters of the underlying normal distribution from the mean and standard deviation
+		mean, stdDev := float64(d.Mean), float64(d.StdDev)
+		sigma2 := math.Log(1 + stdDev*stdDev/(mean*mean))
+		mu := math.Log(mean) - sigma2/2
+		res = math.Exp(mu + math.Sqrt(sigma2)*rand.NormFloat64())
+	case LatencyExponential:
+		res = rand.ExpFloat64() * float64(d.Mean)
+	case LatencyUniform:
+		res = float64(d.Min)
+		if d.Max > d.Min {
+			res += rand.Float64() * float64(d.Max-d.Min)
+		}
+	case LatencyEmpirical:
+		if len(observed) == 0 {
+			return 0
+		}
+		res = float64(observed[rand.Intn(len(observed))])
+	default:
+		return 0
+	}
+
+	if d.Jitter > 0 {
+		res += (rand.Float64()*2 - 1) * float64(d.Jitter)
+	}
+
+	res = math.Max(res, float64(max(d.Min, 0)))
+	if d.Max > 0 {
+		res = math.Min(res, float64(d.Max))
+	}
+	return time.Duration(res)
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyDistribution_Sample(t *testing.T) {
+	const n = 2000
+
+	mean := func(d *LatencyDistribution, observed []time.Duration) time.Duration {
+		var sum time.Duration
+		for range n {
+			sum += d.Sample(observed)
+		}
+		return sum / n
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		var d *LatencyDistribution
+		assert.Zero(t, d.Sample(nil))
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		d := &LatencyDistribution{Type: "pareto", Mean: time.Second}
+		assert.Zero(t, d.Sample(nil))
+	})
+
+	t.Run("normal", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyNormal, Mean: 100 * time.Millisecond, StdDev: 10 * time.Millisecond}
+		assert.InDelta(t, float64(100*time.Millisecond), float64(mean(d, nil)), float64(5*time.Millisecond))
+	})
+
+	t.Run("normal is never negative", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyNormal, Mean: time.Millisecond, StdDev: time.Second}
+		for range n {
+			assert.GreaterOrEqual(t, d.Sample(nil), time.Duration(0))
+		}
+	})
+
+	t.Run("log-normal", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyLogNormal, Mean: 100 * time.Millisecond, StdDev: 20 * time.Millisecond}
+		assert.InDelta(t, float64(100*time.Millisecond), float64(mean(d, nil)), float64(10*time.Millisecond))
+		for range n {
+			assert.Positive(t, d.Sample(nil))
+		}
+	})
+
+	t.Run("log-normal without mean", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyLogNormal, StdDev: 20 * time.Millisecond}
+		assert.Zero(t, d.Sample(nil))
+	})
+
+	t.Run("exponential", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyExponential, Mean: 50 * time.Millisecond}
+		assert.InDelta(t, float64(50*time.Millisecond), float64(mean(d, nil)), float64(10*time.Millisecond))
+	})
+
+	t.Run("uniform", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyUniform, Min: 10 * time.Millisecond, Max: 20 * time.Millisecond}
+		for range n {
+			v := d.Sample(nil)
+			assert.GreaterOrEqual(t, v, 10*time.Millisecond)
+			assert.LessOrEqual(t, v, 20*time.Millisecond)
+		}
+	})
+
+	t.Run("uniform without max", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyUniform, Min: 10 * time.Millisecond}
+		assert.Equal(t, 10*time.Millisecond, d.Sample(nil))
+	})
+
+	t.Run("clamped", func(t *testing.T) {
+		d := &LatencyDistribution{
+			Type: LatencyNormal, Mean: 100 * time.Millisecond, StdDev: 100 * time.Millisecond,
+			Min: 80 * time.Millisecond, Max: 120 * time.Millisecond,
+		}
+		for range n {
+			v := d.Sample(nil)
+			assert.GreaterOrEqual(t, v, 80*time.Millisecond)
+			assert.LessOrEqual(t, v, 120*time.Millisecond)
+		}
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyNormal, Mean: 100 * time.Millisecond, Jitter: 10 * time.Millisecond}
+		varied := false
+		for range n {
+			v := d.Sample(nil)
+			assert.InDelta(t, float64(100*time.Millisecond), float64(v), float64(10*time.Millisecond))
+			varied = varied || v != 100*time.Millisecond
+		}
+		assert.True(t, varied)
+	})
+
+	t.Run("empirical", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyEmpirical}
+		observed := []time.Duration{10 * time.Millisecond, 30 * time.Millisecond}
+		for range n {
+			assert.Contains(t, observed, d.Sample(observed))
+		}
+	})
+
+	t.Run("empirical without observations", func(t *testing.T) {
+		d := &LatencyDistribution{Type: LatencyEmpirical}
+		assert.Zero(t, d.Sample(nil))
+	})
+}
+
+func TestLatencyDistribution_GetSamples(t *testing.T) {
+	var d *LatencyDistribution
+	assert.Equal(t, DefaultLatencySamples, d.GetSamples())
+	assert.Equal(t, DefaultLatencySamples, (&LatencyDistribution{}).GetSamples())
+	assert.Equal(t, 50, (&LatencyDistribution{Samples: 50}).GetSamples())
+}
+
+func TestServiceConfig_LatencyDistribution(t *testing.T) {
+	t.Run("parses yaml", func(t *testing.T) {
+		cfg, err := NewServiceConfigFromBytes([]byte(`
+latency-distribution:
+  type: log-normal
+  mean: 120ms
+  stddev: 80ms
+  max: 2s
+  jitter: 5ms
+`))
+		require.NoError(t, err)
+		assert.Equal(t, &LatencyDistribution{
+			Type:   LatencyLogNormal,
+			Mean:   120 * time.Millisecond,
+			StdDev: 80 * time.Millisecond,
+			Max:    2 * time.Second,
+			Jitter: 5 * time.Millisecond,
+		}, cfg.LatencyDistribution)
+	})
+
+	t.Run("takes precedence over latencies", func(t *testing.T) {
+		cfg := &ServiceConfig{
+			Latencies:           map[string]time.Duration{"p100": time.Second},
+			LatencyDistribution: &LatencyDistribution{Type: LatencyUniform, Min: 5 * time.Millisecond},
+		}
+		cfg.WithDefaults()
+		assert.Equal(t, 5*time.Millisecond, cfg.GetLatency())
+	})
+
+	t.Run("empirical falls back to latencies", func(t *testing.T) {
+		cfg := &ServiceConfig{
+			Latencies:           map[string]time.Duration{"p100": time.Second},
+			LatencyDistribution: &LatencyDistribution{Type: LatencyEmpirical},
+		}
+		cfg.WithDefaults()
+		assert.Equal(t, time.Second, cfg.GetLatency())
+	})
+
+	t.Run("set latency replaces distribution and latencies", func(t *testing.T) {
+		cfg := &ServiceConfig{
+			Latencies:           map[string]time.Duration{"p100": time.Second},
+			LatencyDistribution: &LatencyDistribution{Type: LatencyUniform, Min: 5 * time.Millisecond},
+		}
+		cfg.WithDefaults()
+		cfg.SetLatency(10 * time.Millisecond)
+		assert.Equal(t, 10*time.Millisecond, cfg.GetLatency())
+		assert.Nil(t, cfg.LatencyDistribution)
+	})
+
+	t.Run("overwritten", func(t *testing.T) {
+		dist := &LatencyDistribution{Type: LatencyExponential, Mean: time.Millisecond}
+		cfg := NewServiceConfig().OverwriteWith(&ServiceConfig{LatencyDistribution: dist})
+		assert.Same(t, dist, cfg.LatencyDistribution)
+	})
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// Rate limiting algorithms.
+const (
+	RateLimitFixedWindow   = "fixed-window"
+	RateLimitSlidingWindow = "sliding-window"
+	RateLimitTokenBucket   = "token-bucket"
+)
+
+// Rate limiting client keys.
+// Header and claim keys are followed by the header or claim name, e.g. header:X-Api-Key.
+const (
+	RateLimitKeyIP     = "ip"
+	RateLimitKeyHeader = "header:"
+	RateLimitKeyClaim  = "claim:"
+)
+
+// DefaultRateLimitWindow is the window of limits without one.
+const DefaultRateLimitWindow = time.Minute
+
+// RateLimitConfig simulates the rate limiting of the service.
+// Requests over the limit get 429 with Retry-After, every limited response gets the quota headers.
+// Counters are kept in the service database, so limits hold across instances sharing Redis storage.
+//
+// Example YAML:
+//
+//	rate-limit:
+//	  algorithm: sliding-window
+//	  limit: 100
+//	  window: 1m
+//	  key: header:X-Api-Key
+//	  endpoints:
+//	    /search:
+//	      limit: 10
+//	    /orders:
+//	      POST:
+//	        algorithm: token-bucket
+//	        limit: 1
+//	        window: 1s
+//	        burst: 5
+type RateLimitConfig struct {
+	RateLimit `yaml:",inline"`
+
+	// Endpoints sets limits per path pattern, for any method or for a single one.
+	// Each endpoint limit has its own counters, unset fields are inherited from the service limit.
+	Endpoints map[string]*RateLimitEndpointMethods `yaml:"endpoints,omitempty"`
+}
+
+// RateLimit is a single limit: Limit requests per Window for each client.
+// Algorithm is fixed-window (default), sliding-window or token-bucket.
+// Burst is the bucket size of token-bucket, Limit by default.
+// Key identifies the client: ip (default), header:<name> or claim:<name> of the bearer JWT.
+// Requests without the header or claim are identified by their IP.
+type RateLimit struct {
+	Algorithm string        `yaml:"algorithm,omitempty"`
+	Limit     int           `yaml:"limit,omitempty"`
+	Window    time.Duration `yaml:"window,omitempty"`
+	Burst     int           `yaml:"burst,omitempty"`
+	Key       string        `yaml:"key,omitempty"`
+}
+
+// RateLimitEndpointMethods holds the limit for any method and the limits for single methods.
+type RateLimitEndpointMethods struct {
+	Any     *RateLimit
+	Methods map[string]*RateLimit
+}
+
+// UnmarshalYAML reads the per-method form when every key is an HTTP method, the methodless form otherwise.
+func (m *RateLimitEndpointMethods) UnmarshalYAML(unmarshal func(any) error) error {
+	return unmarshalEndpointMethods(unmarshal, &m.Any, &m.Methods)
+}
+
+// GetLimit returns the limit for a request path and method with defaults applied,
+// and the scope its counters are kept under: empty for the service limit,
+// the method and pattern of the endpoint otherwise.
+// The most specific matching pattern wins, see matchingPatterns.
+// Limits for the method take precedence over the methodless ones.
+// Returns nil if the request isn't limited.
+func (c *RateLimitConfig) GetLimit(requestPath, method string) (*RateLimit, string) {
+	if c == nil {
+		return nil, ""
+	}
+
+	method = strings.ToUpper(method)
+	res, scope := c.RateLimit, ""
+	for _, pattern := range matchingPatterns(c.Endpoints, requestPath) {
+		methods := c.Endpoints[pattern]
+		if methods == nil {
+			continue
+		}
+		if ep, ok := methods.Methods[method]; ok && ep != nil {
+			res, scope = res.overwriteWith(ep), method+" "+pattern
+			break
+		}
+		if methods.Any != nil {
+			res, scope = res.overwriteWith(methods.Any), pattern
+			break
+		}
+	}
+
+	if res.Limit <= 0 {
+		return nil, ""
+	}
+	if res.Algorithm == "" {
+		res.Algorithm = RateLimitFixedWindow
+	}
+	if res.Window <= 0 {
+		res.Window = DefaultRateLimitWindow
+	}
+	if res.Burst <= 0 {
+		res.Burst = res.Limit
+	}
+	if res.Key == "" {
+		res.Key = RateLimitKeyIP
+	}
+	return &res, scope
+}
+
+// overwriteWith returns a copy of r with the non-zero fields of other.
+func (r RateLimit) overwriteWith(other *RateLimit) RateLimit {
+	if other.Algorithm != "" {
+		r.Algorithm = other.Algorithm
+	}
+	if other.Limit != 0 {
+		r.Limit = other.Limit
+	}
+	if other.Window != 0 {
+		r.Window = other.Window
+	}
+	if other.Burst != 0 {
+		r.Burst = other.Burst
+	}
+	if other.Key != "" {
+		r.Key = other.Key
+	}
+	return r
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+)
+
+func TestRateLimitConfig_UnmarshalYAML(t *testing.T) {
+	src := `
+algorithm: sliding-window
+limit: 100
+window: 30s
+key: header:X-Api-Key
+endpoints:
+  /search:
+    limit: 10
+  /orders:
+    POST:
+      algorithm: token-bucket
+      limit: 1
+      burst: 5
+`
+	var cfg RateLimitConfig
+	require.NoError(t, yaml.Unmarshal([]byte(src), &cfg))
+
+	assert.Equal(t, RateLimit{Algorithm: RateLimitSlidingWindow, Limit: 100, Window: 30 * time.Second, Key: "header:X-Api-Key"}, cfg.RateLimit)
+	assert.Equal(t, &RateLimit{Limit: 10}, cfg.Endpoints["/search"].Any)
+	assert.Nil(t, cfg.Endpoints["/search"].Methods)
+	assert.Nil(t, cfg.Endpoints["/orders"].Any)
+	assert.Equal(t, &RateLimit{Algorithm: RateLimitTokenBucket, Limit: 1, Burst: 5}, cfg.Endpoints["/orders"].Methods["POST"])
+
+	t.Run("lowercase methods", func(t *testing.T) {
+		var cfg RateLimitConfig
+		require.NoError(t, yaml.Unmarshal([]byte(`
+endpoints:
+  /orders:
+    post:
+      limit: 1
+`), &cfg))
+		assert.Nil(t, cfg.Endpoints["/orders"].Any)
+		assert.Equal(t, &RateLimit{Limit: 1}, cfg.Endpoints["/orders"].Methods["POST"])
+
+		limit, scope := cfg.GetLimit("/orders", "post")
+		require.NotNil(t, limit)
+		assert.Equal(t, 1, limit.Limit)
+		assert.Equal(t, "POST /orders", scope)
+	})
+}
+
+func TestRateLimitConfig_GetLimit(t *testing.T) {
+	t.Run("nil config", func(t *testing.T) {
+		var cfg *RateLimitConfig
+		limit, scope := cfg.GetLimit("/pets", "GET")
+		assert.Nil(t, limit)
+		assert.Empty(t, scope)
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		limit, _ := (&RateLimitConfig{RateLimit: RateLimit{Window: time.Second}}).GetLimit("/pets", "GET")
+		assert.Nil(t, limit)
+	})
+
+	t.Run("service limit with defaults", func(t *testing.T) {
+		cfg := &RateLimitConfig{RateLimit: RateLimit{Limit: 10}}
+		limit, scope := cfg.GetLimit("/pets", "GET")
+		assert.Equal(t, &RateLimit{
+			Algorithm: RateLimitFixedWindow,
+			Limit:     10,
+			Window:    DefaultRateLimitWindow,
+			Burst:     10,
+			Key:       RateLimitKeyIP,
+		}, limit)
+		assert.Empty(t, scope)
+	})
+
+	t.Run("endpoint limits inherit unset fields", func(t *testing.T) {
+		cfg := &RateLimitConfig{
+			RateLimit: RateLimit{Algorithm: RateLimitSlidingWindow, Limit: 100, Window: time.Second},
+			Endpoints: map[string]*RateLimitEndpointMethods{
+				"/pets/{id}": {
+					Any:     &RateLimit{Limit: 5},
+					Methods: map[string]*RateLimit{"DELETE": {Limit: 1, Key: "claim:sub"}},
+				},
+				"/orders": {Any: &RateLimit{Limit: 2}},
+			},
+		}
+
+		limit, scope := cfg.GetLimit("/pets/1", "GET")
+		assert.Equal(t, 5, limit.Limit)
+		assert.Equal(t, RateLimitSlidingWindow, limit.Algorithm)
+		assert.Equal(t, time.Second, limit.Window)
+		assert.Equal(t, "/pets/{id}", scope)
+
+		limit, scope = cfg.GetLimit("/pets/1", "DELETE")
+		assert.Equal(t, 1, limit.Limit)
+		assert.Equal(t, "claim:sub", limit.Key)
+		assert.Equal(t, "DELETE /pets/{id}", scope)
+
+		limit, scope = cfg.GetLimit("/users", "GET")
+		assert.Equal(t, 100, limit.Limit)
+		assert.Empty(t, scope)
+	})
+
+	t.Run("endpoint limit without service limit", func(t *testing.T) {
+		cfg := &RateLimitConfig{
+			Endpoints: map[string]*RateLimitEndpointMethods{
+				"/orders": {Methods: map[string]*RateLimit{"POST": {Limit: 1}}},
+			},
+		}
+
+		limit, _ := cfg.GetLimit("/orders", "POST")
+		assert.Equal(t, 1, limit.Limit)
+
+		limit, _ = cfg.GetLimit("/orders", "GET")
+		assert.Nil(t, limit)
+	})
+
+	t.Run("most specific pattern wins", func(t *testing.T) {
+		cfg := &RateLimitConfig{
+			Endpoints: map[string]*RateLimitEndpointMethods{
+				"/users/{id}": {Any: &RateLimit{Limit: 10}},
+				"/users/me":   {Any: &RateLimit{Limit: 2}},
+				"/{a}/{b}":    {Methods: map[string]*RateLimit{"GET": {Limit: 50}}},
+			},
+		}
+
+		for range 20 {
+			limit, scope := cfg.GetLimit("/users/me", "GET")
+			assert.Equal(t, 2, limit.Limit)
+			assert.Equal(t, "/users/me", scope)
+
+			limit, scope = cfg.GetLimit("/users/42", "GET")
+			assert.Equal(t, 10, limit.Limit)
+			assert.Equal(t, "/users/{id}", scope)
+		}
+	})
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// RuleConfig returns a canned response for the requests it matches.
+// Rules with a higher Priority are matched first, rules with the same priority in config order.
+// Method and Path, a path pattern relative to the service, match any request when unset.
+// Query, Headers and Body match values of query parameters, headers and body fields.
+// Body fields are dotted paths into JSON bodies, e.g. card.number, or names of form fields.
+//
+// Example YAML:
+//
+//	rules:
+//	  - name: declined card
+//	    priority: 10
+//	    method: POST
+//	    path: /charges
+//	    headers:
+//	      X-Tenant: acme
+//	    body:
+//	      card.number: "4000000000000002"
+//	      amount:
+//	        regex: ^[0-9]{4,}$
+//	    response:
+//	      status: 402
+//	      body: '{"error":"card_declined"}'
+type RuleConfig struct {
+	Name     string                   `yaml:"name,omitempty" json:"name,omitempty"`
+	Priority int                      `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Method   string                   `yaml:"method,omitempty" json:"method,omitempty"`
+	Path     string                   `yaml:"path,omitempty" json:"path,omitempty"`
+	Query    map[string]*ValueMatcher `yaml:"query,omitempty" json:"query,omitempty"`
+	Headers  map[string]*ValueMatcher `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body     map[string]*ValueMatcher `yaml:"body,omitempty" json:"body,omitempty"`
+	Response *RuleResponse            `yaml:"response" json:"response"`
+}
+
+// ValueMatcher matches a single value.
+// Equals compares the value as a string, Regex matches it against a regular expression,
+// Present tells whether the value must be present or absent. All set conditions must hold.
+// In YAML and JSON, a scalar is a shorthand for equals.
+type ValueMatcher struct {
+	Equals  *string `yaml:"equals,omitempty" json:"equals,omitempty"`
+	Regex   string  `yaml:"regex,omitempty" json:"regex,omitempty"`
+	Present *bool   `yaml:"present,omitempty" json:"present,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// RuleResponse is the response of a rule.
+// The body is rendered from Template, read from File or taken from Body, in that order.
+// Without any, the response the service would give otherwise is sent with Status and Headers applied,
+// generated with Context as the replacement context if set.
+// Status defaults to 200 and ContentType to application/json.
+//
+// Templates are Go text/template templates with the request as data:
+// .Method, .Path, .Params (path parameters of the rule path), .Query, .Headers and .Body (parsed JSON).
+type RuleResponse struct {
+	Status      int               `yaml:"status,omitempty" json:"status,omitempty"`
+	Body        string            `yaml:"body,omitempty" json:"body,omitempty"`
+	File        string            `yaml:"file,omitempty" json:"file,omitempty"`
+	Template    string            `yaml:"template,omitempty" json:"template,omitempty"`
+	ContentType string            `yaml:"content-type,omitempty" json:"contentType,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Context     map[string]any    `yaml:"context,omitempty" json:"context,omitempty"`
+
+	template *template.Template
+}
+
+// RuleRequest is the request data rules are matched against and templates are rendered with.
+type RuleRequest struct {
+	Method  string
+	Path    string
+	Params  map[string]string
+	Query   map[string]string
+	Headers map[string]string
+	Body    any
+
+	// Lookups tell whether a query parameter, header or body field is present and return its value.
+	QueryValue  func(name string) (string, bool)
+	HeaderValue func(name string) (string, bool)
+	BodyValue   func(path string) (string, bool)
+}
+
+// UnmarshalYAML reads a scalar as an equals matcher, a mapping as a full matcher.
+func (m *ValueMatcher) UnmarshalYAML(unmarshal func(any) error) error {
+	var scalar string
+	if err := unmarshal(&scalar); err == nil {
+		m.Equals = &scalar
+		return nil
+	}
+
+	type plain ValueMatcher
+	return unmarshal((*plain)(m))
+}
+
+// UnmarshalJSON reads a scalar as an equals matcher, an object as a full matcher.
+func (m *ValueMatcher) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		type plain ValueMatcher
+		return json.Unmarshal(data, (*plain)(m))
+	case []any:
+		return fmt.Errorf("matcher must be a value or an object, got %s", data)
+	case string:
+		m.Equals = &v
+	default:
+		scalar := string(bytes.TrimSpace(data))
+		m.Equals = &scalar
+	}
+	return nil
+}
+
+// Parse compiles the regular expression of the matcher.
+func (m *ValueMatcher) Parse() error {
+	if m.Regex == "" {
+		return nil
+	}
+	re, err := regexp.Compile(m.Regex)
+	if err != nil {
+		return fmt.Errorf("regex %q: %w", m.Regex, err)
+	}
+	m.regex = re
+	return nil
+}
+
+// Match returns whether a value, present or not, satisfies the matcher.
+// A regex that failed to compile never matches.
+func (m *ValueMatcher) Match(value string, present bool) bool {
+	if m == nil {
+		return true
+	}
+	if m.Present != nil && *m.Present != present {
+		return false
+	}
+	if m.Equals != nil && (!present || value != *m.Equals) {
+		return false
+	}
+	if m.Regex != "" {
+		if !present {
+			return false
+		}
+		re := m.regex
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(m.Regex); err != nil {
+				return false
+			}
+		}
+		if !re.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse compiles the regular expressions and the template of the rule.
+func (r *RuleConfig) Parse() error {
+	for _, matchers := range []map[string]*ValueMatcher{r.Query, r.Headers, r.Body} {
+		for name, m := range matchers {
+			if m == nil {
+				continue
+			}
+			if err := m.Parse(); err != nil {
+				return fmt.Errorf("rule %s: %s: %w", r.Name, name, err)
+			}
+		}
+	}
+
+	if r.Response != nil && r.Response.Template != "" {
+		tmpl, err := template.New(r.Name).Option("missingkey=zero").Parse(r.Response.Template)
+		if err != nil {
+			return fmt.Errorf("rule %s: template: %w", r.Name, err)
+		}
+		r.Response.template = tmpl
+	}
+	return nil
+}
+
+// Matches returns whether the rule matches the request.
+func (r *RuleConfig) Matches(req *RuleRequest) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+	if r.Path != "" && !matchesPattern(req.Path, r.Path) {
+		return false
+	}
+	for name, m := range r.Query {
+		if !m.Match(req.QueryValue(name)) {
+			return false
+		}
+	}
+	for name, m := range r.Headers {
+		if !m.Match(req.HeaderValue(name)) {
+			return false
+		}
+	}
+	for path, m := range r.Body {
+		if !m.Match(req.BodyValue(path)) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsGenerated returns whether the response is the one the service would give otherwise.
+func (r *RuleResponse) IsGenerated() bool {
+	return r.Template == "" && r.File == "" && r.Body == ""
+}
+
+// Render returns the body of the response for the request: rendered from the template,
+// read from the file, or the inline body. Files are read on every request, so edits apply immediately.
+func (r *RuleResponse) Render(req *RuleRequest) ([]byte, error) {
+	switch {
+	case r.Template != "":
+		tmpl := r.template
+		if tmpl == nil {
+			var err error
+			if tmpl, err = template.New("").Option("missingkey=zero").Parse(r.Template); err != nil {
+				return nil, fmt.Errorf("template: %w", err)
+			}
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, req); err != nil {
+			return nil, fmt.Errorf("template: %w", err)
+		}
+		return buf.Bytes(), nil
+	case r.File != "":
+		return os.ReadFile(r.File)
+	}
+	return []byte(r.Body), nil
+}
+
+// GetRules returns the rules in matching order: by descending priority, then in config order.
+func (s *ServiceConfig) GetRules() []*RuleConfig {
+	if s.rules == nil && len(s.Rules) > 0 {
+		return sortRules(s.Rules, false)
+	}
+	return s.rules
+}
+
+// ParseRules compiles the rules and returns the first one failing, with its index.
+// Loaders call it so a typo in a regex or template is reported rather than turning the rule off.
+func (s *ServiceConfig) ParseRules() error {
+	for i, rule := range s.Rules {
+		if rule == nil {
+			continue
+		}
+		if err := rule.Parse(); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// parseRules compiles and orders the rules.
+// Rules that fail to compile are left out, ParseRules reports them.
+func (s *ServiceConfig) parseRules() {
+	s.rules = sortRules(s.Rules, true)
+}
+
+// sortRules returns the rules with a response by descending priority, then in config order.
+// With parse, rules that fail to compile are left out.
+func sortRules(rules []*RuleConfig, parse bool) []*RuleConfig {
+	res := make([]*RuleConfig, 0, len(rules))
+	for _, rule := range rules {
+		if rule == nil || rule.Response == nil || (parse && rule.Parse() != nil) {
+			continue
+		}
+		res = append(res, rule)
+	}
+	slices.SortStableFunc(res, func(a, b *RuleConfig) int {
+		return b.Priority - a.Priority
+	})
+	return res
+}
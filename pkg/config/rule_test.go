@@ -0,0 +1,279 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+)
+
+func newTestRuleRequest(method, path string, query, headers, body map[string]string) *RuleRequest {
+	lookup := func(values map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			v, ok := values[name]
+			return v, ok
+		}
+	}
+	return &RuleRequest{
+		Method:      method,
+		Path:        path,
+		Query:       query,
+		Headers:     headers,
+		QueryValue:  lookup(query),
+		HeaderValue: lookup(headers),
+		BodyValue:   lookup(body),
+	}
+}
+
+func TestValueMatcher_UnmarshalYAML(t *testing.T) {
+	src := `
+rules:
+  - path: /charges
+    query:
+      expand:
+        present: true
+    body:
+      card.number: "4242"
+      amount: 100
+      currency:
+        regex: ^[A-Z]{3}$
+        equals: EUR
+    response:
+      status: 402
+`
+	cfg, err := NewServiceConfigFromBytes([]byte(src))
+	require.NoError(t, err)
+	require.Len(t, cfg.Rules, 1)
+
+	rule := cfg.Rules[0]
+	require.NotNil(t, rule.Body["card.number"].Equals)
+	assert.Equal(t, "4242", *rule.Body["card.number"].Equals)
+	assert.Equal(t, "100", *rule.Body["amount"].Equals)
+	assert.Equal(t, "^[A-Z]{3}$", rule.Body["currency"].Regex)
+	assert.Equal(t, "EUR", *rule.Body["currency"].Equals)
+	require.NotNil(t, rule.Query["expand"].Present)
+	assert.True(t, *rule.Query["expand"].Present)
+	assert.Nil(t, rule.Query["expand"].Equals)
+}
+
+func TestValueMatcher_Match(t *testing.T) {
+	yes, no := true, false
+	eur := "EUR"
+
+	tests := []struct {
+		name    string
+		matcher *ValueMatcher
+		value   string
+		present bool
+		want    bool
+	}{
+		{"nil matches anything", nil, "", false, true},
+		{"equals", &ValueMatcher{Equals: &eur}, "EUR", true, true},
+		{"equals other value", &ValueMatcher{Equals: &eur}, "USD", true, false},
+		{"equals missing", &ValueMatcher{Equals: &eur}, "", false, false},
+		{"regex", &ValueMatcher{Regex: "^[A-Z]{3}$"}, "USD", true, true},
+		{"regex mismatch", &ValueMatcher{Regex: "^[A-Z]{3}$"}, "usd", true, false},
+		{"regex missing", &ValueMatcher{Regex: ".*"}, "", false, false},
+		{"invalid regex", &ValueMatcher{Regex: "("}, "(", true, false},
+		{"present", &ValueMatcher{Present: &yes}, "", true, true},
+		{"present missing", &ValueMatcher{Present: &yes}, "", false, false},
+		{"absent", &ValueMatcher{Present: &no}, "", false, true},
+		{"absent present", &ValueMatcher{Present: &no}, "x", true, false},
+		{"all conditions", &ValueMatcher{Equals: &eur, Regex: "^E"}, "EUR", true, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.matcher.Match(tc.value, tc.present))
+		})
+	}
+}
+
+func TestRuleConfig_Matches(t *testing.T) {
+	eur := "EUR"
+	rule := &RuleConfig{
+		Method:  http.MethodPost,
+		Path:    "/charges/{id}",
+		Query:   map[string]*ValueMatcher{"expand": {Regex: "^card"}},
+		Headers: map[string]*ValueMatcher{"X-Tenant": {Equals: &eur}},
+		Body:    map[string]*ValueMatcher{"card.number": {Regex: "^4"}},
+	}
+	query := map[string]string{"expand": "card.brand"}
+	headers := map[string]string{"X-Tenant": "EUR"}
+	body := map[string]string{"card.number": "4242"}
+
+	t.Run("all match", func(t *testing.T) {
+		assert.True(t, rule.Matches(newTestRuleRequest("post", "/charges/1", query, headers, body)))
+	})
+
+	t.Run("method", func(t *testing.T) {
+		assert.False(t, rule.Matches(newTestRuleRequest(http.MethodGet, "/charges/1", query, headers, body)))
+	})
+
+	t.Run("path", func(t *testing.T) {
+		assert.False(t, rule.Matches(newTestRuleRequest(http.MethodPost, "/charges", query, headers, body)))
+	})
+
+	t.Run("query", func(t *testing.T) {
+		assert.False(t, rule.Matches(newTestRuleRequest(http.MethodPost, "/charges/1", nil, headers, body)))
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		assert.False(t, rule.Matches(newTestRuleRequest(http.MethodPost, "/charges/1", query, nil, body)))
+	})
+
+	t.Run("body", func(t *testing.T) {
+		other := map[string]string{"card.number": "5555"}
+		assert.False(t, rule.Matches(newTestRuleRequest(http.MethodPost, "/charges/1", query, headers, other)))
+	})
+
+	t.Run("empty rule matches anything", func(t *testing.T) {
+		assert.True(t, (&RuleConfig{}).Matches(newTestRuleRequest(http.MethodGet, "/", nil, nil, nil)))
+	})
+}
+
+func TestRuleResponse_Render(t *testing.T) {
+	req := newTestRuleRequest(http.MethodGet, "/pets/1", map[string]string{"expand": "owner"}, nil, nil)
+	req.Params = map[string]string{"id": "1"}
+	req.Body = map[string]any{"name": "Rex"}
+
+	t.Run("template", func(t *testing.T) {
+		res := &RuleResponse{
+			Template: `{"id":"{{ .Params.id }}","expand":"{{ .Query.expand }}","name":"{{ .Body.name }}"}`,
+			Body:     "ignored",
+		}
+		body, err := res.Render(req)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"1","expand":"owner","name":"Rex"}`, string(body))
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := (&RuleResponse{Template: "{{ .Params.id"}).Render(req)
+		assert.Error(t, err)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "pet.json")
+		require.NoError(t, os.WriteFile(file, []byte(`{"id":1}`), 0o644))
+
+		body, err := (&RuleResponse{File: file, Body: "ignored"}).Render(req)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":1}`, string(body))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := (&RuleResponse{File: filepath.Join(t.TempDir(), "missing.json")}).Render(req)
+		assert.Error(t, err)
+	})
+
+	t.Run("body", func(t *testing.T) {
+		body, err := (&RuleResponse{Body: `{"id":2}`}).Render(req)
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":2}`, string(body))
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		assert.True(t, (&RuleResponse{Status: 404}).IsGenerated())
+		assert.False(t, (&RuleResponse{Body: "{}"}).IsGenerated())
+	})
+}
+
+func TestServiceConfig_GetRules(t *testing.T) {
+	src := `
+rules:
+  - name: first
+    response:
+      status: 200
+  - name: invalid
+    priority: 5
+    query:
+      q:
+        regex: "("
+    response:
+      status: 200
+  - name: no-response
+    priority: 5
+  - name: high
+    priority: 10
+    response:
+      status: 200
+  - name: second
+    response:
+      status: 200
+`
+	// Loaders report invalid rules, configs built in code leave them out
+	_, err := NewServiceConfigFromBytes([]byte(src))
+	assert.EqualError(t, err, `parsing service config: rules[1]: rule invalid: q: regex "(": error parsing regexp: missing closing ): `+"`(`")
+
+	cfg := &ServiceConfig{}
+	require.NoError(t, yaml.Unmarshal([]byte(src), cfg))
+	cfg.WithDefaults()
+
+	names := func(rules []*RuleConfig) []string {
+		res := make([]string, 0, len(rules))
+		for _, r := range rules {
+			res = append(res, r.Name)
+		}
+		return res
+	}
+	assert.Equal(t, []string{"high", "first", "second"}, names(cfg.GetRules()))
+
+	t.Run("unparsed config", func(t *testing.T) {
+		unparsed := &ServiceConfig{Rules: cfg.Rules}
+		assert.Equal(t, []string{"high", "invalid", "first", "second"}, names(unparsed.GetRules()))
+	})
+
+	t.Run("reports invalid templates", func(t *testing.T) {
+		_, err := NewServiceConfigFromBytes([]byte(`
+rules:
+  - name: broken
+    response:
+      template: "{{ .Query "
+`))
+		assert.ErrorContains(t, err, "rules[0]: rule broken: template:")
+	})
+
+	t.Run("overwrite replaces rules", func(t *testing.T) {
+		other := &ServiceConfig{Rules: []*RuleConfig{{Name: "other", Response: &RuleResponse{}}}}
+		assert.Equal(t, []string{"other"}, names(NewServiceConfig().OverwriteWith(other).GetRules()))
+	})
+}
+
+func TestValueMatcher_UnmarshalJSON(t *testing.T) {
+	var matchers map[string]*ValueMatcher
+	err := json.Unmarshal([]byte(`{
+		"number": "4242",
+		"amount": 100,
+		"express": true,
+		"currency": {"regex": "^[A-Z]{3}$"},
+		"coupon": {"present": false}
+	}`), &matchers)
+	require.NoError(t, err)
+
+	assert.Equal(t, "4242", *matchers["number"].Equals)
+	assert.Equal(t, "100", *matchers["amount"].Equals)
+	assert.Equal(t, "true", *matchers["express"].Equals)
+	assert.Equal(t, "^[A-Z]{3}$", matchers["currency"].Regex)
+	assert.Nil(t, matchers["currency"].Equals)
+	assert.False(t, *matchers["coupon"].Present)
+
+	t.Run("round-trip", func(t *testing.T) {
+		data, err := json.Marshal(matchers)
+		require.NoError(t, err)
+
+		var res map[string]*ValueMatcher
+		require.NoError(t, json.Unmarshal(data, &res))
+		assert.Equal(t, "4242", *res["number"].Equals)
+		assert.Equal(t, "^[A-Z]{3}$", res["currency"].Regex)
+	})
+
+	t.Run("array", func(t *testing.T) {
+		var m ValueMatcher
+		assert.Error(t, json.Unmarshal([]byte(`[1, 2]`), &m))
+	})
+}
@@ -0,0 +1,386 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// RuntimeConfigKeys are the service config keys that can be changed while the server runs.
+// Of the upstream settings, only the URL and the circuit breaker can be changed.
+var RuntimeConfigKeys = []string{"latency", "latencies", "latency-distribution", "errors", "cache", "upstream"}
+
+// runtimeUpstreamKeys are the upstream config keys that can be changed while the server runs.
+var runtimeUpstreamKeys = []string{"url", "circuit-breaker"}
+
+// WithPatch returns a copy of the config with a patch applied, leaving the config unchanged.
+// The patch is a partial service config, YAML or JSON, holding only RuntimeConfigKeys.
+// Mappings are merged, other values replaced. Null removes a percentile or resets a value.
+func (s *ServiceConfig) WithPatch(patch []byte) (*ServiceConfig, error) {
+	doc, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	res := s.runtimeCopy()
+	if err := doc.Decode(res); err != nil {
+		return nil, fmt.Errorf("decoding config patch: %w", err)
+	}
+	for _, name := range nullKeys(mappingValue(doc, "latencies")) {
+		delete(res.Latencies, name)
+	}
+	for _, name := range nullKeys(mappingValue(doc, "errors")) {
+		delete(res.Errors, name)
+	}
+
+	if err := res.validateRuntime(); err != nil {
+		return nil, err
+	}
+
+	// Endpoints and rules are shared with the config, so only the changed values are parsed again
+	res.latencies = res.parseLatencies()
+	res.errors = res.parseErrors()
+	return res, nil
+}
+
+// RuntimeSettings returns the values of RuntimeConfigKeys as they're written in the config.
+func (s *ServiceConfig) RuntimeSettings() (map[string]any, error) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("encoding service config: %w", err)
+	}
+	var all map[string]any
+	if err := yaml.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("decoding service config: %w", err)
+	}
+
+	res := make(map[string]any, len(RuntimeConfigKeys))
+	for _, key := range RuntimeConfigKeys {
+		if value, ok := all[key]; ok {
+			res[key] = value
+		}
+	}
+	if upstream, ok := res["upstream"].(map[string]any); ok {
+		maps.DeleteFunc(upstream, func(key string, _ any) bool {
+			return !slices.Contains(runtimeUpstreamKeys, key)
+		})
+	}
+	return res, nil
+}
+
+// ErrInvalidConfigPatch is returned for config patches that can't be parsed.
+var ErrInvalidConfigPatch = errors.New("invalid config patch")
+
+// MergeYAML merges a patch into a YAML document at the mapping found by following path,
+// keeping the comments and the order of the document. Mappings are merged, other values replaced,
+// null removes a value. The patch may be JSON.
+func MergeYAML(src, patch []byte, path ...string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, fmt.Errorf("parsing document: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var patchDoc yaml.Node
+	if err := yaml.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, fmt.Errorf("parsing patch: %w", err)
+	}
+	if len(patchDoc.Content) == 0 || patchDoc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("patch must be a mapping")
+	}
+
+	target := doc.Content[0]
+	for _, key := range path {
+		if target.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a mapping", key)
+		}
+		next := mappingValue(target, key)
+		if next == nil || next.Kind != yaml.MappingNode {
+			next = &yaml.Node{Kind: yaml.MappingNode}
+			setMappingValue(target, key, next)
+		}
+		target = next
+	}
+	mergeNodes(target, patchDoc.Content[0])
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("encoding document: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encoding document: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// parsePatch parses a config patch and checks it only holds RuntimeConfigKeys.
+func parsePatch(patch []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(patch, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfigPatch, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%w: must be a mapping", ErrInvalidConfigPatch)
+	}
+
+	root := doc.Content[0]
+	for i := 0; i < len(root.Content); i += 2 {
+		key := root.Content[i].Value
+		if !slices.Contains(RuntimeConfigKeys, key) {
+			return nil, fmt.Errorf("%s can't be changed at runtime", key)
+		}
+	}
+	if upstream := mappingValue(root, "upstream"); upstream != nil && upstream.Kind == yaml.MappingNode {
+		for i := 0; i < len(upstream.Content); i += 2 {
+			key := upstream.Content[i].Value
+			if !slices.Contains(runtimeUpstreamKeys, key) {
+				return nil, fmt.Errorf("upstream.%s can't be changed at runtime", key)
+			}
+		}
+	}
+	return root, nil
+}
+
+// runtimeCopy returns a shallow copy of the config with everything a patch can reach deep-copied,
+// so decoding a patch into it doesn't change the config.
+func (s *ServiceConfig) runtimeCopy() *ServiceConfig {
+	res := *s
+	res.Latencies = maps.Clone(s.Latencies)
+	res.Errors = maps.Clone(s.Errors)
+	res.LatencyDistribution = clonePointer(s.LatencyDistribution)
+	res.Cache = s.Cache.clone()
+	res.Upstream = s.Upstream.clone()
+	return &res
+}
+
+// clonePointer returns a pointer to a copy of the value, nil for nil.
+func clonePointer[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	res := *v
+	return &res
+}
+
+// clone returns a deep copy of the cache config.
+func (c *CacheConfig) clone() *CacheConfig {
+	if c == nil {
+		return nil
+	}
+	res := *c
+	if c.Replay != nil {
+		replay := *c.Replay
+		replay.Endpoints = make(map[string]map[string]*ReplayEndpoint, len(c.Replay.Endpoints))
+		for path, methods := range c.Replay.Endpoints {
+			cloned := make(map[string]*ReplayEndpoint, len(methods))
+			for method, ep := range methods {
+				cloned[method] = ep.clone()
+			}
+			replay.Endpoints[path] = cloned
+		}
+		if c.Replay.Endpoints == nil {
+			replay.Endpoints = nil
+		}
+		res.Replay = &replay
+	}
+	return &res
+}
+
+// clone returns a deep copy of the replay endpoint.
+func (e *ReplayEndpoint) clone() *ReplayEndpoint {
+	if e == nil {
+		return nil
+	}
+	res := *e
+	if e.Match != nil {
+		res.Match = &ReplayMatch{
+			Path:  slices.Clone(e.Match.Path),
+			Body:  slices.Clone(e.Match.Body),
+			Query: slices.Clone(e.Match.Query),
+		}
+	}
+	return &res
+}
+
+// clone returns a deep copy of the upstream config.
+func (c *UpstreamConfig) clone() *UpstreamConfig {
+	if c == nil {
+		return nil
+	}
+	res := *c
+	res.Headers = maps.Clone(c.Headers)
+	res.Record = clonePointer(c.Record)
+	if c.CircuitBreaker != nil {
+		cb := *c.CircuitBreaker
+		cb.TripOnStatus = c.CircuitBreaker.TripOnStatus.clone()
+		res.CircuitBreaker = &cb
+	}
+	if c.FailOn != nil {
+		failOn := c.FailOn.clone()
+		res.FailOn = &failOn
+	}
+	return &res
+}
+
+// clone returns a deep copy of the status matchers.
+func (ss HTTPStatusMatchConfig) clone() HTTPStatusMatchConfig {
+	if ss == nil {
+		return nil
+	}
+	res := make(HTTPStatusMatchConfig, len(ss))
+	for i, status := range ss {
+		status.Except = slices.Clone(status.Except)
+		res[i] = status
+	}
+	return res
+}
+
+// validateRuntime checks the values that can be changed at runtime.
+func (s *ServiceConfig) validateRuntime() error {
+	if s.Latency < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	for name, latency := range s.Latencies {
+		if !isPercentile(name) {
+			return fmt.Errorf("latencies: invalid percentile %s", name)
+		}
+		if latency < 0 {
+			return fmt.Errorf("latencies: %s must not be negative", name)
+		}
+	}
+	for name, code := range s.Errors {
+		if !isPercentile(name) {
+			return fmt.Errorf("errors: invalid percentile %s", name)
+		}
+		if code < 100 || code > 599 {
+			return fmt.Errorf("errors: %s is not an HTTP status code", name)
+		}
+	}
+
+	if dist := s.LatencyDistribution; dist != nil {
+		switch dist.Type {
+		case LatencyNormal, LatencyLogNormal, LatencyExponential, LatencyUniform, LatencyEmpirical:
+		default:
+			return fmt.Errorf("latency-distribution: unknown type %q", dist.Type)
+		}
+	}
+
+	if s.Cache != nil && s.Cache.Replay != nil && s.Cache.Replay.TTL < 0 {
+		return fmt.Errorf("cache.replay.ttl must not be negative")
+	}
+
+	if s.Upstream == nil {
+		return nil
+	}
+	if s.Upstream.URL != "" {
+		u, err := url.Parse(s.Upstream.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("upstream.url must be an http or https URL")
+		}
+	}
+	if cb := s.Upstream.CircuitBreaker; cb != nil {
+		if cb.Timeout < 0 || cb.Interval < 0 {
+			return fmt.Errorf("upstream.circuit-breaker durations must not be negative")
+		}
+		if cb.FailureRatio < 0 || cb.FailureRatio > 1 {
+			return fmt.Errorf("upstream.circuit-breaker.failure-ratio must be between 0 and 1")
+		}
+	}
+	return nil
+}
+
+// isPercentile returns whether a key is a percentile, e.g. p95.
+func isPercentile(key string) bool {
+	p, err := strconv.Atoi(strings.TrimPrefix(key, "p"))
+	return err == nil && strings.HasPrefix(key, "p") && p >= 0 && p <= 100
+}
+
+// mappingValue returns the value of a key in a mapping node, nil if there is none.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets the value of a key in a mapping node, appending the key if it's missing.
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// nullKeys returns the keys of a mapping node with a null value.
+func nullKeys(node *yaml.Node) []string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var res []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if isNull(node.Content[i+1]) {
+			res = append(res, node.Content[i].Value)
+		}
+	}
+	return res
+}
+
+// mergeNodes merges the patch mapping node into the target one.
+func mergeNodes(target, patch *yaml.Node) {
+	for i := 0; i+1 < len(patch.Content); i += 2 {
+		key, value := patch.Content[i].Value, patch.Content[i+1]
+
+		if isNull(value) {
+			for j := 0; j+1 < len(target.Content); j += 2 {
+				if target.Content[j].Value == key {
+					target.Content = slices.Delete(target.Content, j, j+2)
+					break
+				}
+			}
+			continue
+		}
+
+		existing := mappingValue(target, key)
+		if existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			mergeNodes(existing, value)
+			continue
+		}
+		value = blockStyle(value)
+		if existing != nil {
+			value.HeadComment, value.LineComment, value.FootComment = existing.HeadComment, existing.LineComment, existing.FootComment
+		}
+		setMappingValue(target, key, value)
+	}
+}
+
+// blockStyle clears the JSON styles of a patch node, so it's written like the rest of the document.
+func blockStyle(node *yaml.Node) *yaml.Node {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+	return node
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceConfig_WithPatch(t *testing.T) {
+	newConfig := func(t *testing.T) *ServiceConfig {
+		t.Helper()
+		cfg, err := NewServiceConfigFromBytes([]byte(`
+name: payments
+latency: 10ms
+latencies:
+  p50: 10ms
+  p99: 1s
+errors:
+  p5: 500
+cache:
+  requests: true
+  replay:
+    ttl: 1h
+upstream:
+  url: http://localhost:8080
+  timeout: 5s
+  circuit-breaker:
+    failure-ratio: 0.5
+endpoints:
+  /search:
+    latency: 2s
+`))
+		require.NoError(t, err)
+		return cfg
+	}
+
+	t.Run("merges mappings and replaces values", func(t *testing.T) {
+		cfg := newConfig(t)
+		res, err := cfg.WithPatch([]byte(`{
+			"latency": "50ms",
+			"latencies": {"p99": null, "p90": "200ms"},
+			"errors": {"p10": 503},
+			"cache": {"replay": {"auto-replay": true}},
+			"upstream": {"url": "https://api.example.com", "circuit-breaker": {"max-requests": 3}}
+		}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, 50*time.Millisecond, res.Latency)
+		assert.Equal(t, map[string]time.Duration{"p50": 10 * time.Millisecond, "p90": 200 * time.Millisecond}, res.Latencies)
+		assert.Equal(t, map[string]int{"p5": 500, "p10": 503}, res.Errors)
+		assert.True(t, res.Cache.Requests)
+		assert.True(t, res.Cache.Replay.AutoReplay)
+		assert.Equal(t, time.Hour, res.Cache.Replay.TTL)
+		assert.Equal(t, "https://api.example.com", res.Upstream.URL)
+		assert.Equal(t, 5*time.Second, res.Upstream.Timeout)
+		assert.Equal(t, uint32(3), res.Upstream.CircuitBreaker.MaxRequests)
+		assert.Equal(t, 0.5, res.Upstream.CircuitBreaker.FailureRatio)
+
+		// Parsed percentiles follow the patch
+		for range 10 {
+			assert.LessOrEqual(t, res.GetLatency(), 200*time.Millisecond)
+		}
+
+		// Settings that can't be patched are kept
+		assert.Equal(t, "payments", res.Name)
+		assert.Equal(t, 2*time.Second, res.ForEndpoint("/search", "GET").Latency)
+	})
+
+	t.Run("leaves the config unchanged", func(t *testing.T) {
+		cfg := newConfig(t)
+		_, err := cfg.WithPatch([]byte(`
+latencies:
+  p99: null
+errors:
+  p5: 400
+cache:
+  requests: false
+  replay:
+    ttl: 2h
+upstream:
+  url: https://other.example.com
+  circuit-breaker:
+    failure-ratio: 0.9
+`))
+		require.NoError(t, err)
+
+		assert.Len(t, cfg.Latencies, 2)
+		assert.Equal(t, 500, cfg.Errors["p5"])
+		assert.True(t, cfg.Cache.Requests)
+		assert.Equal(t, time.Hour, cfg.Cache.Replay.TTL)
+		assert.Equal(t, "http://localhost:8080", cfg.Upstream.URL)
+		assert.Equal(t, 0.5, cfg.Upstream.CircuitBreaker.FailureRatio)
+	})
+
+	t.Run("leaves nested maps of the config unchanged", func(t *testing.T) {
+		cfg, err := NewServiceConfigFromBytes([]byte(`
+cache:
+  replay:
+    endpoints:
+      /a:
+        POST:
+          match:
+            body: [id]
+upstream:
+  url: http://localhost:8080
+  headers:
+    X-Key: one
+  circuit-breaker:
+    trip-on-status:
+      - range: 500-599
+        except: [501]
+`))
+		require.NoError(t, err)
+
+		res, err := cfg.WithPatch([]byte(`
+cache:
+  replay:
+    endpoints:
+      /a:
+        POST:
+          match:
+            body: [name]
+      /b:
+        POST: {}
+upstream:
+  circuit-breaker:
+    trip-on-status:
+      - exact: 502
+`))
+		require.NoError(t, err)
+
+		assert.Len(t, res.Cache.Replay.Endpoints, 2)
+		assert.Len(t, cfg.Cache.Replay.Endpoints, 1)
+		assert.Equal(t, []string{"id"}, cfg.Cache.Replay.Endpoints["/a"]["POST"].Match.Body)
+		assert.Equal(t, HTTPStatusMatchConfig{{Range: "500-599", Except: []int{501}}}, cfg.Upstream.CircuitBreaker.TripOnStatus)
+
+		res.Upstream.Headers["X-Key"] = "two"
+		assert.Equal(t, "one", cfg.Upstream.Headers["X-Key"])
+	})
+
+	t.Run("failed patches leave the config unchanged", func(t *testing.T) {
+		cfg := newConfig(t)
+		_, err := cfg.WithPatch([]byte(`{"cache": {"replay": {"endpoints": {"/b": {"POST": {}}}, "ttl": "-1s"}}}`))
+		require.Error(t, err)
+		assert.Empty(t, cfg.Cache.Replay.Endpoints)
+		assert.Equal(t, time.Hour, cfg.Cache.Replay.TTL)
+	})
+
+	t.Run("null removes the upstream", func(t *testing.T) {
+		res, err := newConfig(t).WithPatch([]byte(`{"upstream": null}`))
+		require.NoError(t, err)
+		assert.Nil(t, res.Upstream)
+	})
+
+	t.Run("rejects invalid patches", func(t *testing.T) {
+		tests := map[string]string{
+			"not a mapping":        `[1]`,
+			"invalid yaml":         `{`,
+			"unknown key":          `{"name": "other"}`,
+			"not a runtime key":    `{"scenarios": {}}`,
+			"upstream headers":     `{"upstream": {"headers": {"X-Key": "1"}}}`,
+			"negative latency":     `{"latency": "-1s"}`,
+			"invalid duration":     `{"latency": "soon"}`,
+			"invalid percentile":   `{"errors": {"half": 500}}`,
+			"invalid status":       `{"errors": {"p5": 42}}`,
+			"invalid distribution": `{"latency-distribution": {"type": "gaussian"}}`,
+			"invalid url":          `{"upstream": {"url": "localhost"}}`,
+			"invalid ratio":        `{"upstream": {"circuit-breaker": {"failure-ratio": 2}}}`,
+		}
+		for name, patch := range tests {
+			_, err := newConfig(t).WithPatch([]byte(patch))
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestMergeYAML(t *testing.T) {
+	src := `# Payments service
+name: payments
+latency: 10ms # default latency
+errors:
+  p5: 500
+upstream:
+  url: http://localhost:8080
+`
+
+	t.Run("merges and keeps comments", func(t *testing.T) {
+		res, err := MergeYAML([]byte(src), []byte(`{"latency": "50ms", "errors": {"p10": 503}, "upstream": null, "cache": {"requests": true}}`))
+		require.NoError(t, err)
+		assert.Equal(t, `# Payments service
+name: payments
+latency: 50ms # default latency
+errors:
+  p5: 500
+  p10: 503
+cache:
+  requests: true
+`, string(res))
+	})
+
+	t.Run("under a path", func(t *testing.T) {
+		res, err := MergeYAML([]byte("app:\n  port: 2200\n"), []byte(`{"latency": "50ms"}`), "services", "payments")
+		require.NoError(t, err)
+		assert.Equal(t, "app:\n  port: 2200\nservices:\n  payments:\n    latency: 50ms\n", string(res))
+	})
+
+	t.Run("empty document", func(t *testing.T) {
+		res, err := MergeYAML(nil, []byte(`latency: 50ms`))
+		require.NoError(t, err)
+		assert.Equal(t, "latency: 50ms\n", string(res))
+	})
+
+	t.Run("keeps string values", func(t *testing.T) {
+		res, err := MergeYAML(nil, []byte(`{"name": "500"}`))
+		require.NoError(t, err)
+		assert.Equal(t, "name: \"500\"\n", string(res))
+	})
+
+	t.Run("invalid patch", func(t *testing.T) {
+		_, err := MergeYAML([]byte(src), []byte(`[1]`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scenario client keys.
+// Header and body keys are followed by the header name or the dotted path of the body field,
+// e.g. header:X-Session-Id or body:card.number.
+const (
+	ScenarioKeyGlobal = "global"
+	ScenarioKeyHeader = "header:"
+	ScenarioKeyBody   = "body:"
+)
+
+// ScenarioConfig is a named flow of responses whose state is kept in the service database,
+// so it holds across instances sharing Redis storage.
+// Key decides who shares the state: global (default), header:<name> or body:<path>.
+// Requests without the header or body field share the global state.
+// TTL expires the state after the last request changing it, it's kept forever when unset.
+//
+// A scenario is either ordered or a state machine.
+// Ordered scenarios answer the calls to Endpoint with Steps in order,
+// each step Repeat times, then keep answering with the last step, or start over with Loop.
+// State machines start in Initial and answer with the response of the current state for the endpoint,
+// then move to its Next state. Endpoints without a response in the current state aren't affected.
+// Endpoints are path patterns, optionally preceded by a method, e.g. "POST /charges".
+//
+// Example YAML:
+//
+//	scenarios:
+//	  charges:
+//	    endpoint: POST /charges
+//	    key: body:card.number
+//	    steps:
+//	      - status: 201
+//	        repeat: 2
+//	      - status: 402
+//	        body: '{"error":"card_declined"}'
+//	  order:
+//	    key: header:X-Session-Id
+//	    initial: pending
+//	    states:
+//	      pending:
+//	        GET /orders/{id}:
+//	          status: 202
+//	          body: '{"status":"pending"}'
+//	          next: done
+//	      done:
+//	        GET /orders/{id}:
+//	          status: 200
+type ScenarioConfig struct {
+	Key string        `yaml:"key,omitempty"`
+	TTL time.Duration `yaml:"ttl,omitempty"`
+
+	Endpoint string          `yaml:"endpoint,omitempty"`
+	Steps    []*ScenarioStep `yaml:"steps,omitempty"`
+	Loop     bool            `yaml:"loop,omitempty"`
+
+	Initial string                              `yaml:"initial,omitempty"`
+	States  map[string]map[string]*ScenarioStep `yaml:"states,omitempty"`
+}
+
+// ScenarioStep is a response of a scenario.
+// Status defaults to 200. Without Body, the response the service would give otherwise is sent
+// with Status and Headers applied. ContentType defaults to application/json.
+// Latency delays the response on top of the service latency.
+// Repeat is how many calls an ordered step answers, 1 by default.
+// Next is the state a state machine moves to after the response, it stays in the current one without it.
+type ScenarioStep struct {
+	Status      int               `yaml:"status,omitempty" json:"status,omitempty"`
+	Body        string            `yaml:"body,omitempty" json:"body,omitempty"`
+	ContentType string            `yaml:"content-type,omitempty" json:"contentType,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Latency     time.Duration     `yaml:"latency,omitempty" json:"latency,omitempty"`
+	Repeat      int               `yaml:"repeat,omitempty" json:"repeat,omitempty"`
+	Next        string            `yaml:"next,omitempty" json:"next,omitempty"`
+}
+
+// IsOrdered returns whether the scenario answers with ordered steps rather than states.
+func (c *ScenarioConfig) IsOrdered() bool {
+	return len(c.Steps) > 0
+}
+
+// GetInitial returns the state a state machine starts in:
+// Initial, or the first state in alphabetical order without it.
+func (c *ScenarioConfig) GetInitial() string {
+	if c.Initial != "" || len(c.States) == 0 {
+		return c.Initial
+	}
+	states := make([]string, 0, len(c.States))
+	for state := range c.States {
+		states = append(states, state)
+	}
+	slices.Sort(states)
+	return states[0]
+}
+
+// Matches returns whether the scenario answers requests for a resource path and method.
+func (c *ScenarioConfig) Matches(resourcePath, method string) bool {
+	if c.IsOrdered() {
+		return matchesEndpoint(resourcePath, method, c.Endpoint)
+	}
+	for _, responses := range c.States {
+		for endpoint := range responses {
+			if matchesEndpoint(resourcePath, method, endpoint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetStep returns the ordered step answering the nth call, starting at 1.
+func (c *ScenarioConfig) GetStep(call int) *ScenarioStep {
+	if !c.IsOrdered() {
+		return nil
+	}
+
+	total := c.StepCalls(len(c.Steps))
+	if call > total {
+		if !c.Loop {
+			return c.Steps[len(c.Steps)-1]
+		}
+		call = (call-1)%total + 1
+	}
+
+	for _, step := range c.Steps {
+		call -= step.repeat()
+		if call <= 0 {
+			return step
+		}
+	}
+	return c.Steps[len(c.Steps)-1]
+}
+
+// GetStepIndex returns the index, starting at 0, of the ordered step answering the nth call.
+func (c *ScenarioConfig) GetStepIndex(call int) int {
+	return slices.Index(c.Steps, c.GetStep(call))
+}
+
+// StepCalls returns the number of calls answered by the ordered steps before the step at index.
+func (c *ScenarioConfig) StepCalls(index int) int {
+	res := 0
+	for i, step := range c.Steps {
+		if i >= index {
+			break
+		}
+		res += step.repeat()
+	}
+	return res
+}
+
+// GetResponse returns the response of a state for a resource path and method, nil if there is none.
+// Responses for the method take precedence over the methodless ones,
+// then the most specific pattern wins, see matchingPatterns.
+func (c *ScenarioConfig) GetResponse(state, resourcePath, method string) *ScenarioStep {
+	var endpoints []string
+	for endpoint := range c.States[state] {
+		if matchesEndpoint(resourcePath, method, endpoint) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(endpoints, func(a, b string) int {
+		aMethod, aPattern := splitEndpoint(a)
+		bMethod, bPattern := splitEndpoint(b)
+		return cmp.Or(
+			cmp.Compare(methodRank(bMethod), methodRank(aMethod)),
+			cmp.Compare(literalSegments(bPattern), literalSegments(aPattern)),
+			cmp.Compare(a, b),
+		)
+	})
+	return c.States[state][endpoints[0]]
+}
+
+// GetScenario returns the name and config of the scenario answering a resource path and method,
+// the first one in alphabetical order if several do.
+func (s *ServiceConfig) GetScenario(resourcePath, method string) (string, *ScenarioConfig) {
+	names := make([]string, 0, len(s.Scenarios))
+	for name, sc := range s.Scenarios {
+		if sc != nil && sc.Matches(resourcePath, method) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	slices.Sort(names)
+	return names[0], s.Scenarios[names[0]]
+}
+
+func (s *ScenarioStep) repeat() int {
+	if s.Repeat <= 0 {
+		return 1
+	}
+	return s.Repeat
+}
+
+// matchesEndpoint returns whether a resource path and method match an endpoint:
+// a path pattern, optionally preceded by a method.
+func matchesEndpoint(resourcePath, method, endpoint string) bool {
+	endpointMethod, pattern := splitEndpoint(endpoint)
+	if endpointMethod == "" {
+		return matchesPattern(resourcePath, pattern)
+	}
+	return strings.EqualFold(endpointMethod, method) && matchesPattern(resourcePath, pattern)
+}
+
+// methodRank ranks endpoints with a method above the methodless ones.
+func methodRank(method string) int {
+	if method == "" {
+		return 0
+	}
+	return 1
+}
+
+// splitEndpoint returns the method, empty if there is none, and the path pattern of an endpoint.
+func splitEndpoint(endpoint string) (string, string) {
+	method, pattern, hasMethod := strings.Cut(strings.TrimSpace(endpoint), " ")
+	if !hasMethod {
+		return "", method
+	}
+	return method, strings.TrimSpace(pattern)
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarioConfig_GetStep(t *testing.T) {
+	first := &ScenarioStep{Status: http.StatusAccepted, Repeat: 2}
+	second := &ScenarioStep{Status: http.StatusOK}
+
+	t.Run("answers steps in order and stays on the last one", func(t *testing.T) {
+		sc := &ScenarioConfig{Steps: []*ScenarioStep{first, second}}
+		assert.Same(t, first, sc.GetStep(1))
+		assert.Same(t, first, sc.GetStep(2))
+		assert.Same(t, second, sc.GetStep(3))
+		assert.Same(t, second, sc.GetStep(10))
+	})
+
+	t.Run("starts over with loop", func(t *testing.T) {
+		sc := &ScenarioConfig{Steps: []*ScenarioStep{first, second}, Loop: true}
+		assert.Same(t, second, sc.GetStep(3))
+		assert.Same(t, first, sc.GetStep(4))
+		assert.Same(t, first, sc.GetStep(5))
+		assert.Same(t, second, sc.GetStep(6))
+	})
+
+	t.Run("step index and calls", func(t *testing.T) {
+		sc := &ScenarioConfig{Steps: []*ScenarioStep{first, second}}
+		assert.Equal(t, 0, sc.GetStepIndex(2))
+		assert.Equal(t, 1, sc.GetStepIndex(3))
+		assert.Equal(t, 0, sc.StepCalls(0))
+		assert.Equal(t, 2, sc.StepCalls(1))
+		assert.Equal(t, 3, sc.StepCalls(5))
+		assert.Equal(t, 0, sc.StepCalls(-1))
+	})
+
+	t.Run("state machines have no steps", func(t *testing.T) {
+		sc := &ScenarioConfig{States: map[string]map[string]*ScenarioStep{"a": {}}}
+		assert.Nil(t, sc.GetStep(1))
+	})
+}
+
+func TestScenarioConfig_GetResponse(t *testing.T) {
+	get := &ScenarioStep{Status: http.StatusAccepted}
+	methodless := &ScenarioStep{Status: http.StatusOK}
+	sc := &ScenarioConfig{States: map[string]map[string]*ScenarioStep{
+		"pending": {
+			"GET /orders/{id}": get,
+			"/orders/{id}":     methodless,
+		},
+		"done": {
+			"/orders/{id}": methodless,
+		},
+	}}
+
+	assert.Same(t, get, sc.GetResponse("pending", "/orders/{orderId}", http.MethodGet))
+	assert.Same(t, methodless, sc.GetResponse("pending", "/orders/{orderId}", http.MethodDelete))
+	assert.Same(t, methodless, sc.GetResponse("done", "/orders/{orderId}", http.MethodGet))
+	assert.Nil(t, sc.GetResponse("done", "/orders", http.MethodGet))
+	assert.Nil(t, sc.GetResponse("missing", "/orders/{orderId}", http.MethodGet))
+	assert.Equal(t, "done", sc.GetInitial())
+
+	t.Run("overlapping endpoints", func(t *testing.T) {
+		me := &ScenarioStep{Status: http.StatusOK}
+		byID := &ScenarioStep{Status: http.StatusNotFound}
+		anyMe := &ScenarioStep{Status: http.StatusNoContent}
+		anyUser := &ScenarioStep{Status: http.StatusGone}
+		sc := &ScenarioConfig{States: map[string]map[string]*ScenarioStep{
+			"active": {
+				"GET /users/{id}": byID,
+				"GET /users/me":   me,
+				"/users/me":       anyMe,
+				"/users/{id}":     anyUser,
+			},
+		}}
+
+		for range 20 {
+			assert.Same(t, me, sc.GetResponse("active", "/users/me", http.MethodGet))
+			assert.Same(t, byID, sc.GetResponse("active", "/users/{userId}", http.MethodGet))
+			assert.Same(t, anyMe, sc.GetResponse("active", "/users/me", http.MethodDelete))
+			assert.Same(t, anyUser, sc.GetResponse("active", "/users/{userId}", http.MethodDelete))
+		}
+	})
+}
+
+func TestServiceConfig_GetScenario(t *testing.T) {
+	cfg, err := NewServiceConfigFromBytes([]byte(`
+scenarios:
+  charges:
+    endpoint: POST /charges
+    key: body:card.number
+    ttl: 1h
+    steps:
+      - status: 201
+        repeat: 2
+      - status: 402
+        body: '{"error":"card_declined"}'
+        latency: 10ms
+  order:
+    key: header:X-Session-Id
+    initial: pending
+    states:
+      pending:
+        GET /orders/{id}:
+          status: 202
+          next: done
+      done:
+        GET /orders/{id}:
+          headers:
+            X-Done: "true"
+`))
+	require.NoError(t, err)
+
+	t.Run("parses yaml", func(t *testing.T) {
+		charges := cfg.Scenarios["charges"]
+		require.NotNil(t, charges)
+		assert.True(t, charges.IsOrdered())
+		assert.Equal(t, time.Hour, charges.TTL)
+		assert.Equal(t, &ScenarioStep{
+			Status:  http.StatusPaymentRequired,
+			Body:    `{"error":"card_declined"}`,
+			Latency: 10 * time.Millisecond,
+		}, charges.Steps[1])
+
+		order := cfg.Scenarios["order"]
+		require.NotNil(t, order)
+		assert.False(t, order.IsOrdered())
+		assert.Equal(t, "done", order.States["pending"]["GET /orders/{id}"].Next)
+		assert.Equal(t, "true", order.States["done"]["GET /orders/{id}"].Headers["X-Done"])
+	})
+
+	t.Run("matches endpoints", func(t *testing.T) {
+		name, sc := cfg.GetScenario("/charges", http.MethodPost)
+		assert.Equal(t, "charges", name)
+		assert.Same(t, cfg.Scenarios["charges"], sc)
+
+		name, _ = cfg.GetScenario("/orders/{orderId}", http.MethodGet)
+		assert.Equal(t, "order", name)
+
+		_, sc = cfg.GetScenario("/charges", http.MethodGet)
+		assert.Nil(t, sc)
+	})
+
+	t.Run("overwritten", func(t *testing.T) {
+		res := NewServiceConfig().OverwriteWith(cfg)
+		assert.Equal(t, cfg.Scenarios, res.Scenarios)
+	})
+}
@@ -0,0 +1,111 @@
+package config
+
+import "strings"
+
+// SecurityConfig enables enforcement of the spec's security schemes and requirements.
+// Requests without the required credentials are rejected with 401,
+// requests with credentials missing from a non-empty allow-list with 403.
+// An empty allow-list accepts any credential, only its presence is checked.
+//
+// Example YAML:
+//
+//	security:
+//	  enforce: true
+//	  api-keys: [secret-key]
+//	  tokens: [secret-token]
+//	  users:
+//	    admin: secret
+//	  jwks-url: http://localhost:2200/.oidc/jwks
+//	  endpoints:
+//	    /health:
+//	      enforce: false
+//	    /admin/{id}:
+//	      DELETE:
+//	        schemes: [adminAuth]
+type SecurityConfig struct {
+	// Enforce turns enforcement on for every operation with security requirements.
+	Enforce bool `yaml:"enforce"`
+
+	// APIKeys lists the accepted values of apiKey schemes.
+	APIKeys []string `yaml:"api-keys,omitempty"`
+
+	// Tokens lists the accepted bearer tokens of HTTP bearer, OAuth2 and OpenID Connect schemes.
+	Tokens []string `yaml:"tokens,omitempty"`
+
+	// Users maps accepted HTTP basic usernames to their passwords.
+	Users map[string]string `yaml:"users,omitempty"`
+
+	// JWKSURL verifies bearer tokens as JWTs signed by a key of the set,
+	// e.g. the tokens of the built-in OIDC provider at http://localhost:2200/.oidc/jwks.
+	// Tokens that are neither listed in Tokens nor verified get 403.
+	JWKSURL string `yaml:"jwks-url,omitempty"`
+
+	// Issuer, when set, is the required iss claim of verified tokens.
+	Issuer string `yaml:"issuer,omitempty"`
+
+	// Endpoints overrides enforcement per path pattern, for any method or for a single one:
+	//
+	//   Without method:
+	//     /health:
+	//       enforce: false
+	//
+	//   With method:
+	//     /admin/{id}:
+	//       DELETE:
+	//         schemes: [adminAuth]
+	Endpoints map[string]*SecurityEndpointMethods `yaml:"endpoints,omitempty"`
+}
+
+// SecurityEndpoint overrides enforcement of a single endpoint.
+// Enforce turns enforcement on or off, nil keeps the service setting.
+// Schemes replaces the spec's requirements: any of the listed schemes is accepted.
+type SecurityEndpoint struct {
+	Enforce *bool    `yaml:"enforce,omitempty"`
+	Schemes []string `yaml:"schemes,omitempty"`
+}
+
+// SecurityEndpointMethods holds the override for any method and the overrides for single methods.
+type SecurityEndpointMethods struct {
+	Any     *SecurityEndpoint
+	Methods map[string]*SecurityEndpoint
+}
+
+// UnmarshalYAML reads the per-method form when every key is an HTTP method, the methodless form otherwise.
+func (m *SecurityEndpointMethods) UnmarshalYAML(unmarshal func(any) error) error {
+	return unmarshalEndpointMethods(unmarshal, &m.Any, &m.Methods)
+}
+
+// GetEndpoint returns the override for a request path and method, nil if there is none.
+// The most specific matching pattern wins, see matchingPatterns.
+// Overrides for the method take precedence over the methodless ones.
+func (c *SecurityConfig) GetEndpoint(requestPath, method string) *SecurityEndpoint {
+	if c == nil {
+		return nil
+	}
+
+	method = strings.ToUpper(method)
+	for _, pattern := range matchingPatterns(c.Endpoints, requestPath) {
+		methods := c.Endpoints[pattern]
+		if methods == nil {
+			continue
+		}
+		if ep, ok := methods.Methods[method]; ok && ep != nil {
+			return ep
+		}
+		if methods.Any != nil {
+			return methods.Any
+		}
+	}
+	return nil
+}
+
+// IsEnforced tells whether the security requirements of an endpoint are enforced.
+func (c *SecurityConfig) IsEnforced(requestPath, method string) bool {
+	if c == nil {
+		return false
+	}
+	if ep := c.GetEndpoint(requestPath, method); ep != nil && ep.Enforce != nil {
+		return *ep.Enforce
+	}
+	return c.Enforce
+}
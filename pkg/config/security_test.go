@@ -0,0 +1,109 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityConfig_UnmarshalYAML(t *testing.T) {
+	cfg, err := NewServiceConfigFromBytes([]byte(`
+security:
+  enforce: true
+  api-keys: [key]
+  tokens: [token]
+  users:
+    admin: secret
+  endpoints:
+    /health:
+      enforce: false
+    /admin/{id}:
+      DELETE:
+        schemes: [adminAuth]
+`))
+	require.NoError(t, err)
+
+	sec := cfg.Security
+	require.NotNil(t, sec)
+	assert.True(t, sec.Enforce)
+	assert.Equal(t, []string{"key"}, sec.APIKeys)
+	assert.Equal(t, []string{"token"}, sec.Tokens)
+	assert.Equal(t, map[string]string{"admin": "secret"}, sec.Users)
+
+	require.Contains(t, sec.Endpoints, "/health")
+	require.NotNil(t, sec.Endpoints["/health"].Any)
+	assert.False(t, *sec.Endpoints["/health"].Any.Enforce)
+
+	require.Contains(t, sec.Endpoints, "/admin/{id}")
+	assert.Nil(t, sec.Endpoints["/admin/{id}"].Any)
+	assert.Equal(t, []string{"adminAuth"}, sec.Endpoints["/admin/{id}"].Methods[http.MethodDelete].Schemes)
+}
+
+func TestSecurityEndpointMethods_UnmarshalYAML(t *testing.T) {
+	cfg, err := NewServiceConfigFromBytes([]byte(`
+security:
+  endpoints:
+    /admin/{id}:
+      delete:
+        enforce: false
+    /empty: {}
+`))
+	require.NoError(t, err)
+
+	admin := cfg.Security.Endpoints["/admin/{id}"]
+	require.Contains(t, admin.Methods, http.MethodDelete)
+	assert.False(t, *admin.Methods[http.MethodDelete].Enforce)
+	assert.False(t, cfg.Security.IsEnforced("/admin/1", "delete"))
+
+	assert.NotNil(t, cfg.Security.Endpoints["/empty"].Any)
+}
+
+func TestSecurityConfig_IsEnforced(t *testing.T) {
+	on, off := true, false
+	cfg := &SecurityConfig{
+		Enforce: true,
+		Endpoints: map[string]*SecurityEndpointMethods{
+			"/health": {Any: &SecurityEndpoint{Enforce: &off}},
+			"/admin/{id}": {
+				Any:     &SecurityEndpoint{Enforce: &off},
+				Methods: map[string]*SecurityEndpoint{http.MethodDelete: {Enforce: &on}},
+			},
+		},
+	}
+
+	t.Run("uses service setting without override", func(t *testing.T) {
+		assert.True(t, cfg.IsEnforced("/pets", http.MethodGet))
+	})
+
+	t.Run("methodless override", func(t *testing.T) {
+		assert.False(t, cfg.IsEnforced("/health", http.MethodGet))
+	})
+
+	t.Run("method override takes precedence", func(t *testing.T) {
+		assert.True(t, cfg.IsEnforced("/admin/1", http.MethodDelete))
+		assert.False(t, cfg.IsEnforced("/admin/1", http.MethodGet))
+	})
+
+	t.Run("most specific pattern wins", func(t *testing.T) {
+		overlapping := &SecurityConfig{
+			Enforce: true,
+			Endpoints: map[string]*SecurityEndpointMethods{
+				"/admin/{id}":      {Any: &SecurityEndpoint{Enforce: &on}},
+				"/admin/stats":     {Any: &SecurityEndpoint{Enforce: &off}},
+				"/{section}/stats": {Any: &SecurityEndpoint{Enforce: &on}},
+			},
+		}
+		for range 20 {
+			assert.False(t, overlapping.IsEnforced("/admin/stats", http.MethodGet))
+			assert.True(t, overlapping.IsEnforced("/admin/1", http.MethodGet))
+		}
+	})
+
+	t.Run("nil config", func(t *testing.T) {
+		var nilCfg *SecurityConfig
+		assert.False(t, nilCfg.IsEnforced("/pets", http.MethodGet))
+		assert.Nil(t, nilCfg.GetEndpoint("/pets", http.MethodGet))
+	})
+}
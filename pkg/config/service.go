@@ -21,25 +21,48 @@ type KeyValue[K, V any] struct {
 // Upstream is the upstream configuration.
 // Latency is the default latency for the service.
 // Latencies is a map of percentiles to latencies.
+// LatencyDistribution draws latencies from a statistical distribution, taking precedence over Latency and Latencies.
 // Errors is a map of percentiles to error codes.
+// Faults is a map of percentiles to transport faults, e.g. reset or slow-headers:10s.
+// Endpoints overrides settings per path pattern and method.
+// Scenarios are named flows of responses with state, e.g. pending then done.
+// Rules return canned responses for requests matching method, path, query, headers and body.
 // Validate is the validation configuration.
+// Security is the security enforcement configuration.
+// Claims is the configuration of the bearer token claims exposed to replacements.
+// CORS is the cross-origin configuration, overriding the app one.
+// RateLimit is the rate limiting simulation.
 // Cache is the cache configuration.
 // ResourcesPrefix is the prefix for helper routes outside OpenAPI spec.
 // SpecOptions allows OpenAPI spec simplifications for code generation.
+// Generation controls mock payload generation for non-OpenAPI service types.
 type ServiceConfig struct {
-	Name            string                   `yaml:"name,omitempty"`
-	Upstream        *UpstreamConfig          `yaml:"upstream,omitempty"`
-	Latency         time.Duration            `yaml:"latency,omitempty"`
-	Latencies       map[string]time.Duration `yaml:"latencies,omitempty"`
-	Errors          map[string]int           `yaml:"errors,omitempty"`
-	Cache           *CacheConfig             `yaml:"cache,omitempty"`
-	History         *HistoryConfig           `yaml:"history,omitempty"`
-	ResourcesPrefix string                   `yaml:"resources-prefix,omitempty"`
-	SpecOptions     *SpecOptions             `yaml:"spec,omitempty"`
-	Extra           map[string]any           `yaml:"extra,omitempty"`
+	Name                string                            `yaml:"name,omitempty"`
+	Upstream            *UpstreamConfig                   `yaml:"upstream,omitempty"`
+	Latency             time.Duration                     `yaml:"latency,omitempty"`
+	Latencies           map[string]time.Duration          `yaml:"latencies,omitempty"`
+	LatencyDistribution *LatencyDistribution              `yaml:"latency-distribution,omitempty"`
+	Errors              map[string]int                    `yaml:"errors,omitempty"`
+	Faults              map[string]string                 `yaml:"faults,omitempty"`
+	Endpoints           map[string]*EndpointConfigMethods `yaml:"endpoints,omitempty"`
+	Scenarios           map[string]*ScenarioConfig        `yaml:"scenarios,omitempty"`
+	Rules               []*RuleConfig                     `yaml:"rules,omitempty"`
+	Validate            *ValidateConfig                   `yaml:"validate,omitempty"`
+	Security            *SecurityConfig                   `yaml:"security,omitempty"`
+	Claims              *ClaimsConfig                     `yaml:"claims,omitempty"`
+	CORS                *CORSConfig                       `yaml:"cors,omitempty"`
+	RateLimit           *RateLimitConfig                  `yaml:"rate-limit,omitempty"`
+	Cache               *CacheConfig                      `yaml:"cache,omitempty"`
+	History             *HistoryConfig                    `yaml:"history,omitempty"`
+	ResourcesPrefix     string                            `yaml:"resources-prefix,omitempty"`
+	SpecOptions         *SpecOptions                      `yaml:"spec,omitempty"`
+	Generation          *GenerationConfig                 `yaml:"generation,omitempty"`
+	Extra               map[string]any                    `yaml:"extra,omitempty"`
 
 	latencies []*KeyValue[int, time.Duration]
 	errors    []*KeyValue[int, int]
+	faults    []*KeyValue[int, string]
+	rules     []*RuleConfig
 }
 
 // NewServiceConfig creates a new ServiceConfig with default values.
@@ -61,6 +84,10 @@ func NewServiceConfigFromBytes(bts []byte) (*ServiceConfig, error) {
 		return nil, fmt.Errorf("unmarshalling service config: %w", err)
 	}
 
+	if err := res.ParseRules(); err != nil {
+		return nil, fmt.Errorf("parsing service config: %w", err)
+	}
+
 	// Fill any nil fields with defaults (in case YAML didn't specify them)
 	res.WithDefaults()
 
@@ -113,6 +140,16 @@ func (s *ServiceConfig) WithDefaults() *ServiceConfig {
 		s.errors = s.parseErrors()
 	}
 
+	if s.faults == nil && len(s.Faults) > 0 {
+		s.faults = parsePercentiles(s.Faults)
+	}
+
+	s.parseEndpoints()
+
+	if s.rules == nil && len(s.Rules) > 0 {
+		s.parseRules()
+	}
+
 	return s
 }
 
@@ -146,6 +183,10 @@ func (s *ServiceConfig) OverwriteWith(other *ServiceConfig) *ServiceConfig {
 		s.Latency = other.Latency
 	}
 
+	if other.LatencyDistribution != nil {
+		s.LatencyDistribution = other.LatencyDistribution
+	}
+
 	// Overwrite map fields if not nil (merge maps)
 	if other.Latencies != nil {
 		if s.Latencies == nil {
@@ -169,6 +210,51 @@ func (s *ServiceConfig) OverwriteWith(other *ServiceConfig) *ServiceConfig {
 		s.errors = s.parseErrors()
 	}
 
+	if other.Faults != nil {
+		if s.Faults == nil {
+			s.Faults = make(map[string]string)
+		}
+		for k, v := range other.Faults {
+			s.Faults[k] = v
+		}
+		// Re-parse faults after merge
+		s.faults = parsePercentiles(s.Faults)
+	}
+
+	if other.Endpoints != nil {
+		s.Endpoints = other.Endpoints
+		s.parseEndpoints()
+	}
+
+	if other.Scenarios != nil {
+		s.Scenarios = other.Scenarios
+	}
+
+	if other.Rules != nil {
+		s.Rules = other.Rules
+		s.parseRules()
+	}
+
+	if other.Validate != nil {
+		s.Validate = other.Validate
+	}
+
+	if other.Security != nil {
+		s.Security = other.Security
+	}
+
+	if other.Claims != nil {
+		s.Claims = other.Claims
+	}
+
+	if other.CORS != nil {
+		s.CORS = other.CORS
+	}
+
+	if other.RateLimit != nil {
+		s.RateLimit = other.RateLimit
+	}
+
 	if other.History != nil {
 		s.History = other.History
 	}
@@ -177,6 +263,10 @@ func (s *ServiceConfig) OverwriteWith(other *ServiceConfig) *ServiceConfig {
 		s.SpecOptions = other.SpecOptions
 	}
 
+	if other.Generation != nil {
+		s.Generation = other.Generation
+	}
+
 	if other.Extra != nil {
 		if s.Extra == nil {
 			s.Extra = make(map[string]any)
@@ -190,7 +280,13 @@ func (s *ServiceConfig) OverwriteWith(other *ServiceConfig) *ServiceConfig {
 }
 
 // GetLatency returns the latency.
+// Empirical distributions need the recorded upstream durations,
+// so they fall back to Latency and Latencies here.
 func (s *ServiceConfig) GetLatency() time.Duration {
+	if s.LatencyDistribution != nil && !s.LatencyDistribution.IsEmpirical() {
+		return s.LatencyDistribution.Sample(nil)
+	}
+
 	if len(s.latencies) == 0 {
 		return s.Latency
 	}
@@ -205,6 +301,13 @@ func (s *ServiceConfig) GetLatency() time.Duration {
 	return 0
 }
 
+// SetLatency sets a fixed latency, replacing the latency percentiles and distribution.
+func (s *ServiceConfig) SetLatency(latency time.Duration) {
+	s.Latency = latency
+	s.Latencies, s.latencies = nil, nil
+	s.LatencyDistribution = nil
+}
+
 // GetError returns the error based on the percentiles:
 //
 //	random number is generated between 1 and 100 to simulate the percentile.
@@ -225,6 +328,11 @@ func (s *ServiceConfig) GetError() int {
 	return 0
 }
 
+// GetFault returns the transport fault based on the percentiles, empty if there is none.
+func (s *ServiceConfig) GetFault() string {
+	return pickPercentile(s.faults)
+}
+
 // HistoryEnabled returns whether request history recording is enabled.
 // Defaults to true when not explicitly set.
 func (s *ServiceConfig) HistoryEnabled() bool {
@@ -232,38 +340,43 @@ func (s *ServiceConfig) HistoryEnabled() bool {
 }
 
 func (s *ServiceConfig) parseLatencies() []*KeyValue[int, time.Duration] {
-	latencies := make([]*KeyValue[int, time.Duration], 0)
-	for k, v := range s.Latencies {
-		if strings.HasPrefix(k, "p") {
-			kNum, err := strconv.Atoi(strings.TrimPrefix(k, "p"))
-			if err == nil {
-				res := &KeyValue[int, time.Duration]{Key: kNum, Value: v}
-				latencies = append(latencies, res)
-			}
+	return parsePercentiles(s.Latencies)
+}
+
+func (s *ServiceConfig) parseErrors() []*KeyValue[int, int] {
+	return parsePercentiles(s.Errors)
+}
+
+// parsePercentiles converts a map of pN keys into percentiles sorted in ascending order.
+// Other keys are ignored.
+func parsePercentiles[V any](values map[string]V) []*KeyValue[int, V] {
+	res := make([]*KeyValue[int, V], 0, len(values))
+	for k, v := range values {
+		if p, err := strconv.Atoi(strings.TrimPrefix(k, "p")); err == nil && strings.HasPrefix(k, "p") {
+			res = append(res, &KeyValue[int, V]{Key: p, Value: v})
 		}
 	}
-
-	sort.Slice(latencies, func(i, j int) bool {
-		return latencies[i].Key < latencies[j].Key
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
 	})
-	return latencies
+	return res
 }
 
-func (s *ServiceConfig) parseErrors() []*KeyValue[int, int] {
-	errors := make([]*KeyValue[int, int], 0)
-	for k, v := range s.Errors {
-		if strings.HasPrefix(k, "p") {
-			kNum, err := strconv.Atoi(strings.TrimPrefix(k, "p"))
-			if err == nil {
-				res := &KeyValue[int, int]{Key: kNum, Value: v}
-				errors = append(errors, res)
-			}
+// pickPercentile returns the value of the first percentile covering a random number
+// between 1 and 100, the zero value if none does.
+func pickPercentile[V any](percentiles []*KeyValue[int, V]) V {
+	var zero V
+	if len(percentiles) == 0 {
+		return zero
+	}
+
+	rnd := rand.Intn(100) + 1
+	for _, kv := range percentiles {
+		if rnd <= kv.Key {
+			return kv.Value
 		}
 	}
-	sort.Slice(errors, func(i, j int) bool {
-		return errors[i].Key < errors[j].Key
-	})
-	return errors
+	return zero
 }
 
 // HistoryConfig controls request/response history recording for a service.
@@ -322,6 +435,33 @@ func (h *HistoryConfig) UnmarshalYAML(unmarshal func(any) error) error {
 	return unmarshal((*plain)(h))
 }
 
+// ValidateConfig controls validation of requests and generated responses against the spec.
+// Request rejects requests that don't match the operation with 400 problem details.
+// Response checks generated responses, failing with 500 problem details instead of serving them.
+// Both are off by default.
+//
+// Example YAML:
+//
+//	validate:
+//	  request: true
+//	  response: true
+type ValidateConfig struct {
+	Request  bool `yaml:"request"`
+	Response bool `yaml:"response"`
+}
+
+// ClaimsConfig controls the claims of bearer JWTs exposed to replacements in the in-claims area.
+// Claims are decoded without verification by default.
+// With JWKSURL set only the claims of tokens signed by a key of the set are used.
+//
+// Example YAML:
+//
+//	claims:
+//	  jwks-url: http://localhost:2200/.oidc/jwks
+type ClaimsConfig struct {
+	JWKSURL string `yaml:"jwks-url,omitempty"`
+}
+
 // CacheConfig defines the cache configuration for a service.
 // Requests is a flag whether to cache GET requests.
 // Replay is the replay configuration for recording and replaying API responses.
@@ -560,3 +700,46 @@ type OptionalProperties struct {
 	Min int `yaml:"min"`
 	Max int `yaml:"max"`
 }
+
+// GenerationConfig controls how mock payloads are generated for service types
+// that are not backed by an OpenAPI spec (e.g. GraphQL), where there is no
+// minItems/maxItems to drive the generator.
+//
+// ListSize sets how many items are generated for list fields.
+// When nil, a single item is generated.
+//
+// Example YAML:
+//
+//	generation:
+//	  list-size:
+//	    min: 2        # Generate a random number of items between 2 and 5
+//	    max: 5
+type GenerationConfig struct {
+	ListSize *ListSize `yaml:"list-size,omitempty"`
+}
+
+// GetListSize returns the number of items to generate for a list field.
+// Safe to call on a nil receiver.
+func (g *GenerationConfig) GetListSize() int {
+	if g == nil || g.ListSize == nil {
+		return 1
+	}
+	return g.ListSize.Get()
+}
+
+// ListSize is an inclusive range of generated list items.
+//   - If Min == Max (or Max is not set), exactly Min items are generated
+//   - If Min < Max, a random number between Min and Max (inclusive) is generated
+type ListSize struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+// Get returns a list size within the configured range, never less than 0.
+func (l *ListSize) Get() int {
+	lo, hi := max(l.Min, 0), l.Max
+	if hi <= lo {
+		return lo
+	}
+	return lo + rand.Intn(hi-lo+1)
+}
@@ -45,6 +45,24 @@ cache:
 		assert.True(t, cfg.Cache.Requests)
 	})
 
+	t.Run("Parses validate config", func(t *testing.T) {
+		cfg, err := NewServiceConfigFromBytes([]byte(`
+validate:
+  request: true
+`))
+		assert.NoError(t, err)
+		assert.Equal(t, &ValidateConfig{Request: true}, cfg.Validate)
+	})
+
+	t.Run("Parses claims config", func(t *testing.T) {
+		cfg, err := NewServiceConfigFromBytes([]byte(`
+claims:
+  jwks-url: http://localhost:2200/.oidc/jwks
+`))
+		assert.NoError(t, err)
+		assert.Equal(t, &ClaimsConfig{JWKSURL: "http://localhost:2200/.oidc/jwks"}, cfg.Claims)
+	})
+
 	t.Run("Returns error for invalid YAML", func(t *testing.T) {
 		yamlData := []byte(`invalid: yaml: data: [`)
 
@@ -183,6 +201,23 @@ func TestServiceConfig_GetError(t *testing.T) {
 	})
 }
 
+func TestServiceConfig_GetFault(t *testing.T) {
+	t.Run("Returns empty when no faults defined", func(t *testing.T) {
+		cfg := (&ServiceConfig{}).WithDefaults()
+		assert.Empty(t, cfg.GetFault())
+	})
+
+	t.Run("Returns fault based on percentiles", func(t *testing.T) {
+		cfg := (&ServiceConfig{Faults: map[string]string{"p100": FaultReset}}).WithDefaults()
+		assert.Equal(t, FaultReset, cfg.GetFault())
+	})
+}
+
+func TestParsePercentiles(t *testing.T) {
+	res := parsePercentiles(map[string]string{"p50": "reset", "p10": "empty", "x": "hang", "pp": "hang"})
+	assert.Equal(t, []*KeyValue[int, string]{{Key: 10, Value: "empty"}, {Key: 50, Value: "reset"}}, res)
+}
+
 func TestServiceConfig_parseLatencies(t *testing.T) {
 	t.Run("Parses percentile latencies", func(t *testing.T) {
 		cfg := &ServiceConfig{
@@ -576,6 +611,51 @@ func TestServiceConfig_OverwriteWith(t *testing.T) {
 		assert.Equal(t, "http://overwritten.com", result.Upstream.URL)
 	})
 
+	t.Run("Overwrites Validate when other has non-nil Validate", func(t *testing.T) {
+		cfg := &ServiceConfig{Validate: &ValidateConfig{Request: true}}
+		other := &ServiceConfig{Validate: &ValidateConfig{Response: true}}
+
+		result := cfg.OverwriteWith(other)
+
+		assert.Equal(t, &ValidateConfig{Response: true}, result.Validate)
+	})
+
+	t.Run("Overwrites CORS when other has non-nil CORS", func(t *testing.T) {
+		cfg := &ServiceConfig{CORS: &CORSConfig{Credentials: true}}
+		other := &ServiceConfig{CORS: &CORSConfig{Origins: []string{"*"}}}
+
+		result := cfg.OverwriteWith(other)
+
+		assert.Equal(t, &CORSConfig{Origins: []string{"*"}}, result.CORS)
+	})
+
+	t.Run("Merges and re-parses faults", func(t *testing.T) {
+		cfg := &ServiceConfig{Faults: map[string]string{"p10": "reset"}}
+		other := &ServiceConfig{Faults: map[string]string{"p20": "empty"}}
+
+		result := cfg.OverwriteWith(other)
+
+		assert.Equal(t, []*KeyValue[int, string]{{Key: 10, Value: "reset"}, {Key: 20, Value: "empty"}}, result.faults)
+	})
+
+	t.Run("Overwrites RateLimit when other has non-nil RateLimit", func(t *testing.T) {
+		cfg := &ServiceConfig{RateLimit: &RateLimitConfig{RateLimit: RateLimit{Limit: 10}}}
+		other := &ServiceConfig{RateLimit: &RateLimitConfig{RateLimit: RateLimit{Limit: 5}}}
+
+		result := cfg.OverwriteWith(other)
+
+		assert.Equal(t, 5, result.RateLimit.Limit)
+	})
+
+	t.Run("Overwrites Claims when other has non-nil Claims", func(t *testing.T) {
+		cfg := &ServiceConfig{Claims: &ClaimsConfig{JWKSURL: "http://a/jwks"}}
+		other := &ServiceConfig{Claims: &ClaimsConfig{JWKSURL: "http://b/jwks"}}
+
+		result := cfg.OverwriteWith(other)
+
+		assert.Equal(t, &ClaimsConfig{JWKSURL: "http://b/jwks"}, result.Claims)
+	})
+
 	t.Run("Does not overwrite Upstream when other has nil Upstream", func(t *testing.T) {
 		originalUpstream := &UpstreamConfig{
 			URL: "http://original.com",
@@ -1286,3 +1366,61 @@ func TestWithDefaults_History(t *testing.T) {
 		assert.Nil(t, cfg.History.MaskHeaders)
 	})
 }
+
+func TestGenerationConfig_GetListSize(t *testing.T) {
+	t.Run("defaults to a single item", func(t *testing.T) {
+		var cfg *GenerationConfig
+		assert.Equal(t, 1, cfg.GetListSize())
+		assert.Equal(t, 1, (&GenerationConfig{}).GetListSize())
+	})
+
+	t.Run("exact size", func(t *testing.T) {
+		cfg := &GenerationConfig{ListSize: &ListSize{Min: 4, Max: 4}}
+		assert.Equal(t, 4, cfg.GetListSize())
+	})
+
+	t.Run("only min", func(t *testing.T) {
+		cfg := &GenerationConfig{ListSize: &ListSize{Min: 0}}
+		assert.Equal(t, 0, cfg.GetListSize())
+	})
+
+	t.Run("range", func(t *testing.T) {
+		cfg := &GenerationConfig{ListSize: &ListSize{Min: 2, Max: 5}}
+		for range 50 {
+			size := cfg.GetListSize()
+			assert.GreaterOrEqual(t, size, 2)
+			assert.LessOrEqual(t, size, 5)
+		}
+	})
+
+	t.Run("parses from service config", func(t *testing.T) {
+		cfg, err := NewServiceConfigFromBytes([]byte(`
+generation:
+  list-size:
+    min: 3
+    max: 3
+`))
+		assert.NoError(t, err)
+		assert.Equal(t, 3, cfg.Generation.GetListSize())
+	})
+
+	t.Run("ignores the codegen generate key", func(t *testing.T) {
+		cfg, err := NewServiceConfigFromBytes([]byte(`
+generate:
+  server: {}
+generation:
+  list-size:
+    min: 2
+`))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, cfg.Generation.GetListSize())
+	})
+
+	t.Run("overwritten by other config", func(t *testing.T) {
+		cfg := NewServiceConfig()
+		other := &ServiceConfig{Generation: &GenerationConfig{ListSize: &ListSize{Min: 2, Max: 2}}}
+
+		result := cfg.OverwriteWith(other)
+		assert.Equal(t, 2, result.Generation.GetListSize())
+	})
+}
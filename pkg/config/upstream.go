@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +17,29 @@ type UpstreamConfig struct {
 	// to the client without falling back to the generator.
 	// nil (omitted): uses default (400-499 except 401, 403). Set to empty list (fail-on: []) to disable.
 	FailOn *HTTPStatusMatchConfig `yaml:"fail-on"`
+
+	// Record persists successful upstream responses as static fixtures.
+	// If not set, recording is disabled.
+	Record *RecordConfig `yaml:"record"`
+}
+
+// RecordConfig configures recording of upstream responses into the static service layout:
+// <dir>/<method>/<path>/index.<ext>, with a sidecar for the status code and headers.
+type RecordConfig struct {
+	// Dir is the static service directory responses are written to.
+	Dir string `yaml:"dir"`
+
+	// Spec is the OpenAPI spec regenerated from Dir after every recorded response.
+	// Defaults to openapi.yml inside Dir.
+	Spec string `yaml:"spec"`
+}
+
+// SpecPath returns the path of the regenerated spec.
+func (c *RecordConfig) SpecPath() string {
+	if c.Spec != "" {
+		return c.Spec
+	}
+	return filepath.Join(c.Dir, "openapi.yml")
 }
 
 // DefaultFailOnStatus is the default fail-on config applied when FailOn is nil.
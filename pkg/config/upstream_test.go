@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -241,3 +242,17 @@ func TestUpstreamConfig_WithCircuitBreaker(t *testing.T) {
 		assert.Equal(0.6, cfg.CircuitBreaker.FailureRatio)
 	})
 }
+
+func TestRecordConfig_SpecPath(t *testing.T) {
+	assert := assert2.New(t)
+
+	t.Run("defaults to openapi.yml in the directory", func(t *testing.T) {
+		cfg := &RecordConfig{Dir: "static/payments"}
+		assert.Equal(filepath.Join("static", "payments", "openapi.yml"), cfg.SpecPath())
+	})
+
+	t.Run("uses configured spec", func(t *testing.T) {
+		cfg := &RecordConfig{Dir: "static/payments", Spec: "payments.yml"}
+		assert.Equal("payments.yml", cfg.SpecPath())
+	})
+}
@@ -0,0 +1,214 @@
+// Package cxstest provides helpers for Go test suites running against a connexions server.
+//
+// Example:
+//
+//	cxs := cxstest.NewClient("http://localhost:2200")
+//	cxs.AssertCalled(t, "payments", &middleware.RequestMatcher{
+//		Method:   http.MethodPost,
+//		Resource: "/charges",
+//		Body:     map[string]*config.ValueMatcher{"$.amount": cxstest.Equals("100")},
+//	}, cxstest.Exactly(2))
+package cxstest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/middleware"
+)
+
+// Client calls the test APIs of a connexions server.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	wait       time.Duration
+}
+
+// DefaultWait is how long assertions retry by default.
+// History is written right after the response, so a request may show up a moment after it's answered.
+const DefaultWait = time.Second
+
+// retryInterval is the pause between the retries of an assertion.
+const retryInterval = 50 * time.Millisecond
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the client requests are sent with, http.DefaultClient by default.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithWait sets how long assertions retry until the expectation is met, DefaultWait by default.
+func WithWait(wait time.Duration) ClientOption {
+	return func(c *Client) {
+		c.wait = wait
+	}
+}
+
+// NewClient returns a client for the server at baseURL, e.g. http://localhost:2200.
+func NewClient(baseURL string, opts ...ClientOption) *Client {
+	res := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		wait:       DefaultWait,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// Verify returns the requests in the history of a service matching the verification request.
+// A failed expectation isn't an error, the Message of the response tells why it failed.
+func (c *Client) Verify(ctx context.Context, service string, req *api.VerifyRequest) (*api.VerifyResponse, error) {
+	if service == "" {
+		service = api.RootServiceName
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding verification: %w", err)
+	}
+
+	endpoint := c.baseURL + api.VerifyURL + "/" + url.PathEscape(service)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusExpectationFailed {
+		var problem api.ProblemDetails
+		if err := json.Unmarshal(data, &problem); err == nil && problem.Detail != "" {
+			return nil, fmt.Errorf("verify %s: %d %s", service, resp.StatusCode, problem.Detail)
+		}
+		return nil, fmt.Errorf("verify %s: %d %s", service, resp.StatusCode, data)
+	}
+
+	var res api.VerifyResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("decoding verification: %w", err)
+	}
+	return &res, nil
+}
+
+// Count returns the number of requests in the history of a service matching the matcher.
+func (c *Client) Count(ctx context.Context, service string, matcher *middleware.RequestMatcher) (int, error) {
+	res, err := c.Verify(ctx, service, newVerifyRequest(matcher, nil))
+	if err != nil {
+		return 0, err
+	}
+	return res.Count, nil
+}
+
+// AssertCalled marks the test as failed unless the number of requests of a service
+// matching the matcher meets the expectation, retrying for the wait of the client.
+// It returns the matching requests, nil on errors.
+func (c *Client) AssertCalled(t testing.TB, service string, matcher *middleware.RequestMatcher, expect *api.VerifyExpect) *api.VerifyResponse {
+	t.Helper()
+
+	deadline := time.Now().Add(c.wait)
+	req := newVerifyRequest(matcher, expect)
+	for {
+		res, err := c.Verify(t.Context(), service, req)
+		if err != nil {
+			t.Errorf("cxstest: %v", err)
+			return nil
+		}
+		if res.Message == "" {
+			return res
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("cxstest: %s: %s", describe(service, matcher), res.Message)
+			return res
+		}
+		time.Sleep(retryInterval)
+	}
+}
+
+// AssertNotCalled marks the test as failed if any request of a service matches the matcher.
+func (c *Client) AssertNotCalled(t testing.TB, service string, matcher *middleware.RequestMatcher) {
+	t.Helper()
+	c.AssertCalled(t, service, matcher, Exactly(0))
+}
+
+// Equals matches values equal to value.
+func Equals(value string) *config.ValueMatcher {
+	return &config.ValueMatcher{Equals: &value}
+}
+
+// Regex matches values matching the regular expression.
+func Regex(pattern string) *config.ValueMatcher {
+	return &config.ValueMatcher{Regex: pattern}
+}
+
+// Present matches present values.
+func Present() *config.ValueMatcher {
+	present := true
+	return &config.ValueMatcher{Present: &present}
+}
+
+// Absent matches absent values.
+func Absent() *config.ValueMatcher {
+	present := false
+	return &config.ValueMatcher{Present: &present}
+}
+
+// Exactly expects n matching requests.
+func Exactly(n int) *api.VerifyExpect {
+	return &api.VerifyExpect{Exactly: &n}
+}
+
+// AtLeast expects n or more matching requests.
+func AtLeast(n int) *api.VerifyExpect {
+	return &api.VerifyExpect{AtLeast: &n}
+}
+
+// AtMost expects n or fewer matching requests.
+func AtMost(n int) *api.VerifyExpect {
+	return &api.VerifyExpect{AtMost: &n}
+}
+
+// Between expects from minimum to maximum matching requests.
+func Between(minimum, maximum int) *api.VerifyExpect {
+	return &api.VerifyExpect{AtLeast: &minimum, AtMost: &maximum}
+}
+
+func newVerifyRequest(matcher *middleware.RequestMatcher, expect *api.VerifyExpect) *api.VerifyRequest {
+	res := &api.VerifyRequest{Expect: expect}
+	if matcher != nil {
+		res.RequestMatcher = *matcher
+	}
+	return res
+}
+
+// describe returns a short description of the requests a matcher selects, e.g. payments POST /charges.
+func describe(service string, matcher *middleware.RequestMatcher) string {
+	parts := []string{service}
+	if matcher != nil {
+		parts = append(parts, matcher.Method, matcher.Resource)
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
@@ -0,0 +1,116 @@
+package cxstest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// paymentsService answers every charge with 200.
+type paymentsService struct{}
+
+func (s *paymentsService) Routes() api.RouteDescriptions {
+	return nil
+}
+
+func (s *paymentsService) RegisterRoutes(router chi.Router) {
+	router.Post("/charges", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (s *paymentsService) Generate(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	router := api.NewRouter(api.WithConfigOption(config.NewDefaultAppConfig(t.TempDir())))
+	cfg := config.NewServiceConfig()
+	cfg.Name = "payments"
+	router.RegisterService(cfg, &paymentsService{})
+	require.NoError(t, api.CreateVerifyRoutes(router))
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func charge(t *testing.T, server *httptest.Server, body string) {
+	t.Helper()
+	resp, err := http.Post(server.URL+"/payments/charges", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+}
+
+// recordingT records the failures of an assertion.
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, format)
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer(t)
+	client := NewClient(server.URL+"/", WithWait(200*time.Millisecond))
+
+	charge(t, server, `{"amount": 100}`)
+	charge(t, server, `{"amount": 100}`)
+	charge(t, server, `{"amount": 200}`)
+
+	amount100 := &middleware.RequestMatcher{
+		Method:   http.MethodPost,
+		Resource: "/charges",
+		Body:     map[string]*config.ValueMatcher{"$.amount": Equals("100")},
+	}
+
+	t.Run("assert called", func(t *testing.T) {
+		res := client.AssertCalled(t, "payments", amount100, Exactly(2))
+		require.NotNil(t, res)
+		assert.Len(t, res.Items, 2)
+
+		client.AssertCalled(t, "payments", &middleware.RequestMatcher{Resource: "/charges"}, Between(3, 5))
+		client.AssertNotCalled(t, "payments", &middleware.RequestMatcher{Method: http.MethodGet})
+	})
+
+	t.Run("failed expectation", func(t *testing.T) {
+		rec := &recordingT{TB: t}
+		res := client.AssertCalled(rec, "payments", amount100, AtLeast(3))
+		require.NotNil(t, res)
+		assert.Equal(t, 2, res.Count)
+		assert.Len(t, rec.failures, 1)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		count, err := client.Count(t.Context(), "payments", &middleware.RequestMatcher{
+			Body: map[string]*config.ValueMatcher{"amount": Regex("^[12]00$"), "currency": Absent()},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := client.Count(t.Context(), "unknown", nil)
+		assert.ErrorContains(t, err, "Service not found")
+
+		rec := &recordingT{TB: t}
+		assert.Nil(t, client.AssertCalled(rec, "payments", &middleware.RequestMatcher{
+			Query: map[string]*config.ValueMatcher{"q": Regex("(")},
+		}, Exactly(1)))
+		assert.Len(t, rec.failures, 1)
+	})
+}
@@ -40,6 +40,17 @@ type Table interface {
 	// Delete removes a value by key.
 	Delete(ctx context.Context, key string)
 
+	// Incr atomically adds delta to the integer stored at key and returns the new value.
+	// Missing or expired keys start at 0.
+	// If ttl > 0, the key expires ttl after the last increment, otherwise its expiry is kept.
+	Incr(ctx context.Context, key string, delta int64, ttl time.Duration) int64
+
+	// CompareAndSwap atomically stores value at key if it holds the integer old, and returns
+	// the integer the key holds afterwards with whether it was swapped.
+	// Missing or expired keys hold 0.
+	// If ttl > 0, the key expires ttl after the swap, otherwise its expiry is kept.
+	CompareAndSwap(ctx context.Context, key string, old, value int64, ttl time.Duration) (int64, bool)
+
 	// Data returns a copy of all data in the table.
 	Data(ctx context.Context) map[string]any
 
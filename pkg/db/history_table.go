@@ -1,6 +1,7 @@
 package db
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"sort"
@@ -25,6 +26,9 @@ type HistoryTable interface {
 	// Data returns all request records as an ordered log.
 	Data(ctx context.Context) []*HistoryEntry
 
+	// Query returns a page of the request records matching the query.
+	Query(ctx context.Context, query *HistoryQuery) *HistoryPage
+
 	// Len returns the number of history entries.
 	Len(ctx context.Context) int
 
@@ -32,6 +36,114 @@ type HistoryTable interface {
 	Clear(ctx context.Context)
 }
 
+// HistoryQuery selects a page of history entries. Zero values don't filter.
+// Method and Resource must match exactly, Resource being the openapi resource path, i.e. /pets/{id}.
+// StatusMin and StatusMax bound the response status code, Source is the X-Cxs-Source of the response.
+// Since and Until bound the time the request was made, Body is a substring of the request or response body.
+// Entries are ordered oldest first, or newest first with Newest.
+// Cursor is the NextCursor of the previous page, Limit the maximum number of entries (0 for all).
+type HistoryQuery struct {
+	Method    string
+	Resource  string
+	StatusMin int
+	StatusMax int
+	Source    string
+	RequestID string
+	Since     time.Time
+	Until     time.Time
+	Body      string
+	Newest    bool
+	Cursor    string
+	Limit     int
+}
+
+// HistoryPage is a page of history entries.
+// NextCursor is set when there are more entries, to be passed as the Cursor of the next query.
+type HistoryPage struct {
+	Items      []*HistoryEntry
+	NextCursor string
+}
+
+// historySourceHeader is the response header telling where a response came from.
+const historySourceHeader = "X-Cxs-Source"
+
+// Matches returns whether the entry satisfies the filters of the query.
+func (q *HistoryQuery) Matches(entry *HistoryEntry) bool {
+	if entry == nil {
+		return false
+	}
+	req, resp := entry.Request, entry.Response
+	if req == nil {
+		req = &HistoryRequest{}
+	}
+
+	if q.Method != "" && !strings.EqualFold(q.Method, req.Method) {
+		return false
+	}
+	if q.Resource != "" && q.Resource != entry.Resource {
+		return false
+	}
+	if q.RequestID != "" && q.RequestID != req.RequestID {
+		return false
+	}
+	if !q.Since.IsZero() && entry.CreatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && entry.CreatedAt.After(q.Until) {
+		return false
+	}
+	if q.StatusMin > 0 || q.StatusMax > 0 {
+		if resp == nil || resp.StatusCode < q.StatusMin || (q.StatusMax > 0 && resp.StatusCode > q.StatusMax) {
+			return false
+		}
+	}
+	if q.Source != "" && !strings.EqualFold(q.Source, responseSource(resp)) {
+		return false
+	}
+	if q.Body != "" {
+		inRequest := bytes.Contains(req.Body, []byte(q.Body))
+		if !inRequest && (resp == nil || !bytes.Contains(resp.Body, []byte(q.Body))) {
+			return false
+		}
+	}
+	return true
+}
+
+// after returns whether an entry ID comes after the cursor in the order of the query.
+// IDs are increasing numbers, so they're compared by length first.
+func (q *HistoryQuery) after(id string) bool {
+	if q.Cursor == "" {
+		return true
+	}
+	cmp := len(id) - len(q.Cursor)
+	if cmp == 0 {
+		cmp = strings.Compare(id, q.Cursor)
+	}
+	if q.Newest {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+// responseSource returns where a recorded response came from: upstream, cache, replay or generated.
+// The X-Cxs-Source header is recorded for served responses,
+// upstream responses recorded directly by the proxy are flagged instead.
+func responseSource(resp *HistoryResponse) string {
+	if resp == nil {
+		return ""
+	}
+	prefix := historySourceHeader + ": "
+	for _, header := range resp.Headers {
+		if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+			return header[len(prefix):]
+		}
+	}
+	if resp.IsFromUpstream {
+		return "upstream"
+	}
+	return "generated"
+}
+
 // HistoryRequest represents the HTTP request stored in a history entry.
 type HistoryRequest struct {
 	Method     string   `json:"method"`
@@ -62,6 +174,9 @@ type HistoryEntry struct {
 // IsFromUpstream is true if the response was received from the upstream server
 // UpstreamURL is the URL that was actually sent to the upstream service
 // Duration is the time taken to produce the response
+// Latency is the simulated latency included in Duration
+// Violations lists where an upstream response disagrees with the spec
+// Fault is the transport fault injected instead of or into the response
 type HistoryResponse struct {
 	Body           []byte        `json:"body"`
 	StatusCode     int           `json:"statusCode"`
@@ -70,7 +185,18 @@ type HistoryResponse struct {
 	UpstreamURL    string        `json:"upstreamURL"`
 	Headers        []string      `json:"headers,omitempty"`
 	Duration       time.Duration `json:"duration,omitempty"`
+	Latency        time.Duration `json:"latency,omitempty"`
 	UpstreamError  string        `json:"upstreamError,omitempty"`
+	Violations     []*Violation  `json:"violations,omitempty"`
+	Fault          string        `json:"fault,omitempty"`
+}
+
+// Violation is a response field that doesn't match the spec.
+// Field is a JSON path such as "$.items[].name", or "status" and "content-type"
+// for undocumented status codes and content types.
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // FlattenHeaders converts http.Header to a sorted slice of "Key: value" strings.
@@ -103,6 +103,29 @@ func (h *memoryHistoryTable) Data(_ context.Context) []*HistoryEntry {
 	return result
 }
 
+// Query returns a page of the request records matching the query.
+func (h *memoryHistoryTable) Query(_ context.Context, query *HistoryQuery) *HistoryPage {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	res := &HistoryPage{Items: make([]*HistoryEntry, 0)}
+	for i := range h.entries {
+		entry := h.entries[i]
+		if query.Newest {
+			entry = h.entries[len(h.entries)-1-i]
+		}
+		if !query.after(entry.ID) || !query.Matches(entry) {
+			continue
+		}
+		if query.Limit > 0 && len(res.Items) == query.Limit {
+			res.NextCursor = res.Items[len(res.Items)-1].ID
+			break
+		}
+		res.Items = append(res.Items, entry)
+	}
+	return res
+}
+
 // Len returns the number of history entries.
 func (h *memoryHistoryTable) Len(_ context.Context) int {
 	h.mu.RLock()
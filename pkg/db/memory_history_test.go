@@ -209,6 +209,103 @@ func TestMemoryHistoryTable_Data(t *testing.T) {
 	assert.NotEmpty(data[0].ID)
 }
 
+// seedQueryHistory stores the entries the query tests run against, with IDs 1 to 5.
+func seedQueryHistory(ctx context.Context, h HistoryTable) {
+	h.Set(ctx, "/pets", &HistoryRequest{Method: "GET", URL: "/pets"}, &HistoryResponse{
+		StatusCode: 200,
+		Body:       []byte(`[{"name":"rex"}]`),
+		Headers:    []string{"X-Cxs-Source: generated"},
+	})
+	h.Set(ctx, "/pets", &HistoryRequest{Method: "POST", URL: "/pets", Body: []byte(`{"name":"tom"}`), RequestID: "req-2"}, &HistoryResponse{
+		StatusCode: 201,
+		Headers:    []string{"X-Cxs-Source: cache"},
+	})
+	h.Set(ctx, "/pets/{id}", &HistoryRequest{Method: "GET", URL: "/pets/1"}, &HistoryResponse{
+		StatusCode: 404,
+		Headers:    []string{"X-Cxs-Source: canned"},
+	})
+	h.Set(ctx, "/pets/{id}", &HistoryRequest{Method: "GET", URL: "/pets/2"}, &HistoryResponse{
+		StatusCode:     503,
+		IsFromUpstream: true,
+	})
+	h.Set(ctx, "/pets/{id}", &HistoryRequest{Method: "DELETE", URL: "/pets/2"}, nil)
+}
+
+// queryIDs returns the IDs of a page of entries.
+func queryIDs(page *HistoryPage) []string {
+	res := make([]string, 0, len(page.Items))
+	for _, entry := range page.Items {
+		res = append(res, entry.ID)
+	}
+	return res
+}
+
+func TestMemoryHistoryTable_Query(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHistoryTable(0)
+	seedQueryHistory(ctx, h)
+
+	tests := map[string]struct {
+		query    *HistoryQuery
+		expected []string
+	}{
+		"everything":    {&HistoryQuery{}, []string{"1", "2", "3", "4", "5"}},
+		"method":        {&HistoryQuery{Method: "get"}, []string{"1", "3", "4"}},
+		"resource":      {&HistoryQuery{Resource: "/pets/{id}"}, []string{"3", "4", "5"}},
+		"status":        {&HistoryQuery{StatusMin: 400, StatusMax: 499}, []string{"3"}},
+		"status from":   {&HistoryQuery{StatusMin: 400}, []string{"3", "4"}},
+		"source":        {&HistoryQuery{Source: "canned"}, []string{"3"}},
+		"upstream":      {&HistoryQuery{Source: "upstream"}, []string{"4"}},
+		"generated":     {&HistoryQuery{Source: "generated"}, []string{"1"}},
+		"request ID":    {&HistoryQuery{RequestID: "req-2"}, []string{"2"}},
+		"request body":  {&HistoryQuery{Body: "tom"}, []string{"2"}},
+		"response body": {&HistoryQuery{Body: "rex"}, []string{"1"}},
+		"newest":        {&HistoryQuery{Newest: true, Method: "GET"}, []string{"4", "3", "1"}},
+		"until":         {&HistoryQuery{Until: time.Now().Add(-time.Hour)}, []string{}},
+		"since":         {&HistoryQuery{Since: time.Now().Add(-time.Hour)}, []string{"1", "2", "3", "4", "5"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			page := h.Query(ctx, tc.query)
+			assert2.Equal(t, tc.expected, queryIDs(page))
+			assert2.Empty(t, page.NextCursor)
+		})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		page := h.Query(ctx, &HistoryQuery{Limit: 2})
+		assert.Equal([]string{"1", "2"}, queryIDs(page))
+		assert.Equal("2", page.NextCursor)
+
+		page = h.Query(ctx, &HistoryQuery{Limit: 2, Cursor: page.NextCursor})
+		assert.Equal([]string{"3", "4"}, queryIDs(page))
+
+		page = h.Query(ctx, &HistoryQuery{Limit: 2, Cursor: page.NextCursor})
+		assert.Equal([]string{"5"}, queryIDs(page))
+		assert.Empty(page.NextCursor)
+	})
+
+	t.Run("pages newest first", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		page := h.Query(ctx, &HistoryQuery{Newest: true, Method: "GET", Limit: 2})
+		assert.Equal([]string{"4", "3"}, queryIDs(page))
+		assert.Equal("3", page.NextCursor)
+
+		page = h.Query(ctx, &HistoryQuery{Newest: true, Method: "GET", Limit: 2, Cursor: page.NextCursor})
+		assert.Equal([]string{"1"}, queryIDs(page))
+		assert.Empty(page.NextCursor)
+	})
+
+	t.Run("compares IDs as numbers", func(t *testing.T) {
+		q := &HistoryQuery{Cursor: "9"}
+		assert2.True(t, q.after("10"))
+		assert2.False(t, q.after("8"))
+	})
+}
+
 func TestMemoryHistoryTable_Len(t *testing.T) {
 	assert := assert2.New(t)
 	ctx := context.Background()
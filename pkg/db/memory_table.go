@@ -75,6 +75,51 @@ func (t *memoryTable) Delete(_ context.Context, key string) {
 	delete(t.data, key)
 }
 
+// Incr atomically adds delta to the integer stored at key and returns the new value.
+// Non-integer values are replaced.
+func (t *memoryTable) Incr(_ context.Context, key string, delta int64, ttl time.Duration) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var current int64
+	var expiresAt time.Time
+	if entry, ok := t.data[key]; ok && !entry.isExpired() {
+		current, _ = entry.value.(int64)
+		expiresAt = entry.expiresAt
+	}
+
+	entry := &memoryEntry{value: current + delta, expiresAt: expiresAt}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	t.data[key] = entry
+	return current + delta
+}
+
+// CompareAndSwap atomically stores value at key if it holds the integer old.
+// Non-integer values hold 0.
+func (t *memoryTable) CompareAndSwap(_ context.Context, key string, old, value int64, ttl time.Duration) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var current int64
+	var expiresAt time.Time
+	if entry, ok := t.data[key]; ok && !entry.isExpired() {
+		current, _ = entry.value.(int64)
+		expiresAt = entry.expiresAt
+	}
+	if current != old {
+		return current, false
+	}
+
+	entry := &memoryEntry{value: value, expiresAt: expiresAt}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	t.data[key] = entry
+	return value, true
+}
+
 // Data returns a copy of all non-expired data in the table.
 func (t *memoryTable) Data(_ context.Context) map[string]any {
 	t.mu.RLock()
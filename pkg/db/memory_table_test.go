@@ -99,6 +99,83 @@ func TestMemoryTable_Delete(t *testing.T) {
 	})
 }
 
+func TestMemoryTable_Incr(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := context.Background()
+
+	t.Run("starts at zero and accumulates", func(t *testing.T) {
+		table := newMemoryTable()
+
+		assert.Equal(int64(1), table.Incr(ctx, "hits", 1, 0))
+		assert.Equal(int64(6), table.Incr(ctx, "hits", 5, 0))
+		assert.Equal(int64(4), table.Incr(ctx, "hits", -2, 0))
+
+		val, ok := table.Get(ctx, "hits")
+		assert.True(ok)
+		assert.Equal(int64(4), val)
+	})
+
+	t.Run("expired keys start over", func(t *testing.T) {
+		table := newMemoryTable()
+
+		table.Incr(ctx, "hits", 3, 10*time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(int64(1), table.Incr(ctx, "hits", 1, 10*time.Millisecond))
+	})
+
+	t.Run("zero ttl keeps the expiry", func(t *testing.T) {
+		table := newMemoryTable()
+
+		table.Incr(ctx, "hits", 3, 10*time.Millisecond)
+		assert.Equal(int64(3), table.Incr(ctx, "hits", 0, 0))
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(int64(1), table.Incr(ctx, "hits", 1, 0))
+	})
+
+	t.Run("replaces non-integer values", func(t *testing.T) {
+		table := newMemoryTable()
+		table.Set(ctx, "hits", "many", 0)
+
+		assert.Equal(int64(1), table.Incr(ctx, "hits", 1, 0))
+	})
+}
+
+func TestMemoryTable_CompareAndSwap(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := context.Background()
+
+	t.Run("swaps the expected value", func(t *testing.T) {
+		table := newMemoryTable()
+
+		current, ok := table.CompareAndSwap(ctx, "tat", 0, 5, 0)
+		assert.True(ok)
+		assert.Equal(int64(5), current)
+
+		current, ok = table.CompareAndSwap(ctx, "tat", 0, 7, 0)
+		assert.False(ok)
+		assert.Equal(int64(5), current)
+	})
+
+	t.Run("expired keys hold zero", func(t *testing.T) {
+		table := newMemoryTable()
+
+		table.CompareAndSwap(ctx, "tat", 0, 5, 10*time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		_, ok := table.CompareAndSwap(ctx, "tat", 0, 1, 0)
+		assert.True(ok)
+	})
+
+	t.Run("zero ttl keeps the expiry", func(t *testing.T) {
+		table := newMemoryTable()
+
+		table.Incr(ctx, "tat", 3, 10*time.Millisecond)
+		_, ok := table.CompareAndSwap(ctx, "tat", 3, 4, 0)
+		assert.True(ok)
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(int64(0), table.Incr(ctx, "tat", 0, 0))
+	})
+}
+
 func TestMemoryTable_Data(t *testing.T) {
 	assert := assert2.New(t)
 	ctx := context.Background()
@@ -8,16 +8,25 @@ import (
 	"log/slog"
 	"net/http"
 	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// redisHistoryBatch is the number of entries Query fetches per round-trip.
+const redisHistoryBatch = 100
+
 // redisHistoryTable is a Redis-backed implementation of HistoryTable.
 type redisHistoryTable struct {
 	client    *redis.Client
 	namespace string // format: {service}:history
 	ttl       time.Duration
+
+	// indexed is set once entries written before the index existed are known to be in it.
+	indexed atomic.Bool
 }
 
 // redisHistoryRecord is the serializable form of HistoryEntry for Redis storage.
@@ -104,10 +113,16 @@ func (h *redisHistoryTable) Set(ctx context.Context, resource string, req *Histo
 		return nil
 	}
 
-	// Pipeline both SETs into a single round-trip.
+	// Pipeline the SETs and the index into a single round-trip.
 	pipe := h.client.Pipeline()
 	pipe.Set(ctx, h.entryKey(id), data, h.ttl)
 	pipe.Set(ctx, h.latestKey(req.Method, req.URL), id, h.ttl)
+	if score, err := strconv.ParseFloat(id, 64); err == nil {
+		pipe.ZAdd(ctx, h.indexKey(), redis.Z{Score: score, Member: id})
+		if h.ttl > 0 {
+			pipe.Expire(ctx, h.indexKey(), h.ttl)
+		}
+	}
 	if _, err := pipe.Exec(ctx); err != nil {
 		slog.Error("Error saving history record", "error", err)
 	}
@@ -215,6 +230,152 @@ func (h *redisHistoryTable) Data(ctx context.Context) []*HistoryEntry {
 	return entries
 }
 
+// Query returns a page of the request records matching the query.
+// It walks the ID index in batches and filters the fetched entries, so only the entries
+// up to the end of the page are read, not the ones after it.
+// The index is scored by ID, so the time range isn't a range of the index: IDs increase with time though,
+// so the walk ends after the batch in which entries pass the end of the range in the order of the query.
+func (h *redisHistoryTable) Query(ctx context.Context, query *HistoryQuery) *HistoryPage {
+	res := &HistoryPage{Items: make([]*HistoryEntry, 0)}
+	h.ensureIndex(ctx)
+
+	bound := "-inf"
+	if query.Newest {
+		bound = "+inf"
+	}
+	if query.Cursor != "" {
+		bound = "(" + query.Cursor
+	}
+
+	for {
+		rng := &redis.ZRangeBy{Min: bound, Max: "+inf", Count: redisHistoryBatch}
+		var ids []string
+		var err error
+		if query.Newest {
+			rng.Min, rng.Max = "-inf", bound
+			ids, err = h.client.ZRevRangeByScore(ctx, h.indexKey(), rng).Result()
+		} else {
+			ids, err = h.client.ZRangeByScore(ctx, h.indexKey(), rng).Result()
+		}
+		if err != nil {
+			slog.Error("Error reading history index", "error", err)
+			return res
+		}
+		if len(ids) == 0 {
+			return res
+		}
+
+		passed := false
+		for _, entry := range h.fetch(ctx, ids) {
+			passed = passed || pastTimeRange(query, entry)
+			if !query.Matches(entry) {
+				continue
+			}
+			if query.Limit > 0 && len(res.Items) == query.Limit {
+				res.NextCursor = res.Items[len(res.Items)-1].ID
+				return res
+			}
+			res.Items = append(res.Items, entry)
+		}
+
+		if passed || len(ids) < redisHistoryBatch {
+			return res
+		}
+		bound = "(" + ids[len(ids)-1]
+	}
+}
+
+// pastTimeRange returns whether the entry was created after the time range of the query,
+// in the order of the query.
+func pastTimeRange(query *HistoryQuery, entry *HistoryEntry) bool {
+	if query.Newest {
+		return !query.Since.IsZero() && entry.CreatedAt.Before(query.Since)
+	}
+	return !query.Until.IsZero() && entry.CreatedAt.After(query.Until)
+}
+
+// ensureIndex adds the entries written before the index existed to it.
+// The indexed marker is set once that's done, so the entries are scanned once per history.
+func (h *redisHistoryTable) ensureIndex(ctx context.Context) {
+	if h.indexed.Load() {
+		return
+	}
+
+	n, err := h.client.Exists(ctx, h.indexedKey()).Result()
+	if err != nil {
+		slog.Error("Error checking history index", "error", err)
+		return
+	}
+	if n > 0 {
+		h.indexed.Store(true)
+		return
+	}
+
+	prefix := h.namespace + ":entry:"
+	var members []redis.Z
+	iter := h.client.Scan(ctx, 0, prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		id := strings.TrimPrefix(iter.Val(), prefix)
+		if score, err := strconv.ParseFloat(id, 64); err == nil {
+			members = append(members, redis.Z{Score: score, Member: id})
+		}
+	}
+	if err := iter.Err(); err != nil {
+		slog.Error("Error scanning history entries", "error", err)
+		return
+	}
+
+	pipe := h.client.Pipeline()
+	if len(members) > 0 {
+		pipe.ZAdd(ctx, h.indexKey(), members...)
+		if h.ttl > 0 {
+			pipe.Expire(ctx, h.indexKey(), h.ttl)
+		}
+	}
+	pipe.Set(ctx, h.indexedKey(), "1", 0)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Error("Error building history index", "error", err)
+		return
+	}
+	h.indexed.Store(true)
+}
+
+// fetch returns the entries with the given IDs in the same order.
+// IDs of expired entries are removed from the index.
+func (h *redisHistoryTable) fetch(ctx context.Context, ids []string) []*HistoryEntry {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = h.entryKey(id)
+	}
+
+	vals, err := h.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		slog.Error("Error getting history records", "error", err)
+		return nil
+	}
+
+	entries := make([]*HistoryEntry, 0, len(vals))
+	var stale []any
+	for i, val := range vals {
+		str, ok := val.(string)
+		if !ok || str == "" {
+			stale = append(stale, ids[i])
+			continue
+		}
+
+		var record redisHistoryRecord
+		if err := json.Unmarshal([]byte(str), &record); err != nil {
+			continue
+		}
+		entries = append(entries, record.toEntry())
+	}
+
+	if len(stale) > 0 {
+		h.client.ZRem(ctx, h.indexKey(), stale...)
+	}
+	return entries
+}
+
 // Len returns the number of history entries.
 func (h *redisHistoryTable) Len(ctx context.Context) int {
 	pattern := h.namespace + ":entry:*"
@@ -246,6 +407,16 @@ func (h *redisHistoryTable) entryKey(id string) string {
 	return h.namespace + ":entry:" + id
 }
 
+// indexKey is the sorted set of entry IDs, scored by ID.
+func (h *redisHistoryTable) indexKey() string {
+	return h.namespace + ":index"
+}
+
+// indexedKey marks the entries written before the index existed as added to it.
+func (h *redisHistoryTable) indexedKey() string {
+	return h.namespace + ":indexed"
+}
+
 func (h *redisHistoryTable) latestKey(method, url string) string {
 	return h.namespace + ":latest:" + method + ":" + url
 }
@@ -2,8 +2,10 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/url"
+	"strconv"
 	"testing"
 	"time"
 
@@ -224,6 +226,118 @@ func TestRedisHistory_Data(t *testing.T) {
 	})
 }
 
+func TestRedisHistory_Query(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("filters", func(t *testing.T) {
+		history, _ := newTestRedisHistory(t)
+		seedQueryHistory(ctx, history)
+
+		assert.Equal(t, []string{"1", "3", "4"}, queryIDs(history.Query(ctx, &HistoryQuery{Method: "GET"})))
+		assert.Equal(t, []string{"4", "3"}, queryIDs(history.Query(ctx, &HistoryQuery{Newest: true, StatusMin: 400})))
+		assert.Equal(t, []string{"2"}, queryIDs(history.Query(ctx, &HistoryQuery{Source: "cache"})))
+		assert.Equal(t, []string{"2"}, queryIDs(history.Query(ctx, &HistoryQuery{Body: "tom"})))
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		history, _ := newTestRedisHistory(t)
+		seedQueryHistory(ctx, history)
+
+		page := history.Query(ctx, &HistoryQuery{Newest: true, Limit: 3})
+		assert.Equal(t, []string{"5", "4", "3"}, queryIDs(page))
+		assert.Equal(t, "3", page.NextCursor)
+
+		page = history.Query(ctx, &HistoryQuery{Newest: true, Limit: 3, Cursor: page.NextCursor})
+		assert.Equal(t, []string{"2", "1"}, queryIDs(page))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("pages across batches", func(t *testing.T) {
+		history, _ := newTestRedisHistory(t)
+		for i := 0; i < redisHistoryBatch+5; i++ {
+			history.Set(ctx, "/pets", &HistoryRequest{Method: "GET", URL: "/pets"}, &HistoryResponse{StatusCode: 200})
+		}
+		history.Set(ctx, "/pets", &HistoryRequest{Method: "POST", URL: "/pets"}, &HistoryResponse{StatusCode: 201})
+
+		page := history.Query(ctx, &HistoryQuery{Method: "POST"})
+		assert.Equal(t, []string{"106"}, queryIDs(page))
+
+		page = history.Query(ctx, &HistoryQuery{Limit: 10, Cursor: "100"})
+		assert.Equal(t, []string{"101", "102", "103", "104", "105", "106"}, queryIDs(page))
+	})
+
+	t.Run("drops expired entries from the index", func(t *testing.T) {
+		history, mr := newTestRedisHistory(t)
+		seedQueryHistory(ctx, history)
+		mr.Del(history.entryKey("2"))
+
+		assert.Equal(t, []string{"1", "3", "4", "5"}, queryIDs(history.Query(ctx, &HistoryQuery{})))
+		members, err := mr.ZMembers(history.indexKey())
+		assert.NoError(t, err)
+		assert.NotContains(t, members, "2")
+	})
+
+	t.Run("cleared with the history", func(t *testing.T) {
+		history, _ := newTestRedisHistory(t)
+		seedQueryHistory(ctx, history)
+		history.Clear(ctx)
+
+		assert.Empty(t, history.Query(ctx, &HistoryQuery{}).Items)
+	})
+
+	t.Run("indexes entries written before the index", func(t *testing.T) {
+		history, mr := newTestRedisHistory(t)
+		start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		for i := 1; i <= 3; i++ {
+			setUnindexedEntry(t, mr, history, i, start)
+		}
+		history.Set(ctx, "/pets", &HistoryRequest{Method: "GET", URL: "/pets"}, nil)
+
+		assert.Equal(t, []string{"1", "2", "3", "4"}, queryIDs(history.Query(ctx, &HistoryQuery{})))
+		members, err := mr.ZMembers(history.indexKey())
+		assert.NoError(t, err)
+		assert.Len(t, members, 4)
+		assert.True(t, mr.Exists(history.indexedKey()))
+
+		// Another instance finds the marker and doesn't scan again
+		other := newRedisHistoryTable(history.client, history.namespace, history.ttl)
+		mr.Del(history.indexKey())
+		assert.Empty(t, other.Query(ctx, &HistoryQuery{}).Items)
+	})
+
+	t.Run("ends the walk after the time range", func(t *testing.T) {
+		history, mr := newTestRedisHistory(t)
+		start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		for i := 1; i <= redisHistoryBatch+5; i++ {
+			setUnindexedEntry(t, mr, history, i, start.Add(time.Duration(i)*time.Minute))
+		}
+		// Out of order and past the batch the range ends in, so it isn't read
+		setUnindexedEntry(t, mr, history, redisHistoryBatch+6, start)
+
+		page := history.Query(ctx, &HistoryQuery{Until: start.Add(3 * time.Minute)})
+		assert.Equal(t, []string{"1", "2", "3"}, queryIDs(page))
+
+		page = history.Query(ctx, &HistoryQuery{Newest: true, Since: start.Add((redisHistoryBatch + 4) * time.Minute)})
+		assert.Equal(t, []string{"105", "104"}, queryIDs(page))
+	})
+}
+
+// setUnindexedEntry writes an entry the way it was stored before the history had an index.
+func setUnindexedEntry(t *testing.T, mr *miniredis.Miniredis, history *redisHistoryTable, id int, createdAt time.Time) {
+	t.Helper()
+	data, err := json.Marshal(&redisHistoryRecord{
+		ID:        strconv.Itoa(id),
+		Resource:  "/pets",
+		Method:    "GET",
+		URL:       "/pets",
+		CreatedAt: createdAt,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mr.Set(history.entryKey(strconv.Itoa(id)), string(data)))
+	_, err = mr.Incr(history.namespace+":counter", 1)
+	assert.NoError(t, err)
+}
+
 func TestRedisHistory_Len(t *testing.T) {
 	ctx := context.Background()
 
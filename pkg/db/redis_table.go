@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strconv"
 	"strings"
 	"time"
 
@@ -63,6 +64,53 @@ func (t *redisTable) Delete(ctx context.Context, key string) {
 	t.client.Del(ctx, fullKey)
 }
 
+// Incr atomically adds delta to the integer stored at key and returns the new value.
+// Returns 0 if Redis is unavailable or the key holds a non-integer value.
+func (t *redisTable) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) int64 {
+	fullKey := t.fullKey(key)
+
+	pipe := t.client.TxPipeline()
+	incr := pipe.IncrBy(ctx, fullKey, delta)
+	if ttl > 0 {
+		pipe.PExpire(ctx, fullKey, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0
+	}
+	return incr.Val()
+}
+
+// compareAndSwapScript swaps KEYS[1] from ARGV[1] to ARGV[2], expiring it in ARGV[3] milliseconds
+// or keeping its expiry for 0. Values are compared as strings: Lua numbers are doubles
+// and would round large integers such as Unix nanoseconds.
+var compareAndSwapScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1]) or '0'
+if current ~= ARGV[1] then
+	return {0, current}
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+end
+return {1, ARGV[2]}
+`)
+
+// CompareAndSwap atomically stores value at key if it holds the integer old.
+// Returns 0 and false if Redis is unavailable, non-integer values hold 0.
+func (t *redisTable) CompareAndSwap(ctx context.Context, key string, old, value int64, ttl time.Duration) (int64, bool) {
+	args := []any{strconv.FormatInt(old, 10), strconv.FormatInt(value, 10), ttl.Milliseconds()}
+	res, err := compareAndSwapScript.Run(ctx, t.client, []string{t.fullKey(key)}, args...).Slice()
+	if err != nil || len(res) != 2 {
+		return 0, false
+	}
+
+	swapped, _ := res[0].(int64)
+	str, _ := res[1].(string)
+	current, _ := strconv.ParseInt(str, 10, 64)
+	return current, swapped == 1
+}
+
 // Data returns a copy of all data in the table.
 // Note: This scans all keys with the namespace prefix, which can be slow for large datasets.
 func (t *redisTable) Data(ctx context.Context) map[string]any {
@@ -115,6 +115,79 @@ func TestRedisTable_Delete(t *testing.T) {
 	})
 }
 
+func TestRedisTable_Incr(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("starts at zero and accumulates", func(t *testing.T) {
+		table, _ := newTestRedisTable(t)
+
+		assert.Equal(t, int64(1), table.Incr(ctx, "hits", 1, 0))
+		assert.Equal(t, int64(6), table.Incr(ctx, "hits", 5, 0))
+
+		val, ok := table.Get(ctx, "hits")
+		assert.True(t, ok)
+		assert.Equal(t, float64(6), val)
+	})
+
+	t.Run("expires after the last increment", func(t *testing.T) {
+		table, mr := newTestRedisTable(t)
+
+		table.Incr(ctx, "hits", 3, time.Minute)
+		assert.Equal(t, time.Minute, mr.TTL("test:users:hits"))
+
+		mr.FastForward(2 * time.Minute)
+		assert.Equal(t, int64(1), table.Incr(ctx, "hits", 1, time.Minute))
+	})
+
+	t.Run("returns zero for non-integer values", func(t *testing.T) {
+		table, _ := newTestRedisTable(t)
+		table.Set(ctx, "hits", "many", 0)
+
+		assert.Equal(t, int64(0), table.Incr(ctx, "hits", 1, 0))
+	})
+}
+
+func TestRedisTable_CompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("swaps the expected value", func(t *testing.T) {
+		table, _ := newTestRedisTable(t)
+
+		current, ok := table.CompareAndSwap(ctx, "tat", 0, 5, 0)
+		assert.True(t, ok)
+		assert.Equal(t, int64(5), current)
+
+		current, ok = table.CompareAndSwap(ctx, "tat", 0, 7, 0)
+		assert.False(t, ok)
+		assert.Equal(t, int64(5), current)
+	})
+
+	t.Run("keeps large integers exact", func(t *testing.T) {
+		table, _ := newTestRedisTable(t)
+		nano := time.Now().UnixNano()
+
+		_, ok := table.CompareAndSwap(ctx, "tat", 0, nano, 0)
+		assert.True(t, ok)
+		current, ok := table.CompareAndSwap(ctx, "tat", nano, nano+1, 0)
+		assert.True(t, ok)
+		assert.Equal(t, nano+1, current)
+	})
+
+	t.Run("sets or keeps the expiry", func(t *testing.T) {
+		table, mr := newTestRedisTable(t)
+
+		table.CompareAndSwap(ctx, "tat", 0, 5, time.Minute)
+		assert.Equal(t, time.Minute, mr.TTL("test:users:tat"))
+
+		table.CompareAndSwap(ctx, "tat", 5, 6, 0)
+		assert.Equal(t, time.Minute, mr.TTL("test:users:tat"))
+
+		mr.FastForward(2 * time.Minute)
+		_, ok := table.CompareAndSwap(ctx, "tat", 0, 1, 0)
+		assert.True(t, ok)
+	})
+}
+
 func TestRedisTable_Data(t *testing.T) {
 	ctx := context.Background()
 
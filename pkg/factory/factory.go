@@ -9,6 +9,7 @@ import (
 	"github.com/mockzilla/connexions/v2/pkg/api"
 	"github.com/mockzilla/connexions/v2/pkg/config"
 	"github.com/mockzilla/connexions/v2/pkg/generator"
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
 	"github.com/mockzilla/connexions/v2/pkg/schema"
 	"github.com/mockzilla/connexions/v2/pkg/typedef"
 )
@@ -16,15 +17,17 @@ import (
 // Factory generates mock requests and responses based on an OpenAPI spec.
 // It wraps the registry and generator for convenient programmatic use.
 type Factory struct {
-	registry typedef.OperationRegistry
-	gen      generator.Generate
-	matcher  *pathMatcher
+	registry   typedef.OperationRegistry
+	gen        generator.Generate
+	matcher    *pathMatcher
+	claimsKeys oidc.KeySet
 }
 
 type factoryConfig struct {
 	serviceContext []byte
 	specOptions    *config.SpecOptions
 	codegenCfg     *codegen.Configuration
+	claimsKeys     oidc.KeySet
 }
 
 // FactoryOption configures a Factory.
@@ -52,6 +55,15 @@ func WithCodegenConfig(cfg codegen.Configuration) FactoryOption {
 	}
 }
 
+// WithClaimsKeySet verifies the bearer JWTs of requests passed to ResponseFromRequest
+// and ResponseBodyFromRequest against the key set before exposing their claims.
+// Without it the claims are decoded unverified.
+func WithClaimsKeySet(keys oidc.KeySet) FactoryOption {
+	return func(c *factoryConfig) {
+		c.claimsKeys = keys
+	}
+}
+
 // NewFactory creates a Factory from raw OpenAPI spec bytes.
 // Default replacement contexts (common, fake, words) are loaded automatically.
 func NewFactory(specBytes []byte, opts ...FactoryOption) (*Factory, error) {
@@ -76,9 +88,10 @@ func NewFactory(specBytes []byte, opts ...FactoryOption) (*Factory, error) {
 	matcher := newPathMatcher(registry.GetRouteInfo())
 
 	return &Factory{
-		registry: registry,
-		gen:      gen,
-		matcher:  matcher,
+		registry:   registry,
+		gen:        gen,
+		matcher:    matcher,
+		claimsKeys: fc.claimsKeys,
 	}, nil
 }
 
@@ -120,12 +133,13 @@ func (f *Factory) Request(path, method string, ctx map[string]any) (schema.Gener
 // It automatically matches the request path (e.g., /users/42) to the corresponding
 // spec path pattern (e.g., /users/{id}) and generates a response.
 // ctx is an optional replacement context for controlling generated values.
+// The claims of the request's bearer JWT are added to ctx in the in-claims area.
 func (f *Factory) ResponseFromRequest(r *http.Request, ctx map[string]any) (schema.ResponseData, error) {
 	specPath, ok := f.matcher.Match(r.URL.Path, r.Method)
 	if !ok {
 		return schema.ResponseData{}, fmt.Errorf("no matching operation for %s %s", r.Method, r.URL.Path)
 	}
-	return f.Response(specPath, r.Method, ctx)
+	return f.Response(specPath, r.Method, api.WithClaims(ctx, api.ExtractClaimsFromRequest(r, f.claimsKeys)))
 }
 
 // ResponseBody generates just the response body bytes for the given spec path and method.
@@ -152,12 +166,13 @@ func (f *Factory) RequestBody(path, method string, ctx map[string]any) (json.Raw
 // It automatically matches the request path (e.g., /users/42) to the corresponding
 // spec path pattern (e.g., /users/{id}).
 // ctx is an optional replacement context for controlling generated values.
+// The claims of the request's bearer JWT are added to ctx in the in-claims area.
 func (f *Factory) ResponseBodyFromRequest(r *http.Request, ctx map[string]any) (json.RawMessage, error) {
 	specPath, ok := f.matcher.Match(r.URL.Path, r.Method)
 	if !ok {
 		return nil, fmt.Errorf("no matching operation for %s %s", r.Method, r.URL.Path)
 	}
-	return f.ResponseBody(specPath, r.Method, ctx)
+	return f.ResponseBody(specPath, r.Method, api.WithClaims(ctx, api.ExtractClaimsFromRequest(r, f.claimsKeys)))
 }
 
 // Operations returns route info for all available operations.
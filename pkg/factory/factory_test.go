@@ -2,12 +2,14 @@ package factory
 
 import (
 	"embed"
+	"encoding/json"
 	"net/http/httptest"
 	"path/filepath"
 	"testing"
 
 	"github.com/doordash-oss/oapi-codegen-dd/v3/pkg/codegen"
 	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
 	assert2 "github.com/stretchr/testify/assert"
 )
 
@@ -237,4 +239,26 @@ func TestFactory_ResponseFromRequest(t *testing.T) {
 		assert.NoError(err)
 		assert.NotEmpty(resp.Body)
 	})
+
+	t.Run("with bearer token claims", func(t *testing.T) {
+		key, err := oidc.GenerateKey()
+		assert.NoError(err)
+		token, err := key.Sign(map[string]any{"name": "Jane"})
+		assert.NoError(err)
+
+		r := httptest.NewRequest("GET", "/pets/42", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+
+		verified, err := NewFactory(spec, WithClaimsKeySet(key))
+		assert.NoError(err)
+
+		for _, fc := range []*Factory{f, verified} {
+			resp, err := fc.ResponseFromRequest(r, nil)
+			assert.NoError(err)
+
+			var pet map[string]any
+			assert.NoError(json.Unmarshal(resp.Body, &pet))
+			assert.Equal("Jane", pet["name"])
+		}
+	})
 }
@@ -52,6 +52,30 @@ func TestGenerator_Generate(t *testing.T) {
 		assert.Equal("hallo, welt!", string(res.Body))
 	})
 
+	t.Run("claims area fills response fields", func(t *testing.T) {
+		res := gen.Response(&schema.ResponseSchema{
+			ContentType: "application/json",
+			Body: &schema.Schema{
+				Type: "object",
+				Properties: map[string]*schema.Schema{
+					"user_id": {Type: "string", Format: "uuid"},
+					"profile": {
+						Type: "object",
+						Properties: map[string]*schema.Schema{
+							"email": {Type: "string", Format: "email"},
+						},
+					},
+				},
+			},
+		}, map[string]any{
+			"in-claims": map[string]any{
+				"user_id": "5f1c9b2e-6a3d-4c1e-9f7a-2b8d4e6c0a11",
+				"email":   "jane@example.com",
+			},
+		})
+		assert.JSONEq(`{"user_id":"5f1c9b2e-6a3d-4c1e-9f7a-2b8d4e6c0a11","profile":{"email":"jane@example.com"}}`, string(res.Body))
+	})
+
 	t.Run("response with headers", func(t *testing.T) {
 		respSchema := &schema.ResponseSchema{
 			ContentType: "application/json",
@@ -0,0 +1,460 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/internal/types"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// execute parses, validates and runs a GraphQL request against the schema.
+// ctx is an optional replacement context for controlling generated values.
+func (h *Handler) execute(req *request, ctx map[string]any) *response {
+	doc, errs := gqlparser.LoadQueryWithRules(h.schema, req.Query, nil)
+	if len(errs) > 0 {
+		return &response{Errors: errs}
+	}
+
+	op, gqlErr := selectOperation(doc, req.OperationName)
+	if gqlErr != nil {
+		return &response{Errors: gqlerror.List{gqlErr}}
+	}
+
+	vars, err := validator.VariableValues(h.schema, op, req.Variables)
+	if err != nil {
+		return &response{Errors: gqlerror.List{gqlerror.WrapIfUnwrapped(err)}}
+	}
+
+	var root *ast.Definition
+	switch op.Operation {
+	case ast.Query:
+		root = h.schema.Query
+	case ast.Mutation:
+		root = h.schema.Mutation
+	case ast.Subscription:
+		return &response{Errors: gqlerror.List{gqlerror.ErrorPosf(op.Position, "subscriptions are not supported")}}
+	}
+	if root == nil {
+		return &response{Errors: gqlerror.List{gqlerror.ErrorPosf(op.Position, "schema does not support %s operations", op.Operation)}}
+	}
+
+	e := &executor{
+		schema:   h.schema,
+		vars:     vars,
+		generate: h.generate,
+	}
+
+	nodes := e.plan(root, op.SelectionSet)
+	rootSchema := e.objectSchema(nodes)
+
+	var generated any
+	res := h.gen.Response(&schema.ResponseSchema{
+		ContentType: "application/json",
+		Body:        rootSchema,
+	}, ctx)
+	if !res.IsError && len(res.Body) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(res.Body))
+		dec.UseNumber()
+		_ = dec.Decode(&generated)
+	}
+
+	return &response{Data: e.shapeObject(nodes, root, rootSchema, generated)}
+}
+
+// selectOperation picks the operation to run by name.
+// The name may be omitted only when the document holds a single operation.
+func selectOperation(doc *ast.QueryDocument, name string) (*ast.OperationDefinition, *gqlerror.Error) {
+	if name == "" {
+		if len(doc.Operations) != 1 {
+			return nil, gqlerror.Errorf("operationName is required when the document contains multiple operations")
+		}
+		return doc.Operations[0], nil
+	}
+
+	op := doc.Operations.ForName(name)
+	if op == nil {
+		return nil, gqlerror.Errorf("unknown operation %q", name)
+	}
+	return op, nil
+}
+
+// executor holds the state of a single operation execution.
+type executor struct {
+	schema   *ast.Schema
+	vars     map[string]any
+	generate *config.GenerationConfig
+}
+
+// collectedField is a response key with all the selections merged into it.
+type collectedField struct {
+	key    string
+	fields []*ast.Field
+}
+
+// field returns the first field with the merged selection set of all fields
+// sharing the response key.
+func (c *collectedField) field() *ast.Field {
+	f := *c.fields[0]
+	if len(c.fields) > 1 {
+		f.SelectionSet = nil
+		for _, other := range c.fields {
+			f.SelectionSet = append(f.SelectionSet, other.SelectionSet...)
+		}
+	}
+	return &f
+}
+
+// collectFields flattens fragments and applies @skip/@include for the given object type,
+// keeping the order in which response keys first appear.
+func (e *executor) collectFields(obj *ast.Definition, set ast.SelectionSet) []*collectedField {
+	var res []*collectedField
+	index := make(map[string]*collectedField)
+	visited := make(map[string]bool)
+
+	var collect func(set ast.SelectionSet)
+	collect = func(set ast.SelectionSet) {
+		for _, sel := range set {
+			switch s := sel.(type) {
+			case *ast.Field:
+				if !e.shouldInclude(s.Directives) {
+					continue
+				}
+				key := s.Alias
+				if key == "" {
+					key = s.Name
+				}
+				if cf, ok := index[key]; ok {
+					cf.fields = append(cf.fields, s)
+					continue
+				}
+				cf := &collectedField{key: key, fields: []*ast.Field{s}}
+				index[key] = cf
+				res = append(res, cf)
+
+			case *ast.InlineFragment:
+				if !e.shouldInclude(s.Directives) || !e.typeApplies(obj, s.TypeCondition) {
+					continue
+				}
+				collect(s.SelectionSet)
+
+			case *ast.FragmentSpread:
+				if visited[s.Name] || !e.shouldInclude(s.Directives) || s.Definition == nil {
+					continue
+				}
+				visited[s.Name] = true
+				if !e.typeApplies(obj, s.Definition.TypeCondition) {
+					continue
+				}
+				collect(s.Definition.SelectionSet)
+			}
+		}
+	}
+	collect(set)
+
+	return res
+}
+
+// shouldInclude evaluates the @skip and @include directives.
+func (e *executor) shouldInclude(directives ast.DirectiveList) bool {
+	if d := directives.ForName("skip"); d != nil {
+		if skip, _ := d.ArgumentMap(e.vars)["if"].(bool); skip {
+			return false
+		}
+	}
+	if d := directives.ForName("include"); d != nil {
+		if include, _ := d.ArgumentMap(e.vars)["if"].(bool); !include {
+			return false
+		}
+	}
+	return true
+}
+
+// typeApplies reports whether a fragment with the given type condition applies to obj.
+func (e *executor) typeApplies(obj *ast.Definition, condition string) bool {
+	if condition == "" || condition == obj.Name {
+		return true
+	}
+	def := e.schema.Types[condition]
+	if def == nil || !def.IsAbstractType() {
+		return false
+	}
+	for _, pt := range e.schema.GetPossibleTypes(def) {
+		if pt.Name == obj.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// concreteType resolves interfaces and unions to the first possible object type.
+func (e *executor) concreteType(def *ast.Definition) *ast.Definition {
+	if !def.IsAbstractType() {
+		return def
+	}
+	if def.Kind == ast.Union {
+		for _, name := range def.Types {
+			if t := e.schema.Types[name]; t != nil {
+				return t
+			}
+		}
+	}
+	if possible := e.schema.GetPossibleTypes(def); len(possible) > 0 {
+		return possible[0]
+	}
+	return def
+}
+
+// node is a planned response field.
+type node struct {
+	key   string
+	field *ast.Field
+	typ   *ast.Type
+
+	// object is the concrete object type for composite fields
+	// and children its planned selection.
+	object   *ast.Definition
+	children []*node
+}
+
+// plan resolves the selection set of obj into a tree of response fields.
+func (e *executor) plan(obj *ast.Definition, set ast.SelectionSet) []*node {
+	collected := e.collectFields(obj, set)
+	nodes := make([]*node, 0, len(collected))
+
+	for _, cf := range collected {
+		f := cf.field()
+		n := &node{key: cf.key, field: f}
+
+		if fd := obj.Fields.ForName(f.Name); fd != nil && !strings.HasPrefix(f.Name, "__") {
+			n.typ = fd.Type
+			if def := e.schema.Types[fd.Type.Name()]; def != nil && def.IsCompositeType() {
+				n.object = e.concreteType(def)
+				n.children = e.plan(n.object, f.SelectionSet)
+			}
+		}
+		nodes = append(nodes, n)
+	}
+
+	return nodes
+}
+
+// objectSchema builds the generator schema for a planned selection.
+// Meta fields (__typename, introspection) are resolved when shaping the result.
+func (e *executor) objectSchema(nodes []*node) *schema.Schema {
+	props := make(map[string]*schema.Schema, len(nodes))
+	required := make([]string, 0, len(nodes))
+
+	for _, n := range nodes {
+		if n.typ == nil {
+			continue
+		}
+		props[n.key] = e.typeSchema(n, n.typ)
+		required = append(required, n.key)
+	}
+
+	return &schema.Schema{
+		Type:       types.TypeObject,
+		Properties: props,
+		Required:   required,
+	}
+}
+
+// typeSchema builds the generator schema for a (possibly wrapped) field type.
+// List sizes come from the generation settings and are kept in MaxItems,
+// as the generator itself never produces less than a single item.
+func (e *executor) typeSchema(n *node, t *ast.Type) *schema.Schema {
+	if t.Elem != nil {
+		size := int64(e.generate.GetListSize())
+		s := &schema.Schema{
+			Type:     types.TypeArray,
+			Items:    e.typeSchema(n, t.Elem),
+			MaxItems: &size,
+		}
+		if size > 0 {
+			s.MinItems = &size
+		}
+		return s
+	}
+
+	if n.object != nil {
+		return e.objectSchema(n.children)
+	}
+
+	return scalarSchema(e.schema.Types[t.NamedType])
+}
+
+// scalarSchema maps GraphQL scalars and enums to generator schemas.
+// Custom scalars are matched by well-known names to pick a format, defaulting to string.
+func scalarSchema(def *ast.Definition) *schema.Schema {
+	if def == nil {
+		return &schema.Schema{Type: types.TypeString}
+	}
+
+	if def.Kind == ast.Enum {
+		enum := make([]any, 0, len(def.EnumValues))
+		for _, v := range def.EnumValues {
+			enum = append(enum, v.Name)
+		}
+		return &schema.Schema{Type: types.TypeString, Enum: enum}
+	}
+
+	switch strings.ToLower(def.Name) {
+	case "int", "long", "int64", "bigint":
+		return &schema.Schema{Type: types.TypeInteger}
+	case "float", "decimal", "bigdecimal":
+		return &schema.Schema{Type: types.TypeNumber}
+	case "boolean":
+		return &schema.Schema{Type: types.TypeBoolean}
+	case "datetime", "timestamp", "time":
+		return &schema.Schema{Type: types.TypeString, Format: "date-time"}
+	case "date":
+		return &schema.Schema{Type: types.TypeString, Format: "date"}
+	case "uuid":
+		return &schema.Schema{Type: types.TypeString, Format: "uuid"}
+	case "email", "emailaddress":
+		return &schema.Schema{Type: types.TypeString, Format: "email"}
+	case "url", "uri":
+		return &schema.Schema{Type: types.TypeString, Format: "uri"}
+	case "json", "jsonobject", "map", "object":
+		return &schema.Schema{Type: types.TypeObject}
+	default:
+		return &schema.Schema{Type: types.TypeString}
+	}
+}
+
+// shapeObject arranges generated values in selection order, resolving meta fields
+// and filling the gaps the generator left for non-null fields.
+func (e *executor) shapeObject(nodes []*node, obj *ast.Definition, s *schema.Schema, value any) *orderedMap {
+	values, _ := value.(map[string]any)
+	res := newOrderedMap(len(nodes))
+
+	for _, n := range nodes {
+		switch n.field.Name {
+		case "__typename":
+			res.set(n.key, obj.Name)
+		case "__schema":
+			res.set(n.key, e.introspectSchema(n.field.SelectionSet))
+		case "__type":
+			name, _ := n.field.ArgumentMap(e.vars)["name"].(string)
+			res.set(n.key, e.introspectNamedType(name, n.field.SelectionSet))
+		default:
+			var propSchema *schema.Schema
+			if s != nil {
+				propSchema = s.Properties[n.key]
+			}
+			res.set(n.key, e.shapeValue(n, n.typ, propSchema, values[n.key]))
+		}
+	}
+
+	return res
+}
+
+// shapeValue shapes a generated value of the given (possibly wrapped) type.
+func (e *executor) shapeValue(n *node, t *ast.Type, s *schema.Schema, value any) any {
+	if t == nil {
+		return value
+	}
+
+	if t.Elem != nil {
+		items, _ := value.([]any)
+		size := len(items)
+		var itemSchema *schema.Schema
+		if s != nil {
+			itemSchema = s.Items
+			if s.MaxItems != nil {
+				size = int(*s.MaxItems)
+			}
+		}
+
+		res := make([]any, size)
+		for i := range res {
+			var item any
+			if i < len(items) {
+				item = items[i]
+			}
+			res[i] = e.shapeValue(n, t.Elem, itemSchema, item)
+		}
+		return res
+	}
+
+	if n.object != nil {
+		return e.shapeObject(n.children, n.object, s, value)
+	}
+
+	if value == nil && t.NonNull {
+		return fallbackValue(e.schema.Types[t.NamedType])
+	}
+	return value
+}
+
+// fallbackValue returns a zero-ish value for non-null leaf fields
+// the generator could not produce a value for.
+func fallbackValue(def *ast.Definition) any {
+	if def == nil {
+		return ""
+	}
+	if def.Kind == ast.Enum && len(def.EnumValues) > 0 {
+		return def.EnumValues[0].Name
+	}
+
+	switch scalarSchema(def).Type {
+	case types.TypeInteger, types.TypeNumber:
+		return 0
+	case types.TypeBoolean:
+		return false
+	case types.TypeObject:
+		return map[string]any{}
+	default:
+		return ""
+	}
+}
+
+// orderedMap is a JSON object keeping the insertion order of its keys,
+// as GraphQL responses follow the order of the selection set.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func newOrderedMap(size int) *orderedMap {
+	return &orderedMap{
+		keys:   make([]string, 0, size),
+		values: make(map[string]any, size),
+	}
+}
+
+func (m *orderedMap) set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// MarshalJSON writes the keys in insertion order.
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, fmt.Errorf("marshalling %q: %w", key, err)
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
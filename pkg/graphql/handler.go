@@ -0,0 +1,193 @@
+// Package graphql serves mock responses for GraphQL schemas (SDL).
+//
+// Queries are parsed and validated against the schema and every selected field
+// is resolved with the generator's value replacer, so service contexts, fake
+// functions and formats apply just as they do for OpenAPI services.
+// Introspection is answered from the schema, so GraphQL clients and code
+// generators can point directly at the mock.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/generator"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Path is the endpoint GraphQL requests are served from, relative to the service root.
+const Path = "/graphql"
+
+// Handler implements api.Handler for a GraphQL schema.
+// It is also an http.Handler serving GraphQL-over-HTTP requests directly.
+type Handler struct {
+	schema   *ast.Schema
+	gen      generator.Generate
+	generate *config.GenerationConfig
+}
+
+type handlerConfig struct {
+	serviceContext []byte
+	generate       *config.GenerationConfig
+}
+
+// Option configures a Handler.
+type Option func(*handlerConfig)
+
+// WithServiceContext sets a service-specific context YAML for value replacements.
+func WithServiceContext(contextYAML []byte) Option {
+	return func(c *handlerConfig) {
+		c.serviceContext = contextYAML
+	}
+}
+
+// WithGenerationConfig sets the generation settings, e.g. the number of list items.
+func WithGenerationConfig(cfg *config.GenerationConfig) Option {
+	return func(c *handlerConfig) {
+		c.generate = cfg
+	}
+}
+
+// NewHandler creates a Handler from raw GraphQL SDL bytes.
+// Default replacement contexts (common, fake, words) are loaded automatically.
+func NewHandler(sdl []byte, opts ...Option) (*Handler, error) {
+	hc := &handlerConfig{}
+	for _, opt := range opts {
+		opt(hc)
+	}
+
+	s, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: string(sdl)})
+	if err != nil {
+		return nil, fmt.Errorf("loading schema: %w", err)
+	}
+
+	defaultContexts := generator.LoadDefaultContexts()
+	orderedCtx := generator.LoadServiceContext(hc.serviceContext, defaultContexts)
+	gen, err := generator.NewGenerator(orderedCtx, defaultContexts)
+	if err != nil {
+		return nil, fmt.Errorf("creating generator: %w", err)
+	}
+
+	return &Handler{
+		schema:   s,
+		gen:      gen,
+		generate: hc.generate,
+	}, nil
+}
+
+// Routes returns the single GraphQL endpoint.
+func (h *Handler) Routes() api.RouteDescriptions {
+	return api.RouteDescriptions{
+		{
+			ID:          "graphql",
+			Method:      http.MethodPost,
+			Path:        Path,
+			ContentType: "application/json",
+		},
+	}
+}
+
+// RegisterRoutes registers the GraphQL endpoint.
+// GET is accepted as well for clients sending queries in the query string.
+func (h *Handler) RegisterRoutes(router chi.Router) {
+	router.Post(Path, h.ServeHTTP)
+	router.Get(Path, h.ServeHTTP)
+}
+
+// Generate handles UI generate requests by returning a sample query
+// for the first field of the query type.
+func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
+	body, _ := json.Marshal(map[string]any{"query": sampleQuery(h.schema)})
+	api.NewJSONResponse(w).Send(map[string]any{
+		"path":        Path,
+		"contentType": "application/json",
+		"body":        json.RawMessage(body),
+	})
+}
+
+// ServeHTTP executes a GraphQL request and writes the mock result.
+// Malformed requests get 400, validation errors are reported in the
+// "errors" member of a 200 response as per GraphQL-over-HTTP.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		slog.Debug("Invalid GraphQL request", "error", err)
+		writeResponse(w, http.StatusBadRequest, &response{Errors: gqlerror.List{gqlerror.Errorf("%s", err.Error())}})
+		return
+	}
+
+	writeResponse(w, http.StatusOK, h.execute(req, api.ExtractContextFromRequest(r)))
+}
+
+// request is a GraphQL-over-HTTP request.
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// parseRequest reads a GraphQL request from a JSON POST body
+// or from GET query string parameters.
+func parseRequest(r *http.Request) (*request, error) {
+	req := &request{}
+
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if vars := q.Get("variables"); vars != "" {
+			if err := decodeJSON([]byte(vars), &req.Variables); err != nil {
+				return nil, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+	} else {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading body: %w", err)
+		}
+
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/graphql" {
+			req.Query = string(body)
+		} else if err := decodeJSON(body, req); err != nil {
+			if errors.Is(err, io.EOF) || len(body) == 0 {
+				return nil, errors.New("request body is empty")
+			}
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, errors.New("query is required")
+	}
+	return req, nil
+}
+
+// decodeJSON decodes JSON keeping numbers as json.Number,
+// so variables are coerced to Int or Float by the schema, not by encoding/json.
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// response is a GraphQL-over-HTTP response.
+type response struct {
+	Data   any           `json:"data,omitempty"`
+	Errors gqlerror.List `json:"errors,omitempty"`
+}
+
+func writeResponse(w http.ResponseWriter, statusCode int, res *response) {
+	api.NewJSONResponse(w).WithStatusCode(statusCode).Send(res)
+}
@@ -0,0 +1,409 @@
+package graphql
+
+import (
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata/**
+var testDataFS embed.FS
+
+func loadTestSchema(t *testing.T, fileName string) []byte {
+	t.Helper()
+	contents, err := testDataFS.ReadFile(filepath.Join("testdata", fileName))
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	return contents
+}
+
+func newTestHandler(t *testing.T, opts ...Option) *Handler {
+	t.Helper()
+	h, err := NewHandler(loadTestSchema(t, "petstore.graphql"), opts...)
+	require.NoError(t, err)
+	return h
+}
+
+type testResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func doQuery(t *testing.T, h http.Handler, query string, variables map[string]any) (*httptest.ResponseRecorder, *testResponse) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	res := &testResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), res), w.Body.String())
+	return w, res
+}
+
+func TestNewHandler(t *testing.T) {
+	assert := assert2.New(t)
+
+	t.Run("valid schema", func(t *testing.T) {
+		h, err := NewHandler(loadTestSchema(t, "petstore.graphql"))
+		assert.NoError(err)
+		assert.NotNil(h)
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		h, err := NewHandler([]byte(`type Query { pet: Unknown }`))
+		assert.Error(err)
+		assert.Nil(h)
+	})
+}
+
+func TestHandler_Routes(t *testing.T) {
+	assert := assert2.New(t)
+	h := newTestHandler(t)
+
+	routes := h.Routes()
+	assert.Len(routes, 1)
+	assert.Equal(http.MethodPost, routes[0].Method)
+	assert.Equal("/graphql", routes[0].Path)
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	assert := assert2.New(t)
+	h := newTestHandler(t)
+
+	t.Run("resolves selected fields", func(t *testing.T) {
+		w, res := doQuery(t, h, `{ pet(id: "1") { id name age weight vaccinated status tags createdAt } }`, nil)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Empty(res.Errors)
+
+		pet := res.Data["pet"].(map[string]any)
+		assert.Len(pet, 8)
+		assert.IsType("", pet["id"])
+		assert.IsType("", pet["name"])
+		assert.IsType(float64(0), pet["age"])
+		assert.IsType(true, pet["vaccinated"])
+		assert.Contains([]any{"AVAILABLE", "PENDING", "SOLD"}, pet["status"])
+		assert.Len(pet["tags"], 1)
+
+		_, err := time.Parse(time.RFC3339, pet["createdAt"].(string))
+		assert.NoError(err)
+	})
+
+	t.Run("keeps selection order", func(t *testing.T) {
+		w, _ := doQuery(t, h, `{ pet(id: "1") { vaccinated name id } }`, nil)
+		body := w.Body.String()
+
+		assert.Less(strings.Index(body, `"vaccinated"`), strings.Index(body, `"name"`))
+		assert.Less(strings.Index(body, `"name"`), strings.Index(body, `"id"`))
+	})
+
+	t.Run("uses aliases and __typename", func(t *testing.T) {
+		_, res := doQuery(t, h, `{ first: pet(id: "1") { __typename petName: name } }`, nil)
+		assert.Empty(res.Errors)
+
+		pet := res.Data["first"].(map[string]any)
+		assert.Equal("Pet", pet["__typename"])
+		assert.NotEmpty(pet["petName"])
+	})
+
+	t.Run("resolves nested objects and lists", func(t *testing.T) {
+		_, res := doQuery(t, h, `{ pets { owner { name pets { id } } } }`, nil)
+		assert.Empty(res.Errors)
+
+		pets := res.Data["pets"].([]any)
+		assert.Len(pets, 1)
+		owner := pets[0].(map[string]any)["owner"].(map[string]any)
+		assert.NotEmpty(owner["name"])
+		assert.Len(owner["pets"], 1)
+	})
+
+	t.Run("resolves interfaces and unions to a concrete type", func(t *testing.T) {
+		_, res := doQuery(t, h, `{
+			node(id: "1") { __typename id ... on Pet { name } }
+			search(term: "x") { __typename ... on Pet { age } ... on Owner { pets { id } } }
+		}`, nil)
+		assert.Empty(res.Errors)
+
+		node := res.Data["node"].(map[string]any)
+		assert.Equal("Pet", node["__typename"])
+		assert.NotEmpty(node["name"])
+
+		result := res.Data["search"].([]any)[0].(map[string]any)
+		assert.Equal("Pet", result["__typename"])
+		assert.Contains(result, "age")
+		assert.NotContains(result, "pets")
+	})
+
+	t.Run("applies fragments and directives", func(t *testing.T) {
+		query := `
+			query Pet($withAge: Boolean!) {
+				pet(id: "1") { ...PetFields age @include(if: $withAge) email @skip(if: true) }
+			}
+			fragment PetFields on Pet { id name }
+		`
+		_, res := doQuery(t, h, query, map[string]any{"withAge": false})
+		assert.Empty(res.Errors)
+
+		pet := res.Data["pet"].(map[string]any)
+		assert.Contains(pet, "id")
+		assert.Contains(pet, "name")
+		assert.NotContains(pet, "age")
+		assert.NotContains(pet, "email")
+	})
+
+	t.Run("runs mutations", func(t *testing.T) {
+		_, res := doQuery(t, h, `mutation { addPet(input: {name: "Rex"}) { id } }`, nil)
+		assert.Empty(res.Errors)
+		assert.Contains(res.Data["addPet"], "id")
+	})
+
+	t.Run("applies context from the request", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]any{"query": `{ pet(id: "1") { name } }`})
+		req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(string(body)))
+		ctxJSON, _ := json.Marshal(map[string]any{"name": "Rex"})
+		req.Header.Set(api.ContextHeaderName, base64.StdEncoding.EncodeToString(ctxJSON))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.JSONEq(`{"data":{"pet":{"name":"Rex"}}}`, w.Body.String())
+	})
+
+	t.Run("accepts GET requests", func(t *testing.T) {
+		q := url.Values{"query": {`{ pet(id: "1") { __typename } }`}}
+		req := httptest.NewRequest(http.MethodGet, Path+"?"+q.Encode(), nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(http.StatusOK, w.Code)
+		assert.JSONEq(`{"data":{"pet":{"__typename":"Pet"}}}`, w.Body.String())
+	})
+
+	t.Run("accepts application/graphql bodies", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(`{ pet(id: "1") { __typename } }`))
+		req.Header.Set("Content-Type", "application/graphql")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.JSONEq(`{"data":{"pet":{"__typename":"Pet"}}}`, w.Body.String())
+	})
+
+	t.Run("reports validation errors", func(t *testing.T) {
+		w, res := doQuery(t, h, `{ pet(id: "1") { unknown } }`, nil)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Nil(res.Data)
+		assert.Len(res.Errors, 1)
+		assert.Contains(res.Errors[0].Message, "unknown")
+	})
+
+	t.Run("reports missing variables", func(t *testing.T) {
+		_, res := doQuery(t, h, `query($id: ID!) { pet(id: $id) { id } }`, nil)
+		assert.Nil(res.Data)
+		assert.Len(res.Errors, 1)
+	})
+
+	t.Run("requires operation name for multiple operations", func(t *testing.T) {
+		_, res := doQuery(t, h, `query A { pets { id } } query B { pets { name } }`, nil)
+		assert.Len(res.Errors, 1)
+		assert.Contains(res.Errors[0].Message, "operationName")
+	})
+
+	t.Run("rejects subscriptions", func(t *testing.T) {
+		_, res := doQuery(t, h, `subscription { petAdded { id } }`, nil)
+		assert.Len(res.Errors, 1)
+		assert.Contains(res.Errors[0].Message, "subscriptions are not supported")
+	})
+
+	t.Run("rejects empty requests", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(""))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(http.StatusBadRequest, w.Code)
+		assert.Contains(w.Body.String(), "request body is empty")
+	})
+}
+
+func TestHandler_ListSize(t *testing.T) {
+	assert := assert2.New(t)
+
+	t.Run("exact size", func(t *testing.T) {
+		h := newTestHandler(t, WithGenerationConfig(&config.GenerationConfig{
+			ListSize: &config.ListSize{Min: 3, Max: 3},
+		}))
+		_, res := doQuery(t, h, `{ pets { id tags } }`, nil)
+
+		pets := res.Data["pets"].([]any)
+		assert.Len(pets, 3)
+		assert.Len(pets[0].(map[string]any)["tags"], 3)
+	})
+
+	t.Run("empty lists", func(t *testing.T) {
+		h := newTestHandler(t, WithGenerationConfig(&config.GenerationConfig{
+			ListSize: &config.ListSize{Min: 0, Max: 0},
+		}))
+		w, _ := doQuery(t, h, `{ pets { id } }`, nil)
+
+		assert.JSONEq(`{"data":{"pets":[]}}`, w.Body.String())
+	})
+}
+
+func TestHandler_ServiceContext(t *testing.T) {
+	assert := assert2.New(t)
+	h := newTestHandler(t, WithServiceContext([]byte("name: Buddy\n")))
+
+	w, _ := doQuery(t, h, `{ pet(id: "1") { name } }`, nil)
+	assert.JSONEq(`{"data":{"pet":{"name":"Buddy"}}}`, w.Body.String())
+}
+
+func TestHandler_Introspection(t *testing.T) {
+	assert := assert2.New(t)
+	h := newTestHandler(t)
+
+	t.Run("schema", func(t *testing.T) {
+		_, res := doQuery(t, h, `{
+			__schema {
+				description
+				queryType { name }
+				mutationType { name }
+				types { name kind }
+				directives { name }
+			}
+		}`, nil)
+		assert.Empty(res.Errors)
+
+		s := res.Data["__schema"].(map[string]any)
+		assert.Equal("A pet store.", s["description"])
+		assert.Equal(map[string]any{"name": "Query"}, s["queryType"])
+		assert.Equal(map[string]any{"name": "Mutation"}, s["mutationType"])
+		assert.Contains(s["types"], map[string]any{"name": "Pet", "kind": "OBJECT"})
+		assert.Contains(s["types"], map[string]any{"name": "SearchResult", "kind": "UNION"})
+		assert.Contains(s["directives"], map[string]any{"name": "deprecated"})
+	})
+
+	t.Run("type with wrapped field types", func(t *testing.T) {
+		_, res := doQuery(t, h, `{
+			__type(name: "Pet") {
+				kind
+				interfaces { name }
+				fields { name type { kind name ofType { kind name ofType { kind name } } } }
+			}
+		}`, nil)
+		assert.Empty(res.Errors)
+
+		typ := res.Data["__type"].(map[string]any)
+		assert.Equal("OBJECT", typ["kind"])
+		assert.Equal([]any{map[string]any{"name": "Node"}}, typ["interfaces"])
+
+		fields := typ["fields"].([]any)
+		assert.Len(fields, 10, "deprecated fields are excluded by default")
+		assert.Contains(fields, map[string]any{
+			"name": "tags",
+			"type": map[string]any{
+				"kind": "NON_NULL",
+				"name": nil,
+				"ofType": map[string]any{
+					"kind":   "LIST",
+					"name":   nil,
+					"ofType": map[string]any{"kind": "NON_NULL", "name": nil},
+				},
+			},
+		})
+	})
+
+	t.Run("deprecated enum values", func(t *testing.T) {
+		_, res := doQuery(t, h, `{
+			__type(name: "Status") {
+				enumValues(includeDeprecated: true) { name isDeprecated deprecationReason }
+			}
+		}`, nil)
+		assert.Empty(res.Errors)
+
+		values := res.Data["__type"].(map[string]any)["enumValues"].([]any)
+		assert.Len(values, 3)
+		assert.Equal(map[string]any{"name": "SOLD", "isDeprecated": true, "deprecationReason": "Use PENDING"}, values[2])
+	})
+
+	t.Run("input fields with defaults", func(t *testing.T) {
+		_, res := doQuery(t, h, `{ __type(name: "PetInput") { inputFields { name defaultValue } } }`, nil)
+		assert.Empty(res.Errors)
+
+		assert.Equal([]any{
+			map[string]any{"name": "name", "defaultValue": nil},
+			map[string]any{"name": "status", "defaultValue": "AVAILABLE"},
+		}, res.Data["__type"].(map[string]any)["inputFields"])
+	})
+
+	t.Run("possible types", func(t *testing.T) {
+		_, res := doQuery(t, h, `{ __type(name: "SearchResult") { possibleTypes { name } } }`, nil)
+		assert.Empty(res.Errors)
+
+		assert.ElementsMatch([]any{
+			map[string]any{"name": "Pet"},
+			map[string]any{"name": "Owner"},
+		}, res.Data["__type"].(map[string]any)["possibleTypes"])
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		w, _ := doQuery(t, h, `{ __type(name: "Nope") { name } }`, nil)
+		assert.JSONEq(`{"data":{"__type":null}}`, w.Body.String())
+	})
+
+	t.Run("mixed with mock fields", func(t *testing.T) {
+		_, res := doQuery(t, h, `{ __typename pet(id: "1") { id } }`, nil)
+		assert.Empty(res.Errors)
+		assert.Equal("Query", res.Data["__typename"])
+		assert.Contains(res.Data["pet"], "id")
+	})
+}
+
+func TestHandler_RegisterRoutes(t *testing.T) {
+	assert := assert2.New(t)
+	h := newTestHandler(t)
+
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	_, res := doQuery(t, router, `{ pet(id: "1") { __typename } }`, nil)
+	assert.Equal("Pet", res.Data["pet"].(map[string]any)["__typename"])
+}
+
+func TestHandler_Generate(t *testing.T) {
+	assert := assert2.New(t)
+	h := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(`{"path":"/graphql","method":"POST"}`))
+	w := httptest.NewRecorder()
+	h.Generate(w, req)
+
+	var res struct {
+		Path string `json:"path"`
+		Body struct {
+			Query string `json:"query"`
+		} `json:"body"`
+	}
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal("/graphql", res.Path)
+	assert.Contains(res.Body.Query, `pet(id: "1")`)
+
+	// the sample query must be valid and executable
+	_, out := doQuery(t, h, res.Body.Query, nil)
+	assert.Empty(out.Errors)
+	assert.Contains(out.Data, "pet")
+}
@@ -0,0 +1,313 @@
+package graphql
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// defaultDeprecationReason is the @deprecated reason when none is given.
+const defaultDeprecationReason = "No longer supported"
+
+// introObject maps the fields of an introspection type to their resolvers.
+type introObject map[string]func(f *ast.Field) any
+
+// resolveIntrospection resolves the selection set of an introspection type.
+func (e *executor) resolveIntrospection(typeName string, obj introObject, set ast.SelectionSet) *orderedMap {
+	def := e.schema.Types[typeName]
+	collected := e.collectFields(def, set)
+	res := newOrderedMap(len(collected))
+
+	for _, cf := range collected {
+		f := cf.field()
+		if f.Name == "__typename" {
+			res.set(cf.key, typeName)
+			continue
+		}
+
+		var value any
+		if resolve, ok := obj[f.Name]; ok {
+			value = resolve(f)
+		}
+		res.set(cf.key, value)
+	}
+
+	return res
+}
+
+// introspectSchema resolves the __schema meta field.
+func (e *executor) introspectSchema(set ast.SelectionSet) any {
+	return e.resolveIntrospection("__Schema", introObject{
+		"description": func(*ast.Field) any {
+			return nilIfEmpty(e.schema.Description)
+		},
+		"types": func(f *ast.Field) any {
+			names := make([]string, 0, len(e.schema.Types))
+			for name := range e.schema.Types {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			res := make([]any, 0, len(names))
+			for _, name := range names {
+				res = append(res, e.introspectDefinition(e.schema.Types[name], f.SelectionSet))
+			}
+			return res
+		},
+		"queryType": func(f *ast.Field) any {
+			return e.introspectDefinition(e.schema.Query, f.SelectionSet)
+		},
+		"mutationType": func(f *ast.Field) any {
+			return e.introspectDefinition(e.schema.Mutation, f.SelectionSet)
+		},
+		"subscriptionType": func(f *ast.Field) any {
+			return e.introspectDefinition(e.schema.Subscription, f.SelectionSet)
+		},
+		"directives": func(f *ast.Field) any {
+			names := make([]string, 0, len(e.schema.Directives))
+			for name := range e.schema.Directives {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			res := make([]any, 0, len(names))
+			for _, name := range names {
+				res = append(res, e.introspectDirective(e.schema.Directives[name], f.SelectionSet))
+			}
+			return res
+		},
+	}, set)
+}
+
+// introspectNamedType resolves the __type(name:) meta field.
+func (e *executor) introspectNamedType(name string, set ast.SelectionSet) any {
+	return e.introspectDefinition(e.schema.Types[name], set)
+}
+
+// introspectType resolves a __Type for a possibly wrapped (list, non-null) type reference.
+func (e *executor) introspectType(t *ast.Type, set ast.SelectionSet) any {
+	if t == nil {
+		return nil
+	}
+
+	if !t.NonNull && t.Elem == nil {
+		return e.introspectDefinition(e.schema.Types[t.NamedType], set)
+	}
+
+	kind := "LIST"
+	ofType := t.Elem
+	if t.NonNull {
+		kind = "NON_NULL"
+		ofType = &ast.Type{NamedType: t.NamedType, Elem: t.Elem}
+	}
+
+	return e.resolveIntrospection("__Type", introObject{
+		"kind": func(*ast.Field) any { return kind },
+		"ofType": func(f *ast.Field) any {
+			return e.introspectType(ofType, f.SelectionSet)
+		},
+	}, set)
+}
+
+// introspectDefinition resolves a __Type for a named type.
+func (e *executor) introspectDefinition(def *ast.Definition, set ast.SelectionSet) any {
+	if def == nil {
+		return nil
+	}
+
+	return e.resolveIntrospection("__Type", introObject{
+		"kind":        func(*ast.Field) any { return string(def.Kind) },
+		"name":        func(*ast.Field) any { return def.Name },
+		"description": func(*ast.Field) any { return nilIfEmpty(def.Description) },
+		"specifiedByURL": func(*ast.Field) any {
+			if d := def.Directives.ForName("specifiedBy"); d != nil {
+				if arg := d.Arguments.ForName("url"); arg != nil && arg.Value != nil {
+					return arg.Value.Raw
+				}
+			}
+			return nil
+		},
+		"fields": func(f *ast.Field) any {
+			if def.Kind != ast.Object && def.Kind != ast.Interface {
+				return nil
+			}
+			includeDeprecated := e.includeDeprecated(f)
+			res := make([]any, 0, len(def.Fields))
+			for _, fd := range def.Fields {
+				if strings.HasPrefix(fd.Name, "__") || (!includeDeprecated && isDeprecated(fd.Directives)) {
+					continue
+				}
+				res = append(res, e.introspectField(fd, f.SelectionSet))
+			}
+			return res
+		},
+		"interfaces": func(f *ast.Field) any {
+			if def.Kind != ast.Object && def.Kind != ast.Interface {
+				return nil
+			}
+			res := make([]any, 0, len(def.Interfaces))
+			for _, name := range def.Interfaces {
+				res = append(res, e.introspectDefinition(e.schema.Types[name], f.SelectionSet))
+			}
+			return res
+		},
+		"possibleTypes": func(f *ast.Field) any {
+			if !def.IsAbstractType() {
+				return nil
+			}
+			possible := e.schema.GetPossibleTypes(def)
+			res := make([]any, 0, len(possible))
+			for _, pt := range possible {
+				res = append(res, e.introspectDefinition(pt, f.SelectionSet))
+			}
+			return res
+		},
+		"enumValues": func(f *ast.Field) any {
+			if def.Kind != ast.Enum {
+				return nil
+			}
+			includeDeprecated := e.includeDeprecated(f)
+			res := make([]any, 0, len(def.EnumValues))
+			for _, ev := range def.EnumValues {
+				if !includeDeprecated && isDeprecated(ev.Directives) {
+					continue
+				}
+				res = append(res, e.introspectEnumValue(ev, f.SelectionSet))
+			}
+			return res
+		},
+		"inputFields": func(f *ast.Field) any {
+			if def.Kind != ast.InputObject {
+				return nil
+			}
+			includeDeprecated := e.includeDeprecated(f)
+			res := make([]any, 0, len(def.Fields))
+			for _, fd := range def.Fields {
+				if !includeDeprecated && isDeprecated(fd.Directives) {
+					continue
+				}
+				res = append(res, e.introspectInputValue(fd.Name, fd.Description, fd.Type, fd.DefaultValue, fd.Directives, f.SelectionSet))
+			}
+			return res
+		},
+		"ofType": func(*ast.Field) any { return nil },
+		"isOneOf": func(*ast.Field) any {
+			if def.Kind != ast.InputObject {
+				return nil
+			}
+			return def.Directives.ForName("oneOf") != nil
+		},
+	}, set)
+}
+
+// introspectField resolves a __Field.
+func (e *executor) introspectField(fd *ast.FieldDefinition, set ast.SelectionSet) any {
+	return e.resolveIntrospection("__Field", introObject{
+		"name":        func(*ast.Field) any { return fd.Name },
+		"description": func(*ast.Field) any { return nilIfEmpty(fd.Description) },
+		"args": func(f *ast.Field) any {
+			return e.introspectArguments(fd.Arguments, f)
+		},
+		"type": func(f *ast.Field) any {
+			return e.introspectType(fd.Type, f.SelectionSet)
+		},
+		"isDeprecated":      func(*ast.Field) any { return isDeprecated(fd.Directives) },
+		"deprecationReason": func(*ast.Field) any { return deprecationReason(fd.Directives) },
+	}, set)
+}
+
+// introspectArguments resolves the args of a field or directive.
+func (e *executor) introspectArguments(args ast.ArgumentDefinitionList, f *ast.Field) any {
+	includeDeprecated := e.includeDeprecated(f)
+	res := make([]any, 0, len(args))
+	for _, arg := range args {
+		if !includeDeprecated && isDeprecated(arg.Directives) {
+			continue
+		}
+		res = append(res, e.introspectInputValue(arg.Name, arg.Description, arg.Type, arg.DefaultValue, arg.Directives, f.SelectionSet))
+	}
+	return res
+}
+
+// introspectInputValue resolves an __InputValue.
+func (e *executor) introspectInputValue(
+	name, description string,
+	typ *ast.Type,
+	defaultValue *ast.Value,
+	directives ast.DirectiveList,
+	set ast.SelectionSet,
+) any {
+	return e.resolveIntrospection("__InputValue", introObject{
+		"name":        func(*ast.Field) any { return name },
+		"description": func(*ast.Field) any { return nilIfEmpty(description) },
+		"type": func(f *ast.Field) any {
+			return e.introspectType(typ, f.SelectionSet)
+		},
+		"defaultValue": func(*ast.Field) any {
+			if defaultValue == nil {
+				return nil
+			}
+			return defaultValue.String()
+		},
+		"isDeprecated":      func(*ast.Field) any { return isDeprecated(directives) },
+		"deprecationReason": func(*ast.Field) any { return deprecationReason(directives) },
+	}, set)
+}
+
+// introspectEnumValue resolves an __EnumValue.
+func (e *executor) introspectEnumValue(ev *ast.EnumValueDefinition, set ast.SelectionSet) any {
+	return e.resolveIntrospection("__EnumValue", introObject{
+		"name":              func(*ast.Field) any { return ev.Name },
+		"description":       func(*ast.Field) any { return nilIfEmpty(ev.Description) },
+		"isDeprecated":      func(*ast.Field) any { return isDeprecated(ev.Directives) },
+		"deprecationReason": func(*ast.Field) any { return deprecationReason(ev.Directives) },
+	}, set)
+}
+
+// introspectDirective resolves a __Directive.
+func (e *executor) introspectDirective(d *ast.DirectiveDefinition, set ast.SelectionSet) any {
+	return e.resolveIntrospection("__Directive", introObject{
+		"name":         func(*ast.Field) any { return d.Name },
+		"description":  func(*ast.Field) any { return nilIfEmpty(d.Description) },
+		"isRepeatable": func(*ast.Field) any { return d.IsRepeatable },
+		"locations": func(*ast.Field) any {
+			res := make([]any, 0, len(d.Locations))
+			for _, loc := range d.Locations {
+				res = append(res, string(loc))
+			}
+			return res
+		},
+		"args": func(f *ast.Field) any {
+			return e.introspectArguments(d.Arguments, f)
+		},
+	}, set)
+}
+
+// includeDeprecated reads the includeDeprecated argument of an introspection field.
+func (e *executor) includeDeprecated(f *ast.Field) bool {
+	include, _ := f.ArgumentMap(e.vars)["includeDeprecated"].(bool)
+	return include
+}
+
+func isDeprecated(directives ast.DirectiveList) bool {
+	return directives.ForName("deprecated") != nil
+}
+
+func deprecationReason(directives ast.DirectiveList) any {
+	d := directives.ForName("deprecated")
+	if d == nil {
+		return nil
+	}
+	if arg := d.Arguments.ForName("reason"); arg != nil && arg.Value != nil {
+		return arg.Value.Raw
+	}
+	return defaultDeprecationReason
+}
+
+func nilIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,140 @@
+package graphql
+
+import (
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/internal/types"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// sampleDepth limits how many levels of nested objects a sample query selects.
+const sampleDepth = 2
+
+// sampleQuery builds a query for the first field of the query type,
+// selecting leaf fields up to sampleDepth levels deep.
+// Required arguments are filled with placeholder literals.
+func sampleQuery(s *ast.Schema) string {
+	if s.Query == nil {
+		return "{ __typename }"
+	}
+
+	for _, fd := range s.Query.Fields {
+		if strings.HasPrefix(fd.Name, "__") {
+			continue
+		}
+		var sb strings.Builder
+		sb.WriteString("query {\n")
+		writeSampleField(&sb, s, fd, 1, sampleDepth)
+		sb.WriteString("}")
+		return sb.String()
+	}
+
+	return "{ __typename }"
+}
+
+// writeSampleField writes a field with its required arguments and selection set.
+// Returns false if the field cannot be selected within the depth limit.
+func writeSampleField(sb *strings.Builder, s *ast.Schema, fd *ast.FieldDefinition, indent, depth int) bool {
+	def := s.Types[fd.Type.Name()]
+	if def == nil {
+		return false
+	}
+
+	var selection strings.Builder
+	if def.IsCompositeType() {
+		if depth < 0 {
+			return false
+		}
+		writeSampleSelection(&selection, s, def, indent+1, depth-1)
+	}
+
+	pad := strings.Repeat("  ", indent)
+	sb.WriteString(pad)
+	sb.WriteString(fd.Name)
+
+	var args []string
+	for _, arg := range fd.Arguments {
+		if arg.Type.NonNull && arg.DefaultValue == nil {
+			args = append(args, arg.Name+": "+sampleLiteral(s, arg.Type, sampleDepth))
+		}
+	}
+	if len(args) > 0 {
+		sb.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+
+	if selection.Len() > 0 {
+		sb.WriteString(" {\n")
+		sb.WriteString(selection.String())
+		sb.WriteString(pad + "}")
+	}
+	sb.WriteString("\n")
+	return true
+}
+
+// writeSampleSelection writes the selection set body for a composite type.
+// Abstract types and objects without selectable fields fall back to __typename.
+func writeSampleSelection(sb *strings.Builder, s *ast.Schema, def *ast.Definition, indent, depth int) {
+	written := false
+	if !def.IsAbstractType() {
+		for _, fd := range def.Fields {
+			if strings.HasPrefix(fd.Name, "__") {
+				continue
+			}
+			if writeSampleField(sb, s, fd, indent, depth) {
+				written = true
+			}
+		}
+	}
+
+	if !written {
+		sb.WriteString(strings.Repeat("  ", indent) + "__typename\n")
+	}
+}
+
+// sampleLiteral returns a GraphQL literal for an input type.
+func sampleLiteral(s *ast.Schema, t *ast.Type, depth int) string {
+	if t.Elem != nil {
+		return "[" + sampleLiteral(s, t.Elem, depth) + "]"
+	}
+
+	def := s.Types[t.NamedType]
+	if def == nil {
+		return `""`
+	}
+
+	switch def.Kind {
+	case ast.Enum:
+		if len(def.EnumValues) > 0 {
+			return def.EnumValues[0].Name
+		}
+		return "null"
+
+	case ast.InputObject:
+		if depth <= 0 {
+			return "{}"
+		}
+		var fields []string
+		for _, fd := range def.Fields {
+			if fd.Type.NonNull && fd.DefaultValue == nil {
+				fields = append(fields, fd.Name+": "+sampleLiteral(s, fd.Type, depth-1))
+			}
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+
+	switch scalarSchema(def).Type {
+	case types.TypeInteger:
+		return "1"
+	case types.TypeNumber:
+		return "1.5"
+	case types.TypeBoolean:
+		return "true"
+	case types.TypeObject:
+		return "{}"
+	default:
+		if def.Name == "ID" {
+			return `"1"`
+		}
+		return `"string"`
+	}
+}
@@ -0,0 +1,279 @@
+package grpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// fillMessage sets the fields of msg from a generated JSON value keyed by proto field names.
+// Values are coerced to the field kinds; those that cannot be converted are left unset,
+// so a context returning an unexpected type never fails the whole response.
+func fillMessage(msg protoreflect.Message, value any) {
+	md := msg.Descriptor()
+
+	if fillWellKnown(msg, value) {
+		return
+	}
+
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		v, ok := obj[string(fd.Name())]
+		if !ok || v == nil {
+			continue
+		}
+
+		switch {
+		case fd.IsMap():
+			fillMap(msg, fd, v)
+		case fd.IsList():
+			fillList(msg, fd, v)
+		case fd.Message() != nil:
+			fillMessage(msg.Mutable(fd).Message(), v)
+		default:
+			if pv, ok := scalarValue(fd, v); ok {
+				msg.Set(fd, pv)
+			}
+		}
+	}
+}
+
+func fillList(msg protoreflect.Message, fd protoreflect.FieldDescriptor, value any) {
+	items, ok := value.([]any)
+	if !ok {
+		return
+	}
+
+	list := msg.Mutable(fd).List()
+	for _, item := range items {
+		if fd.Message() != nil {
+			elem := list.NewElement()
+			fillMessage(elem.Message(), item)
+			list.Append(elem)
+			continue
+		}
+		if pv, ok := scalarValue(fd, item); ok {
+			list.Append(pv)
+		}
+	}
+}
+
+// fillMap sets map entries. Keys that do not parse as the key kind
+// (generated keys are words) are replaced with the entry index.
+func fillMap(msg protoreflect.Message, fd protoreflect.FieldDescriptor, value any) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+
+	keyFd, valFd := fd.MapKey(), fd.MapValue()
+	m := msg.Mutable(fd).Map()
+	i := 0
+	for k, item := range obj {
+		i++
+		key, ok := scalarValue(keyFd, k)
+		if !ok {
+			if key, ok = scalarValue(keyFd, json.Number(strconv.Itoa(i))); !ok {
+				continue
+			}
+		}
+
+		if valFd.Message() != nil {
+			elem := m.NewValue()
+			fillMessage(elem.Message(), item)
+			m.Set(key.MapKey(), elem)
+			continue
+		}
+		if pv, ok := scalarValue(valFd, item); ok {
+			m.Set(key.MapKey(), pv)
+		}
+	}
+}
+
+// fillWellKnown handles well-known types whose JSON form is not an object of their fields.
+// Returns false for regular messages.
+func fillWellKnown(msg protoreflect.Message, value any) bool {
+	md := msg.Descriptor()
+
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		s, _ := value.(string)
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			ts = time.Now().UTC()
+		}
+		setInt(msg, "seconds", ts.Unix())
+		setInt(msg, "nanos", int64(ts.Nanosecond()))
+		return true
+
+	case "google.protobuf.Duration":
+		if n, ok := toInt64(value); ok {
+			setInt(msg, "seconds", n)
+		}
+		return true
+
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue":
+		if data, err := json.Marshal(value); err == nil {
+			_ = protojson.Unmarshal(data, msg.Interface())
+		}
+		return true
+
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		if fd := md.Fields().ByName("value"); fd != nil {
+			if pv, ok := scalarValue(fd, value); ok {
+				msg.Set(fd, pv)
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func setInt(msg protoreflect.Message, name protoreflect.Name, n int64) {
+	fd := msg.Descriptor().Fields().ByName(name)
+	if fd == nil {
+		return
+	}
+	if pv, ok := scalarValue(fd, json.Number(strconv.FormatInt(n, 10))); ok {
+		msg.Set(fd, pv)
+	}
+}
+
+// scalarValue converts a generated JSON value to a protobuf value of the field kind.
+// Integers are clamped to the range of the kind.
+func scalarValue(fd protoreflect.FieldDescriptor, value any) (protoreflect.Value, bool) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		switch v := value.(type) {
+		case bool:
+			return protoreflect.ValueOfBool(v), true
+		case string:
+			b, err := strconv.ParseBool(v)
+			return protoreflect.ValueOfBool(b), err == nil
+		}
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if n, ok := toInt64(value); ok {
+			return protoreflect.ValueOfInt32(int32(clamp(n, math.MinInt32, math.MaxInt32))), true
+		}
+
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if n, ok := toInt64(value); ok {
+			return protoreflect.ValueOfInt64(n), true
+		}
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if n, ok := toInt64(value); ok {
+			return protoreflect.ValueOfUint32(uint32(clamp(n, 0, math.MaxUint32))), true
+		}
+
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if n, ok := toInt64(value); ok {
+			return protoreflect.ValueOfUint64(uint64(clamp(n, 0, math.MaxInt64))), true
+		}
+
+	case protoreflect.FloatKind:
+		if f, ok := toFloat64(value); ok {
+			return protoreflect.ValueOfFloat32(float32(f)), true
+		}
+
+	case protoreflect.DoubleKind:
+		if f, ok := toFloat64(value); ok {
+			return protoreflect.ValueOfFloat64(f), true
+		}
+
+	case protoreflect.StringKind:
+		if s, ok := value.(string); ok {
+			return protoreflect.ValueOfString(s), true
+		}
+		return protoreflect.ValueOfString(fmt.Sprint(value)), true
+
+	case protoreflect.BytesKind:
+		s, ok := value.(string)
+		if !ok {
+			s = fmt.Sprint(value)
+		}
+		if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+			return protoreflect.ValueOfBytes(b), true
+		}
+		return protoreflect.ValueOfBytes([]byte(s)), true
+
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		if s, ok := value.(string); ok {
+			if ev := values.ByName(protoreflect.Name(s)); ev != nil {
+				return protoreflect.ValueOfEnum(ev.Number()), true
+			}
+		}
+		if n, ok := toInt64(value); ok {
+			if ev := values.ByNumber(protoreflect.EnumNumber(clamp(n, math.MinInt32, math.MaxInt32))); ev != nil {
+				return protoreflect.ValueOfEnum(ev.Number()), true
+			}
+		}
+		if values.Len() > 0 {
+			return protoreflect.ValueOfEnum(values.Get(0).Number()), true
+		}
+	}
+
+	return protoreflect.Value{}, false
+}
+
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, true
+		}
+		if f, err := v.Float64(); err == nil {
+			return int64(f), true
+		}
+	case float64:
+		return int64(v), true
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func clamp(n, lo, hi int64) int64 {
+	return max(lo, min(hi, n))
+}
@@ -0,0 +1,198 @@
+package grpc
+
+import (
+	"math"
+
+	"github.com/mockzilla/connexions/v2/internal/types"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// schemaBuilder converts protobuf message descriptors to generator schemas.
+// Object properties are keyed by the proto field name,
+// so contexts match fields the same way they do for OpenAPI properties.
+type schemaBuilder struct {
+	generate *config.GenerationConfig
+	visiting map[protoreflect.FullName]bool
+}
+
+func newSchemaBuilder(generate *config.GenerationConfig) *schemaBuilder {
+	return &schemaBuilder{
+		generate: generate,
+		visiting: make(map[protoreflect.FullName]bool),
+	}
+}
+
+// messageSchema builds the schema for a message.
+// Messages already being expanded higher up are marked recursive, so the generator skips them.
+// Only the first field of every oneof is generated.
+// No field is required: proto3 fields are optional on the wire,
+// and a required recursive field would drop the whole message.
+func (b *schemaBuilder) messageSchema(md protoreflect.MessageDescriptor) *schema.Schema {
+	if s := wellKnownSchema(md); s != nil {
+		return s
+	}
+
+	if b.visiting[md.FullName()] {
+		return &schema.Schema{Type: types.TypeObject, Recursive: true}
+	}
+	b.visiting[md.FullName()] = true
+	defer delete(b.visiting, md.FullName())
+
+	fields := md.Fields()
+	props := make(map[string]*schema.Schema, fields.Len())
+
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() && oneof.Fields().Get(0) != fd {
+			continue
+		}
+		if isSkipped(fd) {
+			continue
+		}
+
+		s := b.fieldSchema(fd)
+		if s == nil {
+			continue
+		}
+		props[string(fd.Name())] = s
+	}
+
+	return &schema.Schema{
+		Type:       types.TypeObject,
+		Properties: props,
+	}
+}
+
+// fieldSchema builds the schema for a field, wrapping repeated fields and maps.
+// Returns nil for repeated fields and maps when the configured list size is 0.
+func (b *schemaBuilder) fieldSchema(fd protoreflect.FieldDescriptor) *schema.Schema {
+	if fd.IsMap() || fd.IsList() {
+		size := int64(b.generate.GetListSize())
+		if size == 0 {
+			return nil
+		}
+
+		if fd.IsMap() {
+			return &schema.Schema{
+				Type:                 types.TypeObject,
+				AdditionalProperties: b.kindSchema(fd.MapValue()),
+				MaxProperties:        &size,
+			}
+		}
+
+		return &schema.Schema{
+			Type:     types.TypeArray,
+			Items:    b.kindSchema(fd),
+			MinItems: &size,
+			MaxItems: &size,
+		}
+	}
+
+	return b.kindSchema(fd)
+}
+
+// kindSchema builds the schema for a single value of a field.
+func (b *schemaBuilder) kindSchema(fd protoreflect.FieldDescriptor) *schema.Schema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return &schema.Schema{Type: types.TypeBoolean}
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &schema.Schema{Type: types.TypeInteger, Format: "int32"}
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		minimum, maximum := 0.0, float64(math.MaxInt32)
+		return &schema.Schema{Type: types.TypeInteger, Format: "int32", Minimum: &minimum, Maximum: &maximum}
+
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &schema.Schema{Type: types.TypeInteger, Format: "int64"}
+
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		minimum := 0.0
+		return &schema.Schema{Type: types.TypeInteger, Format: "int64", Minimum: &minimum}
+
+	case protoreflect.FloatKind:
+		return &schema.Schema{Type: types.TypeNumber, Format: "float"}
+
+	case protoreflect.DoubleKind:
+		return &schema.Schema{Type: types.TypeNumber, Format: "double"}
+
+	case protoreflect.StringKind:
+		return &schema.Schema{Type: types.TypeString}
+
+	case protoreflect.BytesKind:
+		return &schema.Schema{Type: types.TypeString, Format: "byte"}
+
+	case protoreflect.EnumKind:
+		return enumSchema(fd.Enum())
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return b.messageSchema(fd.Message())
+	}
+
+	return &schema.Schema{Type: types.TypeString}
+}
+
+// enumSchema lists the enum value names.
+// The zero value is left out when there are others, as by convention it means "unspecified".
+func enumSchema(ed protoreflect.EnumDescriptor) *schema.Schema {
+	values := ed.Values()
+	enum := make([]any, 0, values.Len())
+	for i := 0; i < values.Len(); i++ {
+		v := values.Get(i)
+		if v.Number() == 0 && values.Len() > 1 {
+			continue
+		}
+		enum = append(enum, string(v.Name()))
+	}
+	return &schema.Schema{Type: types.TypeString, Enum: enum}
+}
+
+// isSkipped reports whether a field holds a type that cannot be generated meaningfully:
+// Any needs a resolvable type URL and FieldMask paths must name real fields.
+func isSkipped(fd protoreflect.FieldDescriptor) bool {
+	if fd.IsMap() {
+		fd = fd.MapValue()
+	}
+	if md := fd.Message(); md != nil {
+		switch md.FullName() {
+		case "google.protobuf.Any", "google.protobuf.FieldMask":
+			return true
+		}
+	}
+	return false
+}
+
+// wellKnownSchema returns the schema for well-known types with a special JSON mapping,
+// or nil for regular messages.
+func wellKnownSchema(md protoreflect.MessageDescriptor) *schema.Schema {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return &schema.Schema{Type: types.TypeString, Format: "date-time"}
+
+	case "google.protobuf.Duration":
+		minimum, maximum := 0.0, 3600.0
+		return &schema.Schema{Type: types.TypeInteger, Minimum: &minimum, Maximum: &maximum}
+
+	case "google.protobuf.Struct", "google.protobuf.Empty":
+		return &schema.Schema{Type: types.TypeObject}
+
+	case "google.protobuf.Value":
+		return &schema.Schema{Type: types.TypeString}
+
+	case "google.protobuf.ListValue":
+		return &schema.Schema{Type: types.TypeArray, Items: &schema.Schema{Type: types.TypeString}}
+
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		if fd := md.Fields().ByName("value"); fd != nil {
+			return (&schemaBuilder{}).kindSchema(fd)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package grpc
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/mockzilla/connexions/v2/internal/types"
+	assert2 "github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func petDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	return methodDescriptor(t, newTestService(t), "/pets.v1.PetService/GetPet").Output()
+}
+
+func TestSchemaBuilder_messageSchema(t *testing.T) {
+	assert := assert2.New(t)
+	pet := petDescriptor(t)
+
+	t.Run("maps field kinds", func(t *testing.T) {
+		s := newSchemaBuilder(listSize(2)).messageSchema(pet)
+
+		assert.Equal(types.TypeObject, s.Type)
+		assert.Equal("int64", s.Properties["id"].Format)
+		assert.Equal(types.TypeString, s.Properties["name"].Type)
+		assert.Equal([]any{"STATUS_AVAILABLE", "STATUS_SOLD"}, s.Properties["status"].Enum)
+		assert.Equal(types.TypeArray, s.Properties["tags"].Type)
+		assert.Equal(int64(2), *s.Properties["tags"].MinItems)
+		assert.NotNil(s.Properties["scores"].AdditionalProperties)
+		assert.Equal("date-time", s.Properties["created_at"].Format)
+		assert.Equal("byte", s.Properties["photo"].Format)
+		assert.Equal(0.0, *s.Properties["age"].Minimum)
+		assert.Equal(types.TypeBoolean, s.Properties["vaccinated"].Type)
+	})
+
+	t.Run("recursive messages are truncated", func(t *testing.T) {
+		s := newSchemaBuilder(nil).messageSchema(pet)
+		assert.True(s.Properties["parent"].Recursive)
+	})
+
+	t.Run("only the first oneof field", func(t *testing.T) {
+		s := newSchemaBuilder(nil).messageSchema(pet)
+		assert.Contains(s.Properties, "owner_email")
+		assert.NotContains(s.Properties, "owner_id")
+	})
+
+	t.Run("list size 0 leaves out repeated fields", func(t *testing.T) {
+		s := newSchemaBuilder(listSize(0)).messageSchema(pet)
+		assert.NotContains(s.Properties, "tags")
+		assert.NotContains(s.Properties, "scores")
+	})
+}
+
+func TestFillMessage(t *testing.T) {
+	assert := assert2.New(t)
+	pet := petDescriptor(t)
+	fields := pet.Fields()
+
+	t.Run("coerces values", func(t *testing.T) {
+		msg := dynamicpb.NewMessage(pet)
+		fillMessage(msg, map[string]any{
+			"id":         "12",
+			"name":       123,
+			"status":     "STATUS_SOLD",
+			"tags":       []any{"a", "b"},
+			"scores":     map[string]any{"rock": json.Number("5")},
+			"created_at": "2024-01-02T03:04:05Z",
+			"photo":      "aGVsbG8=",
+			"age":        json.Number("-3"),
+			"weight":     json.Number("1.5"),
+			"vaccinated": "true",
+		})
+
+		assert.Equal(int64(12), msg.Get(fields.ByName("id")).Int())
+		assert.Equal("123", msg.Get(fields.ByName("name")).String())
+		assert.Equal(protoreflect.EnumNumber(2), msg.Get(fields.ByName("status")).Enum())
+		assert.Equal(2, msg.Get(fields.ByName("tags")).List().Len())
+		assert.Equal(int64(5), msg.Get(fields.ByName("scores")).Map().Get(protoreflect.ValueOfString("rock").MapKey()).Int())
+		assert.Equal([]byte("hello"), msg.Get(fields.ByName("photo")).Bytes())
+		assert.Equal(uint64(0), msg.Get(fields.ByName("age")).Uint(), "clamped to the unsigned range")
+		assert.Equal(1.5, msg.Get(fields.ByName("weight")).Float())
+		assert.True(msg.Get(fields.ByName("vaccinated")).Bool())
+
+		ts := msg.Get(fields.ByName("created_at")).Message()
+		assert.Equal(int64(1704164645), ts.Get(ts.Descriptor().Fields().ByName("seconds")).Int())
+	})
+
+	t.Run("skips values that do not convert", func(t *testing.T) {
+		msg := dynamicpb.NewMessage(pet)
+		fillMessage(msg, map[string]any{
+			"id":   []any{1},
+			"tags": "not-a-list",
+		})
+
+		assert.False(msg.Has(fields.ByName("id")))
+		assert.False(msg.Has(fields.ByName("tags")))
+	})
+
+	t.Run("clamps int32", func(t *testing.T) {
+		v, ok := scalarValue(fields.ByName("owner_id"), json.Number("99999999999"))
+		assert.True(ok)
+		assert.Equal(int64(math.MaxInt32), v.Int())
+	})
+}
@@ -0,0 +1,336 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Server serves registered Services over gRPC.
+// Calls are dispatched by full method name, so services can be registered
+// and replaced while the server is running.
+type Server struct {
+	server *grpclib.Server
+
+	mu       sync.RWMutex
+	services map[string]*registration
+}
+
+// registration is a Service registered under a connexions service name.
+type registration struct {
+	service *Service
+	config  *config.ServiceConfig
+	db      db.DB
+}
+
+// NewServer creates a Server with server reflection enabled.
+// Additional gRPC server options, e.g. credentials, may be passed.
+func NewServer(opts ...grpclib.ServerOption) *Server {
+	s := &Server{
+		services: make(map[string]*registration),
+	}
+
+	opts = append(opts, grpclib.UnknownServiceHandler(s.handleStream))
+	s.server = grpclib.NewServer(opts...)
+
+	reflectionServer := reflection.NewServerV1(reflection.ServerOptions{
+		Services:           s,
+		DescriptorResolver: serverResolver{s},
+	})
+	reflectionv1.RegisterServerReflectionServer(s.server, reflectionServer)
+	reflectionv1alpha.RegisterServerReflectionServer(s.server, reflection.NewServer(reflection.ServerOptions{
+		Services:           s,
+		DescriptorResolver: serverResolver{s},
+	}))
+
+	return s
+}
+
+// Register adds or replaces the Service for cfg.Name.
+// The service config provides simulated errors, latencies and history settings;
+// calls are recorded in the history of serviceDB when it is set.
+func (s *Server) Register(svc *Service, cfg *config.ServiceConfig, serviceDB db.DB) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.services[cfg.Name] = &registration{
+		service: svc,
+		config:  cfg,
+		db:      serviceDB,
+	}
+}
+
+// Serve accepts gRPC connections on the listener until Stop or GracefulStop is called.
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// GracefulStop stops accepting connections and waits for pending calls to finish.
+func (s *Server) GracefulStop() {
+	s.server.GracefulStop()
+}
+
+// Stop closes all connections immediately.
+func (s *Server) Stop() {
+	s.server.Stop()
+}
+
+// GetServiceInfo lists the served gRPC services for server reflection.
+func (s *Server) GetServiceInfo() map[string]grpclib.ServiceInfo {
+	res := s.server.GetServiceInfo()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, reg := range s.services {
+		for _, name := range reg.service.Services() {
+			res[name] = grpclib.ServiceInfo{}
+		}
+	}
+	return res
+}
+
+// lookup finds the registration serving a full method name.
+// Services are searched in name order, so the outcome is stable when several define the same method.
+func (s *Server) lookup(fullMethod string) (*registration, protoreflect.MethodDescriptor) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.services))
+	for name := range s.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		reg := s.services[name]
+		if md, ok := reg.service.Method(fullMethod); ok {
+			return reg, md
+		}
+	}
+	return nil, nil
+}
+
+// handleStream serves every call to a non-reflection method.
+func (s *Server) handleStream(_ any, stream grpclib.ServerStream) error {
+	start := time.Now()
+
+	fullMethod, _ := grpclib.MethodFromServerStream(stream)
+	reg, md := s.lookup(fullMethod)
+	if reg == nil {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+	}
+	if md.IsStreamingClient() {
+		return status.Errorf(codes.Unimplemented, "client streaming is not supported: %s", fullMethod)
+	}
+
+	req := dynamicpb.NewMessage(md.Input())
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	responses, err := reg.respond(ctx, md)
+	if err == nil {
+		for _, res := range responses {
+			if err = stream.SendMsg(res); err != nil {
+				break
+			}
+		}
+	}
+
+	reg.record(ctx, md, fullMethod, req, responses, err, time.Since(start))
+	return err
+}
+
+// respond applies simulated latency and errors, then generates the response messages.
+func (r *registration) respond(ctx context.Context, md protoreflect.MethodDescriptor) ([]*dynamicpb.Message, error) {
+	if r.config != nil {
+		if latency := r.config.GetLatency(); latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-ctx.Done():
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+		}
+
+		if statusCode := r.config.GetError(); statusCode != 0 {
+			code := codeFromHTTP(statusCode)
+			return nil, status.Errorf(code, "simulated error: %s", code.String())
+		}
+	}
+
+	replacements := contextFromMetadata(ctx)
+	if md.IsStreamingServer() {
+		return r.service.newStream(md, replacements), nil
+	}
+	return []*dynamicpb.Message{r.service.newMessage(md.Output(), replacements)}, nil
+}
+
+// record writes the call to the service history.
+// Bodies are stored in the protobuf JSON mapping; server streams as a JSON array of messages.
+// The status code is the HTTP equivalent of the gRPC code, which is kept in the Grpc-Status header.
+func (r *registration) record(
+	ctx context.Context,
+	md protoreflect.MethodDescriptor,
+	fullMethod string,
+	req *dynamicpb.Message,
+	responses []*dynamicpb.Message,
+	callErr error,
+	duration time.Duration,
+) {
+	if r.db == nil || (r.config != nil && !r.config.HistoryEnabled()) {
+		return
+	}
+
+	reqBody, _ := protojson.Marshal(req)
+	histReq := &db.HistoryRequest{
+		Method:    http.MethodPost,
+		URL:       fullMethod,
+		Body:      reqBody,
+		RequestID: uuid.NewString(),
+	}
+	if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+		// Metadata keys are lowercase, canonicalize them like HTTP headers.
+		headers := make(http.Header, len(incoming))
+		for key, values := range incoming {
+			for _, v := range values {
+				headers.Add(key, v)
+			}
+		}
+		histReq.Headers = db.FlattenHeaders(headers)
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		histReq.RemoteAddr = p.Addr.String()
+	}
+
+	st := status.Convert(callErr)
+	histResp := &db.HistoryResponse{
+		StatusCode:  httpFromCode(st.Code()),
+		ContentType: "application/grpc",
+		Headers: []string{
+			"Grpc-Status: " + strconv.Itoa(int(st.Code())),
+			"Grpc-Message: " + st.Message(),
+		},
+		Duration: duration,
+	}
+
+	if callErr == nil {
+		if md.IsStreamingServer() {
+			histResp.Body = marshalMessages(responses)
+		} else if len(responses) == 1 {
+			histResp.Body, _ = protojson.Marshal(responses[0])
+		}
+	}
+
+	if r.config != nil && r.config.History != nil && len(r.config.History.MaskHeaders) > 0 {
+		db.MaskHeaderValues(histReq.Headers, r.config.History.MaskHeaders)
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		r.db.History().Set(ctx, fullMethod, histReq, histResp)
+	}()
+}
+
+// contextFromMetadata decodes replacement context from the x-cxs-context metadata,
+// the gRPC counterpart of the X-Cxs-Context header.
+func contextFromMetadata(ctx context.Context) map[string]any {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	values := md.Get(api.ContextHeaderName)
+	if len(values) == 0 {
+		return nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(values[0])
+	if err != nil {
+		return nil
+	}
+	var res map[string]any
+	if err := json.Unmarshal(decoded, &res); err != nil {
+		slog.Debug("Invalid gRPC context metadata", "error", err)
+		return nil
+	}
+	return res
+}
+
+func marshalMessages(messages []*dynamicpb.Message) []byte {
+	items := make([]json.RawMessage, 0, len(messages))
+	for _, msg := range messages {
+		data, err := protojson.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		items = append(items, data)
+	}
+	res, _ := json.Marshal(items)
+	return res
+}
+
+// serverResolver resolves descriptors for server reflection
+// from the registered services, then from the descriptors linked into the binary.
+type serverResolver struct {
+	server *Server
+}
+
+func (r serverResolver) resolvers() resolvers {
+	r.server.mu.RLock()
+	defer r.server.mu.RUnlock()
+
+	names := make([]string, 0, len(r.server.services))
+	for name := range r.server.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := make(resolvers, 0, len(names)+1)
+	for _, name := range names {
+		res = append(res, r.server.services[name].service.files)
+	}
+	return append(res, protoregistry.GlobalFiles)
+}
+
+func (r serverResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	fd, err := r.resolvers().FindFileByPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("file %s: %w", path, err)
+	}
+	return fd, nil
+}
+
+func (r serverResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	d, err := r.resolvers().FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("symbol %s: %w", name, err)
+	}
+	return d, nil
+}
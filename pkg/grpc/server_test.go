@@ -0,0 +1,277 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// startTestServer serves the server over an in-memory listener and returns a client connection.
+func startTestServer(t *testing.T, srv *Server) *grpclib.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpclib.NewClient("passthrough:///bufnet",
+		grpclib.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpclib.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func newTestServiceConfig(name string) *config.ServiceConfig {
+	cfg := config.NewServiceConfig()
+	cfg.Name = name
+	return cfg
+}
+
+func methodDescriptor(t *testing.T, svc *Service, fullMethod string) protoreflect.MethodDescriptor {
+	t.Helper()
+	md, ok := svc.Method(fullMethod)
+	require.True(t, ok)
+	return md
+}
+
+func TestServer_Unary(t *testing.T) {
+	assert := assert2.New(t)
+
+	svc := newTestService(t, WithGenerationConfig(listSize(2)))
+	srv := NewServer()
+	srv.Register(svc, newTestServiceConfig("pets"), nil)
+	conn := startTestServer(t, srv)
+
+	md := methodDescriptor(t, svc, "/pets.v1.PetService/GetPet")
+
+	t.Run("generates response", func(t *testing.T) {
+		req := dynamicpb.NewMessage(md.Input())
+		res := dynamicpb.NewMessage(md.Output())
+		err := conn.Invoke(context.Background(), "/pets.v1.PetService/GetPet", req, res)
+		require.NoError(t, err)
+
+		fields := md.Output().Fields()
+		assert.NotEmpty(res.Get(fields.ByName("name")).String())
+		assert.Equal(2, res.Get(fields.ByName("tags")).List().Len())
+		assert.True(res.Has(fields.ByName("created_at")))
+	})
+
+	t.Run("context from metadata", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(),
+			api.ContextHeaderName, encodeContext(t, map[string]any{"name": "Rex"}))
+
+		res := dynamicpb.NewMessage(md.Output())
+		err := conn.Invoke(ctx, "/pets.v1.PetService/GetPet", dynamicpb.NewMessage(md.Input()), res)
+		require.NoError(t, err)
+		assert.Equal("Rex", res.Get(md.Output().Fields().ByName("name")).String())
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		res := dynamicpb.NewMessage(md.Output())
+		err := conn.Invoke(context.Background(), "/pets.v1.PetService/Missing", dynamicpb.NewMessage(md.Input()), res)
+		assert.Equal(codes.Unimplemented, status.Code(err))
+	})
+}
+
+func TestServer_ServerStreaming(t *testing.T) {
+	assert := assert2.New(t)
+
+	svc := newTestService(t, WithGenerationConfig(listSize(3)))
+	srv := NewServer()
+	srv.Register(svc, newTestServiceConfig("pets"), nil)
+	conn := startTestServer(t, srv)
+
+	md := methodDescriptor(t, svc, "/pets.v1.PetService/ListPets")
+	stream, err := conn.NewStream(context.Background(), &grpclib.StreamDesc{ServerStreams: true}, "/pets.v1.PetService/ListPets")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(dynamicpb.NewMessage(md.Input())))
+	require.NoError(t, stream.CloseSend())
+
+	count := 0
+	for {
+		res := dynamicpb.NewMessage(md.Output())
+		if err := stream.RecvMsg(res); err != nil {
+			assert.ErrorIs(err, io.EOF)
+			break
+		}
+		count++
+	}
+	assert.Equal(3, count)
+}
+
+func TestServer_ClientStreamingUnsupported(t *testing.T) {
+	svc := newTestService(t)
+	srv := NewServer()
+	srv.Register(svc, newTestServiceConfig("pets"), nil)
+	conn := startTestServer(t, srv)
+
+	md := methodDescriptor(t, svc, "/pets.v1.PetService/Upload")
+	stream, err := conn.NewStream(context.Background(), &grpclib.StreamDesc{ClientStreams: true}, "/pets.v1.PetService/Upload")
+	require.NoError(t, err)
+	_ = stream.SendMsg(dynamicpb.NewMessage(md.Input()))
+	_ = stream.CloseSend()
+
+	err = stream.RecvMsg(dynamicpb.NewMessage(md.Output()))
+	assert2.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestServer_SimulatedErrors(t *testing.T) {
+	assert := assert2.New(t)
+
+	t.Run("HTTP code is mapped", func(t *testing.T) {
+		cfg := newTestServiceConfig("pets")
+		cfg.Errors = map[string]int{"p100": http.StatusServiceUnavailable}
+		cfg.WithDefaults()
+
+		svc := newTestService(t)
+		srv := NewServer()
+		srv.Register(svc, cfg, nil)
+		conn := startTestServer(t, srv)
+
+		md := methodDescriptor(t, svc, "/pets.v1.PetService/GetPet")
+		err := conn.Invoke(context.Background(), "/pets.v1.PetService/GetPet",
+			dynamicpb.NewMessage(md.Input()), dynamicpb.NewMessage(md.Output()))
+		assert.Equal(codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("gRPC code is used as is", func(t *testing.T) {
+		cfg := newTestServiceConfig("pets")
+		cfg.Errors = map[string]int{"p100": int(codes.NotFound)}
+		cfg.WithDefaults()
+
+		svc := newTestService(t)
+		srv := NewServer()
+		srv.Register(svc, cfg, nil)
+		conn := startTestServer(t, srv)
+
+		md := methodDescriptor(t, svc, "/pets.v1.PetService/GetPet")
+		err := conn.Invoke(context.Background(), "/pets.v1.PetService/GetPet",
+			dynamicpb.NewMessage(md.Input()), dynamicpb.NewMessage(md.Output()))
+		assert.Equal(codes.NotFound, status.Code(err))
+	})
+}
+
+func TestServer_History(t *testing.T) {
+	assert := assert2.New(t)
+
+	cfg := newTestServiceConfig("pets")
+	cfg.History.MaskHeaders = []string{"Authorization"}
+	serviceDB := db.NewStorage(nil).NewDB(cfg.Name, 100*time.Second)
+
+	svc := newTestService(t, WithGenerationConfig(listSize(2)))
+	srv := NewServer()
+	srv.Register(svc, cfg, serviceDB)
+	conn := startTestServer(t, srv)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret-token")
+	md := methodDescriptor(t, svc, "/pets.v1.PetService/GetPet")
+	req := dynamicpb.NewMessage(md.Input())
+	req.Set(md.Input().Fields().ByName("id"), protoreflect.ValueOfInt64(7))
+	require.NoError(t, conn.Invoke(ctx, "/pets.v1.PetService/GetPet", req, dynamicpb.NewMessage(md.Output())))
+
+	var entries []*db.HistoryEntry
+	require.Eventually(t, func() bool {
+		entries = serviceDB.History().Data(context.Background())
+		return len(entries) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	entry := entries[0]
+	assert.Equal("/pets.v1.PetService/GetPet", entry.Resource)
+	assert.Equal(http.MethodPost, entry.Request.Method)
+	assert.JSONEq(`{"id": "7"}`, string(entry.Request.Body))
+	assert.NotEmpty(entry.Request.RemoteAddr)
+	assert.NotContains(entry.Request.Headers, "Authorization: Bearer secret-token")
+
+	assert.Equal(http.StatusOK, entry.Response.StatusCode)
+	assert.Equal("application/grpc", entry.Response.ContentType)
+	assert.Contains(entry.Response.Headers, "Grpc-Status: 0")
+
+	var pet map[string]any
+	require.NoError(t, json.Unmarshal(entry.Response.Body, &pet))
+	assert.NotEmpty(pet["name"])
+}
+
+func TestServer_Reflection(t *testing.T) {
+	assert := assert2.New(t)
+
+	srv := NewServer()
+	srv.Register(newTestService(t), newTestServiceConfig("pets"), nil)
+	conn := startTestServer(t, srv)
+
+	stream, err := reflectionv1.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+
+	t.Run("lists services", func(t *testing.T) {
+		require.NoError(t, stream.Send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{},
+		}))
+		res, err := stream.Recv()
+		require.NoError(t, err)
+
+		var names []string
+		for _, s := range res.GetListServicesResponse().GetService() {
+			names = append(names, s.GetName())
+		}
+		assert.Contains(names, "pets.v1.PetService")
+		assert.Contains(names, "grpc.reflection.v1.ServerReflection")
+	})
+
+	t.Run("resolves file by symbol", func(t *testing.T) {
+		require.NoError(t, stream.Send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: "pets.v1.PetService",
+			},
+		}))
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		assert.NotEmpty(res.GetFileDescriptorResponse().GetFileDescriptorProto())
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		require.NoError(t, stream.Send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: "pets.v1.Missing",
+			},
+		}))
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(int32(codes.NotFound), res.GetErrorResponse().GetErrorCode())
+	})
+}
+
+func TestServer_RegisterReplaces(t *testing.T) {
+	assert := assert2.New(t)
+
+	srv := NewServer()
+	first := newTestService(t)
+	second := newTestService(t)
+	srv.Register(first, newTestServiceConfig("pets"), nil)
+	srv.Register(second, newTestServiceConfig("pets"), nil)
+
+	reg, _ := srv.lookup("/pets.v1.PetService/GetPet")
+	require.NotNil(t, reg)
+	assert.Same(second, reg.service)
+}
@@ -0,0 +1,350 @@
+// Package grpc serves mock responses for gRPC services described by protobuf descriptors.
+//
+// Services are loaded from a serialized FileDescriptorSet, as produced by
+// `protoc --include_imports --descriptor_set_out` or `buf build -o`.
+// Response messages are generated with the generator's value replacer,
+// so service contexts, fake functions and formats apply just as they do for OpenAPI services.
+//
+// A Server hosts the services on a dedicated port and supports server reflection,
+// so tools like grpcurl and Postman can discover them without local proto files.
+// Every method is also available as JSON over HTTP through the Service's api.Handler routes.
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/generator"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// Register the well-known types, so descriptor sets built without --include_imports still resolve.
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Service implements api.Handler for the gRPC services of a descriptor set.
+type Service struct {
+	files    *protoregistry.Files
+	services []protoreflect.ServiceDescriptor
+	methods  map[string]protoreflect.MethodDescriptor
+	routes   api.RouteDescriptions
+	gen      generator.Generate
+	generate *config.GenerationConfig
+}
+
+type serviceConfig struct {
+	serviceContext []byte
+	generate       *config.GenerationConfig
+}
+
+// Option configures a Service.
+type Option func(*serviceConfig)
+
+// WithServiceContext sets a service-specific context YAML for value replacements.
+func WithServiceContext(contextYAML []byte) Option {
+	return func(c *serviceConfig) {
+		c.serviceContext = contextYAML
+	}
+}
+
+// WithGenerationConfig sets the generation settings, e.g. the number of
+// repeated field items and server stream messages.
+func WithGenerationConfig(cfg *config.GenerationConfig) Option {
+	return func(c *serviceConfig) {
+		c.generate = cfg
+	}
+}
+
+// NewService creates a Service from a serialized FileDescriptorSet.
+// Default replacement contexts (common, fake, words) are loaded automatically.
+func NewService(descriptorSet []byte, opts ...Option) (*Service, error) {
+	sc := &serviceConfig{}
+	for _, opt := range opts {
+		opt(sc)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(descriptorSet, set); err != nil {
+		return nil, fmt.Errorf("parsing descriptor set: %w", err)
+	}
+
+	files, err := newFiles(set)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		files:    files,
+		methods:  make(map[string]protoreflect.MethodDescriptor),
+		generate: sc.generate,
+	}
+
+	// Only services of the files given explicitly are served, not those of their imports.
+	for _, fdp := range set.GetFile() {
+		fd, err := files.FindFileByPath(fdp.GetName())
+		if err != nil {
+			continue
+		}
+		svcs := fd.Services()
+		for i := 0; i < svcs.Len(); i++ {
+			s.addService(svcs.Get(i))
+		}
+	}
+	if len(s.services) == 0 {
+		return nil, fmt.Errorf("descriptor set contains no services")
+	}
+	s.routes.Sort()
+
+	defaultContexts := generator.LoadDefaultContexts()
+	orderedCtx := generator.LoadServiceContext(sc.serviceContext, defaultContexts)
+	gen, err := generator.NewGenerator(orderedCtx, defaultContexts)
+	if err != nil {
+		return nil, fmt.Errorf("creating generator: %w", err)
+	}
+	s.gen = gen
+
+	return s, nil
+}
+
+// newFiles builds a registry from a descriptor set in dependency order.
+// Imports missing from the set are resolved from the well-known types linked into the binary.
+func newFiles(set *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	pending := make(map[string]*descriptorpb.FileDescriptorProto, len(set.GetFile()))
+	for _, fdp := range set.GetFile() {
+		pending[fdp.GetName()] = fdp
+	}
+
+	files := new(protoregistry.Files)
+	resolver := resolvers{files, protoregistry.GlobalFiles}
+
+	var register func(path string) error
+	register = func(path string) error {
+		if _, err := resolver.FindFileByPath(path); err == nil {
+			return nil
+		}
+		fdp, ok := pending[path]
+		if !ok {
+			return fmt.Errorf("missing import %q, build the descriptor set with --include_imports", path)
+		}
+		delete(pending, path)
+
+		for _, dep := range fdp.GetDependency() {
+			if err := register(dep); err != nil {
+				return err
+			}
+		}
+
+		fd, err := protodesc.NewFile(fdp, resolver)
+		if err != nil {
+			return fmt.Errorf("building descriptor for %s: %w", path, err)
+		}
+		return files.RegisterFile(fd)
+	}
+
+	for _, fdp := range set.GetFile() {
+		if err := register(fdp.GetName()); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+func (s *Service) addService(sd protoreflect.ServiceDescriptor) {
+	s.services = append(s.services, sd)
+
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		fullMethod := "/" + string(sd.FullName()) + "/" + string(md.Name())
+		s.methods[fullMethod] = md
+
+		contentType := "application/json"
+		if md.IsStreamingServer() {
+			contentType = "application/x-ndjson"
+		}
+		s.routes = append(s.routes, &api.RouteDescription{
+			ID:          string(md.FullName()),
+			Method:      http.MethodPost,
+			Path:        fullMethod,
+			ContentType: contentType,
+		})
+	}
+}
+
+// Services returns the fully-qualified names of the served gRPC services.
+func (s *Service) Services() []string {
+	res := make([]string, 0, len(s.services))
+	for _, sd := range s.services {
+		res = append(res, string(sd.FullName()))
+	}
+	return res
+}
+
+// Method returns the method for a full method name, e.g. "/pets.PetService/GetPet".
+func (s *Service) Method(fullMethod string) (protoreflect.MethodDescriptor, bool) {
+	md, ok := s.methods[fullMethod]
+	return md, ok
+}
+
+// Routes returns a POST route per gRPC method, at the gRPC full method path.
+func (s *Service) Routes() api.RouteDescriptions {
+	return s.routes
+}
+
+// RegisterRoutes registers the JSON endpoints of all methods.
+func (s *Service) RegisterRoutes(router chi.Router) {
+	for fullMethod, md := range s.methods {
+		router.Post(fullMethod, func(w http.ResponseWriter, r *http.Request) {
+			s.serveMethod(w, r, md)
+		})
+	}
+}
+
+// Generate handles UI generate requests by returning a generated request message
+// for the method at the requested path.
+func (s *Service) Generate(w http.ResponseWriter, r *http.Request) {
+	var req api.GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	md, ok := s.methods[req.Path]
+	if !ok {
+		http.Error(w, fmt.Sprintf("no matching operation: %s %s", req.Method, req.Path), http.StatusNotFound)
+		return
+	}
+
+	body, err := protojson.Marshal(s.newMessage(md.Input(), req.Context))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.NewJSONResponse(w).Send(map[string]any{
+		"path":        req.Path,
+		"method":      http.MethodPost,
+		"contentType": "application/json",
+		"body":        json.RawMessage(body),
+	})
+}
+
+// ServeHTTP serves the JSON endpoints from a catch-all route,
+// with chi's "*" param holding the full method path, e.g. "pets.PetService/GetPet".
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	md, ok := s.methods["/"+chi.URLParam(r, "*")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("no matching operation: %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		return
+	}
+	s.serveMethod(w, r, md)
+}
+
+// serveMethod serves a method as JSON over HTTP, using the protobuf JSON mapping.
+// The request body must match the input message, if given.
+// Server streaming methods respond with newline-delimited JSON messages.
+func (s *Service) serveMethod(w http.ResponseWriter, r *http.Request, md protoreflect.MethodDescriptor) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := protojson.Unmarshal(body, dynamicpb.NewMessage(md.Input())); err != nil {
+			slog.Debug("Invalid gRPC JSON request", "method", md.FullName(), "error", err)
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := api.ExtractContextFromRequest(r)
+	if !md.IsStreamingServer() {
+		res, _ := protojson.Marshal(s.newMessage(md.Output(), ctx))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(res)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for _, msg := range s.newStream(md, ctx) {
+		res, _ := protojson.Marshal(msg)
+		_, _ = w.Write(append(res, '\n'))
+	}
+}
+
+// newMessage generates a message of the given type.
+// ctx is an optional replacement context for controlling generated values.
+func (s *Service) newMessage(md protoreflect.MessageDescriptor, ctx map[string]any) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(md)
+
+	res := s.gen.Response(&schema.ResponseSchema{
+		ContentType: "application/json",
+		Body:        newSchemaBuilder(s.generate).messageSchema(md),
+	}, ctx)
+	if res.IsError || len(res.Body) == 0 {
+		return msg
+	}
+
+	var value any
+	dec := json.NewDecoder(bytes.NewReader(res.Body))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return msg
+	}
+
+	fillMessage(msg, value)
+	return msg
+}
+
+// newStream generates the messages of a server stream.
+// The number of messages follows the configured list size.
+func (s *Service) newStream(md protoreflect.MethodDescriptor, ctx map[string]any) []*dynamicpb.Message {
+	size := s.generate.GetListSize()
+	res := make([]*dynamicpb.Message, 0, size)
+	for range size {
+		res = append(res, s.newMessage(md.Output(), ctx))
+	}
+	return res
+}
+
+// resolvers looks up descriptors in each resolver in turn.
+type resolvers []interface {
+	FindFileByPath(string) (protoreflect.FileDescriptor, error)
+	FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+}
+
+func (rs resolvers) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	for _, r := range rs {
+		if fd, err := r.FindFileByPath(path); err == nil {
+			return fd, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (rs resolvers) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	for _, r := range rs {
+		if d, err := r.FindDescriptorByName(name); err == nil {
+			return d, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
@@ -0,0 +1,231 @@
+package grpc
+
+import (
+	"bufio"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+//go:embed testdata/**
+var testDataFS embed.FS
+
+func loadTestDescriptorSet(t *testing.T, fileName string) []byte {
+	t.Helper()
+	contents, err := testDataFS.ReadFile(filepath.Join("testdata", fileName))
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	return contents
+}
+
+func newTestService(t *testing.T, opts ...Option) *Service {
+	t.Helper()
+	svc, err := NewService(loadTestDescriptorSet(t, "pets.protoset"), opts...)
+	require.NoError(t, err)
+	return svc
+}
+
+func listSize(n int) *config.GenerationConfig {
+	return &config.GenerationConfig{ListSize: &config.ListSize{Min: n, Max: n}}
+}
+
+func encodeContext(t *testing.T, ctx map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(ctx)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestNewService(t *testing.T) {
+	assert := assert2.New(t)
+
+	t.Run("loads services and methods", func(t *testing.T) {
+		svc := newTestService(t)
+
+		assert.Equal([]string{"pets.v1.PetService"}, svc.Services())
+
+		md, ok := svc.Method("/pets.v1.PetService/GetPet")
+		assert.True(ok)
+		assert.Equal("pets.v1.Pet", string(md.Output().FullName()))
+
+		_, ok = svc.Method("/pets.v1.PetService/Missing")
+		assert.False(ok)
+	})
+
+	t.Run("invalid descriptor set", func(t *testing.T) {
+		_, err := NewService([]byte("not a descriptor set"))
+		assert.Error(err)
+		assert.Contains(err.Error(), "parsing descriptor set")
+	})
+
+	t.Run("no services", func(t *testing.T) {
+		data, _ := proto.Marshal(&descriptorpb.FileDescriptorSet{
+			File: []*descriptorpb.FileDescriptorProto{{
+				Name:    proto.String("empty.proto"),
+				Package: proto.String("empty"),
+				Syntax:  proto.String("proto3"),
+			}},
+		})
+		_, err := NewService(data)
+		assert.Error(err)
+		assert.Contains(err.Error(), "no services")
+	})
+
+	t.Run("missing import", func(t *testing.T) {
+		data, _ := proto.Marshal(&descriptorpb.FileDescriptorSet{
+			File: []*descriptorpb.FileDescriptorProto{{
+				Name:       proto.String("orders.proto"),
+				Package:    proto.String("orders"),
+				Syntax:     proto.String("proto3"),
+				Dependency: []string{"common/money.proto"},
+			}},
+		})
+		_, err := NewService(data)
+		assert.Error(err)
+		assert.Contains(err.Error(), "--include_imports")
+	})
+}
+
+func TestService_Routes(t *testing.T) {
+	assert := assert2.New(t)
+	svc := newTestService(t)
+
+	routes := svc.Routes()
+	assert.Len(routes, 3)
+
+	paths := make(map[string]string)
+	for _, r := range routes {
+		assert.Equal(http.MethodPost, r.Method)
+		paths[r.Path] = r.ContentType
+	}
+	assert.Equal("application/json", paths["/pets.v1.PetService/GetPet"])
+	assert.Equal("application/x-ndjson", paths["/pets.v1.PetService/ListPets"])
+}
+
+func TestService_ServeHTTP(t *testing.T) {
+	assert := assert2.New(t)
+
+	serve := func(h http.Handler, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("unary method", func(t *testing.T) {
+		router := chi.NewRouter()
+		newTestService(t, WithGenerationConfig(listSize(2))).RegisterRoutes(router)
+
+		w := serve(router, "/pets.v1.PetService/GetPet", `{"id": "1"}`, nil)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("application/json", w.Header().Get("Content-Type"))
+
+		var pet map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pet))
+		assert.NotEmpty(pet["name"])
+		assert.Contains([]any{"STATUS_AVAILABLE", "STATUS_SOLD"}, pet["status"])
+		assert.Len(pet["tags"], 2)
+		assert.NotEmpty(pet["createdAt"])
+		assert.NotContains(pet, "ownerId", "only the first oneof field is generated")
+	})
+
+	t.Run("server streaming method", func(t *testing.T) {
+		router := chi.NewRouter()
+		newTestService(t, WithGenerationConfig(listSize(3))).RegisterRoutes(router)
+
+		w := serve(router, "/pets.v1.PetService/ListPets", "", nil)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("application/x-ndjson", w.Header().Get("Content-Type"))
+
+		lines := 0
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var pet map[string]any
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &pet))
+			lines++
+		}
+		assert.Equal(3, lines)
+	})
+
+	t.Run("context replacements", func(t *testing.T) {
+		router := chi.NewRouter()
+		newTestService(t).RegisterRoutes(router)
+
+		w := serve(router, "/pets.v1.PetService/GetPet", "", map[string]string{
+			api.ContextHeaderName: encodeContext(t, map[string]any{"name": "Rex", "id": 42}),
+		})
+		assert.Equal(http.StatusOK, w.Code)
+
+		var pet map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pet))
+		assert.Equal("Rex", pet["name"])
+		assert.Equal("42", pet["id"], "int64 is a string in the JSON mapping")
+	})
+
+	t.Run("invalid request body", func(t *testing.T) {
+		router := chi.NewRouter()
+		newTestService(t).RegisterRoutes(router)
+
+		w := serve(router, "/pets.v1.PetService/GetPet", `{"unknown": 1}`, nil)
+		assert.Equal(http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("catch-all route", func(t *testing.T) {
+		svc := newTestService(t)
+		router := chi.NewRouter()
+		router.HandleFunc("/*", svc.ServeHTTP)
+
+		w := serve(router, "/pets.v1.PetService/GetPet", "", nil)
+		assert.Equal(http.StatusOK, w.Code)
+
+		w = serve(router, "/pets.v1.PetService/Missing", "", nil)
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+}
+
+func TestService_Generate(t *testing.T) {
+	assert := assert2.New(t)
+	svc := newTestService(t)
+
+	t.Run("generates request message", func(t *testing.T) {
+		body, _ := json.Marshal(api.GenerateRequest{Path: "/pets.v1.PetService/GetPet", Method: http.MethodPost})
+		req := httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		svc.Generate(w, req)
+
+		assert.Equal(http.StatusOK, w.Code)
+		var res struct {
+			Path string         `json:"path"`
+			Body map[string]any `json:"body"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal("/pets.v1.PetService/GetPet", res.Path)
+		assert.Contains(res.Body, "id")
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		body, _ := json.Marshal(api.GenerateRequest{Path: "/pets.v1.PetService/Missing", Method: http.MethodPost})
+		req := httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		svc.Generate(w, req)
+
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+}
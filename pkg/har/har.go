@@ -0,0 +1,213 @@
+// Package har reads and writes HTTP Archive (HAR) 1.2 documents,
+// as produced by browser devtools and recording proxies.
+package har
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Version is the HAR format version written by this package.
+const Version = "1.2"
+
+// HAR is the root of an HTTP Archive document.
+type HAR struct {
+	Log *Log `json:"log"`
+}
+
+// Log holds the recorded entries.
+type Log struct {
+	Version string   `json:"version"`
+	Creator *Creator `json:"creator"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Creator names the application that produced the archive.
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is a single request/response exchange.
+type Entry struct {
+	StartedDateTime string    `json:"startedDateTime"`
+	Time            float64   `json:"time"`
+	Request         *Request  `json:"request"`
+	Response        *Response `json:"response"`
+	Cache           struct{}  `json:"cache"`
+	Timings         *Timings  `json:"timings"`
+	Comment         string    `json:"comment,omitempty"`
+
+	// Source is a custom field telling where the response came from, see FromHistory.
+	Source string `json:"_source,omitempty"`
+}
+
+// Request is the recorded request of an entry.
+type Request struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []*Cookie    `json:"cookies"`
+	Headers     []*NameValue `json:"headers"`
+	QueryString []*NameValue `json:"queryString"`
+	PostData    *PostData    `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+}
+
+// Response is the recorded response of an entry.
+type Response struct {
+	Status      int          `json:"status"`
+	StatusText  string       `json:"statusText"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []*Cookie    `json:"cookies"`
+	Headers     []*NameValue `json:"headers"`
+	Content     *Content     `json:"content"`
+	RedirectURL string       `json:"redirectURL"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+	Comment     string       `json:"comment,omitempty"`
+}
+
+// NameValue is a header or query string pair.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Cookie is a recorded cookie.
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData is the recorded request body.
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Content is the recorded response body.
+// Text is base64 encoded when Encoding is "base64".
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Timings holds the phases of an exchange in milliseconds, -1 when not applicable.
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// Parse reads a HAR document.
+func Parse(data []byte) (*HAR, error) {
+	var doc HAR
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing HAR: %w", err)
+	}
+	if doc.Log == nil {
+		return nil, errors.New("parsing HAR: missing log")
+	}
+	return &doc, nil
+}
+
+// Bytes returns the decoded body.
+func (c *Content) Bytes() ([]byte, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if c.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(c.Text)
+	}
+	return []byte(c.Text), nil
+}
+
+// Header returns the first value of a header, matched case-insensitively.
+func Header(headers []*NameValue, name string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// FromHTTPHeader converts http.Header to sorted name/value pairs.
+func FromHTTPHeader(h http.Header) []*NameValue {
+	res := make([]*NameValue, 0, len(h))
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		for _, value := range h[name] {
+			res = append(res, &NameValue{Name: name, Value: value})
+		}
+	}
+	return res
+}
+
+// volatileHeaders are headers that change with every exchange or describe the transport,
+// so they make no sense in a recorded fixture.
+var volatileHeaders = map[string]bool{
+	"Age":                       true,
+	"Alt-Svc":                   true,
+	"Cf-Cache-Status":           true,
+	"Cf-Ray":                    true,
+	"Connection":                true,
+	"Content-Encoding":          true,
+	"Content-Length":            true,
+	"Cookie":                    true,
+	"Date":                      true,
+	"Etag":                      true,
+	"Expires":                   true,
+	"Keep-Alive":                true,
+	"Last-Modified":             true,
+	"Nel":                       true,
+	"Report-To":                 true,
+	"Server-Timing":             true,
+	"Set-Cookie":                true,
+	"Strict-Transport-Security": true,
+	"Traceparent":               true,
+	"Tracestate":                true,
+	"Transfer-Encoding":         true,
+	"Via":                       true,
+	"X-Amz-Cf-Id":               true,
+	"X-Amz-Cf-Pop":              true,
+	"X-Amzn-Requestid":          true,
+	"X-Amzn-Trace-Id":           true,
+	"X-Cache":                   true,
+	"X-Correlation-Id":          true,
+	"X-Request-Id":              true,
+	"X-Runtime":                 true,
+	"X-Served-By":               true,
+	"X-Timer":                   true,
+	"X-Trace-Id":                true,
+}
+
+// IsVolatileHeader reports whether a header should be dropped from recorded fixtures.
+// HTTP/2 pseudo headers such as ":status" are volatile too.
+func IsVolatileHeader(name string) bool {
+	return strings.HasPrefix(name, ":") || volatileHeaders[http.CanonicalHeaderKey(name)]
+}
+
+// StableHeaders returns the headers without volatile ones.
+func StableHeaders(headers []*NameValue) []*NameValue {
+	var res []*NameValue
+	for _, h := range headers {
+		if !IsVolatileHeader(h.Name) {
+			res = append(res, h)
+		}
+	}
+	return res
+}
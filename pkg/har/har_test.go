@@ -0,0 +1,87 @@
+package har
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("parses entries", func(t *testing.T) {
+		doc, err := Parse([]byte(`{"log": {"version": "1.2", "entries": [
+			{"request": {"method": "GET", "url": "https://api.example.com/users"},
+			 "response": {"status": 200, "content": {"mimeType": "application/json", "text": "[]"}}}
+		]}}`))
+		require.NoError(t, err)
+		require.Len(t, doc.Log.Entries, 1)
+		assert.Equal(t, "GET", doc.Log.Entries[0].Request.Method)
+		assert.Equal(t, 200, doc.Log.Entries[0].Response.Status)
+	})
+
+	t.Run("missing log", func(t *testing.T) {
+		_, err := Parse([]byte(`{}`))
+		assert.ErrorContains(t, err, "missing log")
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := Parse([]byte(`{`))
+		assert.Error(t, err)
+	})
+}
+
+func TestContent_Bytes(t *testing.T) {
+	t.Run("plain text", func(t *testing.T) {
+		b, err := (&Content{Text: `{"a":1}`}).Bytes()
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a":1}`, string(b))
+	})
+
+	t.Run("base64", func(t *testing.T) {
+		b, err := (&Content{Text: "eyJhIjoxfQ==", Encoding: "base64"}).Bytes()
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a":1}`, string(b))
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		b, err := (*Content)(nil).Bytes()
+		assert.NoError(t, err)
+		assert.Nil(t, b)
+	})
+}
+
+func TestHeader(t *testing.T) {
+	headers := []*NameValue{{Name: "content-type", Value: "application/json"}}
+	assert.Equal(t, "application/json", Header(headers, "Content-Type"))
+	assert.Equal(t, "", Header(headers, "X-Missing"))
+}
+
+func TestFromHTTPHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-B", "2")
+	h.Add("X-A", "1")
+	h.Add("X-A", "3")
+
+	assert.Equal(t, []*NameValue{
+		{Name: "X-A", Value: "1"},
+		{Name: "X-A", Value: "3"},
+		{Name: "X-B", Value: "2"},
+	}, FromHTTPHeader(h))
+}
+
+func TestStableHeaders(t *testing.T) {
+	headers := []*NameValue{
+		{Name: "date", Value: "Mon, 01 Jan 2024 00:00:00 GMT"},
+		{Name: ":status", Value: "200"},
+		{Name: "Set-Cookie", Value: "a=b"},
+		{Name: "x-request-id", Value: "abc"},
+		{Name: "X-Rate-Limit", Value: "100"},
+		{Name: "Content-Type", Value: "application/json"},
+	}
+
+	assert.Equal(t, []*NameValue{
+		{Name: "X-Rate-Limit", Value: "100"},
+		{Name: "Content-Type", Value: "application/json"},
+	}, StableHeaders(headers))
+}
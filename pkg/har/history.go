@@ -0,0 +1,176 @@
+package har
+
+import (
+	"encoding/base64"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mockzilla/connexions/v2/pkg/db"
+)
+
+// Response sources, as reported by the X-Cxs-Source header.
+const (
+	SourceUpstream  = "upstream"
+	SourceCache     = "cache"
+	SourceReplay    = "replay"
+	SourceGenerated = "generated"
+)
+
+// sourceHeader is the response header recording where a response came from.
+const sourceHeader = "X-Cxs-Source"
+
+// FromHistory converts history entries to a HAR document.
+// Relative request URLs are resolved against baseURL, e.g. "http://localhost:2200".
+//
+// Entry times come from HistoryResponse.Duration, reported as the wait phase.
+// Every entry carries the source of its response in the "_source" custom field:
+// upstream, cache, replay or generated.
+func FromHistory(entries []*db.HistoryEntry, baseURL string, creator *Creator) *HAR {
+	base, _ := url.Parse(baseURL)
+
+	res := &HAR{Log: &Log{
+		Version: Version,
+		Creator: creator,
+		Entries: make([]*Entry, 0, len(entries)),
+	}}
+
+	for _, entry := range entries {
+		if entry == nil || entry.Request == nil {
+			continue
+		}
+		res.Log.Entries = append(res.Log.Entries, historyEntry(entry, base))
+	}
+	return res
+}
+
+func historyEntry(entry *db.HistoryEntry, base *url.URL) *Entry {
+	req := entry.Request
+
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		u = &url.URL{Path: req.URL}
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	reqHeaders := unflattenHeaders(req.Headers)
+	request := &Request{
+		Method:      req.Method,
+		URL:         u.String(),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []*Cookie{},
+		Headers:     reqHeaders,
+		QueryString: queryString(u.Query()),
+		HeadersSize: -1,
+		BodySize:    len(req.Body),
+	}
+	if len(req.Body) > 0 {
+		request.PostData = &PostData{
+			MimeType: Header(reqHeaders, "Content-Type"),
+			Text:     string(req.Body),
+		}
+	}
+
+	var ms float64
+	response := &Response{
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []*Cookie{},
+		Headers:     []*NameValue{},
+		Content:     &Content{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	source := ""
+
+	if resp := entry.Response; resp != nil {
+		ms = float64(resp.Duration) / float64(time.Millisecond)
+
+		headers := unflattenHeaders(resp.Headers)
+		if Header(headers, "Content-Type") == "" && resp.ContentType != "" {
+			headers = append(headers, &NameValue{Name: "Content-Type", Value: resp.ContentType})
+		}
+
+		response.Status = resp.StatusCode
+		response.StatusText = http.StatusText(resp.StatusCode)
+		response.Headers = headers
+		response.Content = newContent(resp.Body, resp.ContentType)
+		response.RedirectURL = Header(headers, "Location")
+		response.BodySize = len(resp.Body)
+		response.Comment = resp.UpstreamError
+
+		source = historySource(resp, headers)
+	}
+
+	return &Entry{
+		StartedDateTime: entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Time:            ms,
+		Request:         request,
+		Response:        response,
+		Timings: &Timings{
+			Blocked: -1,
+			DNS:     -1,
+			Connect: -1,
+			SSL:     -1,
+			Wait:    ms,
+		},
+		Source: source,
+	}
+}
+
+// historySource tells where a recorded response came from.
+// The X-Cxs-Source header is recorded for served responses,
+// upstream responses recorded directly by the proxy are flagged instead.
+func historySource(resp *db.HistoryResponse, headers []*NameValue) string {
+	if source := Header(headers, sourceHeader); source != "" {
+		return source
+	}
+	if resp.IsFromUpstream {
+		return SourceUpstream
+	}
+	return SourceGenerated
+}
+
+// newContent creates response content, base64 encoding bodies that aren't valid UTF-8.
+func newContent(body []byte, contentType string) *Content {
+	c := &Content{Size: len(body), MimeType: contentType}
+	if len(body) == 0 {
+		return c
+	}
+	if utf8.Valid(body) {
+		c.Text = string(body)
+	} else {
+		c.Text = base64.StdEncoding.EncodeToString(body)
+		c.Encoding = "base64"
+	}
+	return c
+}
+
+// unflattenHeaders reverses db.FlattenHeaders, splitting "Key: value" strings.
+// Multiple values joined into one line are kept as a single header.
+func unflattenHeaders(lines []string) []*NameValue {
+	res := make([]*NameValue, 0, len(lines))
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		res = append(res, &NameValue{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	return res
+}
+
+func queryString(values url.Values) []*NameValue {
+	res := make([]*NameValue, 0, len(values))
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		for _, value := range values[name] {
+			res = append(res, &NameValue{Name: name, Value: value})
+		}
+	}
+	return res
+}
@@ -0,0 +1,101 @@
+package har
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromHistory(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	creator := &Creator{Name: "connexions", Version: "v2"}
+
+	t.Run("converts entries", func(t *testing.T) {
+		doc := FromHistory([]*db.HistoryEntry{{
+			ID:        "1",
+			CreatedAt: createdAt,
+			Request: &db.HistoryRequest{
+				Method:  "POST",
+				URL:     "/petstore/pets?tag=a&tag=b",
+				Body:    []byte(`{"name":"Rex"}`),
+				Headers: []string{"Content-Type: application/json", "X-Trace: 1"},
+			},
+			Response: &db.HistoryResponse{
+				StatusCode:  201,
+				ContentType: "application/json",
+				Body:        []byte(`{"id":1}`),
+				Headers:     []string{"Location: /petstore/pets/1", "X-Cxs-Source: generated"},
+				Duration:    1500 * time.Microsecond,
+			},
+		}}, "http://localhost:2200", creator)
+
+		assert.Equal(t, Version, doc.Log.Version)
+		assert.Equal(t, creator, doc.Log.Creator)
+		require.Len(t, doc.Log.Entries, 1)
+
+		entry := doc.Log.Entries[0]
+		assert.Equal(t, "2024-05-01T10:00:00Z", entry.StartedDateTime)
+		assert.Equal(t, 1.5, entry.Time)
+		assert.Equal(t, 1.5, entry.Timings.Wait)
+		assert.Equal(t, float64(-1), entry.Timings.DNS)
+		assert.Equal(t, SourceGenerated, entry.Source)
+
+		assert.Equal(t, "http://localhost:2200/petstore/pets?tag=a&tag=b", entry.Request.URL)
+		assert.Equal(t, []*NameValue{{Name: "tag", Value: "a"}, {Name: "tag", Value: "b"}}, entry.Request.QueryString)
+		assert.Equal(t, &PostData{MimeType: "application/json", Text: `{"name":"Rex"}`}, entry.Request.PostData)
+		assert.Equal(t, "1", Header(entry.Request.Headers, "X-Trace"))
+
+		assert.Equal(t, 201, entry.Response.Status)
+		assert.Equal(t, "Created", entry.Response.StatusText)
+		assert.Equal(t, "/petstore/pets/1", entry.Response.RedirectURL)
+		assert.Equal(t, &Content{Size: 8, MimeType: "application/json", Text: `{"id":1}`}, entry.Response.Content)
+	})
+
+	t.Run("marks response sources", func(t *testing.T) {
+		entries := []*db.HistoryEntry{
+			{Request: &db.HistoryRequest{Method: "GET", URL: "/a"}, Response: &db.HistoryResponse{
+				StatusCode: 200, Headers: []string{"X-Cxs-Source: cache"},
+			}},
+			{Request: &db.HistoryRequest{Method: "GET", URL: "/b"}, Response: &db.HistoryResponse{
+				StatusCode: 200, Headers: []string{"X-Cxs-Source: replay"},
+			}},
+			{Request: &db.HistoryRequest{Method: "GET", URL: "/c"}, Response: &db.HistoryResponse{
+				StatusCode: 502, IsFromUpstream: true, UpstreamError: "connection refused",
+			}},
+			{Request: &db.HistoryRequest{Method: "GET", URL: "/d"}, Response: &db.HistoryResponse{StatusCode: 200}},
+			{Request: &db.HistoryRequest{Method: "GET", URL: "/e"}},
+		}
+
+		doc := FromHistory(entries, "", creator)
+		require.Len(t, doc.Log.Entries, 5)
+
+		var sources []string
+		for _, e := range doc.Log.Entries {
+			sources = append(sources, e.Source)
+		}
+		assert.Equal(t, []string{SourceCache, SourceReplay, SourceUpstream, SourceGenerated, ""}, sources)
+		assert.Equal(t, "connection refused", doc.Log.Entries[2].Response.Comment)
+		assert.Equal(t, "/a", doc.Log.Entries[0].Request.URL)
+	})
+
+	t.Run("base64 encodes binary bodies", func(t *testing.T) {
+		doc := FromHistory([]*db.HistoryEntry{{
+			Request:  &db.HistoryRequest{Method: "GET", URL: "/img"},
+			Response: &db.HistoryResponse{StatusCode: 200, ContentType: "image/png", Body: []byte{0xff, 0xfe}},
+		}}, "", creator)
+
+		content := doc.Log.Entries[0].Response.Content
+		assert.Equal(t, "base64", content.Encoding)
+		b, err := content.Bytes()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xff, 0xfe}, b)
+	})
+
+	t.Run("skips entries without request", func(t *testing.T) {
+		doc := FromHistory([]*db.HistoryEntry{nil, {ID: "x"}}, "", creator)
+		assert.Empty(t, doc.Log.Entries)
+	})
+}
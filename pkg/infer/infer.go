@@ -0,0 +1,460 @@
+// Package infer builds OpenAPI 3.1 specs from observed traffic.
+//
+// Concrete request paths are grouped into templates such as /users/{id},
+// the schemas inferred from every request and response body are merged,
+// and object properties seen in every sample are marked as required.
+package infer
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/har"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+	"go.yaml.in/yaml/v4"
+)
+
+// Options configures spec inference.
+// Title is the info title of the spec.
+// BasePath is stripped from request paths, e.g. "/petstore" for a service's history.
+// Requests outside of it are ignored.
+type Options struct {
+	Title    string
+	BasePath string
+}
+
+var (
+	uuidRe  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	tokenRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// sample is a single observed request/response pair.
+type sample struct {
+	method         string
+	path           string
+	query          url.Values
+	reqContentType string
+	reqBody        []byte
+	status         int
+	contentType    string
+	body           []byte
+}
+
+// operation collects the samples of a templated path and method.
+type operation struct {
+	method     string
+	path       string
+	params     []string
+	samples    []*sample
+	paramValue [][]string
+}
+
+// FromHAR infers a spec from the entries of a HAR document.
+func FromHAR(doc *har.HAR, opts Options) ([]byte, error) {
+	if doc == nil || doc.Log == nil {
+		return nil, fmt.Errorf("empty HAR document")
+	}
+
+	var samples []*sample
+	for _, entry := range doc.Log.Entries {
+		if entry == nil || entry.Request == nil || entry.Response == nil {
+			continue
+		}
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			continue
+		}
+
+		s := &sample{
+			method: entry.Request.Method,
+			path:   u.Path,
+			query:  u.Query(),
+			status: entry.Response.Status,
+		}
+		if pd := entry.Request.PostData; pd != nil {
+			s.reqContentType = pd.MimeType
+			s.reqBody = []byte(pd.Text)
+		}
+		if c := entry.Response.Content; c != nil {
+			s.contentType = c.MimeType
+			s.body, _ = c.Bytes()
+		}
+		if s.contentType == "" {
+			s.contentType = har.Header(entry.Response.Headers, "Content-Type")
+		}
+		samples = append(samples, s)
+	}
+
+	return build(samples, opts)
+}
+
+// FromHistory infers a spec from recorded history entries.
+func FromHistory(entries []*db.HistoryEntry, opts Options) ([]byte, error) {
+	return FromHAR(har.FromHistory(entries, "", nil), opts)
+}
+
+func build(samples []*sample, opts Options) ([]byte, error) {
+	basePath := "/" + strings.Trim(opts.BasePath, "/")
+	if basePath == "/" {
+		basePath = ""
+	}
+
+	ops := make(map[string]*operation)
+	for _, s := range samples {
+		if s.status == 0 || s.method == "" {
+			continue
+		}
+		p := s.path
+		if basePath != "" {
+			if p != basePath && !strings.HasPrefix(p, basePath+"/") {
+				continue
+			}
+			p = strings.TrimPrefix(p, basePath)
+		}
+
+		tmpl, params, values := templatePath(p)
+		method := strings.ToLower(s.method)
+		key := method + " " + tmpl
+
+		op, ok := ops[key]
+		if !ok {
+			op = &operation{method: method, path: tmpl, params: params}
+			ops[key] = op
+		}
+		op.samples = append(op.samples, s)
+		op.paramValue = append(op.paramValue, values)
+	}
+
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("no requests to infer a spec from")
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = "Inferred API"
+	}
+
+	paths := make(map[string]any)
+	opIDs := make(map[string]int)
+	for _, key := range slices.Sorted(maps.Keys(ops)) {
+		op := ops[key]
+		pathItem, ok := paths[op.path].(map[string]any)
+		if !ok {
+			pathItem = make(map[string]any)
+			paths[op.path] = pathItem
+		}
+
+		opID := operationID(op.method, op.path)
+		opIDs[opID]++
+		if n := opIDs[opID]; n > 1 {
+			opID += strconv.Itoa(n)
+		}
+
+		pathItem[op.method] = op.toMap(opID)
+	}
+
+	spec := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   title,
+			"version": "1.0.0",
+		},
+		"paths": paths,
+	}
+
+	res, err := yaml.Dump(spec, yaml.WithIndent(2))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OpenAPI spec: %w", err)
+	}
+	return res, nil
+}
+
+// templatePath replaces ID-like segments of a path with parameters.
+// The first parameter is named "id", later ones after the segment before them,
+// e.g. /users/1/orders/2 becomes /users/{id}/orders/{orderId}.
+// Names only depend on the preceding segments, so paths sharing a prefix share parameter names.
+func templatePath(path string) (string, []string, []string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) == 1 && segments[0] == "" {
+		return "/", nil, nil
+	}
+
+	var params, values []string
+	used := make(map[string]bool)
+
+	for i, seg := range segments {
+		if !isIDSegment(seg) {
+			continue
+		}
+
+		name := "id"
+		if len(params) > 0 && !strings.HasPrefix(segments[i-1], "{") {
+			name = singular(segments[i-1]) + "Id"
+		}
+		for base, n := name, 2; used[name]; n++ {
+			name = base + strconv.Itoa(n)
+		}
+		used[name] = true
+
+		params = append(params, name)
+		values = append(values, seg)
+		segments[i] = "{" + name + "}"
+	}
+
+	return "/" + strings.Join(segments, "/"), params, values
+}
+
+// isIDSegment reports whether a path segment looks like an identifier rather than a resource name:
+// numbers, UUIDs and longer tokens mixing letters and digits.
+func isIDSegment(seg string) bool {
+	if seg == "" {
+		return false
+	}
+	if isDigits(seg) || uuidRe.MatchString(seg) {
+		return true
+	}
+	if len(seg) < 8 || !tokenRe.MatchString(seg) {
+		return false
+	}
+	return strings.ContainsAny(seg, "0123456789") &&
+		strings.ContainsFunc(seg, func(r rune) bool { return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' })
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// singular turns a plural resource name into a parameter name prefix, e.g. "categories" -> "category".
+func singular(s string) string {
+	s = strings.ToLower(strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' {
+			return -1
+		}
+		return r
+	}, s))
+	switch {
+	case strings.HasSuffix(s, "ies") && len(s) > 3:
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "ss"):
+		return s
+	case strings.HasSuffix(s, "s") && len(s) > 1:
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+// operationID creates an operation ID from method and templated path.
+// Example: "get", "/users/{id}" -> "getUsersById"
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(method)
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		if strings.HasPrefix(part, "{") {
+			b.WriteString("By")
+			part = strings.Trim(part, "{}")
+		}
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	if b.Len() == len(method) {
+		b.WriteString("Root")
+	}
+	return b.String()
+}
+
+func (op *operation) toMap(opID string) map[string]any {
+	res := map[string]any{"operationId": opID}
+
+	var parameters []any
+	for i, name := range op.params {
+		values := make([]string, 0, len(op.paramValue))
+		for _, v := range op.paramValue {
+			values = append(values, v[i])
+		}
+		parameters = append(parameters, map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   valuesSchema(values),
+		})
+	}
+	parameters = append(parameters, op.queryParams()...)
+	if len(parameters) > 0 {
+		res["parameters"] = parameters
+	}
+
+	if reqBody := op.requestBody(); reqBody != nil {
+		res["requestBody"] = reqBody
+	}
+	res["responses"] = op.responses()
+	return res
+}
+
+// queryParams describes every query parameter seen.
+// Parameters sent with every request are required.
+func (op *operation) queryParams() []any {
+	seen := make(map[string][]string)
+	counts := make(map[string]int)
+	for _, s := range op.samples {
+		for name, values := range s.query {
+			seen[name] = append(seen[name], values...)
+			counts[name]++
+		}
+	}
+
+	res := make([]any, 0, len(seen))
+	for _, name := range slices.Sorted(maps.Keys(seen)) {
+		param := map[string]any{
+			"name":   name,
+			"in":     "query",
+			"schema": valuesSchema(seen[name]),
+		}
+		if counts[name] == len(op.samples) {
+			param["required"] = true
+		}
+		res = append(res, param)
+	}
+	return res
+}
+
+func (op *operation) requestBody() map[string]any {
+	bodies := make(map[string][][]byte)
+	withBody := 0
+	for _, s := range op.samples {
+		if len(strings.TrimSpace(string(s.reqBody))) == 0 {
+			continue
+		}
+		ct := mediaType(s.reqContentType)
+		bodies[ct] = append(bodies[ct], s.reqBody)
+		withBody++
+	}
+	if withBody == 0 {
+		return nil
+	}
+
+	res := map[string]any{"content": contentMap(bodies)}
+	if withBody == len(op.samples) {
+		res["required"] = true
+	}
+	return res
+}
+
+func (op *operation) responses() map[string]any {
+	byStatus := make(map[int]map[string][][]byte)
+	for _, s := range op.samples {
+		if byStatus[s.status] == nil {
+			byStatus[s.status] = make(map[string][][]byte)
+		}
+		if len(strings.TrimSpace(string(s.body))) == 0 {
+			continue
+		}
+		ct := mediaType(s.contentType)
+		byStatus[s.status][ct] = append(byStatus[s.status][ct], s.body)
+	}
+
+	res := make(map[string]any, len(byStatus))
+	for status, bodies := range byStatus {
+		response := map[string]any{"description": statusDescription(status)}
+		if len(bodies) > 0 {
+			response["content"] = contentMap(bodies)
+		}
+		res[strconv.Itoa(status)] = response
+	}
+	return res
+}
+
+// contentMap merges the schemas of all bodies per media type.
+// Bodies that don't parse as their content type are left out, the first body is kept as an example.
+func contentMap(bodies map[string][][]byte) map[string]any {
+	res := make(map[string]any, len(bodies))
+	for ct, list := range bodies {
+		var merged *schema.Schema
+		for _, body := range list {
+			s, err := schema.BuildSchemaFromContent(body, ct)
+			if err != nil {
+				continue
+			}
+			markRequired(s)
+			merged = mergeSchemas(merged, s)
+		}
+		if merged == nil {
+			merged = &schema.Schema{Type: "string"}
+		}
+
+		media := map[string]any{"schema": schemaToMap(merged)}
+		if example := exampleValue(list[0], ct); example != nil {
+			media["example"] = example
+		}
+		res[ct] = media
+	}
+	return res
+}
+
+func statusDescription(status int) string {
+	if text := http.StatusText(status); text != "" {
+		return text
+	}
+	return "Status " + strconv.Itoa(status)
+}
+
+// mediaType strips parameters from a content type, defaulting to application/octet-stream.
+func mediaType(contentType string) string {
+	ct, _, _ := strings.Cut(contentType, ";")
+	ct = strings.ToLower(strings.TrimSpace(ct))
+	if ct == "" {
+		return "application/octet-stream"
+	}
+	return ct
+}
+
+// valuesSchema infers the schema of a parameter from its observed values.
+func valuesSchema(values []string) map[string]any {
+	allInt, allNum, allBool, allUUID := true, true, true, true
+	for _, v := range values {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			allInt = false
+		}
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			allNum = false
+		}
+		if v != "true" && v != "false" {
+			allBool = false
+		}
+		if !uuidRe.MatchString(v) {
+			allUUID = false
+		}
+	}
+
+	switch {
+	case len(values) == 0:
+		return map[string]any{"type": "string"}
+	case allInt:
+		return map[string]any{"type": "integer"}
+	case allNum:
+		return map[string]any{"type": "number"}
+	case allBool:
+		return map[string]any{"type": "boolean"}
+	case allUUID:
+		return map[string]any{"type": "string", "format": "uuid"}
+	}
+	return map[string]any{"type": "string"}
+}
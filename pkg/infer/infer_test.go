@@ -0,0 +1,203 @@
+package infer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/har"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+)
+
+func harEntry(method, rawURL string, status int, contentType, body string) *har.Entry {
+	return &har.Entry{
+		Request: &har.Request{Method: method, URL: rawURL},
+		Response: &har.Response{
+			Status:  status,
+			Content: &har.Content{MimeType: contentType, Text: body},
+		},
+	}
+}
+
+func decodeSpec(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var spec map[string]any
+	require.NoError(t, yaml.Unmarshal(data, &spec))
+	return spec
+}
+
+func dig(t *testing.T, v any, keys ...string) any {
+	t.Helper()
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		require.True(t, ok, "expected object at %q", key)
+		v, ok = m[key]
+		require.True(t, ok, "missing key %q", key)
+	}
+	return v
+}
+
+func TestFromHAR(t *testing.T) {
+	doc := &har.HAR{Log: &har.Log{Entries: []*har.Entry{
+		harEntry("GET", "https://api.example.com/users/1?expand=true", 200, "application/json",
+			`{"id":1,"name":"Jane","email":"jane@example.com","manager":null}`),
+		harEntry("GET", "https://api.example.com/users/2", 200, "application/json; charset=utf-8",
+			`{"id":2,"name":"John","manager":{"id":1}}`),
+		harEntry("GET", "https://api.example.com/users/3", 404, "application/json", `{"error":"not found"}`),
+		harEntry("GET", "https://api.example.com/users/1/orders/9f1c2b3a-1d2e-4f5a-8b6c-7d8e9f0a1b2c", 200,
+			"application/json", `{"total":9.5}`),
+		harEntry("GET", "https://api.example.com/users/me", 200, "application/json", `{"id":1}`),
+		{
+			Request: &har.Request{
+				Method:   "POST",
+				URL:      "https://api.example.com/users",
+				PostData: &har.PostData{MimeType: "application/json", Text: `{"name":"Jane"}`},
+			},
+			Response: &har.Response{Status: 201, Content: &har.Content{}},
+		},
+	}}}
+
+	data, err := FromHAR(doc, Options{Title: "users"})
+	require.NoError(t, err)
+
+	t.Run("is a valid OpenAPI 3.1 document", func(t *testing.T) {
+		parsed, err := libopenapi.NewDocument(data)
+		require.NoError(t, err)
+		model, err := parsed.BuildV3Model()
+		require.NoError(t, err)
+		assert.Equal(t, "3.1.0", model.Model.Version)
+		assert.Equal(t, "users", model.Model.Info.Title)
+	})
+
+	spec := decodeSpec(t, data)
+	paths := dig(t, spec, "paths").(map[string]any)
+
+	t.Run("groups paths into templates", func(t *testing.T) {
+		assert.Len(t, paths, 4)
+		assert.Contains(t, paths, "/users")
+		assert.Contains(t, paths, "/users/{id}")
+		assert.Contains(t, paths, "/users/me")
+		assert.Contains(t, paths, "/users/{id}/orders/{orderId}")
+
+		params := dig(t, paths, "/users/{id}/orders/{orderId}", "get", "parameters").([]any)
+		require.Len(t, params, 2)
+		assert.Equal(t, "integer", dig(t, params[0], "schema", "type"))
+		assert.Equal(t, "uuid", dig(t, params[1], "schema", "format"))
+	})
+
+	t.Run("records status codes", func(t *testing.T) {
+		responses := dig(t, paths, "/users/{id}", "get", "responses").(map[string]any)
+		assert.Contains(t, responses, "200")
+		assert.Contains(t, responses, "404")
+		assert.Equal(t, "Not Found", dig(t, responses, "404", "description"))
+		assert.Equal(t, "Created", dig(t, paths, "/users", "post", "responses", "201", "description"))
+	})
+
+	t.Run("merges schemas across samples", func(t *testing.T) {
+		s := dig(t, paths, "/users/{id}", "get", "responses", "200", "content", "application/json", "schema")
+		assert.Equal(t, []any{"id", "name"}, dig(t, s, "required"))
+		assert.Equal(t, "string", dig(t, s, "properties", "email", "type"))
+		assert.Equal(t, []any{"object", "null"}, dig(t, s, "properties", "manager", "type"))
+		assert.Equal(t, "integer", dig(t, s, "properties", "manager", "properties", "id", "type"))
+	})
+
+	t.Run("records query parameters", func(t *testing.T) {
+		params := dig(t, paths, "/users/{id}", "get", "parameters").([]any)
+		require.Len(t, params, 2)
+		assert.Equal(t, "expand", dig(t, params[1], "name"))
+		assert.Equal(t, "query", dig(t, params[1], "in"))
+		assert.Equal(t, "boolean", dig(t, params[1], "schema", "type"))
+		assert.NotContains(t, params[1], "required")
+	})
+
+	t.Run("records request bodies", func(t *testing.T) {
+		body := dig(t, paths, "/users", "post", "requestBody")
+		assert.Equal(t, true, dig(t, body, "required"))
+		assert.Equal(t, "string", dig(t, body, "content", "application/json", "schema", "properties", "name", "type"))
+	})
+
+	t.Run("fails without requests", func(t *testing.T) {
+		_, err := FromHAR(&har.HAR{Log: &har.Log{}}, Options{})
+		assert.Error(t, err)
+	})
+}
+
+func TestFromHistory(t *testing.T) {
+	entries := []*db.HistoryEntry{
+		{
+			Request:   &db.HistoryRequest{Method: "GET", URL: "/petstore/pets/12"},
+			Response:  &db.HistoryResponse{StatusCode: 200, ContentType: "application/json", Body: []byte(`{"id":12}`)},
+			CreatedAt: time.Now(),
+		},
+		{
+			Request:   &db.HistoryRequest{Method: "GET", URL: "/other/pets/12"},
+			Response:  &db.HistoryResponse{StatusCode: 200, ContentType: "application/json", Body: []byte(`{"id":12}`)},
+			CreatedAt: time.Now(),
+		},
+		{
+			Request:   &db.HistoryRequest{Method: "GET", URL: "/petstore/pets"},
+			CreatedAt: time.Now(),
+		},
+	}
+
+	data, err := FromHistory(entries, Options{Title: "petstore", BasePath: "/petstore"})
+	require.NoError(t, err)
+
+	paths := dig(t, decodeSpec(t, data), "paths").(map[string]any)
+	assert.Len(t, paths, 1)
+	assert.Contains(t, paths, "/pets/{id}")
+}
+
+func TestTemplatePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		params   []string
+	}{
+		{"/", "/", nil},
+		{"/users", "/users", nil},
+		{"/users/42", "/users/{id}", []string{"id"}},
+		{"/users/42/orders/7", "/users/{id}/orders/{orderId}", []string{"id", "orderId"}},
+		{"/categories/1/entries/2", "/categories/{id}/entries/{entryId}", []string{"id", "entryId"}},
+		{"/files/1/2", "/files/{id}/{id2}", []string{"id", "id2"}},
+		{"/customers/cus_NffrFeUfNV2Hib", "/customers/{id}", []string{"id"}},
+		{"/api/v2/oauth2/token", "/api/v2/oauth2/token", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			tmpl, params, _ := templatePath(tt.path)
+			assert.Equal(t, tt.expected, tmpl)
+			assert.Equal(t, tt.params, params)
+		})
+	}
+}
+
+func TestMergeSchemas(t *testing.T) {
+	build := func(body string) *schema.Schema {
+		s, err := schema.BuildSchemaFromContent([]byte(body), "application/json")
+		require.NoError(t, err)
+		markRequired(s)
+		return s
+	}
+
+	t.Run("integer and number", func(t *testing.T) {
+		res := mergeSchemas(build(`{"n":1}`), build(`{"n":1.5}`))
+		assert.Equal(t, "number", res.Properties["n"].Type)
+	})
+
+	t.Run("empty array items", func(t *testing.T) {
+		res := mergeSchemas(build(`{"tags":[]}`), build(`{"tags":[{"id":1}]}`))
+		assert.Equal(t, "object", res.Properties["tags"].Items.Type)
+	})
+
+	t.Run("required narrows down", func(t *testing.T) {
+		res := mergeSchemas(build(`{"a":1,"b":2}`), build(`{"a":1,"c":3}`))
+		assert.Equal(t, []string{"a"}, res.Required)
+		assert.Len(t, res.Properties, 3)
+	})
+}
@@ -0,0 +1,156 @@
+package infer
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+)
+
+// markRequired marks every non-null property of the objects in s as required.
+// Merging samples later narrows the list down to properties present in all of them.
+func markRequired(s *schema.Schema) {
+	if s == nil {
+		return
+	}
+	if s.Type == "object" {
+		s.Required = nil
+		for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+			prop := s.Properties[name]
+			if prop.Type != "null" {
+				s.Required = append(s.Required, name)
+			}
+			markRequired(prop)
+		}
+	}
+	markRequired(s.Items)
+}
+
+// mergeSchemas combines the schemas of two samples of the same body:
+//   - null merged with any type makes that type nullable
+//   - integer merged with number is a number
+//   - object properties are united, only properties required by both stay required
+//   - array items are merged
+//
+// Empty arrays are inferred with string items, so a string loses against any other type.
+// For other conflicts the first type seen wins.
+func mergeSchemas(a, b *schema.Schema) *schema.Schema {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.Type == "null":
+		b.Nullable = true
+		return b
+	case b.Type == "null":
+		a.Nullable = true
+		return a
+	}
+
+	nullable := a.Nullable || b.Nullable
+
+	if a.Type != b.Type {
+		switch {
+		case isNumeric(a.Type) && isNumeric(b.Type):
+			a.Type = "number"
+		case a.Type == "string" && a.Format == "" && len(a.Properties) == 0:
+			a = b
+		}
+		a.Nullable = nullable
+		return a
+	}
+
+	res := a
+	res.Nullable = nullable
+	if a.Format != b.Format {
+		res.Format = ""
+	}
+
+	switch res.Type {
+	case "object":
+		props := make(map[string]*schema.Schema, len(a.Properties)+len(b.Properties))
+		maps.Copy(props, a.Properties)
+		for name, prop := range b.Properties {
+			props[name] = mergeSchemas(props[name], prop)
+		}
+		res.Properties = props
+
+		var required []string
+		for _, name := range a.Required {
+			if slices.Contains(b.Required, name) {
+				required = append(required, name)
+			}
+		}
+		res.Required = required
+
+	case "array":
+		res.Items = mergeSchemas(a.Items, b.Items)
+	}
+
+	return res
+}
+
+func isNumeric(typ string) bool {
+	return typ == "integer" || typ == "number"
+}
+
+// schemaToMap converts a merged schema to an OpenAPI 3.1 schema.
+// Nullable types are written as a type list including "null".
+func schemaToMap(s *schema.Schema) map[string]any {
+	m := make(map[string]any)
+	if s == nil {
+		return m
+	}
+
+	switch {
+	case s.Type == "null":
+		m["type"] = "null"
+	case s.Type != "" && s.Nullable:
+		m["type"] = []string{s.Type, "null"}
+	case s.Type != "":
+		m["type"] = s.Type
+	}
+
+	if s.Format != "" && s.Format != "xml" {
+		m["format"] = s.Format
+	}
+
+	if s.Items != nil {
+		m["items"] = schemaToMap(s.Items)
+	}
+
+	if len(s.Properties) > 0 {
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = schemaToMap(prop)
+		}
+		m["properties"] = props
+	}
+
+	if len(s.Required) > 0 {
+		m["required"] = s.Required
+	}
+
+	return m
+}
+
+// exampleValue decodes a body to use as an example: JSON is decoded, other text is kept as is.
+// Binary bodies have no example.
+func exampleValue(body []byte, contentType string) any {
+	if strings.Contains(contentType, "json") {
+		var v any
+		if err := json.Unmarshal(body, &v); err == nil {
+			return v
+		}
+		return nil
+	}
+	if !utf8.Valid(body) || !strings.HasPrefix(contentType, "text/") && !strings.Contains(contentType, "xml") &&
+		!strings.Contains(contentType, "yaml") {
+		return nil
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -58,7 +58,9 @@ func CreateCacheWriteMiddleware(params *Params) func(http.Handler) http.Handler
 					ContentType:   respContentType,
 					Headers:       db.FlattenHeaders(rw.Header()),
 					Duration:      GetDuration(req),
+					Latency:       GetLatency(req),
 					UpstreamError: GetUpstreamError(req),
+					Fault:         GetFault(req),
 				}
 				params.transformHistory(params.serviceConfig, histReq, histResp)
 				resourcePath := GetResourcePath(req)
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+)
+
+// cannedResponse is a configured response sent instead of the one of the service.
+// Status defaults to 200 and contentType to application/json.
+type cannedResponse struct {
+	status      int
+	contentType string
+	headers     map[string]string
+	body        []byte
+	source      string
+}
+
+// writeCannedResponse sends a canned response and records it in the history.
+func writeCannedResponse(params *Params, cfg *config.ServiceConfig, w http.ResponseWriter, req *http.Request, res *cannedResponse) {
+	status := cmp.Or(res.status, http.StatusOK)
+	contentType := cmp.Or(res.contentType, "application/json")
+
+	for k, v := range res.headers {
+		w.Header().Set(k, v)
+	}
+	SetRequestIDHeader(w, req)
+	SetDurationHeader(w, req)
+	w.Header().Set(ResponseHeaderSource, res.source)
+	w.Header().Set("Content-Type", contentType)
+
+	if cfg.HistoryEnabled() {
+		histReq := &db.HistoryRequest{
+			Method:     req.Method,
+			URL:        req.URL.String(),
+			Body:       readAndRestoreBody(req),
+			Headers:    db.FlattenHeaders(req.Header),
+			RemoteAddr: req.RemoteAddr,
+			RequestID:  GetRequestID(req),
+		}
+		histResp := &db.HistoryResponse{
+			Body:        res.body,
+			StatusCode:  status,
+			ContentType: contentType,
+			Headers:     db.FlattenHeaders(w.Header()),
+			Duration:    GetDuration(req),
+			Latency:     GetLatency(req),
+		}
+		params.transformHistory(cfg, histReq, histResp)
+		resourcePath := GetResourcePath(req)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), asyncWriteTimeout)
+			defer cancel()
+			params.DB().History().Set(ctx, resourcePath, histReq, histResp)
+		}()
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write(res.body)
+}
+
+// overrideResponseWriter applies a configured status and headers to the response of the service.
+type overrideResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	headers     map[string]string
+	wroteHeader bool
+}
+
+func (w *overrideResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	for k, v := range w.headers {
+		w.Header().Set(k, v)
+	}
+	w.ResponseWriter.WriteHeader(cmp.Or(w.status, code))
+}
+
+func (w *overrideResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *overrideResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -51,20 +51,24 @@ var browserHeaders = map[string]bool{
 	"Service-Worker-Navigation-Preload": true,
 }
 
-// CreateConfigOverrideMiddleware creates a middleware that reads X-Cxs-* headers
+// CreateConfigOverrideMiddleware creates a middleware that merges the settings of the
+// requested endpoint over the ServiceConfig with its runtime changes, then reads X-Cxs-* headers
 // and temporarily overrides ServiceConfig values for the current request.
+// Endpoints are resolved by the resource path, so it must run after the resource resolver.
 // Headers are case-insensitive. The original config is restored after the request completes.
 func CreateConfigOverrideMiddleware(params *Params) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			overrides := parseConfigOverrides(req.Header)
+			cfg := params.ServiceConfig(req.Context()).ForEndpoint(GetResourcePath(req), req.Method)
 
-			if len(overrides) > 0 {
-				cfg := applyOverrides(params.serviceConfig, overrides)
-				ctx := context.WithValue(req.Context(), serviceConfigKey, cfg)
-				req = req.WithContext(ctx)
+			if overrides := parseConfigOverrides(req.Header); len(overrides) > 0 {
+				cfg = applyOverrides(cfg, overrides)
 			}
 
+			// The config is kept for the whole request, even if it's changed at runtime meanwhile
+			ctx := context.WithValue(req.Context(), serviceConfigKey, cfg)
+			req = req.WithContext(ctx)
+
 			fromUI := req.Header.Get(headerPrefix+headerSource) == sourceUI
 			stripBrowserHeaders(req, fromUI)
 			next.ServeHTTP(w, req)
@@ -159,7 +163,7 @@ func applyOverride(cfg *config.ServiceConfig, o configOverride) {
 
 	case headerLatency:
 		if d, err := time.ParseDuration(o.value); err == nil {
-			cfg.Latency = d
+			cfg.SetLatency(d)
 		}
 
 	case headerUpstreamURL:
@@ -117,6 +117,20 @@ func TestApplyOverrides(t *testing.T) {
 		assert.Equal(200*time.Millisecond, result.Latency)
 	})
 
+	t.Run("Latency replaces latencies and distribution", func(t *testing.T) {
+		original := &config.ServiceConfig{
+			Latencies:           map[string]time.Duration{"p100": time.Second},
+			LatencyDistribution: &config.LatencyDistribution{Type: config.LatencyExponential, Mean: time.Second},
+		}
+		original.WithDefaults()
+		result := applyOverrides(original, []configOverride{
+			{key: headerLatency, value: "200ms"},
+		})
+		assert.Equal(200*time.Millisecond, result.GetLatency())
+		assert.Equal(time.Second, original.Latencies["p100"])
+		assert.NotNil(original.LatencyDistribution)
+	})
+
 	t.Run("invalid latency ignored", func(t *testing.T) {
 		original := &config.ServiceConfig{Latency: 50 * time.Millisecond}
 		result := applyOverrides(original, []configOverride{
@@ -230,6 +244,37 @@ func TestCreateConfigOverrideMiddleware(t *testing.T) {
 		assert.Equal(100*time.Millisecond, original.Latency)
 	})
 
+	t.Run("merges endpoint settings by resource path", func(t *testing.T) {
+		original := &config.ServiceConfig{
+			Name:    "test",
+			Latency: 100 * time.Millisecond,
+			Endpoints: map[string]*config.EndpointConfigMethods{
+				"/search": {Any: &config.EndpointConfig{Latency: 2 * time.Second}},
+			},
+		}
+		params := newTestParams(original.WithDefaults(), nil)
+
+		var capturedLatency time.Duration
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			capturedLatency = params.GetServiceConfig(r).GetLatency()
+			w.WriteHeader(http.StatusOK)
+		})
+		mw := CreateResourceResolverMiddleware(params)(CreateConfigOverrideMiddleware(params)(handler))
+
+		mw.ServeHTTP(NewBufferedResponseWriter(), httptest.NewRequest(http.MethodGet, "/test/search", nil))
+		assert.Equal(2*time.Second, capturedLatency)
+
+		mw.ServeHTTP(NewBufferedResponseWriter(), httptest.NewRequest(http.MethodGet, "/test/health", nil))
+		assert.Equal(100*time.Millisecond, capturedLatency)
+
+		// Headers take precedence over endpoint settings
+		req := httptest.NewRequest(http.MethodGet, "/test/search", nil)
+		req.Header.Set("X-Cxs-Latency", "500ms")
+		mw.ServeHTTP(NewBufferedResponseWriter(), req)
+		assert.Equal(500*time.Millisecond, capturedLatency)
+		assert.Equal(100*time.Millisecond, original.Latency)
+	})
+
 	t.Run("X-Cxs headers are preserved on request", func(t *testing.T) {
 		params := newTestParams(&config.ServiceConfig{Name: "test"}, nil)
 
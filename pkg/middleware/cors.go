@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+)
+
+// CORSResolverFunc returns the CORS configuration that applies to a request, nil if there is none.
+type CORSResolverFunc func(req *http.Request) *config.CORSConfig
+
+// CORSMiddleware answers preflight requests from allowed origins and adds the
+// Access-Control-* headers to the responses of other cross-origin requests.
+// Access-Control-* headers set further down the chain, e.g. by an upstream, are replaced.
+// Requests without an Origin header or without CORS configuration pass through untouched.
+func CORSMiddleware(resolve CORSResolverFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			cfg := resolve(req)
+			if !cfg.AllowsOrigin(origin) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			requestMethod := req.Header.Get("Access-Control-Request-Method")
+			if req.Method == http.MethodOptions && requestMethod != "" {
+				writePreflight(w, req, cfg, origin, requestMethod)
+				return
+			}
+
+			next.ServeHTTP(&corsResponseWriter{ResponseWriter: w, cfg: cfg, origin: origin}, req)
+		})
+	}
+}
+
+// writePreflight answers a preflight request with 204.
+// Disallowed methods or headers get 204 without the allow headers, so the browser rejects the request.
+func writePreflight(w http.ResponseWriter, req *http.Request, cfg *config.CORSConfig, origin, requestMethod string) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	var requestHeaders []string
+	for _, h := range strings.Split(req.Header.Get("Access-Control-Request-Headers"), ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			requestHeaders = append(requestHeaders, h)
+		}
+	}
+
+	allowed := cfg.AllowsMethod(requestMethod)
+	for _, h := range requestHeaders {
+		allowed = allowed && cfg.AllowsHeader(h)
+	}
+	if !allowed {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	setCORSOrigin(header, cfg, origin)
+	header.Set("Access-Control-Allow-Methods", strings.ToUpper(requestMethod))
+	if len(requestHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+	}
+	if cfg.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setCORSOrigin sets the headers shared by preflight and actual responses.
+// The origin is echoed instead of *, so credentials work with any allowed origin.
+func setCORSOrigin(header http.Header, cfg *config.CORSConfig, origin string) {
+	header.Set("Access-Control-Allow-Origin", origin)
+	if cfg.Credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// corsResponseWriter replaces the Access-Control-* headers of a response right before it's written.
+type corsResponseWriter struct {
+	http.ResponseWriter
+	cfg         *config.CORSConfig
+	origin      string
+	wroteHeader bool
+}
+
+func (cw *corsResponseWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		cw.setHeaders()
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *corsResponseWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original writer for http.ResponseController.
+func (cw *corsResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *corsResponseWriter) setHeaders() {
+	header := cw.Header()
+	for name := range header {
+		if strings.HasPrefix(http.CanonicalHeaderKey(name), "Access-Control-") {
+			header.Del(name)
+		}
+	}
+
+	header.Add("Vary", "Origin")
+	setCORSOrigin(header, cw.cfg, cw.origin)
+	if len(cw.cfg.Expose) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(cw.cfg.Expose, ", "))
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	assert := assert2.New(t)
+
+	cfg := &config.CORSConfig{
+		Origins:     []string{"https://*.example.com"},
+		Methods:     []string{"GET", "POST"},
+		Headers:     []string{"Authorization", "Content-Type"},
+		Expose:      []string{"X-Cxs-Request-Id"},
+		Credentials: true,
+		MaxAge:      10 * time.Minute,
+	}
+
+	reached := false
+	handler := CORSMiddleware(func(*http.Request) *config.CORSConfig {
+		return cfg
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.Header().Set("Access-Control-Allow-Origin", "https://upstream.test")
+		w.Header().Set("Access-Control-Allow-Methods", "PUT")
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		reached = false
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("answers preflight requests", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/pets", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+
+		w := serve(req)
+		assert.False(reached)
+		assert.Equal(http.StatusNoContent, w.Code)
+		assert.Equal("https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal("POST", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal("authorization, content-type", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal("true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal("600", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("preflight with disallowed method", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/pets", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "DELETE")
+
+		w := serve(req)
+		assert.Equal(http.StatusNoContent, w.Code)
+		assert.Empty(w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(w.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("rewrites headers of actual responses", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+
+		w := serve(req)
+		assert.True(reached)
+		assert.Equal("ok", w.Body.String())
+		assert.Equal("https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal("X-Cxs-Request-Id", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Empty(w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal("Origin", w.Header().Get("Vary"))
+	})
+
+	t.Run("disallowed origins pass through", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+		req.Header.Set("Origin", "https://evil.test")
+
+		w := serve(req)
+		assert.True(reached)
+		assert.Equal("https://upstream.test", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("same-origin requests pass through", func(t *testing.T) {
+		w := serve(httptest.NewRequest(http.MethodOptions, "/pets", nil))
+		assert.True(reached)
+		assert.Equal("https://upstream.test", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("no config", func(t *testing.T) {
+		handler := CORSMiddleware(func(*http.Request) *config.CORSConfig {
+			return nil
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}))
+
+		req := httptest.NewRequest(http.MethodOptions, "/pets", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(http.StatusMethodNotAllowed, w.Code)
+		assert.Empty(w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+)
+
+// ResponseHeaderContractViolations is the response header with the number of fields
+// in which an upstream response disagrees with the spec.
+const ResponseHeaderContractViolations = "X-Cxs-Contract-Violations"
+
+// DriftTable is the name of the per-service table holding the drift report.
+const DriftTable = "drift"
+
+// ResponseValidatorFunc validates a response against the spec of the service.
+// path is the request path relative to the service root.
+// It returns the spec path of the matched operation, or "" if no operation matches.
+type ResponseValidatorFunc func(method, path string, statusCode int, contentType string, body []byte) (string, []*schema.ValidationError)
+
+// DriftOperation aggregates the contract violations of an operation's upstream responses.
+// Count is the number of responses with violations.
+type DriftOperation struct {
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	Count     int           `json:"count"`
+	FirstSeen time.Time     `json:"firstSeen"`
+	LastSeen  time.Time     `json:"lastSeen"`
+	Fields    []*DriftField `json:"fields"`
+}
+
+// DriftField aggregates the violations of a single field.
+// Message is the most recent violation message.
+type DriftField struct {
+	Field     string    `json:"field"`
+	Message   string    `json:"message"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Drift report attributes. Every counter and timestamp of the report is a value of its own,
+// so responses recorded at the same time, also by other instances sharing the storage, don't lose counts.
+// Keys are "<method> <path>|<attribute>" for operations and "<method> <path>|<field>|<attribute>" for fields.
+const (
+	driftCount   = "count"
+	driftFirst   = "first"
+	driftLast    = "last"
+	driftMessage = "message"
+)
+
+// driftSwapAttempts bounds the compare-and-swap retries of a last-seen time,
+// so a failing storage doesn't keep the recording busy.
+const driftSwapAttempts = 10
+
+// GetDriftReport reads the drift report of a service, sorted by path and method.
+func GetDriftReport(ctx context.Context, table db.Table) []*DriftOperation {
+	ops := make(map[string]*DriftOperation)
+	fields := make(map[string]map[string]*DriftField)
+
+	for key, raw := range table.Data(ctx) {
+		opKey, rest, ok := strings.Cut(key, "|")
+		method, path, ok2 := strings.Cut(opKey, " ")
+		if !ok || !ok2 {
+			continue
+		}
+		op := ops[opKey]
+		if op == nil {
+			op = &DriftOperation{Method: method, Path: path}
+			ops[opKey] = op
+			fields[opKey] = make(map[string]*DriftField)
+		}
+
+		idx := strings.LastIndex(rest, "|")
+		if idx < 0 {
+			setDriftAttribute(rest, raw, &op.Count, &op.FirstSeen, &op.LastSeen, nil)
+			continue
+		}
+		name := rest[:idx]
+		f := fields[opKey][name]
+		if f == nil {
+			f = &DriftField{Field: name}
+			fields[opKey][name] = f
+		}
+		setDriftAttribute(rest[idx+1:], raw, &f.Count, &f.FirstSeen, &f.LastSeen, &f.Message)
+	}
+
+	res := make([]*DriftOperation, 0, len(ops))
+	for opKey, op := range ops {
+		// The operation count is written last, so operations without it are still being recorded
+		if op.Count == 0 {
+			continue
+		}
+		for _, f := range fields[opKey] {
+			if f.Count > 0 {
+				op.Fields = append(op.Fields, f)
+			}
+		}
+		slices.SortFunc(op.Fields, func(a, b *DriftField) int { return cmp.Compare(a.Field, b.Field) })
+		res = append(res, op)
+	}
+	slices.SortFunc(res, func(a, b *DriftOperation) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Method, b.Method))
+	})
+	return res
+}
+
+// setDriftAttribute sets a counter, timestamp or message read from the drift table.
+// Timestamps are stored as Unix milliseconds.
+func setDriftAttribute(attr string, raw any, count *int, first, last *time.Time, message *string) {
+	switch attr {
+	case driftCount:
+		*count = storedCount(raw)
+	case driftFirst:
+		*first = time.UnixMilli(int64(storedCount(raw))).UTC()
+	case driftLast:
+		*last = time.UnixMilli(int64(storedCount(raw))).UTC()
+	case driftMessage:
+		if message != nil {
+			*message, _ = raw.(string)
+		}
+	}
+}
+
+// RecordDrift adds the violations of an upstream response of an operation to the drift report.
+// A field failing in several array elements counts once per response.
+func RecordDrift(ctx context.Context, table db.Table, method, path string, violations []*db.Violation, now time.Time) {
+	opKey := method + " " + path
+	ms := now.UnixMilli()
+
+	seen := make(map[string]bool, len(violations))
+	for _, v := range violations {
+		if seen[v.Field] {
+			continue
+		}
+		seen[v.Field] = true
+
+		prefix := opKey + "|" + v.Field + "|"
+		table.Set(ctx, prefix+driftMessage, v.Message, 0)
+		recordDriftTimes(ctx, table, prefix, ms)
+		table.Incr(ctx, prefix+driftCount, 1, 0)
+	}
+
+	recordDriftTimes(ctx, table, opKey+"|", ms)
+	table.Incr(ctx, opKey+"|"+driftCount, 1, 0)
+}
+
+// recordDriftTimes sets the first-seen time unless it's set and moves the last-seen time forward.
+func recordDriftTimes(ctx context.Context, table db.Table, prefix string, ms int64) {
+	table.CompareAndSwap(ctx, prefix+driftFirst, 0, ms, 0)
+
+	// A lost swap means another response moved the time, so it's tried again only while it's still earlier
+	current := table.Incr(ctx, prefix+driftLast, 0, 0)
+	for range driftSwapAttempts {
+		if current >= ms {
+			return
+		}
+		var ok bool
+		if current, ok = table.CompareAndSwap(ctx, prefix+driftLast, current, ms, 0); ok {
+			return
+		}
+	}
+}
+
+// driftRecorder validates upstream responses and aggregates violations into the drift table.
+type driftRecorder struct {
+	log      *slog.Logger
+	validate ResponseValidatorFunc
+	table    db.Table
+}
+
+func newDriftRecorder(log *slog.Logger, params *Params) *driftRecorder {
+	if params.responseValidator == nil {
+		return nil
+	}
+	return &driftRecorder{
+		log:      log,
+		validate: params.responseValidator,
+		table:    params.DB().Table(DriftTable),
+	}
+}
+
+// check validates an upstream response and records its violations in the drift report.
+// The nil recorder validates nothing.
+func (d *driftRecorder) check(svcCfg *config.ServiceConfig, req *http.Request, statusCode int, contentType string, body []byte) []*db.Violation {
+	if d == nil {
+		return nil
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/"+svcCfg.Name)
+	specPath, errs := d.validate(req.Method, path, statusCode, contentType, body)
+	if specPath == "" || len(errs) == 0 {
+		return nil
+	}
+
+	violations := make([]*db.Violation, 0, len(errs))
+	for _, e := range errs {
+		violations = append(violations, &db.Violation{Field: e.Field, Message: e.Message})
+	}
+
+	RequestLog(d.log, req).Warn("Upstream response doesn't match the spec",
+		"path", specPath,
+		"status", statusCode,
+		"violations", len(violations),
+	)
+
+	now := time.Now().UTC()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), asyncWriteTimeout)
+		defer cancel()
+		RecordDrift(ctx, d.table, req.Method, specPath, violations, now)
+	}()
+
+	return violations
+}
+
+// setViolationsHeader flags a response that disagrees with the spec.
+func setViolationsHeader(w http.ResponseWriter, violations []*db.Violation) {
+	if len(violations) > 0 {
+		w.Header().Set(ResponseHeaderContractViolations, strconv.Itoa(len(violations)))
+	}
+}
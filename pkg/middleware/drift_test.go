@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUpstreamRequestMiddleware_Drift(t *testing.T) {
+	assert := assert2.New(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Hello, from local!"))
+	})
+
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/users/1":
+			_, _ = w.Write([]byte(`{"id": "1"}`))
+		case "/users/2":
+			_, _ = w.Write([]byte(`{"id": 2, "name": "Jane"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message": "bad"}`))
+		}
+	}))
+	defer upstreamServer.Close()
+
+	user := &schema.Response{
+		SuccessCode: 200,
+		All: map[int]*schema.ResponseItem{
+			200: {ContentType: "application/json", Content: &schema.Schema{
+				Type:     "object",
+				Required: []string{"id", "name"},
+				Properties: map[string]*schema.Schema{
+					"id":   {Type: "integer"},
+					"name": {Type: "string"},
+				},
+			}},
+		},
+	}
+	validator := func(method, path string, statusCode int, contentType string, body []byte) (string, []*schema.ValidationError) {
+		if method != http.MethodGet {
+			return "", nil
+		}
+		return "/users/{id}", schema.ValidateResponse(user, statusCode, contentType, body)
+	}
+
+	newParams := func() *Params {
+		params := newTestParams(&config.ServiceConfig{
+			Name:     "test",
+			Upstream: &config.UpstreamConfig{URL: upstreamServer.URL},
+		}, nil)
+		params.SetResponseValidator(validator)
+		return params
+	}
+
+	serve := func(params *Params, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		CreateUpstreamRequestMiddleware(params)(handler).ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	t.Run("flags violations and records them", func(t *testing.T) {
+		params := newParams()
+
+		w := serve(params, http.MethodGet, "/test/users/1")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(`{"id": "1"}`, w.Body.String())
+		assert.Equal("2", w.Header().Get(ResponseHeaderContractViolations))
+
+		w = serve(params, http.MethodGet, "/test/users/1")
+		assert.Equal("2", w.Header().Get(ResponseHeaderContractViolations))
+
+		var entries []*db.HistoryEntry
+		assert.Eventually(func() bool {
+			entries = params.DB().History().Data(context.Background())
+			return len(entries) == 2
+		}, time.Second, 10*time.Millisecond)
+		assert.ElementsMatch([]*db.Violation{
+			{Field: "$.id", Message: "expected integer, got string"},
+			{Field: "$.name", Message: "required property is missing"},
+		}, entries[0].Response.Violations)
+
+		var report []*DriftOperation
+		assert.Eventually(func() bool {
+			report = GetDriftReport(context.Background(), params.DB().Table(DriftTable))
+			return len(report) == 1 && report[0].Count == 2
+		}, time.Second, 10*time.Millisecond)
+
+		op := report[0]
+		assert.Equal(http.MethodGet, op.Method)
+		assert.Equal("/users/{id}", op.Path)
+		assert.False(op.FirstSeen.IsZero())
+		assert.False(op.LastSeen.Before(op.FirstSeen))
+		assert.Len(op.Fields, 2)
+		assert.Equal("$.id", op.Fields[0].Field)
+		assert.Equal("expected integer, got string", op.Fields[0].Message)
+		assert.Equal(2, op.Fields[0].Count)
+		assert.Equal("$.name", op.Fields[1].Field)
+	})
+
+	t.Run("conforming responses are not flagged", func(t *testing.T) {
+		params := newParams()
+
+		w := serve(params, http.MethodGet, "/test/users/2")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Empty(w.Header().Get(ResponseHeaderContractViolations))
+
+		waitForAsync()
+		assert.Empty(GetDriftReport(context.Background(), params.DB().Table(DriftTable)))
+	})
+
+	t.Run("forwarded error responses are validated", func(t *testing.T) {
+		params := newParams()
+
+		w := serve(params, http.MethodGet, "/test/users/3")
+		assert.Equal(http.StatusBadRequest, w.Code)
+		assert.Equal("1", w.Header().Get(ResponseHeaderContractViolations))
+
+		assert.Eventually(func() bool {
+			report := GetDriftReport(context.Background(), params.DB().Table(DriftTable))
+			return len(report) == 1 && report[0].Fields[0].Field == "status"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("unmatched operations are not validated", func(t *testing.T) {
+		params := newParams()
+
+		w := serve(params, http.MethodPost, "/test/users/1")
+		assert.Empty(w.Header().Get(ResponseHeaderContractViolations))
+	})
+
+	t.Run("without validator", func(t *testing.T) {
+		params := newTestParams(&config.ServiceConfig{
+			Name:     "test",
+			Upstream: &config.UpstreamConfig{URL: upstreamServer.URL},
+		}, nil)
+
+		w := serve(params, http.MethodGet, "/test/users/1")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Empty(w.Header().Get(ResponseHeaderContractViolations))
+	})
+}
+
+func TestRecordDrift(t *testing.T) {
+	first := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	violations := []*db.Violation{
+		{Field: "$.name", Message: "required property is missing"},
+		{Field: "$.items[].id", Message: "expected integer, got string"},
+		{Field: "$.items[].id", Message: "expected integer, got string"},
+	}
+
+	tables := map[string]func(t *testing.T) db.Table{
+		"memory": func(t *testing.T) db.Table {
+			return db.NewStorage(nil).NewDB("test", time.Minute).Table(DriftTable)
+		},
+		"redis": func(t *testing.T) db.Table {
+			mr := miniredis.RunT(t)
+			storage := db.NewStorage(&config.StorageConfig{
+				Type:  config.StorageTypeRedis,
+				Redis: &config.RedisConfig{Address: mr.Addr()},
+			})
+			return storage.NewDB("test", time.Minute).Table(DriftTable)
+		},
+	}
+
+	for name, newTable := range tables {
+		t.Run(name+" aggregates responses", func(t *testing.T) {
+			ctx := context.Background()
+			table := newTable(t)
+
+			RecordDrift(ctx, table, http.MethodGet, "/users/{id}", violations, first.Add(time.Minute))
+			RecordDrift(ctx, table, http.MethodGet, "/users/{id}", violations[:1], first)
+			RecordDrift(ctx, table, http.MethodPost, "/users", violations[1:], first)
+
+			report := GetDriftReport(ctx, table)
+			require.Len(t, report, 2)
+
+			op := report[0]
+			assert2.Equal(t, http.MethodPost, op.Method)
+			assert2.Equal(t, "/users", op.Path)
+			assert2.Equal(t, 1, op.Count)
+			require.Len(t, op.Fields, 1)
+			assert2.Equal(t, 1, op.Fields[0].Count)
+
+			op = report[1]
+			assert2.Equal(t, http.MethodGet, op.Method)
+			assert2.Equal(t, 2, op.Count)
+			assert2.Equal(t, first.Add(time.Minute), op.FirstSeen)
+			assert2.Equal(t, first.Add(time.Minute), op.LastSeen)
+			require.Len(t, op.Fields, 2)
+			assert2.Equal(t, &DriftField{
+				Field:     "$.items[].id",
+				Message:   "expected integer, got string",
+				Count:     1,
+				FirstSeen: first.Add(time.Minute),
+				LastSeen:  first.Add(time.Minute),
+			}, op.Fields[0])
+			assert2.Equal(t, "$.name", op.Fields[1].Field)
+			assert2.Equal(t, 2, op.Fields[1].Count)
+		})
+
+		t.Run(name+" keeps every count under concurrency", func(t *testing.T) {
+			ctx := context.Background()
+			table := newTable(t)
+
+			var wg sync.WaitGroup
+			for i := range 20 {
+				wg.Go(func() {
+					RecordDrift(ctx, table, http.MethodGet, "/users/{id}", violations, first.Add(time.Duration(i)*time.Second))
+				})
+			}
+			wg.Wait()
+
+			report := GetDriftReport(ctx, table)
+			require.Len(t, report, 1)
+			assert2.Equal(t, 20, report[0].Count)
+			assert2.Equal(t, first.Add(19*time.Second), report[0].LastSeen)
+			for _, f := range report[0].Fields {
+				assert2.Equal(t, 20, f.Count, f.Field)
+			}
+		})
+	}
+}
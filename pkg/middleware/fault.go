@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+)
+
+// headerFault injects a transport fault into a single request, e.g.
+//
+//	X-Cxs-Fault: reset
+//	X-Cxs-Fault: slow-headers:10s
+const headerFault = "X-Cxs-Fault"
+
+const faultKey ctxKey = "fault"
+
+// GetFault returns the transport fault injected into the response of the request, if any.
+func GetFault(req *http.Request) string {
+	if v, ok := req.Context().Value(faultKey).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// CreateFaultMiddleware injects transport faults from the X-Cxs-Fault header
+// or, without it, from the fault percentiles of the endpoint or service.
+// Faults without a response are recorded in the history here,
+// faults damaging a response are recorded with the response further down the chain.
+func CreateFaultMiddleware(params *Params) func(http.Handler) http.Handler {
+	log := params.Logger("fault")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			reqLog := RequestLog(log, req)
+			cfg := params.GetServiceConfig(req)
+
+			spec := req.Header.Get(headerFault)
+			if spec == "" {
+				spec = cfg.GetFault()
+			}
+			if spec == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			fault, duration, err := config.ParseFault(spec)
+			if err != nil {
+				reqLog.Warn("Ignoring fault", "error", err)
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			reqLog.Info("Injecting fault", "fault", fault)
+			req = req.WithContext(context.WithValue(req.Context(), faultKey, fault))
+
+			switch fault {
+			case config.FaultReset, config.FaultEmpty:
+				recordFault(params, cfg, req, fault)
+				closeConnection(w, fault == config.FaultReset)
+				return
+			case config.FaultHang:
+				recordFault(params, cfg, req, fault)
+				if duration > 0 {
+					sleepContext(req.Context(), duration)
+				} else {
+					<-req.Context().Done()
+				}
+				closeConnection(w, false)
+				return
+			}
+
+			rw := &responseWriter{
+				ResponseWriter: w,
+				body:           new(bytes.Buffer),
+				statusCode:     http.StatusOK,
+			}
+			next.ServeHTTP(rw, req)
+
+			switch fault {
+			case config.FaultTruncate:
+				writeTruncated(w, rw)
+			case config.FaultMalformed:
+				writeMalformed(w, rw)
+			case config.FaultSlowHeaders:
+				if duration <= 0 {
+					duration = config.DefaultSlowHeadersDelay
+				}
+				writeSlowHeaders(req.Context(), w, rw, duration)
+			}
+		})
+	}
+}
+
+// recordFault saves a request that got no response because of a fault in the history.
+func recordFault(params *Params, cfg *config.ServiceConfig, req *http.Request, fault string) {
+	if !cfg.HistoryEnabled() {
+		return
+	}
+
+	histReq := &db.HistoryRequest{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Body:       readAndRestoreBody(req),
+		Headers:    db.FlattenHeaders(req.Header),
+		RemoteAddr: req.RemoteAddr,
+		RequestID:  GetRequestID(req),
+	}
+	histResp := &db.HistoryResponse{
+		Duration: GetDuration(req),
+		Latency:  GetLatency(req),
+		Fault:    fault,
+	}
+	params.transformHistory(cfg, histReq, histResp)
+	resourcePath := GetResourcePath(req)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), asyncWriteTimeout)
+		defer cancel()
+		params.DB().History().Set(ctx, resourcePath, histReq, histResp)
+	}()
+}
+
+// closeConnection closes the client connection without a response, resetting it if reset is set.
+// Connections that can't be hijacked, e.g. HTTP/2 streams, are aborted.
+func closeConnection(w http.ResponseWriter, reset bool) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	if reset {
+		raw := conn
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			raw = tlsConn.NetConn()
+		}
+		if tcpConn, ok := raw.(*net.TCPConn); ok {
+			// Discard unsent data and send RST instead of FIN
+			_ = tcpConn.SetLinger(0)
+		}
+		_ = raw.Close()
+		return
+	}
+	_ = conn.Close()
+}
+
+// writeTruncated declares the full body length but closes the connection halfway through the body.
+func writeTruncated(w http.ResponseWriter, rw *responseWriter) {
+	body := rw.body.Bytes()
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(rw.statusCode)
+	_, _ = w.Write(body[:len(body)/2])
+	_ = http.NewResponseController(w).Flush()
+	closeConnection(w, false)
+}
+
+// writeMalformed writes the first half of the body, which is no longer valid JSON.
+func writeMalformed(w http.ResponseWriter, rw *responseWriter) {
+	body := rw.body.Bytes()
+	malformed := []byte("{")
+	if len(body) > 1 {
+		malformed = body[:len(body)/2]
+	}
+
+	w.Header().Del("Content-Length")
+	w.WriteHeader(rw.statusCode)
+	_, _ = w.Write(malformed)
+}
+
+// writeSlowHeaders writes the status line and headers one line at a time, spread over delay.
+// Connections that can't be hijacked, e.g. HTTP/2 streams, get all headers after the delay.
+func writeSlowHeaders(ctx context.Context, w http.ResponseWriter, rw *responseWriter, delay time.Duration) {
+	conn, buf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		if sleepContext(ctx, delay) {
+			w.WriteHeader(rw.statusCode)
+			_, _ = w.Write(rw.body.Bytes())
+		}
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	resp := &http.Response{
+		StatusCode:    rw.statusCode,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.Header(),
+		ContentLength: int64(rw.body.Len()),
+		Body:          io.NopCloser(rw.body),
+		Close:         true,
+	}
+	var raw bytes.Buffer
+	if err := resp.Write(&raw); err != nil {
+		return
+	}
+
+	data := raw.Bytes()
+	headerEnd := bytes.Index(data, []byte("\r\n\r\n")) + 4
+	lines := bytes.SplitAfter(data[:headerEnd], []byte("\r\n"))
+	lines = lines[:len(lines)-1]
+	body := data[headerEnd:]
+	pause := delay / time.Duration(len(lines))
+	for _, line := range lines {
+		if !sleepContext(ctx, pause) {
+			return
+		}
+		if _, err := buf.Write(line); err != nil || buf.Flush() != nil {
+			return
+		}
+	}
+	_, _ = buf.Write(body)
+	_ = buf.Flush()
+}
+
+// sleepContext waits for d, returning false if the context is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateFaultMiddleware(t *testing.T) {
+	body := `{"id":1,"name":"Fluffy","tags":["cat","small"]}`
+
+	serve := func(t *testing.T, cfg *config.ServiceConfig) (*httptest.Server, *Params) {
+		t.Helper()
+		if cfg == nil {
+			cfg = config.NewServiceConfig()
+		}
+		cfg.Name = "test"
+		params := newTestParams(cfg.WithDefaults(), nil)
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		})
+		chain := CreateFaultMiddleware(params)(CreateCacheWriteMiddleware(params)(handler))
+
+		server := httptest.NewServer(chain)
+		t.Cleanup(server.Close)
+		return server, params
+	}
+
+	get := func(t *testing.T, server *httptest.Server, fault string) (*http.Response, []byte, error) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/test/pets", nil)
+		require.NoError(t, err)
+		if fault != "" {
+			req.Header.Set(headerFault, fault)
+		}
+
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, err := io.ReadAll(resp.Body)
+		return resp, data, err
+	}
+
+	t.Run("no fault", func(t *testing.T) {
+		server, _ := serve(t, nil)
+		resp, data, err := get(t, server, "")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, body, string(data))
+	})
+
+	t.Run("unknown fault is ignored", func(t *testing.T) {
+		server, _ := serve(t, nil)
+		resp, _, err := get(t, server, "explode")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		server, params := serve(t, nil)
+		_, _, err := get(t, server, "reset")
+		assert.Error(t, err)
+
+		waitForAsync()
+		entries := params.DB().History().Data(context.Background())
+		require.Len(t, entries, 1)
+		assert.Equal(t, config.FaultReset, entries[0].Response.Fault)
+		assert.Zero(t, entries[0].Response.StatusCode)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		server, _ := serve(t, nil)
+		_, _, err := get(t, server, "empty")
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		server, params := serve(t, nil)
+		resp, data, err := get(t, server, "truncate")
+		require.NotNil(t, resp)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, body[:len(body)/2], string(data))
+
+		waitForAsync()
+		entries := params.DB().History().Data(context.Background())
+		require.Len(t, entries, 1)
+		assert.Equal(t, config.FaultTruncate, entries[0].Response.Fault)
+		assert.Equal(t, body, string(entries[0].Response.Body))
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		server, _ := serve(t, nil)
+		resp, data, err := get(t, server, "malformed")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.False(t, json.Valid(data))
+	})
+
+	t.Run("hang", func(t *testing.T) {
+		server, _ := serve(t, nil)
+		start := time.Now()
+		_, _, err := get(t, server, "hang:50ms")
+		assert.Error(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("slow headers", func(t *testing.T) {
+		server, _ := serve(t, nil)
+		start := time.Now()
+		resp, data, err := get(t, server, "slow-headers:100ms")
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.Equal(t, body, string(data))
+	})
+
+	t.Run("configured faults", func(t *testing.T) {
+		cfg := config.NewServiceConfig()
+		cfg.Faults = map[string]string{"p100": config.FaultEmpty}
+		server, _ := serve(t, cfg)
+
+		_, _, err := get(t, server, "")
+		assert.Error(t, err)
+
+		// The header takes precedence
+		resp, _, err := get(t, server, "slow-headers:1ms")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
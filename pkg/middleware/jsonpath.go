@@ -21,6 +21,7 @@ type pathSegment struct {
 //   - Array index: "data.items[0].name" - specific element
 //   - Array wildcard: "data.items.name" - when items is an array, search each element
 //   - Top-level array: "[0].name" - index into a root-level array
+//   - Root: "$.data.name" - same as "data.name"
 func extractJSONPath(data []byte, path string) any {
 	var parsed any
 	if err := json.Unmarshal(data, &parsed); err != nil {
@@ -35,6 +36,9 @@ func extractJSONPath(data []byte, path string) any {
 // "data.items[0].name" → [{key:"data"}, {key:"items", index:0, isArr:true}, {key:"name"}]
 // "[0].name"           → [{key:"", index:0, isArr:true}, {key:"name"}]
 func parseDottedPath(path string) []pathSegment {
+	if path == "$" || strings.HasPrefix(path, "$.") || strings.HasPrefix(path, "$[") {
+		path = path[1:]
+	}
 	parts := strings.Split(path, ".")
 	segments := make([]pathSegment, 0, len(parts))
 
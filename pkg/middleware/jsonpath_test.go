@@ -31,6 +31,7 @@ func TestExtractJSONPath(t *testing.T) {
 		expected any
 	}{
 		{"simple nested", "data.name", "Jane"},
+		{"root prefix", "$.data.address.zip", "12345"},
 		{"deep nested", "data.address.zip", "12345"},
 		{"array index 0", "data.items[0].label", "first"},
 		{"array index 1", "data.items[1].id", float64(2)},
@@ -1,21 +1,47 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"sync"
 	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/db"
 )
 
+const latencyKey ctxKey = "latency"
+
+// latencySamplesRefresh is how often the upstream durations of empirical latencies are reloaded from the history.
+const latencySamplesRefresh = 10 * time.Second
+
+// GetLatency returns the latency simulated for the request, if any.
+func GetLatency(req *http.Request) time.Duration {
+	if v, ok := req.Context().Value(latencyKey).(time.Duration); ok {
+		return v
+	}
+	return 0
+}
+
 func CreateLatencyAndErrorMiddleware(params *Params) func(http.Handler) http.Handler {
 	log := params.Logger("latency-error")
+	samples := &latencySamples{params: params}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			reqLog := RequestLog(log, req)
 			cfg := params.GetServiceConfig(req)
-			latency := cfg.GetLatency()
+
+			var latency time.Duration
+			if dist := cfg.LatencyDistribution; dist.IsEmpirical() {
+				latency = dist.Sample(samples.get(req.Context(), GetResourcePath(req), req.Method, dist.GetSamples()))
+			}
+			if latency == 0 {
+				latency = cfg.GetLatency()
+			}
 			if latency > 0 {
 				reqLog.Info("Latency", "delay", latency)
 				time.Sleep(latency)
+				req = req.WithContext(context.WithValue(req.Context(), latencyKey, latency))
 			}
 
 			errorCode := cfg.GetError()
@@ -24,7 +50,9 @@ func CreateLatencyAndErrorMiddleware(params *Params) func(http.Handler) http.Han
 				SetRequestIDHeader(w, req)
 				SetDurationHeader(w, req)
 				w.Header().Set(ResponseHeaderSource, ResponseHeaderSourceGenerated)
-				http.Error(w, "Simulated error", errorCode)
+				if params.errorWriter == nil || !params.errorWriter(w, req, errorCode) {
+					http.Error(w, "Simulated error", errorCode)
+				}
 				return
 			}
 
@@ -32,3 +60,71 @@ func CreateLatencyAndErrorMiddleware(params *Params) func(http.Handler) http.Han
 		})
 	}
 }
+
+// latencySamples keeps the durations of the upstream responses recorded in the history,
+// without the latency simulated on top of them, oldest first.
+// The history is read outside the lock: one request reloads it while the others keep using the current samples.
+type latencySamples struct {
+	params *Params
+
+	mu         sync.Mutex
+	loading    bool
+	loadedAt   time.Time
+	byEndpoint map[string][]time.Duration
+	all        []time.Duration
+}
+
+// get returns the last n durations of the endpoint, or of the whole service if the endpoint has none.
+func (s *latencySamples) get(ctx context.Context, resourcePath, method string, n int) []time.Duration {
+	s.mu.Lock()
+	reload := !s.loading && time.Since(s.loadedAt) >= latencySamplesRefresh
+	if reload {
+		s.loading = true
+	}
+	s.mu.Unlock()
+
+	if reload {
+		byEndpoint, all := s.load(ctx)
+
+		s.mu.Lock()
+		s.byEndpoint, s.all = byEndpoint, all
+		s.loadedAt = time.Now()
+		s.loading = false
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := s.byEndpoint[method+" "+resourcePath]
+	if len(res) == 0 {
+		res = s.all
+	}
+	return res[max(len(res)-n, 0):]
+}
+
+// load reads the upstream durations from the history, by endpoint and for the whole service.
+func (s *latencySamples) load(ctx context.Context) (map[string][]time.Duration, []time.Duration) {
+	byEndpoint := make(map[string][]time.Duration)
+	var all []time.Duration
+
+	for _, entry := range s.params.DB().History().Data(ctx) {
+		if !isUpstreamTiming(entry) {
+			continue
+		}
+		d := entry.Response.Duration - entry.Response.Latency
+		if d <= 0 {
+			continue
+		}
+		key := entry.Request.Method + " " + entry.Resource
+		byEndpoint[key] = append(byEndpoint[key], d)
+		all = append(all, d)
+	}
+	return byEndpoint, all
+}
+
+// isUpstreamTiming returns whether the entry holds the duration of a complete upstream response.
+func isUpstreamTiming(entry *db.HistoryEntry) bool {
+	return entry.Request != nil && entry.Response != nil &&
+		entry.Response.IsFromUpstream && entry.Response.StatusCode > 0 && entry.Response.Fault == ""
+}
@@ -2,14 +2,17 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
 	assert2 "github.com/stretchr/testify/assert"
 )
 
@@ -133,4 +136,169 @@ errors:
 		assert.Equal(http.StatusServiceUnavailable, w.statusCode)
 		assert.True(strings.Contains(string(w.buf), "Simulated error"), "Expected error message")
 	})
+
+	t.Run("with error writer", func(t *testing.T) {
+		cfg, _ := config.NewServiceConfigFromBytes([]byte(`
+errors:
+  p100: 400
+`))
+		params := newTestParams(cfg, nil)
+
+		var gotCode int
+		params.SetErrorWriter(func(w http.ResponseWriter, req *http.Request, statusCode int) bool {
+			gotCode = statusCode
+			w.WriteHeader(statusCode)
+			_, _ = w.Write([]byte("<Fault/>"))
+			return true
+		})
+
+		w := NewBufferedResponseWriter()
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		CreateLatencyAndErrorMiddleware(params)(handler).ServeHTTP(w, req)
+
+		assert.Equal(http.StatusBadRequest, gotCode)
+		assert.Equal(http.StatusBadRequest, w.statusCode)
+		assert.Equal("<Fault/>", string(w.buf))
+		assert.Equal(ResponseHeaderSourceGenerated, w.header.Get(ResponseHeaderSource))
+	})
+
+	t.Run("error writer falls back", func(t *testing.T) {
+		cfg, _ := config.NewServiceConfigFromBytes([]byte(`
+errors:
+  p100: 500
+`))
+		params := newTestParams(cfg, nil)
+		params.SetErrorWriter(func(http.ResponseWriter, *http.Request, int) bool {
+			return false
+		})
+
+		w := NewBufferedResponseWriter()
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		CreateLatencyAndErrorMiddleware(params)(handler).ServeHTTP(w, req)
+
+		assert.Equal(http.StatusInternalServerError, w.statusCode)
+		assert.Contains(string(w.buf), "Simulated error")
+	})
+
+	t.Run("with latency distribution", func(t *testing.T) {
+		cfg, _ := config.NewServiceConfigFromBytes([]byte(`
+latency-distribution:
+  type: uniform
+  min: 20ms
+  max: 30ms
+`))
+		params := newTestParams(cfg, nil)
+
+		var got time.Duration
+		w := NewBufferedResponseWriter()
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		CreateLatencyAndErrorMiddleware(params)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = GetLatency(r)
+		})).ServeHTTP(w, req)
+
+		assert.GreaterOrEqual(got, 20*time.Millisecond)
+		assert.LessOrEqual(got, 30*time.Millisecond)
+	})
+}
+
+func TestCreateLatencyAndErrorMiddleware_Empirical(t *testing.T) {
+	assert := assert2.New(t)
+
+	cfg, err := config.NewServiceConfigFromBytes([]byte(`
+latency: 1ms
+latency-distribution:
+  type: empirical
+`))
+	assert.NoError(err)
+	cfg.Name = "test"
+
+	serve := func(params *Params, method, resourcePath string) time.Duration {
+		var got time.Duration
+		req := httptest.NewRequest(method, "/test", nil)
+		req = req.WithContext(context.WithValue(req.Context(), resourcePathKey, resourcePath))
+		CreateLatencyAndErrorMiddleware(params)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = GetLatency(r)
+		})).ServeHTTP(NewBufferedResponseWriter(), req)
+		return got
+	}
+
+	t.Run("falls back without upstream responses", func(t *testing.T) {
+		params := newTestParams(cfg, nil)
+		assert.Equal(time.Millisecond, serve(params, http.MethodGet, "/pets/{id}"))
+	})
+
+	t.Run("replays upstream durations", func(t *testing.T) {
+		params := newTestParams(cfg, nil)
+		history := params.DB().History()
+		ctx := context.Background()
+		history.Set(ctx, "/pets/{id}", &db.HistoryRequest{Method: http.MethodGet, URL: "/pets/1"}, &db.HistoryResponse{
+			StatusCode:     http.StatusOK,
+			IsFromUpstream: true,
+			Duration:       25 * time.Millisecond,
+			Latency:        5 * time.Millisecond,
+		})
+		history.Set(ctx, "/orders", &db.HistoryRequest{Method: http.MethodPost, URL: "/orders"}, &db.HistoryResponse{
+			StatusCode:     http.StatusCreated,
+			IsFromUpstream: true,
+			Duration:       30 * time.Millisecond,
+		})
+		// Generated responses and faults don't count
+		history.Set(ctx, "/pets/{id}", &db.HistoryRequest{Method: http.MethodGet, URL: "/pets/2"}, &db.HistoryResponse{
+			StatusCode: http.StatusOK,
+			Duration:   time.Second,
+		})
+		history.Set(ctx, "/pets/{id}", &db.HistoryRequest{Method: http.MethodGet, URL: "/pets/3"}, &db.HistoryResponse{
+			IsFromUpstream: true,
+			Duration:       time.Second,
+			Fault:          config.FaultReset,
+		})
+
+		assert.Equal(20*time.Millisecond, serve(params, http.MethodGet, "/pets/{id}"))
+		assert.Equal(30*time.Millisecond, serve(params, http.MethodPost, "/orders"))
+
+		// Endpoints without upstream responses draw from the whole service
+		got := serve(params, http.MethodDelete, "/pets/{id}")
+		assert.Contains([]time.Duration{20 * time.Millisecond, 30 * time.Millisecond}, got)
+	})
+}
+
+func TestLatencySamples(t *testing.T) {
+	assert := assert2.New(t)
+
+	cfg := config.NewServiceConfig()
+	cfg.Name = "test"
+	ctx := context.Background()
+
+	t.Run("keeps current samples while another request reloads", func(t *testing.T) {
+		params := newTestParams(cfg, nil)
+		params.DB().History().Set(ctx, "/pets", &db.HistoryRequest{Method: http.MethodGet, URL: "/pets"}, &db.HistoryResponse{
+			StatusCode:     http.StatusOK,
+			IsFromUpstream: true,
+			Duration:       40 * time.Millisecond,
+		})
+
+		samples := &latencySamples{params: params, loading: true, all: []time.Duration{10 * time.Millisecond}}
+		assert.Equal([]time.Duration{10 * time.Millisecond}, samples.get(ctx, "/pets", http.MethodGet, 5))
+
+		samples.loading = false
+		assert.Equal([]time.Duration{40 * time.Millisecond}, samples.get(ctx, "/pets", http.MethodGet, 5))
+		assert.False(samples.loading)
+		assert.False(samples.loadedAt.IsZero())
+	})
+
+	t.Run("concurrent requests", func(t *testing.T) {
+		params := newTestParams(cfg, nil)
+		samples := &latencySamples{params: params}
+
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				samples.get(ctx, "/pets", http.MethodGet, 5)
+			}()
+		}
+		wg.Wait()
+		assert.False(samples.loading)
+	})
 }
@@ -23,6 +23,8 @@ const (
 	ResponseHeaderSourceCache     = "cache"
 	ResponseHeaderSourceGenerated = "generated"
 	ResponseHeaderSourceReplay    = "replay"
+	ResponseHeaderSourceScenario  = "scenario"
+	ResponseHeaderSourceRule      = "rule"
 )
 
 const serviceConfigKey ctxKey = "serviceConfig"
@@ -32,14 +34,22 @@ const serviceConfigKey ctxKey = "serviceConfig"
 // in place (e.g. masking sensitive headers or redacting body fields).
 type HistoryTransformFunc func(req *db.HistoryRequest, resp *db.HistoryResponse)
 
+// ErrorWriterFunc writes the response for a simulated error, e.g. as a SOAP Fault.
+// It returns false to fall back to the default plain text response.
+type ErrorWriterFunc func(w http.ResponseWriter, req *http.Request, statusCode int) bool
+
 // Params provides access to service configuration and database for middleware.
 type Params struct {
-	serviceConfig    *config.ServiceConfig
-	storageConfig    *config.StorageConfig
-	database         db.DB
-	log              *slog.Logger
-	router           chi.Routes
-	historyTransform HistoryTransformFunc
+	serviceConfig     *config.ServiceConfig
+	storageConfig     *config.StorageConfig
+	database          db.DB
+	log               *slog.Logger
+	router            chi.Routes
+	historyTransform  HistoryTransformFunc
+	errorWriter       ErrorWriterFunc
+	responseValidator ResponseValidatorFunc
+	requestChecker    RequestCheckerFunc
+	runtime           runtimeConfig
 }
 
 // NewParams creates a new Params instance with the given configuration and database.
@@ -60,6 +70,24 @@ func (p *Params) SetHistoryTransform(fn HistoryTransformFunc) {
 	p.historyTransform = fn
 }
 
+// SetErrorWriter registers a callback that writes simulated error responses.
+// Must be called during setup, before the server starts serving requests.
+func (p *Params) SetErrorWriter(fn ErrorWriterFunc) {
+	p.errorWriter = fn
+}
+
+// SetResponseValidator registers a callback that validates upstream responses against the spec.
+// Must be called during setup, before the middleware is created.
+func (p *Params) SetResponseValidator(fn ResponseValidatorFunc) {
+	p.responseValidator = fn
+}
+
+// SetRequestChecker registers a callback that checks requests against the spec before they're served.
+// Must be called during setup, before the middleware is created.
+func (p *Params) SetRequestChecker(fn RequestCheckerFunc) {
+	p.requestChecker = fn
+}
+
 // transformHistory applies the user callback (if set) and then masks headers
 // listed in the service config's MaskHeaders field.
 func (p *Params) transformHistory(svcCfg *config.ServiceConfig, req *db.HistoryRequest, resp *db.HistoryResponse) {
@@ -75,12 +103,13 @@ func (p *Params) transformHistory(svcCfg *config.ServiceConfig, req *db.HistoryR
 }
 
 // GetServiceConfig returns the per-request service config from the context if set
-// by the config override middleware, otherwise falls back to the shared config.
+// by the config override middleware, otherwise falls back to the shared config
+// with the runtime changes applied.
 func (p *Params) GetServiceConfig(req *http.Request) *config.ServiceConfig {
 	if cfg, ok := req.Context().Value(serviceConfigKey).(*config.ServiceConfig); ok {
 		return cfg
 	}
-	return p.serviceConfig
+	return p.ServiceConfig(req.Context())
 }
 
 // SetRouter stores the router for resource path resolution at request time.
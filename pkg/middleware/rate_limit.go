@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
+)
+
+// rateLimitResult is the outcome of counting a request against a limit.
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// CreateRateLimitMiddleware rejects requests over the configured rate limit with 429.
+// Limited responses get the X-RateLimit-* and RateLimit-* quota headers, rejected ones also Retry-After.
+// Counters are kept in the rate-limit table of the service database.
+func CreateRateLimitMiddleware(params *Params) func(http.Handler) http.Handler {
+	log := params.Logger("rate-limit")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			cfg := params.GetServiceConfig(req)
+			limit, scope := cfg.RateLimit.GetLimit(getEndpointPath(req, cfg.Name), req.Method)
+			if limit == nil {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			if scope == "" {
+				scope = "*"
+			}
+			key := scope + "|" + rateLimitClient(req, limit.Key)
+			now := time.Now()
+			res := countRequest(req.Context(), params.DB().Table("rate-limit"), key, limit, now)
+			setRateLimitHeaders(w.Header(), limit, res, now)
+
+			if res.allowed {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			RequestLog(log, req).Info("Rate limit exceeded", "key", key, "limit", limit.Limit, "window", limit.Window)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.retryAfter, 1)))
+			SetRequestIDHeader(w, req)
+			SetDurationHeader(w, req)
+			w.Header().Set(ResponseHeaderSource, ResponseHeaderSourceGenerated)
+			if params.errorWriter == nil || !params.errorWriter(w, req, http.StatusTooManyRequests) {
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			}
+		})
+	}
+}
+
+// countRequest counts a request against the limit with its algorithm.
+// Unknown algorithms count in fixed windows.
+func countRequest(ctx context.Context, table db.Table, key string, limit *config.RateLimit, now time.Time) rateLimitResult {
+	switch limit.Algorithm {
+	case config.RateLimitSlidingWindow:
+		return countSlidingWindow(ctx, table, key, limit, now)
+	case config.RateLimitTokenBucket:
+		return countTokenBucket(ctx, table, key, limit, now)
+	default:
+		return countFixedWindow(ctx, table, key, limit, now)
+	}
+}
+
+// countFixedWindow allows Limit requests in each window, counters reset at the window boundaries.
+func countFixedWindow(ctx context.Context, table db.Table, key string, limit *config.RateLimit, now time.Time) rateLimitResult {
+	window := now.UnixNano() / int64(limit.Window)
+	reset := time.Unix(0, (window+1)*int64(limit.Window)).Sub(now)
+
+	count := table.Incr(ctx, fmt.Sprintf("%s|%d", key, window), 1, limit.Window)
+	if count > int64(limit.Limit) {
+		return rateLimitResult{reset: reset, retryAfter: reset}
+	}
+	return rateLimitResult{allowed: true, remaining: limit.Limit - int(count), reset: reset}
+}
+
+// countSlidingWindow weights the count of the previous window by how much of it still overlaps
+// the window ending now, smoothing the bursts fixed windows allow at their boundaries.
+// Rejected requests aren't counted.
+func countSlidingWindow(ctx context.Context, table db.Table, key string, limit *config.RateLimit, now time.Time) rateLimitResult {
+	window := now.UnixNano() / int64(limit.Window)
+	start := time.Unix(0, window*int64(limit.Window))
+	reset := start.Add(limit.Window).Sub(now)
+	overlap := 1 - float64(now.Sub(start))/float64(limit.Window)
+
+	currentKey := fmt.Sprintf("%s|%d", key, window)
+	previous := table.Incr(ctx, fmt.Sprintf("%s|%d", key, window-1), 0, 2*limit.Window)
+	count := table.Incr(ctx, currentKey, 1, 2*limit.Window)
+
+	used := int(math.Ceil(float64(previous)*overlap)) + int(count)
+	if used > limit.Limit {
+		table.Incr(ctx, currentKey, -1, 2*limit.Window)
+		return rateLimitResult{reset: reset, retryAfter: reset}
+	}
+	return rateLimitResult{allowed: true, remaining: limit.Limit - used, reset: reset}
+}
+
+// tokenBucketAttempts bounds the retries of a token bucket update losing races to other requests.
+// The request is allowed when they're exhausted, as it is when the database is unavailable.
+const tokenBucketAttempts = 10
+
+// countTokenBucket refills Limit tokens per window into a bucket of Burst tokens, one per request.
+// It's implemented as the generic cell rate algorithm: the stored value is the time,
+// in Unix nanoseconds, at which the bucket is full again.
+// The time is moved forward with a compare-and-swap, so concurrent requests can't both take the same token.
+func countTokenBucket(ctx context.Context, table db.Table, key string, limit *config.RateLimit, now time.Time) rateLimitResult {
+	interval := max(int64(limit.Window)/int64(limit.Limit), 1)
+	capacity := int64(limit.Burst) * interval
+	ttl := time.Duration(capacity + interval)
+	nowNano := now.UnixNano()
+
+	stored := table.Incr(ctx, key, 0, 0)
+	for range tokenBucketAttempts {
+		// A bucket full before now is just full
+		full := max(stored, nowNano) + interval
+		if full-nowNano > capacity {
+			return rateLimitResult{
+				reset:      time.Duration(full - interval - nowNano),
+				retryAfter: time.Duration(full - nowNano - capacity),
+			}
+		}
+
+		current, swapped := table.CompareAndSwap(ctx, key, stored, full, ttl)
+		if swapped {
+			return rateLimitResult{
+				allowed:   true,
+				remaining: int((capacity - (full - nowNano)) / interval),
+				reset:     time.Duration(full - nowNano),
+			}
+		}
+		stored = current
+	}
+	return rateLimitResult{allowed: true, reset: time.Duration(capacity)}
+}
+
+// setRateLimitHeaders sets the quota headers in both the X-RateLimit-* form, with the reset
+// as Unix time, and the RateLimit-* form of the IETF draft, with the reset in seconds.
+func setRateLimitHeaders(header http.Header, limit *config.RateLimit, res rateLimitResult, now time.Time) {
+	quota := limit.Limit
+	policy := fmt.Sprintf("%d;w=%d", limit.Limit, ceilSeconds(limit.Window, 1))
+	if limit.Algorithm == config.RateLimitTokenBucket {
+		quota = limit.Burst
+		policy += fmt.Sprintf(";burst=%d", limit.Burst)
+	}
+
+	reset := ceilSeconds(res.reset, 0)
+	header.Set("X-RateLimit-Limit", strconv.Itoa(quota))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(res.remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(now.Unix()+int64(reset), 10))
+	header.Set("RateLimit-Limit", strconv.Itoa(quota))
+	header.Set("RateLimit-Remaining", strconv.Itoa(res.remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(reset))
+	header.Set("RateLimit-Policy", policy)
+}
+
+// rateLimitClient identifies the client of a request by the configured key,
+// falling back to the IP when the header or claim is missing.
+func rateLimitClient(req *http.Request, key string) string {
+	switch {
+	case strings.HasPrefix(key, config.RateLimitKeyHeader):
+		if value := req.Header.Get(strings.TrimPrefix(key, config.RateLimitKeyHeader)); value != "" {
+			return "header:" + value
+		}
+	case strings.HasPrefix(key, config.RateLimitKeyClaim):
+		if value := bearerClaim(req, strings.TrimPrefix(key, config.RateLimitKeyClaim)); value != "" {
+			return "claim:" + value
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return "ip:" + req.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// bearerClaim returns a claim of the request's bearer JWT, empty if there is none.
+// The token isn't verified: it only tells clients apart.
+func bearerClaim(req *http.Request, name string) string {
+	scheme, token, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "bearer") {
+		return ""
+	}
+	_, claims, err := oidc.Parse(strings.TrimSpace(token))
+	if err != nil || claims[name] == nil {
+		return ""
+	}
+	return fmt.Sprint(claims[name])
+}
+
+// ceilSeconds rounds a duration up to whole seconds, to at least minimum.
+func ceilSeconds(d time.Duration, minimum int) int {
+	return max(int(math.Ceil(d.Seconds())), minimum)
+}
@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRateLimitMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	newHandler := func(rateLimit *config.RateLimitConfig) http.Handler {
+		cfg := config.NewServiceConfig()
+		cfg.Name = "test"
+		cfg.RateLimit = rateLimit
+		return CreateRateLimitMiddleware(newTestParams(cfg, nil))(handler)
+	}
+
+	send := func(h http.Handler, method, path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("no rate limit", func(t *testing.T) {
+		w := send(newHandler(nil), http.MethodGet, "/test/pets", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+	})
+
+	t.Run("rejects requests over the limit", func(t *testing.T) {
+		h := newHandler(&config.RateLimitConfig{RateLimit: config.RateLimit{Limit: 2, Window: time.Hour}})
+
+		w := send(h, http.MethodGet, "/test/pets", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=3600", w.Header().Get("RateLimit-Policy"))
+
+		reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+		require.NoError(t, err)
+		assert.Greater(t, reset, time.Now().Unix())
+
+		w = send(h, http.MethodGet, "/test/pets", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+		w = send(h, http.MethodGet, "/test/pets", nil)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, ResponseHeaderSourceGenerated, w.Header().Get(ResponseHeaderSource))
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		assert.Equal(t, w.Header().Get("RateLimit-Reset"), w.Header().Get("Retry-After"))
+	})
+
+	t.Run("clients are counted separately", func(t *testing.T) {
+		h := newHandler(&config.RateLimitConfig{RateLimit: config.RateLimit{Limit: 1, Key: "header:X-Api-Key"}})
+
+		assert.Equal(t, http.StatusOK, send(h, http.MethodGet, "/test/pets", http.Header{"X-Api-Key": {"a"}}).Code)
+		assert.Equal(t, http.StatusTooManyRequests, send(h, http.MethodGet, "/test/pets", http.Header{"X-Api-Key": {"a"}}).Code)
+		assert.Equal(t, http.StatusOK, send(h, http.MethodGet, "/test/pets", http.Header{"X-Api-Key": {"b"}}).Code)
+
+		// Without the header the client is identified by its IP
+		assert.Equal(t, http.StatusOK, send(h, http.MethodGet, "/test/pets", nil).Code)
+		assert.Equal(t, http.StatusTooManyRequests, send(h, http.MethodGet, "/test/pets", nil).Code)
+	})
+
+	t.Run("endpoint limits have their own counters", func(t *testing.T) {
+		h := newHandler(&config.RateLimitConfig{
+			RateLimit: config.RateLimit{Limit: 100},
+			Endpoints: map[string]*config.RateLimitEndpointMethods{
+				"/orders": {Methods: map[string]*config.RateLimit{"POST": {Limit: 1}}},
+			},
+		})
+
+		assert.Equal(t, http.StatusOK, send(h, http.MethodPost, "/test/orders", nil).Code)
+		w := send(h, http.MethodPost, "/test/orders", nil)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+
+		w = send(h, http.MethodGet, "/test/orders", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "99", w.Header().Get("X-RateLimit-Remaining"))
+	})
+
+	t.Run("custom error writer", func(t *testing.T) {
+		cfg := config.NewServiceConfig()
+		cfg.Name = "test"
+		cfg.RateLimit = &config.RateLimitConfig{RateLimit: config.RateLimit{Limit: 1}}
+		params := newTestParams(cfg, nil)
+		params.SetErrorWriter(func(w http.ResponseWriter, req *http.Request, statusCode int) bool {
+			w.WriteHeader(statusCode)
+			_, _ = w.Write([]byte("custom"))
+			return true
+		})
+		h := CreateRateLimitMiddleware(params)(handler)
+
+		send(h, http.MethodGet, "/test/pets", nil)
+		w := send(h, http.MethodGet, "/test/pets", nil)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "custom", w.Body.String())
+	})
+}
+
+func TestCountRequest(t *testing.T) {
+	ctx := context.Background()
+	newTable := func() db.Table {
+		return db.NewStorage(nil).NewDB("test", time.Minute).Table("rate-limit")
+	}
+	start := time.Unix(0, 0).Add(1000 * time.Hour)
+
+	t.Run("fixed window resets at the boundary", func(t *testing.T) {
+		table := newTable()
+		limit := &config.RateLimit{Algorithm: config.RateLimitFixedWindow, Limit: 2, Window: time.Minute}
+
+		assert.True(t, countRequest(ctx, table, "k", limit, start).allowed)
+		assert.True(t, countRequest(ctx, table, "k", limit, start.Add(30*time.Second)).allowed)
+
+		res := countRequest(ctx, table, "k", limit, start.Add(50*time.Second))
+		assert.False(t, res.allowed)
+		assert.Equal(t, 10*time.Second, res.retryAfter)
+
+		res = countRequest(ctx, table, "k", limit, start.Add(time.Minute))
+		assert.True(t, res.allowed)
+		assert.Equal(t, 1, res.remaining)
+	})
+
+	t.Run("sliding window weights the previous window", func(t *testing.T) {
+		table := newTable()
+		limit := &config.RateLimit{Algorithm: config.RateLimitSlidingWindow, Limit: 4, Window: time.Minute}
+
+		for range 4 {
+			assert.True(t, countRequest(ctx, table, "k", limit, start.Add(50*time.Second)).allowed)
+		}
+		assert.False(t, countRequest(ctx, table, "k", limit, start.Add(55*time.Second)).allowed)
+
+		// A quarter into the next window, 3 of the previous 4 requests still count
+		res := countRequest(ctx, table, "k", limit, start.Add(75*time.Second))
+		assert.True(t, res.allowed)
+		assert.Equal(t, 0, res.remaining)
+		assert.False(t, countRequest(ctx, table, "k", limit, start.Add(75*time.Second)).allowed)
+
+		// Halfway, 2 of them do
+		assert.True(t, countRequest(ctx, table, "k", limit, start.Add(90*time.Second)).allowed)
+	})
+
+	t.Run("token bucket allows bursts and refills", func(t *testing.T) {
+		table := newTable()
+		limit := &config.RateLimit{Algorithm: config.RateLimitTokenBucket, Limit: 1, Window: time.Second, Burst: 3}
+
+		for i := range 3 {
+			res := countRequest(ctx, table, "k", limit, start)
+			assert.True(t, res.allowed)
+			assert.Equal(t, 2-i, res.remaining)
+		}
+
+		res := countRequest(ctx, table, "k", limit, start)
+		assert.False(t, res.allowed)
+		assert.Equal(t, time.Second, res.retryAfter)
+		assert.Equal(t, 3*time.Second, res.reset)
+
+		assert.True(t, countRequest(ctx, table, "k", limit, start.Add(time.Second)).allowed)
+		assert.False(t, countRequest(ctx, table, "k", limit, start.Add(time.Second)).allowed)
+
+		res = countRequest(ctx, table, "k", limit, start.Add(10*time.Second))
+		assert.True(t, res.allowed)
+		assert.Equal(t, 2, res.remaining)
+	})
+
+	concurrentBurst := func(t *testing.T, table db.Table) {
+		t.Helper()
+		limit := &config.RateLimit{Algorithm: config.RateLimitTokenBucket, Limit: 1, Window: time.Second, Burst: 3}
+		now := time.Now()
+
+		var wg sync.WaitGroup
+		var allowed atomic.Int32
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if countRequest(ctx, table, "k", limit, now).allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(3), allowed.Load())
+
+		res := countRequest(ctx, table, "k", limit, now)
+		assert.False(t, res.allowed)
+		assert.Equal(t, time.Second, res.retryAfter)
+		assert.True(t, countRequest(ctx, table, "k", limit, now.Add(time.Second)).allowed)
+	}
+
+	t.Run("token bucket takes each token once under concurrency", func(t *testing.T) {
+		concurrentBurst(t, newTable())
+	})
+
+	t.Run("token bucket takes each token once under concurrency in redis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		storage := db.NewStorage(&config.StorageConfig{
+			Type:  config.StorageTypeRedis,
+			Redis: &config.RedisConfig{Address: mr.Addr()},
+		})
+		concurrentBurst(t, storage.NewDB("test", time.Minute).Table("rate-limit"))
+	})
+}
+
+func TestRateLimitClient(t *testing.T) {
+	key, err := oidc.GenerateKey()
+	require.NoError(t, err)
+	token, err := key.Sign(map[string]any{"sub": "alice"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	assert.Equal(t, "ip:10.0.0.1", rateLimitClient(req, config.RateLimitKeyIP))
+	assert.Equal(t, "header:secret", rateLimitClient(req, "header:X-Api-Key"))
+	assert.Equal(t, "ip:10.0.0.1", rateLimitClient(req, "header:X-Other"))
+	assert.Equal(t, "claim:alice", rateLimitClient(req, "claim:sub"))
+	assert.Equal(t, "ip:10.0.0.1", rateLimitClient(req, "claim:email"))
+
+	req.RemoteAddr = "10.0.0.2"
+	assert.Equal(t, "ip:10.0.0.2", rateLimitClient(req, config.RateLimitKeyIP))
+}
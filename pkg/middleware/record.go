@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/har"
+	"github.com/mockzilla/connexions/v2/pkg/static"
+)
+
+// recorder writes successful upstream responses into the static service layout
+// and regenerates the OpenAPI spec from it.
+// Writes are serialized, so concurrent responses never see a half-written directory.
+type recorder struct {
+	log *slog.Logger
+	mu  sync.Mutex
+}
+
+func newRecorder(log *slog.Logger) *recorder {
+	return &recorder{log: log}
+}
+
+// record persists a response asynchronously.
+// path is the request path relative to the service root.
+func (r *recorder) record(cfg *config.RecordConfig, serviceName, method, path string, resp *upstreamResponse) {
+	if cfg == nil || cfg.Dir == "" {
+		return
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return
+	}
+	if len(bytes.TrimSpace(resp.Body)) == 0 || static.Extension(resp.ContentType) == "" {
+		r.log.Debug("Skipping recording of upstream response",
+			"method", method,
+			"path", path,
+			"contentType", resp.ContentType,
+		)
+		return
+	}
+
+	meta := &static.Meta{Status: resp.StatusCode}
+	for name, values := range resp.Header {
+		if len(values) == 0 || har.IsVolatileHeader(name) || strings.EqualFold(name, "Content-Type") ||
+			strings.HasPrefix(http.CanonicalHeaderKey(name), "X-Cxs-") {
+			continue
+		}
+		if meta.Headers == nil {
+			meta.Headers = make(map[string]string)
+		}
+		meta.Headers[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+	}
+
+	go func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if err := r.write(cfg, serviceName, method, path, resp, meta); err != nil {
+			r.log.Error("Failed to record upstream response", "method", method, "path", path, "error", err)
+			return
+		}
+		r.log.Info("Recorded upstream response", "method", method, "path", path, "dir", cfg.Dir)
+	}()
+}
+
+func (r *recorder) write(cfg *config.RecordConfig, serviceName, method, path string, resp *upstreamResponse, meta *static.Meta) error {
+	if err := static.WriteResponse(cfg.Dir, method, path, resp.ContentType, resp.Body, meta); err != nil {
+		return err
+	}
+
+	specBytes, err := static.GenerateSpec(cfg.Dir, serviceName)
+	if err != nil {
+		return fmt.Errorf("regenerating spec: %w", err)
+	}
+
+	specPath := cfg.SpecPath()
+	if err := os.MkdirAll(filepath.Dir(specPath), 0o755); err != nil {
+		return fmt.Errorf("creating spec directory: %w", err)
+	}
+	if err := os.WriteFile(specPath, specBytes, 0o644); err != nil {
+		return fmt.Errorf("writing spec: %w", err)
+	}
+	return nil
+}
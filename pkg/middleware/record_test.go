@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/static"
+	assert2 "github.com/stretchr/testify/assert"
+	"go.yaml.in/yaml/v4"
+)
+
+func TestCreateUpstreamRequestMiddleware_Record(t *testing.T) {
+	assert := assert2.New(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Hello, from local!"))
+	})
+
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Date", "Mon, 01 Jan 2024 00:00:00 GMT")
+			w.Header().Set("Location", "/users/1")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 1, "name": "Jane"}`))
+		case "/users/1":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id": 1, "name": "Jane"}`))
+		case "/logo":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png"))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer upstreamServer.Close()
+
+	serve := func(params *Params, method, path string) {
+		f := CreateUpstreamRequestMiddleware(params)
+		f(handler).ServeHTTP(NewBufferedResponseWriter(), httptest.NewRequest(method, path, nil))
+	}
+
+	t.Run("records responses and regenerates spec", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "test")
+		params := newTestParams(&config.ServiceConfig{
+			Name: "test",
+			Upstream: &config.UpstreamConfig{
+				URL:    upstreamServer.URL,
+				Record: &config.RecordConfig{Dir: dir},
+			},
+		}, nil)
+
+		serve(params, http.MethodPost, "/test/users")
+		assert.Eventually(func() bool {
+			_, err := os.Stat(filepath.Join(dir, "openapi.yml"))
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+
+		serve(params, http.MethodGet, "/test/users/1")
+		assert.Eventually(func() bool {
+			spec, err := os.ReadFile(filepath.Join(dir, "openapi.yml"))
+			return err == nil && strings.Contains(string(spec), "/users/1:")
+		}, time.Second, 10*time.Millisecond)
+
+		content, err := os.ReadFile(filepath.Join(dir, "post", "users", "index.json"))
+		assert.NoError(err)
+		assert.Equal(`{"id": 1, "name": "Jane"}`, string(content))
+
+		var meta static.Meta
+		metaBytes, err := os.ReadFile(filepath.Join(dir, "post", "users", "index"+static.MetaSuffix))
+		assert.NoError(err)
+		assert.NoError(yaml.Unmarshal(metaBytes, &meta))
+		assert.Equal(static.Meta{Status: http.StatusCreated, Headers: map[string]string{"Location": "/users/1"}}, meta)
+
+		assert.NoFileExists(filepath.Join(dir, "get", "users", "1", "index"+static.MetaSuffix))
+	})
+
+	t.Run("writes spec to configured path", func(t *testing.T) {
+		dir := t.TempDir()
+		specPath := filepath.Join(t.TempDir(), "specs", "test.yml")
+		params := newTestParams(&config.ServiceConfig{
+			Name: "test",
+			Upstream: &config.UpstreamConfig{
+				URL:    upstreamServer.URL,
+				Record: &config.RecordConfig{Dir: dir, Spec: specPath},
+			},
+		}, nil)
+
+		serve(params, http.MethodGet, "/test/users/1")
+		assert.Eventually(func() bool {
+			_, err := os.Stat(specPath)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		assert.NoFileExists(filepath.Join(dir, "openapi.yml"))
+	})
+
+	t.Run("skips empty and unsupported responses", func(t *testing.T) {
+		dir := t.TempDir()
+		params := newTestParams(&config.ServiceConfig{
+			Name: "test",
+			Upstream: &config.UpstreamConfig{
+				URL:    upstreamServer.URL,
+				Record: &config.RecordConfig{Dir: dir},
+			},
+		}, nil)
+
+		serve(params, http.MethodGet, "/test/logo")
+		serve(params, http.MethodDelete, "/test/users/2")
+		waitForAsync()
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(err)
+		assert.Empty(entries)
+	})
+
+	t.Run("record disabled", func(t *testing.T) {
+		dir := t.TempDir()
+		params := newTestParams(&config.ServiceConfig{
+			Name:     "test",
+			Upstream: &config.UpstreamConfig{URL: upstreamServer.URL},
+		}, nil)
+
+		serve(params, http.MethodGet, "/test/users/1")
+		waitForAsync()
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(err)
+		assert.Empty(entries)
+	})
+}
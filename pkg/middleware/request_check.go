@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RequestCheckerFunc checks a request against the spec of the service, e.g. its security requirements.
+// path is the request path relative to the service root.
+// It writes the rejection and returns false when the request must not be served.
+type RequestCheckerFunc func(w http.ResponseWriter, req *http.Request, path string) bool
+
+// CreateRequestCheckMiddleware rejects requests failing the checks of the service handler
+// before rate limits, rules, scenarios, replays, the cache or the upstream can answer them.
+// Without a registered checker, requests pass through.
+func CreateRequestCheckMiddleware(params *Params) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if params.requestChecker == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			path := getEndpointPath(req, params.serviceConfig.Name)
+			if !strings.HasPrefix(path, "/") {
+				path = "/" + path
+			}
+
+			SetRequestIDHeader(w, req)
+			if !params.requestChecker(w, req, path) {
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRequestCheckMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("passes through without a checker", func(t *testing.T) {
+		params := newTestParams(&config.ServiceConfig{Name: "pets"}, nil)
+
+		w := httptest.NewRecorder()
+		CreateRequestCheckMiddleware(params)(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pets/1", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("checks the path relative to the service", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			service, url, expected string
+		}{
+			"service": {"pets", "/pets/dogs/1", "/dogs/1"},
+			"root":    {"", "/dogs/1", "/dogs/1"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				var checked string
+				params := newTestParams(&config.ServiceConfig{Name: tc.service}, nil)
+				params.SetRequestChecker(func(_ http.ResponseWriter, _ *http.Request, path string) bool {
+					checked = path
+					return true
+				})
+
+				w := httptest.NewRecorder()
+				CreateRequestCheckMiddleware(params)(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Equal(t, tc.expected, checked)
+			})
+		}
+	})
+
+	t.Run("stops rejected requests", func(t *testing.T) {
+		params := newTestParams(&config.ServiceConfig{Name: "pets"}, nil)
+		params.SetRequestChecker(func(w http.ResponseWriter, _ *http.Request, _ string) bool {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		})
+
+		w := httptest.NewRecorder()
+		CreateRequestCheckMiddleware(params)(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pets/1", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"net/http"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+)
+
+// contextHeaderName is the header carrying the replacement context of the generated responses,
+// base64-encoded JSON, as read by api.ExtractContextFromRequest.
+const contextHeaderName = "X-Cxs-Context"
+
+// CreateRuleMiddleware answers the requests matching a stub or a rule with its response.
+// Stubs are matched first, then the rules of the config.
+// Rules without a body pass the request on, with their context added to the replacement context,
+// and apply their status and headers to the response.
+func CreateRuleMiddleware(params *Params) func(http.Handler) http.Handler {
+	log := params.Logger("rule")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			cfg := params.GetServiceConfig(req)
+			rule := matchRule(req, params, cfg)
+			if rule == nil {
+				next.ServeHTTP(w, req)
+				return
+			}
+			ruleReq := rule.request
+
+			reqLog := RequestLog(log, req)
+			reqLog.Info("Rule matched", "rule", rule.name, "status", rule.Response.Status)
+
+			res := rule.Response
+			if res.IsGenerated() {
+				if len(res.Context) > 0 {
+					setContextHeader(req, res.Context)
+				}
+				next.ServeHTTP(&overrideResponseWriter{ResponseWriter: w, status: res.Status, headers: res.Headers}, req)
+				return
+			}
+
+			if rule.Path != "" {
+				ruleReq.Params = config.ExtractPathValues(ruleReq.Path, rule.Path)
+			}
+			body, err := res.Render(ruleReq)
+			if err != nil {
+				reqLog.Error("Failed to render rule response", "rule", rule.name, "error", err)
+				w.Header().Set(ResponseHeaderSource, ResponseHeaderSourceRule)
+				http.Error(w, "Failed to render rule response", http.StatusInternalServerError)
+				return
+			}
+
+			writeCannedResponse(params, cfg, w, req, &cannedResponse{
+				status:      res.Status,
+				contentType: res.ContentType,
+				headers:     res.Headers,
+				body:        body,
+				source:      ResponseHeaderSourceRule,
+			})
+		})
+	}
+}
+
+// matchedRule is the stub or rule answering a request.
+type matchedRule struct {
+	*config.RuleConfig
+	name    string
+	request *config.RuleRequest
+}
+
+// matchRule returns the first stub, then rule of the config, matching the request, nil if none does.
+// Matching stubs limited to a number of uses are used up.
+func matchRule(req *http.Request, params *Params, cfg *config.ServiceConfig) *matchedRule {
+	ctx := req.Context()
+	stubs := loadStubs(ctx, params.DB())
+	rules := cfg.GetRules()
+	if len(stubs) == 0 && len(rules) == 0 {
+		return nil
+	}
+
+	ruleReq := newRuleRequest(req, cfg.Name)
+	for _, stub := range stubs {
+		if stub.Matches(ruleReq) && useStub(ctx, params.DB(), stub) {
+			return &matchedRule{RuleConfig: &stub.RuleConfig, name: cmp.Or(stub.Name, stub.ID), request: ruleReq}
+		}
+	}
+	for _, rule := range rules {
+		if rule.Matches(ruleReq) {
+			return &matchedRule{RuleConfig: rule, name: rule.Name, request: ruleReq}
+		}
+	}
+	return nil
+}
+
+// newRuleRequest collects the request data rules are matched against.
+// Paths are relative to the service, query parameters and headers hold their first value.
+func newRuleRequest(req *http.Request, serviceName string) *config.RuleRequest {
+	query := req.URL.Query()
+	body := readAndRestoreBody(req)
+	contentType := req.Header.Get("Content-Type")
+
+	res := &config.RuleRequest{
+		Method:  req.Method,
+		Path:    getEndpointPath(req, serviceName),
+		Query:   make(map[string]string, len(query)),
+		Headers: make(map[string]string, len(req.Header)),
+		QueryValue: func(name string) (string, bool) {
+			return query.Get(name), query.Has(name)
+		},
+		HeaderValue: func(name string) (string, bool) {
+			values := req.Header.Values(name)
+			if len(values) == 0 {
+				return "", false
+			}
+			return values[0], true
+		},
+		BodyValue: func(path string) (string, bool) {
+			value := extractBodyValue(body, contentType, path)
+			if value == nil {
+				return "", false
+			}
+			return formatValue(value), true
+		},
+	}
+	for name := range query {
+		res.Query[name] = query.Get(name)
+	}
+	for name := range req.Header {
+		res.Headers[name] = req.Header.Get(name)
+	}
+	if len(body) > 0 {
+		_ = json.Unmarshal(body, &res.Body)
+	}
+	return res
+}
+
+// setContextHeader merges values into the replacement context of the request.
+func setContextHeader(req *http.Request, values map[string]any) {
+	merged := make(map[string]any)
+	if encoded := req.Header.Get(contextHeaderName); encoded != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+			_ = json.Unmarshal(decoded, &merged)
+		}
+	}
+	maps.Copy(merged, values)
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return
+	}
+	req.Header.Set(contextHeaderName, base64.StdEncoding.EncodeToString(data))
+}
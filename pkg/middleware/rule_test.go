@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRuleMiddleware(t *testing.T) {
+	src := `
+rules:
+  - name: declined
+    method: POST
+    path: /charges
+    body:
+      card.number: "4000000000000002"
+    response:
+      status: 402
+      body: '{"error":"card_declined"}'
+      headers:
+        X-Decline: "true"
+  - name: big amount
+    priority: 10
+    method: POST
+    path: /charges
+    body:
+      amount:
+        regex: ^[0-9]{6,}$
+    response:
+      status: 422
+      body: '{"error":"amount_too_large"}'
+  - name: pet
+    path: /pets/{id}
+    query:
+      expand:
+        present: true
+    response:
+      template: '{"id":"{{ .Params.id }}","expand":"{{ .Query.expand }}"}'
+  - name: limited
+    method: GET
+    path: /pets
+    headers:
+      X-Plan: free
+    response:
+      status: 206
+      headers:
+        X-Limited: "true"
+      context:
+        name: limited
+  - name: form
+    method: POST
+    path: /login
+    body:
+      user: admin
+    response:
+      status: 403
+`
+	setup := func(t *testing.T) *Params {
+		t.Helper()
+		cfg, err := config.NewServiceConfigFromBytes([]byte(src))
+		require.NoError(t, err)
+		cfg.Name = "test"
+		return newTestParams(cfg, nil)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]string{"context": r.Header.Get(contextHeaderName)})
+	})
+
+	serve := func(params *Params, method, path, resourcePath, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), resourcePathKey, resourcePath))
+		w := httptest.NewRecorder()
+		CreateRuleMiddleware(params)(handler).ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("passes unmatched requests through", func(t *testing.T) {
+		params := setup(t)
+		w := serve(params, http.MethodPost, "/test/charges", "/charges", `{"card":{"number":"4242"},"amount":100}`, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"context":""}`, w.Body.String())
+	})
+
+	t.Run("body", func(t *testing.T) {
+		params := setup(t)
+		w := serve(params, http.MethodPost, "/test/charges", "/charges", `{"card":{"number":"4000000000000002"},"amount":100}`, nil)
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+		assert.JSONEq(t, `{"error":"card_declined"}`, w.Body.String())
+		assert.Equal(t, "true", w.Header().Get("X-Decline"))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, ResponseHeaderSourceRule, w.Header().Get(ResponseHeaderSource))
+
+		waitForAsync()
+		entries := params.DB().History().Data(context.Background())
+		require.Len(t, entries, 1)
+		assert.Equal(t, http.StatusPaymentRequired, entries[0].Response.StatusCode)
+		assert.Equal(t, "/charges", entries[0].Resource)
+	})
+
+	t.Run("priority", func(t *testing.T) {
+		params := setup(t)
+		w := serve(params, http.MethodPost, "/test/charges", "/charges", `{"card":{"number":"4000000000000002"},"amount":1000000}`, nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.JSONEq(t, `{"error":"amount_too_large"}`, w.Body.String())
+	})
+
+	t.Run("template", func(t *testing.T) {
+		params := setup(t)
+		w := serve(params, http.MethodGet, "/test/pets/7?expand=owner", "/pets/{id}", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"id":"7","expand":"owner"}`, w.Body.String())
+
+		w = serve(params, http.MethodGet, "/test/pets/7", "/pets/{id}", "", nil)
+		assert.JSONEq(t, `{"context":""}`, w.Body.String())
+	})
+
+	t.Run("generated with context", func(t *testing.T) {
+		params := setup(t)
+		existing := base64.StdEncoding.EncodeToString([]byte(`{"id":1}`))
+		w := serve(params, http.MethodGet, "/test/pets", "/pets", "", map[string]string{
+			"X-Plan":          "free",
+			contextHeaderName: existing,
+		})
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "true", w.Header().Get("X-Limited"))
+
+		var res map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		decoded, err := base64.StdEncoding.DecodeString(res["context"])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":1,"name":"limited"}`, string(decoded))
+	})
+
+	t.Run("form body", func(t *testing.T) {
+		params := setup(t)
+		w := serve(params, http.MethodPost, "/test/login", "/login", "user=admin&password=x", map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.JSONEq(t, `{"context":""}`, w.Body.String())
+	})
+
+	t.Run("keeps the body for the next handler", func(t *testing.T) {
+		params := setup(t)
+		var got string
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = string(readAndRestoreBody(r))
+		})
+		req := httptest.NewRequest(http.MethodPost, "/test/charges", strings.NewReader(`{"amount":1}`))
+		CreateRuleMiddleware(params)(next).ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, `{"amount":1}`, got)
+	})
+
+	t.Run("no rules", func(t *testing.T) {
+		params := newTestParams(&config.ServiceConfig{Name: "test"}, nil)
+		w := serve(params, http.MethodGet, "/test/pets", "/pets", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
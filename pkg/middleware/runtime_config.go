@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+)
+
+// RuntimeConfigTable is the name of the table holding the runtime config patches of a service.
+const RuntimeConfigTable = "config"
+
+// Keys of the runtime config table.
+// Patches are stored as patch:<seq> and applied in seq order.
+// The revision changes with every update, so instances sharing the storage notice it.
+const (
+	runtimeConfigKeySeq      = "seq"
+	runtimeConfigKeyRevision = "revision"
+	runtimeConfigPatchPrefix = "patch:"
+)
+
+// runtimeConfigRefresh is how often the revision is checked for updates made by other instances.
+const runtimeConfigRefresh = 2 * time.Second
+
+// runtimeConfig holds the service config with the runtime patches applied.
+type runtimeConfig struct {
+	mu        sync.Mutex
+	current   atomic.Pointer[config.ServiceConfig]
+	checkedAt atomic.Int64
+
+	// revision is the applied revision, guarded by mu.
+	revision int
+}
+
+// ServiceConfig returns the service config with the runtime changes applied.
+// Changes made through another instance sharing the storage are picked up within a few seconds.
+func (p *Params) ServiceConfig(ctx context.Context) *config.ServiceConfig {
+	rt := &p.runtime
+	current := rt.current.Load()
+	if current != nil && time.Since(time.Unix(0, rt.checkedAt.Load())) < runtimeConfigRefresh {
+		return current
+	}
+
+	// Only one request refreshes, the others keep using the current config
+	if current != nil && !rt.mu.TryLock() {
+		return current
+	}
+	if current == nil {
+		rt.mu.Lock()
+	}
+	defer rt.mu.Unlock()
+
+	if current = rt.current.Load(); current == nil || runtimeRevision(ctx, p.runtimeTable()) != rt.revision {
+		current = p.reloadServiceConfig(ctx)
+	}
+	rt.checkedAt.Store(time.Now().UnixNano())
+	return current
+}
+
+// UpdateServiceConfig validates a patch of RuntimeConfigKeys against the current config,
+// stores it and returns the updated config.
+func (p *Params) UpdateServiceConfig(ctx context.Context, patch []byte) (*config.ServiceConfig, error) {
+	rt := &p.runtime
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	current := p.reloadServiceConfig(ctx)
+	if _, err := current.WithPatch(patch); err != nil {
+		return nil, err
+	}
+
+	table := p.runtimeTable()
+	seq := table.Incr(ctx, runtimeConfigKeySeq, 1, 0)
+	table.Set(ctx, runtimeConfigPatchPrefix+strconv.FormatInt(seq, 10), string(patch), 0)
+	table.Incr(ctx, runtimeConfigKeyRevision, 1, 0)
+
+	res := p.reloadServiceConfig(ctx)
+	rt.checkedAt.Store(time.Now().UnixNano())
+	return res, nil
+}
+
+// ResetServiceConfig removes the runtime changes and returns the registered config.
+func (p *Params) ResetServiceConfig(ctx context.Context) *config.ServiceConfig {
+	rt := &p.runtime
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	table := p.runtimeTable()
+	for key := range table.Data(ctx) {
+		if strings.HasPrefix(key, runtimeConfigPatchPrefix) {
+			table.Delete(ctx, key)
+		}
+	}
+	table.Incr(ctx, runtimeConfigKeyRevision, 1, 0)
+
+	res := p.reloadServiceConfig(ctx)
+	rt.checkedAt.Store(time.Now().UnixNano())
+	return res
+}
+
+// reloadServiceConfig applies the stored patches to the registered config.
+// Patches that no longer apply are skipped. Must be called with the runtime lock held.
+func (p *Params) reloadServiceConfig(ctx context.Context) *config.ServiceConfig {
+	table := p.runtimeTable()
+	revision := runtimeRevision(ctx, table)
+
+	type storedPatch struct {
+		seq   int
+		patch string
+	}
+	var patches []storedPatch
+	for key, value := range table.Data(ctx) {
+		seq, err := strconv.Atoi(strings.TrimPrefix(key, runtimeConfigPatchPrefix))
+		if err != nil || !strings.HasPrefix(key, runtimeConfigPatchPrefix) {
+			continue
+		}
+		patch, ok := value.(string)
+		if !ok {
+			continue
+		}
+		patches = append(patches, storedPatch{seq: seq, patch: patch})
+	}
+	slices.SortFunc(patches, func(a, b storedPatch) int {
+		return a.seq - b.seq
+	})
+
+	cfg := p.serviceConfig
+	for _, patch := range patches {
+		next, err := cfg.WithPatch([]byte(patch.patch))
+		if err != nil {
+			p.Logger("config").Warn("Skipping runtime config patch", "seq", patch.seq, "error", err)
+			continue
+		}
+		cfg = next
+	}
+
+	p.runtime.current.Store(cfg)
+	p.runtime.revision = revision
+	return cfg
+}
+
+// runtimeTable returns the table holding the runtime config patches.
+func (p *Params) runtimeTable() db.Table {
+	return p.DB().Table(RuntimeConfigTable)
+}
+
+// runtimeRevision returns the revision of the stored runtime config patches.
+func runtimeRevision(ctx context.Context, table db.Table) int {
+	raw, _ := table.Get(ctx, runtimeConfigKeyRevision)
+	return storedCount(raw)
+}
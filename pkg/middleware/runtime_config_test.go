@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParams_RuntimeServiceConfig(t *testing.T) {
+	ctx := context.Background()
+
+	newConfig := func() *config.ServiceConfig {
+		cfg := config.NewServiceConfig()
+		cfg.Name = "test"
+		cfg.Latency = 10 * time.Millisecond
+		cfg.WithDefaults()
+		return cfg
+	}
+
+	t.Run("update applies the patch", func(t *testing.T) {
+		base := newConfig()
+		params := newTestParams(base, nil)
+
+		cfg, err := params.UpdateServiceConfig(ctx, []byte("latency: 50ms\nerrors:\n  p10: 503\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 50*time.Millisecond, cfg.Latency)
+		assert.Equal(t, map[string]int{"p10": 503}, cfg.Errors)
+
+		assert.Same(t, cfg, params.ServiceConfig(ctx))
+		assert.Equal(t, 10*time.Millisecond, base.Latency)
+	})
+
+	t.Run("patches are applied in order", func(t *testing.T) {
+		params := newTestParams(newConfig(), nil)
+
+		_, err := params.UpdateServiceConfig(ctx, []byte(`{"latency": "50ms"}`))
+		require.NoError(t, err)
+		cfg, err := params.UpdateServiceConfig(ctx, []byte(`{"latency": "20ms"}`))
+		require.NoError(t, err)
+		assert.Equal(t, 20*time.Millisecond, cfg.Latency)
+	})
+
+	t.Run("invalid patch is not stored", func(t *testing.T) {
+		params := newTestParams(newConfig(), nil)
+
+		_, err := params.UpdateServiceConfig(ctx, []byte("latency: -1s"))
+		require.Error(t, err)
+		_, err = params.UpdateServiceConfig(ctx, []byte("name: other"))
+		require.Error(t, err)
+
+		assert.Equal(t, 10*time.Millisecond, params.ServiceConfig(ctx).Latency)
+		assert.Equal(t, 0, runtimeRevision(ctx, params.runtimeTable()))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		base := newConfig()
+		params := newTestParams(base, nil)
+
+		_, err := params.UpdateServiceConfig(ctx, []byte("latency: 50ms"))
+		require.NoError(t, err)
+
+		assert.Same(t, base, params.ResetServiceConfig(ctx))
+		assert.Same(t, base, params.ServiceConfig(ctx))
+	})
+
+	t.Run("shared storage", func(t *testing.T) {
+		storage := db.NewStorage(nil)
+		first := NewParams(newConfig(), nil, storage.NewDB("test", time.Minute))
+		second := NewParams(newConfig(), nil, storage.NewDB("test", time.Minute))
+		assert.Equal(t, 10*time.Millisecond, second.ServiceConfig(ctx).Latency)
+
+		_, err := first.UpdateServiceConfig(ctx, []byte("latency: 50ms"))
+		require.NoError(t, err)
+
+		// Changes are picked up on the next refresh
+		assert.Equal(t, 10*time.Millisecond, second.ServiceConfig(ctx).Latency)
+		second.runtime.checkedAt.Store(0)
+		assert.Equal(t, 50*time.Millisecond, second.ServiceConfig(ctx).Latency)
+	})
+
+	t.Run("requests use the updated config", func(t *testing.T) {
+		params := newTestParams(newConfig(), nil)
+		_, err := params.UpdateServiceConfig(ctx, []byte("errors:\n  p100: 503\n"))
+		require.NoError(t, err)
+
+		handler := CreateConfigOverrideMiddleware(params)(CreateLatencyAndErrorMiddleware(params)(
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}),
+		))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test/pets", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		params.ResetServiceConfig(ctx)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test/pets", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestUpstreamCircuitBreaker_Get(t *testing.T) {
+	params := newTestParams(nil, nil)
+	breaker := &upstreamCircuitBreaker{params: params, log: params.Logger("upstream")}
+
+	upstream := &config.UpstreamConfig{
+		URL:            "http://localhost:9999",
+		CircuitBreaker: &config.CircuitBreakerConfig{MinRequests: 3},
+	}
+
+	cb := breaker.get(upstream)
+	require.NotNil(t, cb)
+
+	t.Run("same settings keep the breaker", func(t *testing.T) {
+		same := *upstream
+		cbCopy := *upstream.CircuitBreaker
+		same.CircuitBreaker = &cbCopy
+		assert.Same(t, cb, breaker.get(&same))
+	})
+
+	t.Run("changed url creates a new breaker", func(t *testing.T) {
+		changed := *upstream
+		changed.URL = "http://localhost:9998"
+		assert.NotSame(t, cb, breaker.get(&changed))
+	})
+
+	t.Run("no circuit breaker", func(t *testing.T) {
+		assert.Nil(t, breaker.get(&config.UpstreamConfig{URL: "http://localhost:9999"}))
+		assert.Nil(t, breaker.get(nil))
+	})
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+)
+
+// ScenarioTable is the name of the per-service table holding the scenario states.
+const ScenarioTable = "scenarios"
+
+// scenarioSwapAttempts bounds the retries of a state transition losing to concurrent requests.
+const scenarioSwapAttempts = 10
+
+// ScenarioState is the state of a scenario shared by the requests with the same key.
+// Key is the header or body field value, empty for the global state.
+// Calls and Step, starting at 1, are the calls made so far and the step answering the next one of an ordered scenario.
+// State is the current state of a state machine.
+type ScenarioState struct {
+	Scenario string `json:"scenario"`
+	Key      string `json:"key"`
+	Calls    int    `json:"calls,omitempty"`
+	Step     int    `json:"step,omitempty"`
+	State    string `json:"state,omitempty"`
+}
+
+// CreateScenarioMiddleware answers the requests of scenario endpoints with the current step or state.
+// Steps without a body pass the request on and apply their status and headers to the response.
+func CreateScenarioMiddleware(params *Params) func(http.Handler) http.Handler {
+	log := params.Logger("scenario")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			cfg := params.GetServiceConfig(req)
+			resourcePath := GetResourcePath(req)
+			name, sc := cfg.GetScenario(resourcePath, req.Method)
+			if sc == nil {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			ctx := req.Context()
+			table := params.DB().Table(ScenarioTable)
+			key := ScenarioKey(name, scenarioClient(req, sc.Key))
+
+			var step *config.ScenarioStep
+			if sc.IsOrdered() {
+				step = sc.GetStep(int(table.Incr(ctx, key, 1, sc.TTL)))
+			} else {
+				step = advanceScenario(ctx, table, key, sc, resourcePath, req.Method)
+			}
+			if step == nil {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			RequestLog(log, req).Info("Scenario step", "scenario", name, "status", step.Status)
+			if step.Latency > 0 && !sleepContext(ctx, step.Latency) {
+				return
+			}
+
+			if step.Body == "" {
+				next.ServeHTTP(&overrideResponseWriter{ResponseWriter: w, status: step.Status, headers: step.Headers}, req)
+				return
+			}
+			writeCannedResponse(params, cfg, w, req, &cannedResponse{
+				status:      step.Status,
+				contentType: step.ContentType,
+				headers:     step.Headers,
+				body:        []byte(step.Body),
+				source:      ResponseHeaderSourceScenario,
+			})
+		})
+	}
+}
+
+// advanceScenario returns the response of the current state of a state machine and moves it to the next state.
+// The move is a compare-and-swap from the state the response was picked in:
+// when a concurrent request moved the state first, the response is picked again in the new state.
+func advanceScenario(ctx context.Context, table db.Table, key string, sc *config.ScenarioConfig, resourcePath, method string) *config.ScenarioStep {
+	var current int64
+	if v, ok := table.Get(ctx, key); ok {
+		current = int64(storedCount(v))
+	}
+
+	var step *config.ScenarioStep
+	for range scenarioSwapAttempts {
+		state := scenarioStateName(sc, current)
+		step = sc.GetResponse(state, resourcePath, method)
+		if step == nil || step.Next == "" || step.Next == state {
+			break
+		}
+
+		var swapped bool
+		if current, swapped = table.CompareAndSwap(ctx, key, current, scenarioStateID(step.Next), sc.TTL); swapped {
+			break
+		}
+	}
+	return step
+}
+
+// scenarioStateID returns the integer a state is stored as, so transitions can be compare-and-swapped.
+// It's a hash of the name rather than an index, so states keep their IDs when the config changes.
+// IDs fit in 33 bits, so they survive the float64 round-trip of Redis tables, and 0 is left for no state.
+func scenarioStateID(state string) int64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(state))
+	return int64(h.Sum32()) + 1
+}
+
+// scenarioStateName returns the state stored as id, the initial state for no or an unknown state.
+func scenarioStateName(sc *config.ScenarioConfig, id int64) string {
+	if id != 0 {
+		for state := range sc.States {
+			if scenarioStateID(state) == id {
+				return state
+			}
+		}
+	}
+	return sc.GetInitial()
+}
+
+// ScenarioKey returns the table key of the scenario state shared by the requests with the key.
+func ScenarioKey(scenario, key string) string {
+	return scenario + ":" + key
+}
+
+// GetScenarioStates reads the states of the scenarios of a service, sorted by scenario and key.
+// States of scenarios missing from the config are left out.
+func GetScenarioStates(ctx context.Context, table db.Table, cfg *config.ServiceConfig) []*ScenarioState {
+	data := table.Data(ctx)
+	res := make([]*ScenarioState, 0, len(data))
+	for tableKey, raw := range data {
+		name, key, _ := strings.Cut(tableKey, ":")
+		sc := cfg.Scenarios[name]
+		if sc == nil {
+			continue
+		}
+
+		state := &ScenarioState{Scenario: name, Key: key}
+		if sc.IsOrdered() {
+			state.Calls = storedCount(raw)
+			state.Step = sc.GetStepIndex(state.Calls+1) + 1
+		} else {
+			state.State = scenarioStateName(sc, int64(storedCount(raw)))
+		}
+		res = append(res, state)
+	}
+	slices.SortFunc(res, func(a, b *ScenarioState) int {
+		return cmp.Or(cmp.Compare(a.Scenario, b.Scenario), cmp.Compare(a.Key, b.Key))
+	})
+	return res
+}
+
+// SetScenarioStep makes the next call of an ordered scenario answered by the step, starting at 1.
+func SetScenarioStep(ctx context.Context, table db.Table, name string, sc *config.ScenarioConfig, key string, step int) error {
+	if !sc.IsOrdered() {
+		return fmt.Errorf("scenario %s has no steps", name)
+	}
+	if step < 1 || step > len(sc.Steps) {
+		return fmt.Errorf("scenario %s has no step %d", name, step)
+	}
+	table.Set(ctx, ScenarioKey(name, key), int64(sc.StepCalls(step-1)), sc.TTL)
+	return nil
+}
+
+// SetScenarioState moves a state machine scenario to the state.
+func SetScenarioState(ctx context.Context, table db.Table, name string, sc *config.ScenarioConfig, key, state string) error {
+	if _, ok := sc.States[state]; !ok {
+		return fmt.Errorf("scenario %s has no state %s", name, state)
+	}
+	table.Set(ctx, ScenarioKey(name, key), scenarioStateID(state), sc.TTL)
+	return nil
+}
+
+// ResetScenario resets the states of a scenario for all keys.
+func ResetScenario(ctx context.Context, table db.Table, name string) {
+	prefix := ScenarioKey(name, "")
+	for key := range table.Data(ctx) {
+		if strings.HasPrefix(key, prefix) {
+			table.Delete(ctx, key)
+		}
+	}
+}
+
+// scenarioClient returns the key of the scenario state shared by the request, empty for the global state.
+func scenarioClient(req *http.Request, key string) string {
+	switch {
+	case strings.HasPrefix(key, config.ScenarioKeyHeader):
+		return req.Header.Get(strings.TrimPrefix(key, config.ScenarioKeyHeader))
+	case strings.HasPrefix(key, config.ScenarioKeyBody):
+		if value := extractJSONPath(readAndRestoreBody(req), strings.TrimPrefix(key, config.ScenarioKeyBody)); value != nil {
+			return formatValue(value)
+		}
+	}
+	return ""
+}
+
+// storedCount converts a stored counter to an int.
+// In-memory tables hold an int64, Redis tables a float64 after the JSON round-trip.
+func storedCount(raw any) int {
+	switch v := raw.(type) {
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
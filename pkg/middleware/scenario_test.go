@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateScenarioMiddleware(t *testing.T) {
+	src := `
+scenarios:
+  charges:
+    endpoint: POST /charges
+    key: body:card.number
+    steps:
+      - status: 201
+        repeat: 2
+      - status: 402
+        body: '{"error":"card_declined"}'
+        headers:
+          X-Decline: "true"
+  order:
+    key: header:X-Session-Id
+    initial: pending
+    states:
+      pending:
+        GET /orders/{id}:
+          status: 202
+          body: '{"status":"pending"}'
+          next: done
+      done:
+        GET /orders/{id}:
+          body: '{"status":"done"}'
+`
+	setup := func(t *testing.T) *Params {
+		t.Helper()
+		cfg, err := config.NewServiceConfigFromBytes([]byte(src))
+		require.NoError(t, err)
+		cfg.Name = "test"
+		return newTestParams(cfg, nil)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"generated":true}`))
+	})
+
+	serve := func(params *Params, method, path, resourcePath, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), resourcePathKey, resourcePath))
+		w := httptest.NewRecorder()
+		CreateScenarioMiddleware(params)(handler).ServeHTTP(w, req)
+		return w
+	}
+
+	charge := func(params *Params, card string) *httptest.ResponseRecorder {
+		return serve(params, http.MethodPost, "/test/charges", "/charges", `{"card":{"number":"`+card+`"}}`, nil)
+	}
+
+	t.Run("passes other endpoints through", func(t *testing.T) {
+		params := setup(t)
+		w := serve(params, http.MethodGet, "/test/charges", "/charges", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"generated":true}`, w.Body.String())
+	})
+
+	t.Run("ordered steps", func(t *testing.T) {
+		params := setup(t)
+
+		for range 2 {
+			w := charge(params, "4242")
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.JSONEq(t, `{"generated":true}`, w.Body.String())
+		}
+
+		w := charge(params, "4242")
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+		assert.JSONEq(t, `{"error":"card_declined"}`, w.Body.String())
+		assert.Equal(t, "true", w.Header().Get("X-Decline"))
+		assert.Equal(t, ResponseHeaderSourceScenario, w.Header().Get(ResponseHeaderSource))
+
+		// Other cards have their own state
+		assert.Equal(t, http.StatusCreated, charge(params, "5555").Code)
+
+		waitForAsync()
+		entries := params.DB().History().Data(context.Background())
+		require.Len(t, entries, 1)
+		assert.Equal(t, http.StatusPaymentRequired, entries[0].Response.StatusCode)
+		assert.Equal(t, "/charges", entries[0].Resource)
+	})
+
+	t.Run("state machine", func(t *testing.T) {
+		params := setup(t)
+		session := map[string]string{"X-Session-Id": "a"}
+
+		w := serve(params, http.MethodGet, "/test/orders/1", "/orders/{id}", "", session)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.JSONEq(t, `{"status":"pending"}`, w.Body.String())
+
+		for range 2 {
+			w = serve(params, http.MethodGet, "/test/orders/1", "/orders/{id}", "", session)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, `{"status":"done"}`, w.Body.String())
+		}
+
+		// Requests without the header share the global state
+		w = serve(params, http.MethodGet, "/test/orders/1", "/orders/{id}", "", nil)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("states and jumps", func(t *testing.T) {
+		params := setup(t)
+		cfg := params.serviceConfig
+		table := params.DB().Table(ScenarioTable)
+		ctx := context.Background()
+
+		charge(params, "4242")
+		serve(params, http.MethodGet, "/test/orders/1", "/orders/{id}", "", map[string]string{"X-Session-Id": "a"})
+
+		assert.Equal(t, []*ScenarioState{
+			{Scenario: "charges", Key: "4242", Calls: 1, Step: 1},
+			{Scenario: "order", Key: "a", State: "done"},
+		}, GetScenarioStates(ctx, table, cfg))
+
+		require.NoError(t, SetScenarioStep(ctx, table, "charges", cfg.Scenarios["charges"], "4242", 2))
+		assert.Equal(t, http.StatusPaymentRequired, charge(params, "4242").Code)
+
+		require.NoError(t, SetScenarioState(ctx, table, "order", cfg.Scenarios["order"], "a", "pending"))
+		w := serve(params, http.MethodGet, "/test/orders/1", "/orders/{id}", "", map[string]string{"X-Session-Id": "a"})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		assert.Error(t, SetScenarioStep(ctx, table, "charges", cfg.Scenarios["charges"], "4242", 3))
+		assert.Error(t, SetScenarioStep(ctx, table, "order", cfg.Scenarios["order"], "a", 1))
+		assert.Error(t, SetScenarioState(ctx, table, "order", cfg.Scenarios["order"], "a", "shipped"))
+
+		ResetScenario(ctx, table, "charges")
+		states := GetScenarioStates(ctx, table, cfg)
+		require.Len(t, states, 1)
+		assert.Equal(t, "order", states[0].Scenario)
+		assert.Equal(t, http.StatusCreated, charge(params, "4242").Code)
+	})
+}
+
+func TestAdvanceScenario(t *testing.T) {
+	sc := &config.ScenarioConfig{
+		Initial: "s1",
+		States: map[string]map[string]*config.ScenarioStep{
+			"s1":   {"GET /orders": {Status: http.StatusCreated, Next: "s2"}},
+			"s2":   {"GET /orders": {Status: http.StatusAccepted, Next: "s3"}},
+			"s3":   {"GET /orders": {Status: http.StatusNonAuthoritativeInfo, Next: "s4"}},
+			"s4":   {"GET /orders": {Status: http.StatusNoContent, Next: "done"}},
+			"done": {"GET /orders": {Status: http.StatusOK}},
+		},
+	}
+
+	tables := map[string]func(t *testing.T) db.Table{
+		"memory": func(t *testing.T) db.Table {
+			return db.NewStorage(nil).NewDB("test", time.Minute).Table(ScenarioTable)
+		},
+		"redis": func(t *testing.T) db.Table {
+			mr := miniredis.RunT(t)
+			storage := db.NewStorage(&config.StorageConfig{
+				Type:  config.StorageTypeRedis,
+				Redis: &config.RedisConfig{Address: mr.Addr()},
+			})
+			return storage.NewDB("test", time.Minute).Table(ScenarioTable)
+		},
+	}
+
+	for name, newTable := range tables {
+		t.Run(name+" moves through the states", func(t *testing.T) {
+			ctx := context.Background()
+			table := newTable(t)
+
+			var got []int
+			for range 6 {
+				got = append(got, advanceScenario(ctx, table, "order:", sc, "/orders", http.MethodGet).Status)
+			}
+			assert.Equal(t, []int{201, 202, 203, 204, 200, 200}, got)
+
+			require.NoError(t, SetScenarioState(ctx, table, "order", sc, "", "s3"))
+			assert.Equal(t, http.StatusNonAuthoritativeInfo, advanceScenario(ctx, table, "order:", sc, "/orders", http.MethodGet).Status)
+			assert.Equal(t, []*ScenarioState{{Scenario: "order", State: "s4"}},
+				GetScenarioStates(ctx, table, &config.ServiceConfig{Scenarios: map[string]*config.ScenarioConfig{"order": sc}}))
+		})
+
+		t.Run(name+" concurrent requests take one step each", func(t *testing.T) {
+			ctx := context.Background()
+			table := newTable(t)
+
+			var (
+				mu  sync.Mutex
+				wg  sync.WaitGroup
+				got []int
+			)
+			for range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					status := advanceScenario(ctx, table, "order:", sc, "/orders", http.MethodGet).Status
+					mu.Lock()
+					got = append(got, status)
+					mu.Unlock()
+				}()
+			}
+			wg.Wait()
+
+			slices.Sort(got)
+			assert.Equal(t, []int{201, 202, 203, 204}, got)
+			assert.Equal(t, http.StatusOK, advanceScenario(ctx, table, "order:", sc, "/orders", http.MethodGet).Status)
+		})
+	}
+}
+
+func TestStoredCount(t *testing.T) {
+	assert.Equal(t, 3, storedCount(int64(3)))
+	assert.Equal(t, 3, storedCount(float64(3)))
+	assert.Equal(t, 0, storedCount("3"))
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+)
+
+// Per-service tables holding the stubs and the number of times they've been used.
+const (
+	StubTable     = "stubs"
+	StubUsesTable = "stub-uses"
+)
+
+// stubUsesRetention is how long the uses of a used up stub are kept,
+// so requests that matched it before it was removed can't use it again.
+const stubUsesRetention = time.Minute
+
+// Stub is a rule created at runtime, kept in the service database,
+// so it's served by all instances sharing Redis storage.
+// Stubs are matched before the rules of the config, by descending priority, then oldest first.
+// Times removes the stub after that many uses, ExpiresAt after that time.
+// Uses is the number of times it's been used so far.
+type Stub struct {
+	ID string `json:"id"`
+	config.RuleConfig
+	Times     int        `json:"times,omitempty"`
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// GetStubs returns the stubs of a service in matching order, with their uses.
+func GetStubs(ctx context.Context, database db.DB) []*Stub {
+	stubs := loadStubs(ctx, database)
+	uses := database.Table(StubUsesTable)
+	for _, stub := range stubs {
+		if v, ok := uses.Get(ctx, stub.ID); ok {
+			stub.Uses = storedCount(v)
+		}
+	}
+	return stubs
+}
+
+// GetStub returns a stub with its uses, nil if there is none with the ID.
+func GetStub(ctx context.Context, database db.DB, id string) *Stub {
+	v, ok := database.Table(StubTable).Get(ctx, id)
+	if !ok {
+		return nil
+	}
+	stub := deserializeStub(v)
+	if stub == nil {
+		return nil
+	}
+	if v, ok := database.Table(StubUsesTable).Get(ctx, id); ok {
+		stub.Uses = storedCount(v)
+	}
+	return stub
+}
+
+// SetStub stores a stub, replacing the one with the same ID and resetting its uses.
+func SetStub(ctx context.Context, database db.DB, stub *Stub) {
+	database.Table(StubUsesTable).Delete(ctx, stub.ID)
+	stub.Uses = 0
+	database.Table(StubTable).Set(ctx, stub.ID, stub, stub.remaining())
+}
+
+// DeleteStub removes a stub, returning whether it existed.
+func DeleteStub(ctx context.Context, database db.DB, id string) bool {
+	table := database.Table(StubTable)
+	_, ok := table.Get(ctx, id)
+	table.Delete(ctx, id)
+	database.Table(StubUsesTable).Delete(ctx, id)
+	return ok
+}
+
+// ResetStubs removes all stubs of a service.
+func ResetStubs(ctx context.Context, database db.DB) {
+	database.Table(StubTable).Clear(ctx)
+	database.Table(StubUsesTable).Clear(ctx)
+}
+
+// loadStubs returns the stubs of a service in matching order.
+func loadStubs(ctx context.Context, database db.DB) []*Stub {
+	data := database.Table(StubTable).Data(ctx)
+	res := make([]*Stub, 0, len(data))
+	for _, v := range data {
+		if stub := deserializeStub(v); stub != nil && stub.Response != nil {
+			res = append(res, stub)
+		}
+	}
+	slices.SortFunc(res, func(a, b *Stub) int {
+		return cmp.Or(
+			cmp.Compare(b.Priority, a.Priority),
+			a.CreatedAt.Compare(b.CreatedAt),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+	return res
+}
+
+// useStub counts a use of a matched stub and returns whether it may answer the request.
+// Stubs limited by Times are removed with their last use, so concurrent requests can't use them more often.
+func useStub(ctx context.Context, database db.DB, stub *Stub) bool {
+	uses := database.Table(StubUsesTable).Incr(ctx, stub.ID, 1, stub.remaining())
+	if stub.Times <= 0 {
+		return true
+	}
+	if uses >= int64(stub.Times) {
+		database.Table(StubTable).Delete(ctx, stub.ID)
+		database.Table(StubUsesTable).Incr(ctx, stub.ID, 0, stubUsesRetention)
+	}
+	return uses <= int64(stub.Times)
+}
+
+// remaining returns the time left until the stub expires, 0 if it doesn't.
+func (s *Stub) remaining() time.Duration {
+	if s.ExpiresAt == nil {
+		return 0
+	}
+	return max(time.Until(*s.ExpiresAt), time.Millisecond)
+}
+
+// deserializeStub converts a value retrieved from the DB table into a Stub.
+// Handles both direct *Stub (memory backend) and map[string]any (Redis backend).
+func deserializeStub(val any) *Stub {
+	if stub, ok := val.(*Stub); ok {
+		// Copy so callers filling in uses don't change the stored stub
+		res := *stub
+		return &res
+	}
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil
+	}
+	var stub Stub
+	if err := json.Unmarshal(data, &stub); err != nil {
+		return nil
+	}
+	return &stub
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStubs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	newStub := func(id string, priority int, createdAt time.Time) *Stub {
+		return &Stub{
+			ID:         id,
+			RuleConfig: config.RuleConfig{Priority: priority, Response: &config.RuleResponse{Status: http.StatusAccepted}},
+			CreatedAt:  createdAt,
+		}
+	}
+
+	t.Run("matching order", func(t *testing.T) {
+		database := newTestParams(nil, nil).DB()
+		SetStub(ctx, database, newStub("newer", 0, now))
+		SetStub(ctx, database, newStub("older", 0, now.Add(-time.Minute)))
+		SetStub(ctx, database, newStub("high", 10, now))
+
+		var ids []string
+		for _, stub := range GetStubs(ctx, database) {
+			ids = append(ids, stub.ID)
+		}
+		assert.Equal(t, []string{"high", "older", "newer"}, ids)
+	})
+
+	t.Run("get and delete", func(t *testing.T) {
+		database := newTestParams(nil, nil).DB()
+		SetStub(ctx, database, newStub("a", 0, now))
+
+		stub := GetStub(ctx, database, "a")
+		require.NotNil(t, stub)
+		assert.Equal(t, http.StatusAccepted, stub.Response.Status)
+
+		assert.True(t, DeleteStub(ctx, database, "a"))
+		assert.False(t, DeleteStub(ctx, database, "a"))
+		assert.Nil(t, GetStub(ctx, database, "a"))
+	})
+
+	t.Run("replacing resets uses", func(t *testing.T) {
+		database := newTestParams(nil, nil).DB()
+		stub := newStub("a", 0, now)
+		SetStub(ctx, database, stub)
+		assert.True(t, useStub(ctx, database, stub))
+		assert.Equal(t, 1, GetStub(ctx, database, "a").Uses)
+
+		SetStub(ctx, database, newStub("a", 0, now))
+		assert.Equal(t, 0, GetStub(ctx, database, "a").Uses)
+	})
+
+	t.Run("times", func(t *testing.T) {
+		database := newTestParams(nil, nil).DB()
+		stub := newStub("a", 0, now)
+		stub.Times = 3
+		SetStub(ctx, database, stub)
+
+		var used atomic.Int32
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if useStub(ctx, database, stub) {
+					used.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(3), used.Load())
+		assert.Nil(t, GetStub(ctx, database, "a"))
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		database := newTestParams(nil, nil).DB()
+		stub := newStub("a", 0, now)
+		expiresAt := time.Now().Add(50 * time.Millisecond)
+		stub.ExpiresAt = &expiresAt
+		SetStub(ctx, database, stub)
+		require.NotNil(t, GetStub(ctx, database, "a"))
+
+		time.Sleep(100 * time.Millisecond)
+		assert.Nil(t, GetStub(ctx, database, "a"))
+		assert.Empty(t, GetStubs(ctx, database))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		database := newTestParams(nil, nil).DB()
+		SetStub(ctx, database, newStub("a", 0, now))
+		SetStub(ctx, database, newStub("b", 0, now))
+		ResetStubs(ctx, database)
+		assert.Empty(t, GetStubs(ctx, database))
+	})
+}
+
+func TestDeserializeStub(t *testing.T) {
+	t.Run("stored value is copied", func(t *testing.T) {
+		stored := &Stub{ID: "a"}
+		stub := deserializeStub(stored)
+		stub.Uses = 5
+		assert.Equal(t, 0, stored.Uses)
+	})
+
+	t.Run("json round-trip", func(t *testing.T) {
+		stub := deserializeStub(map[string]any{
+			"id":     "a",
+			"method": "POST",
+			"body": map[string]any{
+				"card.number": map[string]any{"equals": "4000"},
+			},
+			"response": map[string]any{"status": float64(402)},
+			"times":    float64(2),
+		})
+		require.NotNil(t, stub)
+		assert.Equal(t, "a", stub.ID)
+		assert.Equal(t, http.MethodPost, stub.Method)
+		assert.Equal(t, "4000", *stub.Body["card.number"].Equals)
+		assert.Equal(t, http.StatusPaymentRequired, stub.Response.Status)
+		assert.Equal(t, 2, stub.Times)
+	})
+}
+
+func TestCreateRuleMiddleware_Stubs(t *testing.T) {
+	cfg, err := config.NewServiceConfigFromBytes([]byte(`
+rules:
+  - priority: 100
+    path: /charges
+    response:
+      status: 201
+      body: '{"from":"config"}'
+`))
+	require.NoError(t, err)
+	cfg.Name = "test"
+	params := newTestParams(cfg, nil)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/test/charges", strings.NewReader(`{}`))
+		w := httptest.NewRecorder()
+		CreateRuleMiddleware(params)(handler).ServeHTTP(w, req)
+		return w
+	}
+
+	SetStub(context.Background(), params.DB(), &Stub{
+		ID:         "a",
+		RuleConfig: config.RuleConfig{Path: "/charges", Response: &config.RuleResponse{Status: 402, Body: `{"from":"stub"}`}},
+		Times:      1,
+	})
+
+	// Stubs are matched before the rules of the config, whatever their priority
+	w := serve()
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.JSONEq(t, `{"from":"stub"}`, w.Body.String())
+
+	w = serve()
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"from":"config"}`, w.Body.String())
+}
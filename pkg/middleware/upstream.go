@@ -8,7 +8,9 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mockzilla/connexions/v2/pkg/config"
@@ -44,6 +46,8 @@ type upstreamResponse struct {
 	Body        []byte
 	ContentType string
 	StatusCode  int
+	Header      http.Header
+	Violations  []*db.Violation
 }
 
 // circuitBreakerExecutor defines the interface for circuit breaker execution.
@@ -82,50 +86,89 @@ func (o *observableCircuitBreaker) Execute(req func() (*upstreamResponse, error)
 	return resp, err
 }
 
-// CreateUpstreamRequestMiddleware returns a middleware that fetches data from an upstream service.
-// If circuit breaker is configured and the upstream service fails, consequent requests will be blocked.
-func CreateUpstreamRequestMiddleware(params *Params) func(http.Handler) http.Handler {
-	log := params.Logger("upstream")
+// upstreamCircuitBreaker holds the circuit breaker of the service upstream.
+type upstreamCircuitBreaker struct {
+	params *Params
+	log    *slog.Logger
 
-	// Circuit breaker is created at startup with the original config.
-	// It's tied to the upstream URL and shared across requests.
-	var cb circuitBreakerExecutor
-	if cfg := params.serviceConfig.Upstream; cfg != nil && cfg.URL != "" && cfg.CircuitBreaker != nil {
-		cbTable := params.DB().Table("circuit-breaker")
-		var lastError string
-		settings := buildCircuitBreakerSettings(log, cfg.URL, cfg.CircuitBreaker, cbTable, &lastError)
+	mu       sync.Mutex
+	upstream *config.UpstreamConfig
+	cb       circuitBreakerExecutor
+}
 
-		var inner circuitBreakerExecutor
-		var localCB *gobreaker.CircuitBreaker[*upstreamResponse]
+// get returns the circuit breaker for the upstream config, nil if it has none.
+// The breaker is kept while the URL and the circuit breaker settings stay the same.
+func (h *upstreamCircuitBreaker) get(cfg *config.UpstreamConfig) circuitBreakerExecutor {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-		if storageCfg := params.storageConfig; storageCfg != nil && storageCfg.Type == config.StorageTypeRedis {
-			dcb, err := gobreaker.NewDistributedCircuitBreaker[*upstreamResponse](params.DB().CircuitBreakerStore(), settings)
-			if err != nil {
-				log.Error("Failed to create distributed circuit breaker, falling back to local",
-					"name", settings.Name,
-					"error", err,
-				)
-			} else {
-				log.Info("Created distributed circuit breaker", "name", settings.Name)
-				inner = dcb
-				localCB = dcb.CircuitBreaker
-			}
-		}
+	if cfg == h.upstream {
+		return h.cb
+	}
+	prev := h.upstream
+	h.upstream = cfg
+	if prev != nil && cfg != nil && prev.URL == cfg.URL && reflect.DeepEqual(prev.CircuitBreaker, cfg.CircuitBreaker) {
+		return h.cb
+	}
 
-		if inner == nil {
-			lcb := gobreaker.NewCircuitBreaker[*upstreamResponse](settings)
-			inner = lcb
-			localCB = lcb
-		}
+	h.cb = nil
+	if cfg != nil && cfg.URL != "" && cfg.CircuitBreaker != nil {
+		h.cb = newCircuitBreaker(h.params, h.log, cfg)
+	}
+	return h.cb
+}
 
-		cb = &observableCircuitBreaker{
-			inner:     inner,
-			cb:        localCB,
-			cbTable:   cbTable,
-			lastError: &lastError,
+// newCircuitBreaker creates the circuit breaker for the upstream,
+// distributed when the storage is shared.
+func newCircuitBreaker(params *Params, log *slog.Logger, cfg *config.UpstreamConfig) circuitBreakerExecutor {
+	cbTable := params.DB().Table("circuit-breaker")
+	var lastError string
+	settings := buildCircuitBreakerSettings(log, cfg.URL, cfg.CircuitBreaker, cbTable, &lastError)
+
+	var inner circuitBreakerExecutor
+	var localCB *gobreaker.CircuitBreaker[*upstreamResponse]
+
+	if storageCfg := params.storageConfig; storageCfg != nil && storageCfg.Type == config.StorageTypeRedis {
+		dcb, err := gobreaker.NewDistributedCircuitBreaker[*upstreamResponse](params.DB().CircuitBreakerStore(), settings)
+		if err != nil {
+			log.Error("Failed to create distributed circuit breaker, falling back to local",
+				"name", settings.Name,
+				"error", err,
+			)
+		} else {
+			log.Info("Created distributed circuit breaker", "name", settings.Name)
+			inner = dcb
+			localCB = dcb.CircuitBreaker
 		}
 	}
 
+	if inner == nil {
+		lcb := gobreaker.NewCircuitBreaker[*upstreamResponse](settings)
+		inner = lcb
+		localCB = lcb
+	}
+
+	return &observableCircuitBreaker{
+		inner:     inner,
+		cb:        localCB,
+		cbTable:   cbTable,
+		lastError: &lastError,
+	}
+}
+
+// CreateUpstreamRequestMiddleware returns a middleware that fetches data from an upstream service.
+// If circuit breaker is configured and the upstream service fails, consequent requests will be blocked.
+func CreateUpstreamRequestMiddleware(params *Params) func(http.Handler) http.Handler {
+	log := params.Logger("upstream")
+
+	// Circuit breaker is tied to the service upstream and shared across requests.
+	// It's built again when the upstream is changed at runtime.
+	breaker := &upstreamCircuitBreaker{params: params, log: log}
+	breaker.get(params.serviceConfig.Upstream)
+
+	rec := newRecorder(params.Logger("record"))
+	drift := newDriftRecorder(params.Logger("drift"), params)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			svcCfg := params.GetServiceConfig(req)
@@ -141,19 +184,22 @@ func CreateUpstreamRequestMiddleware(params *Params) func(http.Handler) http.Han
 			var resp *upstreamResponse
 			var err error
 
-			if cb != nil {
+			if cb := breaker.get(params.ServiceConfig(req.Context()).Upstream); cb != nil {
 				resp, err = cb.Execute(func() (*upstreamResponse, error) {
-					return getUpstreamResponse(log, svcCfg, params, req)
+					return getUpstreamResponse(log, svcCfg, params, drift, req)
 				})
 			} else {
-				resp, err = getUpstreamResponse(log, svcCfg, params, req)
+				resp, err = getUpstreamResponse(log, svcCfg, params, drift, req)
 			}
 
 			// If an upstream service returns a successful response, write it and return immediately
 			if err == nil && resp != nil {
+				rec.record(cfg.Record, svcCfg.Name, req.Method, strings.TrimPrefix(req.URL.Path, "/"+svcCfg.Name), resp)
+
 				SetRequestIDHeader(w, req)
 				SetDurationHeader(w, req)
 				w.Header().Set(ResponseHeaderSource, ResponseHeaderSourceUpstream)
+				setViolationsHeader(w, resp.Violations)
 				if resp.ContentType != "" {
 					w.Header().Set("Content-Type", resp.ContentType)
 				}
@@ -179,6 +225,7 @@ func CreateUpstreamRequestMiddleware(params *Params) func(http.Handler) http.Han
 
 					requestID := GetRequestID(req)
 					duration := GetDuration(req)
+					violations := drift.check(svcCfg, req, httpErr.StatusCode, httpErr.ContentType, []byte(httpErr.Body))
 					if svcCfg.HistoryEnabled() {
 						histReq := &db.HistoryRequest{
 							Method:     req.Method,
@@ -193,6 +240,9 @@ func CreateUpstreamRequestMiddleware(params *Params) func(http.Handler) http.Han
 							ContentType:    httpErr.ContentType,
 							IsFromUpstream: true,
 							Duration:       duration,
+							Latency:        GetLatency(req),
+							Violations:     violations,
+							Fault:          GetFault(req),
 						}
 						params.transformHistory(svcCfg, histReq, histResp)
 						resourcePath := GetResourcePath(req)
@@ -207,6 +257,7 @@ func CreateUpstreamRequestMiddleware(params *Params) func(http.Handler) http.Han
 					SetRequestIDHeader(w, req)
 					SetDurationHeader(w, req)
 					w.Header().Set(ResponseHeaderSource, ResponseHeaderSourceUpstream)
+					setViolationsHeader(w, violations)
 					if httpErr.ContentType != "" {
 						w.Header().Set("Content-Type", httpErr.ContentType)
 					}
@@ -307,7 +358,7 @@ func buildCircuitBreakerSettings(log *slog.Logger, upstreamURL string, cbCfg *co
 	return settings
 }
 
-func getUpstreamResponse(log *slog.Logger, svcCfg *config.ServiceConfig, params *Params, req *http.Request) (*upstreamResponse, error) {
+func getUpstreamResponse(log *slog.Logger, svcCfg *config.ServiceConfig, params *Params, drift *driftRecorder, req *http.Request) (*upstreamResponse, error) {
 	log = RequestLog(log, req)
 	cfg := svcCfg.Upstream
 
@@ -385,6 +436,7 @@ func getUpstreamResponse(log *slog.Logger, svcCfg *config.ServiceConfig, params
 	log.Info("Received successful upstream response", "body", string(body))
 
 	contentType := resp.Header.Get("Content-Type")
+	violations := drift.check(svcCfg, req, statusCode, contentType, body)
 
 	if recordHistory {
 		histReq := &db.HistoryRequest{
@@ -403,6 +455,9 @@ func getUpstreamResponse(log *slog.Logger, svcCfg *config.ServiceConfig, params
 			UpstreamURL:    outURL,
 			Headers:        db.FlattenHeaders(resp.Header),
 			Duration:       GetDuration(req),
+			Latency:        GetLatency(req),
+			Violations:     violations,
+			Fault:          GetFault(req),
 		}
 		params.transformHistory(svcCfg, histReq, histResp)
 		resourcePath := GetResourcePath(req)
@@ -417,6 +472,8 @@ func getUpstreamResponse(log *slog.Logger, svcCfg *config.ServiceConfig, params
 		Body:        body,
 		ContentType: contentType,
 		StatusCode:  statusCode,
+		Header:      resp.Header,
+		Violations:  violations,
 	}, nil
 }
 
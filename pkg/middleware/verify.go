@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+)
+
+// RequestMatcher matches requests recorded in the history of a service.
+// Resource is a path pattern relative to the service, e.g. /pets/{id}.
+// Query, Headers and Body match like the ones of rules; body fields are JSON paths, e.g. $.card.number.
+// Since and Until limit the time the requests were made in.
+// Unset fields match any request.
+type RequestMatcher struct {
+	Method   string                          `json:"method,omitempty"`
+	Resource string                          `json:"resource,omitempty"`
+	Query    map[string]*config.ValueMatcher `json:"query,omitempty"`
+	Headers  map[string]*config.ValueMatcher `json:"headers,omitempty"`
+	Body     map[string]*config.ValueMatcher `json:"body,omitempty"`
+	Since    *time.Time                      `json:"since,omitempty"`
+	Until    *time.Time                      `json:"until,omitempty"`
+}
+
+// MatchHistory returns the history entries of a service matching the request matcher, in history order.
+// It returns an error when a regex of the matcher doesn't compile.
+func MatchHistory(entries []*db.HistoryEntry, serviceName string, m *RequestMatcher) ([]*db.HistoryEntry, error) {
+	rule := &config.RuleConfig{
+		Method:  m.Method,
+		Path:    m.Resource,
+		Query:   m.Query,
+		Headers: m.Headers,
+		Body:    m.Body,
+	}
+	if err := rule.Parse(); err != nil {
+		return nil, err
+	}
+
+	res := make([]*db.HistoryEntry, 0)
+	for _, entry := range entries {
+		if entry == nil || entry.Request == nil {
+			continue
+		}
+		if m.Since != nil && entry.CreatedAt.Before(*m.Since) {
+			continue
+		}
+		if m.Until != nil && entry.CreatedAt.After(*m.Until) {
+			continue
+		}
+		if rule.Matches(newHistoryRuleRequest(entry.Request, serviceName)) {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+// newHistoryRuleRequest returns the data of a recorded request to match rules against.
+func newHistoryRuleRequest(req *db.HistoryRequest, serviceName string) *config.RuleRequest {
+	var path string
+	var query url.Values
+	if u, err := url.Parse(req.URL); err == nil {
+		path, query = u.Path, u.Query()
+	}
+	if serviceName != "" {
+		path = strings.TrimPrefix(path, "/"+serviceName)
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	headers := make(http.Header, len(req.Headers))
+	for _, line := range req.Headers {
+		name, value, ok := strings.Cut(line, ": ")
+		if ok {
+			headers.Set(name, value)
+		}
+	}
+	contentType := headers.Get("Content-Type")
+
+	return &config.RuleRequest{
+		Method: req.Method,
+		Path:   path,
+		QueryValue: func(name string) (string, bool) {
+			return query.Get(name), query.Has(name)
+		},
+		HeaderValue: func(name string) (string, bool) {
+			values := headers.Values(name)
+			if len(values) == 0 {
+				return "", false
+			}
+			return values[0], true
+		},
+		BodyValue: func(path string) (string, bool) {
+			value := extractBodyValue(req.Body, contentType, path)
+			if value == nil {
+				return "", false
+			}
+			return formatValue(value), true
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchHistory(t *testing.T) {
+	now := time.Now()
+	equals := func(value string) *config.ValueMatcher {
+		return &config.ValueMatcher{Equals: &value}
+	}
+
+	entries := []*db.HistoryEntry{
+		{
+			ID: "charge-100",
+			Request: &db.HistoryRequest{
+				Method:  http.MethodPost,
+				URL:     "/payments/charges?currency=eur",
+				Body:    []byte(`{"amount": 100, "card": {"number": "4242"}}`),
+				Headers: []string{"Content-Type: application/json", "X-Tenant: acme"},
+			},
+			CreatedAt: now.Add(-time.Hour),
+		},
+		{
+			ID: "charge-200",
+			Request: &db.HistoryRequest{
+				Method:  http.MethodPost,
+				URL:     "/payments/charges",
+				Body:    []byte(`{"amount": 200}`),
+				Headers: []string{"Content-Type: application/json"},
+			},
+			CreatedAt: now,
+		},
+		{
+			ID: "refund",
+			Request: &db.HistoryRequest{
+				Method:  http.MethodPost,
+				URL:     "/payments/charges/ch_1/refunds",
+				Body:    []byte("amount=100"),
+				Headers: []string{"Content-Type: application/x-www-form-urlencoded"},
+			},
+			CreatedAt: now,
+		},
+		{
+			ID:        "get",
+			Request:   &db.HistoryRequest{Method: http.MethodGet, URL: "/payments/charges/ch_1"},
+			CreatedAt: now,
+		},
+		{ID: "no request"},
+	}
+
+	ids := func(t *testing.T, m *RequestMatcher) []string {
+		t.Helper()
+		matched, err := MatchHistory(entries, "payments", m)
+		require.NoError(t, err)
+		res := make([]string, 0, len(matched))
+		for _, entry := range matched {
+			res = append(res, entry.ID)
+		}
+		return res
+	}
+
+	halfHourAgo := now.Add(-30 * time.Minute)
+
+	tests := map[string]struct {
+		matcher  *RequestMatcher
+		expected []string
+	}{
+		"everything":       {&RequestMatcher{}, []string{"charge-100", "charge-200", "refund", "get"}},
+		"method":           {&RequestMatcher{Method: "get"}, []string{"get"}},
+		"resource pattern": {&RequestMatcher{Resource: "/charges/{id}/refunds"}, []string{"refund"}},
+		"resource":         {&RequestMatcher{Method: http.MethodPost, Resource: "/charges"}, []string{"charge-100", "charge-200"}},
+		"query":            {&RequestMatcher{Query: map[string]*config.ValueMatcher{"currency": equals("eur")}}, []string{"charge-100"}},
+		"header":           {&RequestMatcher{Headers: map[string]*config.ValueMatcher{"x-tenant": equals("acme")}}, []string{"charge-100"}},
+		"json path":        {&RequestMatcher{Body: map[string]*config.ValueMatcher{"$.amount": equals("100")}}, []string{"charge-100"}},
+		"body field":       {&RequestMatcher{Body: map[string]*config.ValueMatcher{"amount": equals("100")}}, []string{"charge-100", "refund"}},
+		"nested json path": {&RequestMatcher{Body: map[string]*config.ValueMatcher{"$.card.number": {Regex: "^42"}}}, []string{"charge-100"}},
+		"since":            {&RequestMatcher{Resource: "/charges", Since: &halfHourAgo}, []string{"charge-200"}},
+		"until":            {&RequestMatcher{Resource: "/charges", Until: &halfHourAgo}, []string{"charge-100"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ids(t, tc.matcher))
+		})
+	}
+
+	t.Run("root service", func(t *testing.T) {
+		matched, err := MatchHistory([]*db.HistoryEntry{
+			{ID: "root", Request: &db.HistoryRequest{Method: http.MethodGet, URL: "/pets/1"}},
+		}, "", &RequestMatcher{Resource: "/pets/{id}"})
+		require.NoError(t, err)
+		assert.Len(t, matched, 1)
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		_, err := MatchHistory(entries, "payments", &RequestMatcher{
+			Query: map[string]*config.ValueMatcher{"q": {Regex: "("}},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,109 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// remoteRefreshInterval limits how often an unknown key ID triggers a refetch of the key set.
+const remoteRefreshInterval = time.Minute
+
+// RemoteKeySet is a KeySet fetched from a JWKS URL.
+// Keys are cached and refetched when a token names an unknown key.
+// Lookups during a fetch wait for it without holding the cache lock, so cached keys are served meanwhile.
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+	group  singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewRemoteKeySet creates a key set fetching keys from url on first use.
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// PublicKey returns the key with the given ID, fetching the key set if the key isn't cached.
+func (s *RemoteKeySet) PublicKey(kid string) (*rsa.PublicKey, error) {
+	key, recent := s.cached(kid)
+	if key != nil {
+		return key, nil
+	}
+	if recent {
+		return nil, ErrUnknownKey
+	}
+
+	// Concurrent lookups share a single fetch
+	if _, err, _ := s.group.Do(s.url, func() (any, error) { return nil, s.fetch() }); err != nil {
+		return nil, err
+	}
+	if key, _ := s.cached(kid); key != nil {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// cached returns the cached key with the given ID and whether the key set was fetched recently.
+func (s *RemoteKeySet) cached(kid string) (*rsa.PublicKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[kid], time.Since(s.fetchedAt) < remoteRefreshInterval
+}
+
+// fetch replaces the cached keys with the key set at the URL,
+// unless it was fetched recently, e.g. by a fetch that ended just before.
+// The fetch time is set when it ends, also when it fails, so lookups during a fetch wait for it.
+func (s *RemoteKeySet) fetch() error {
+	s.mu.Lock()
+	recent := time.Since(s.fetchedAt) < remoteRefreshInterval
+	s.mu.Unlock()
+	if recent {
+		return nil
+	}
+	defer func() {
+		s.mu.Lock()
+		s.fetchedAt = time.Now()
+		s.mu.Unlock()
+	}()
+
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return fmt.Errorf("fetching key set: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching key set: status %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decoding key set: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
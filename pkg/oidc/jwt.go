@@ -0,0 +1,233 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnknownKey       = errors.New("unknown signing key")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenNotYetValid = errors.New("token not valid yet")
+)
+
+// Key is an RSA key signing tokens with RS256.
+type Key struct {
+	ID      string
+	Private *rsa.PrivateKey
+}
+
+// KeySet looks up the public keys verifying tokens by key ID.
+type KeySet interface {
+	PublicKey(kid string) (*rsa.PublicKey, error)
+}
+
+// JWK is an RSA public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// GenerateKey generates a 2048-bit signing key.
+func GenerateKey() (*Key, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+	return newKey(private), nil
+}
+
+// LoadOrCreateKey reads a PEM encoded RSA key from path.
+// A missing file is created with a generated key.
+func LoadOrCreateKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key.Private)}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("creating key directory: %w", err)
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			return nil, fmt.Errorf("writing key: %w", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("reading key: no PEM data in %s", path)
+	}
+
+	if private, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return newKey(private), nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing key: %w", err)
+	}
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("parsing key: %T is not an RSA key", parsed)
+	}
+	return newKey(private), nil
+}
+
+// newKey derives the key ID from the modulus, so it stays the same for the same key.
+func newKey(private *rsa.PrivateKey) *Key {
+	sum := sha256.Sum256(private.N.Bytes())
+	return &Key{
+		ID:      base64.RawURLEncoding.EncodeToString(sum[:])[:16],
+		Private: private,
+	}
+}
+
+// JWK returns the public part of the key.
+func (k *Key) JWK() JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: k.ID,
+		N:   base64.RawURLEncoding.EncodeToString(k.Private.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.Private.E)).Bytes()),
+	}
+}
+
+// PublicKey implements KeySet for tokens signed with this key.
+func (k *Key) PublicKey(kid string) (*rsa.PublicKey, error) {
+	if kid != k.ID {
+		return nil, ErrUnknownKey
+	}
+	return &k.Private.PublicKey, nil
+}
+
+// Sign encodes the claims as a JWT signed with RS256.
+func (k *Key) Sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": k.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("encoding claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.Private, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// PublicKey decodes the RSA public key.
+func (j JWK) PublicKey() (*rsa.PublicKey, error) {
+	if j.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil {
+		return nil, fmt.Errorf("decoding modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil {
+		return nil, fmt.Errorf("decoding exponent: %w", err)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// Parse decodes the header and claims of a JWT without verifying it.
+func Parse(token string) (map[string]any, map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, ErrMalformedToken
+	}
+
+	var header, claims map[string]any
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, nil, err
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, nil, err
+	}
+	return header, claims, nil
+}
+
+// Verify checks the RS256 signature of a JWT with the keys of the set
+// and its exp and nbf claims, returning the claims of a valid token.
+func Verify(token string, keys KeySet) (map[string]any, error) {
+	header, claims, err := Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if alg, _ := header["alg"].(string); alg != "RS256" {
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	kid, _ := header["kid"].(string)
+	public, err := keys.PublicKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := strings.LastIndex(token, ".")
+	signature, err := base64.RawURLEncoding.DecodeString(token[idx+1:])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	digest := sha256.Sum256([]byte(token[:idx]))
+	if err := rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	now := float64(time.Now().Unix())
+	if exp, ok := claims["exp"].(float64); ok && now >= exp {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return nil, ErrTokenNotYetValid
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, dest any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey_SignAndVerify(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		token, err := key.Sign(map[string]any{"sub": "jane", "exp": time.Now().Add(time.Minute).Unix()})
+		require.NoError(t, err)
+
+		header, _, err := Parse(token)
+		require.NoError(t, err)
+		assert.Equal(t, "RS256", header["alg"])
+		assert.Equal(t, key.ID, header["kid"])
+
+		claims, err := Verify(token, key)
+		require.NoError(t, err)
+		assert.Equal(t, "jane", claims["sub"])
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		token, err := key.Sign(map[string]any{"exp": time.Now().Add(-time.Minute).Unix()})
+		require.NoError(t, err)
+
+		_, err = Verify(token, key)
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+
+	t.Run("not yet valid", func(t *testing.T) {
+		token, err := key.Sign(map[string]any{"nbf": time.Now().Add(time.Minute).Unix()})
+		require.NoError(t, err)
+
+		_, err = Verify(token, key)
+		assert.ErrorIs(t, err, ErrTokenNotYetValid)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		token, err := key.Sign(map[string]any{"sub": "jane"})
+		require.NoError(t, err)
+
+		other, err := key.Sign(map[string]any{"sub": "admin"})
+		require.NoError(t, err)
+
+		parts := strings.Split(token, ".")
+		parts[1] = strings.Split(other, ".")[1]
+		_, err = Verify(strings.Join(parts, "."), key)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		other, err := GenerateKey()
+		require.NoError(t, err)
+
+		token, err := other.Sign(map[string]any{"sub": "jane"})
+		require.NoError(t, err)
+
+		_, err = Verify(token, key)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := Verify("abc", key)
+		assert.ErrorIs(t, err, ErrMalformedToken)
+
+		_, err = Verify("a.b.c", key)
+		assert.ErrorIs(t, err, ErrMalformedToken)
+	})
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "oidc.pem")
+
+	created, err := LoadOrCreateKey(path)
+	require.NoError(t, err)
+	assert.FileExists(t, path)
+
+	loaded, err := LoadOrCreateKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, loaded.ID)
+
+	t.Run("rejects non-PEM files", func(t *testing.T) {
+		bad := filepath.Join(t.TempDir(), "bad.pem")
+		require.NoError(t, os.WriteFile(bad, []byte("nope"), 0o600))
+
+		_, err := LoadOrCreateKey(bad)
+		assert.Error(t, err)
+	})
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_ = json.NewEncoder(w).Encode(&JWKS{Keys: []JWK{key.JWK()}})
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL)
+
+	token, err := key.Sign(map[string]any{"sub": "jane"})
+	require.NoError(t, err)
+
+	claims, err := Verify(token, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "jane", claims["sub"])
+
+	_, err = Verify(token, keys)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	t.Run("unknown keys don't refetch right away", func(t *testing.T) {
+		_, err := keys.PublicKey("unknown")
+		assert.ErrorIs(t, err, ErrUnknownKey)
+		assert.Equal(t, 1, requests)
+	})
+}
+
+func TestRemoteKeySet_ConcurrentFetch(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		_ = json.NewEncoder(w).Encode(&JWKS{Keys: []JWK{key.JWK()}})
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL)
+	cachedKey, err := GenerateKey()
+	require.NoError(t, err)
+	keys.keys = map[string]*rsa.PublicKey{"cached": &cachedKey.Private.PublicKey}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			res, err := keys.PublicKey(key.ID)
+			assert.NoError(t, err)
+			assert.NotNil(t, res)
+		})
+	}
+
+	// Cached keys are served while the key set is fetched
+	assert.Eventually(t, func() bool { return requests.Load() == 1 }, time.Second, 5*time.Millisecond)
+	res, err := keys.PublicKey("cached")
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), requests.Load())
+}
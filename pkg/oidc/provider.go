@@ -0,0 +1,451 @@
+// Package oidc implements a mock OAuth2 / OpenID Connect provider issuing RS256 signed JWTs,
+// and the JWT and JWKS handling to verify them.
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+)
+
+// DiscoveryPath is the path of the discovery document relative to the issuer.
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+// authCodeTTL is how long an authorization code can be exchanged for tokens.
+const authCodeTTL = 10 * time.Minute
+
+// refreshTokenTTL is how long a refresh token can be exchanged for tokens.
+const refreshTokenTTL = 24 * time.Hour
+
+// grant is what an authorization code or refresh token stands for.
+type grant struct {
+	clientID        string
+	subject         string
+	scope           string
+	nonce           string
+	redirectURI     string
+	challenge       string
+	challengeMethod string
+	expiresAt       time.Time
+}
+
+// Provider is a mock OAuth2 / OpenID Connect provider.
+// Authorization codes and refresh tokens are kept in memory.
+type Provider struct {
+	cfg *config.OIDCConfig
+	key *Key
+
+	mu            sync.Mutex
+	codes         map[string]*grant
+	refreshTokens map[string]*grant
+}
+
+// NewProvider creates a provider from the config, loading or generating its signing key.
+func NewProvider(cfg *config.OIDCConfig) (*Provider, error) {
+	cfg.WithDefaults()
+
+	var key *Key
+	var err error
+	if cfg.KeyFile != "" {
+		key, err = LoadOrCreateKey(cfg.KeyFile)
+	} else {
+		key, err = GenerateKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		cfg:           cfg,
+		key:           key,
+		codes:         make(map[string]*grant),
+		refreshTokens: make(map[string]*grant),
+	}, nil
+}
+
+// Key returns the signing key of the provider.
+func (p *Provider) Key() *Key {
+	return p.key
+}
+
+// Routes registers the provider endpoints relative to its mount point.
+func (p *Provider) Routes(r chi.Router) {
+	r.Get(DiscoveryPath, p.discovery)
+	r.Get("/jwks", p.jwks)
+	r.Post("/token", p.token)
+	r.Get("/authorize", p.authorize)
+	r.Get("/userinfo", p.userinfo)
+	r.Post("/userinfo", p.userinfo)
+}
+
+// issuer returns the configured issuer or the provider URL on the host of the request.
+func (p *Provider) issuer(r *http.Request) string {
+	if p.cfg.Issuer != "" {
+		return strings.TrimSuffix(p.cfg.Issuer, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + "/" + strings.Trim(p.cfg.URL, "/")
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := p.issuer(r)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials", "password", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256", "plain"},
+		"scopes_supported":                      []string{"openid", "profile", "email", "offline_access"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &JWKS{Keys: []JWK{p.key.JWK()}})
+}
+
+// authorize issues an authorization code without a login page:
+// the subject is the login_hint user, the only configured user or "user".
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	clientID := q.Get("client_id")
+	if len(p.cfg.Clients) > 0 {
+		if _, ok := p.cfg.Clients[clientID]; !ok {
+			oauthError(w, http.StatusBadRequest, "invalid_client", "unknown client_id")
+			return
+		}
+	}
+
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "redirect_uri must be an absolute URL")
+		return
+	}
+
+	redirect := func(params url.Values) {
+		query := redirectURI.Query()
+		for k, v := range params {
+			query[k] = v
+		}
+		if state := q.Get("state"); state != "" {
+			query.Set("state", state)
+		}
+		redirectURI.RawQuery = query.Encode()
+		http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+	}
+
+	if q.Get("response_type") != "code" {
+		redirect(url.Values{"error": {"unsupported_response_type"}})
+		return
+	}
+
+	method := q.Get("code_challenge_method")
+	if method == "" && q.Get("code_challenge") != "" {
+		method = "plain"
+	}
+	if method != "" && method != "S256" && method != "plain" {
+		redirect(url.Values{"error": {"invalid_request"}, "error_description": {"unsupported code_challenge_method"}})
+		return
+	}
+
+	subject := q.Get("login_hint")
+	if subject == "" {
+		subject = "user"
+		if len(p.cfg.Users) == 1 {
+			subject = slices.Collect(maps.Keys(p.cfg.Users))[0]
+		}
+	}
+	if len(p.cfg.Users) > 0 && p.cfg.Users[subject] == nil {
+		redirect(url.Values{"error": {"access_denied"}, "error_description": {"unknown user"}})
+		return
+	}
+
+	code := randomString()
+	p.put(p.codes, code, &grant{
+		clientID:        clientID,
+		subject:         subject,
+		scope:           q.Get("scope"),
+		nonce:           q.Get("nonce"),
+		redirectURI:     q.Get("redirect_uri"),
+		challenge:       q.Get("code_challenge"),
+		challengeMethod: method,
+		expiresAt:       time.Now().Add(authCodeTTL),
+	})
+
+	redirect(url.Values{"code": {code}})
+}
+
+// token implements the client_credentials, password, refresh_token and authorization_code grants.
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "malformed form body")
+		return
+	}
+
+	clientID, secret, basic := r.BasicAuth()
+	if !basic {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if !p.authenticateClient(clientID, secret, r.PostForm.Get("grant_type")) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oidc"`)
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	var g *grant
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case "client_credentials":
+		g = &grant{clientID: clientID, subject: clientID, scope: r.PostForm.Get("scope")}
+
+	case "password":
+		username, password := r.PostForm.Get("username"), r.PostForm.Get("password")
+		if username == "" || !p.authenticateUser(username, password) {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "invalid username or password")
+			return
+		}
+		g = &grant{clientID: clientID, subject: username, scope: r.PostForm.Get("scope")}
+
+	case "refresh_token":
+		g = p.take(p.refreshTokens, r.PostForm.Get("refresh_token"))
+		if g == nil || g.clientID != clientID || time.Now().After(g.expiresAt) {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "invalid refresh token")
+			return
+		}
+
+	case "authorization_code":
+		g = p.take(p.codes, r.PostForm.Get("code"))
+		if g == nil || g.clientID != clientID || time.Now().After(g.expiresAt) {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "invalid authorization code")
+			return
+		}
+		if g.redirectURI != r.PostForm.Get("redirect_uri") {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri does not match")
+			return
+		}
+		if !verifyChallenge(g.challenge, g.challengeMethod, r.PostForm.Get("code_verifier")) {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match")
+			return
+		}
+
+	default:
+		oauthError(w, http.StatusBadRequest, "unsupported_grant_type", "unsupported grant_type "+grantType)
+		return
+	}
+
+	res, err := p.issue(r, g, r.PostForm.Get("grant_type") != "client_credentials")
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, res)
+}
+
+// issue signs the tokens of a grant, with a refresh token for user grants
+// and an ID token when the openid scope is requested.
+func (p *Provider) issue(r *http.Request, g *grant, withRefresh bool) (map[string]any, error) {
+	now := time.Now()
+	issuer := p.issuer(r)
+
+	audience := p.cfg.Audience
+	if audience == "" {
+		audience = g.clientID
+	}
+
+	claims := p.claims(g.subject)
+	claims["iss"] = issuer
+	claims["sub"] = g.subject
+	claims["aud"] = audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(p.cfg.TokenTTL).Unix()
+	claims["jti"] = randomString()
+	if g.clientID != "" {
+		claims["client_id"] = g.clientID
+	}
+	if g.scope != "" {
+		claims["scope"] = g.scope
+	}
+
+	accessToken, err := p.key.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(p.cfg.TokenTTL.Seconds()),
+	}
+	if g.scope != "" {
+		res["scope"] = g.scope
+	}
+
+	if withRefresh {
+		refreshToken := randomString()
+		p.put(p.refreshTokens, refreshToken, &grant{
+			clientID:  g.clientID,
+			subject:   g.subject,
+			scope:     g.scope,
+			expiresAt: now.Add(refreshTokenTTL),
+		})
+		res["refresh_token"] = refreshToken
+	}
+
+	if withRefresh && slices.Contains(strings.Fields(g.scope), "openid") {
+		idClaims := p.claims(g.subject)
+		idClaims["iss"] = issuer
+		idClaims["sub"] = g.subject
+		idClaims["aud"] = g.clientID
+		idClaims["iat"] = now.Unix()
+		idClaims["exp"] = now.Add(p.cfg.TokenTTL).Unix()
+		if g.nonce != "" {
+			idClaims["nonce"] = g.nonce
+		}
+		idToken, err := p.key.Sign(idClaims)
+		if err != nil {
+			return nil, err
+		}
+		res["id_token"] = idToken
+	}
+
+	return res, nil
+}
+
+// userinfo returns the claims of the subject of a valid access token.
+func (p *Provider) userinfo(w http.ResponseWriter, r *http.Request) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "bearer") || token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oidc"`)
+		oauthError(w, http.StatusUnauthorized, "invalid_token", "access token is missing")
+		return
+	}
+
+	claims, err := Verify(token, p.key)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oidc", error="invalid_token"`)
+		oauthError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+
+	sub, _ := claims["sub"].(string)
+	res := p.claims(sub)
+	res["sub"] = sub
+	writeJSON(w, http.StatusOK, res)
+}
+
+// claims returns the configured claims merged with the claims of the user.
+func (p *Provider) claims(subject string) map[string]any {
+	res := make(map[string]any, len(p.cfg.Claims))
+	maps.Copy(res, p.cfg.Claims)
+	if user := p.cfg.Users[subject]; user != nil {
+		maps.Copy(res, user.Claims)
+	}
+	return res
+}
+
+// authenticateClient checks the client credentials against the configured clients.
+// Public clients of the authorization code flow may omit the secret, PKCE protects the exchange.
+func (p *Provider) authenticateClient(clientID, secret, grantType string) bool {
+	if len(p.cfg.Clients) == 0 {
+		return true
+	}
+	expected, ok := p.cfg.Clients[clientID]
+	if !ok {
+		return false
+	}
+	if secret == "" && (grantType == "authorization_code" || grantType == "refresh_token") {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) == 1
+}
+
+// authenticateUser checks a password against the configured users.
+func (p *Provider) authenticateUser(username, password string) bool {
+	if len(p.cfg.Users) == 0 {
+		return true
+	}
+	user, ok := p.cfg.Users[username]
+	return ok && subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) == 1
+}
+
+// put stores a grant, removing the expired grants of the map,
+// so codes and refresh tokens that are never exchanged don't pile up.
+func (p *Provider) put(grants map[string]*grant, key string, g *grant) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for k, existing := range grants {
+		if now.After(existing.expiresAt) {
+			delete(grants, k)
+		}
+	}
+	grants[key] = g
+}
+
+// take removes and returns a single-use grant.
+func (p *Provider) take(grants map[string]*grant, key string) *grant {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	g, ok := grants[key]
+	if !ok {
+		return nil
+	}
+	delete(grants, key)
+	return g
+}
+
+// verifyChallenge checks a PKCE code verifier against the challenge of the authorization request.
+func verifyChallenge(challenge, method, verifier string) bool {
+	if challenge == "" {
+		return true
+	}
+	if method == "S256" {
+		sum := sha256.Sum256([]byte(verifier))
+		return base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+	}
+	return verifier == challenge
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func oauthError(w http.ResponseWriter, statusCode int, code, description string) {
+	writeJSON(w, statusCode, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
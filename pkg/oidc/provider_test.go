@@ -0,0 +1,303 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider(t *testing.T) {
+	newServer := func(t *testing.T, cfg *config.OIDCConfig) (*Provider, *httptest.Server) {
+		t.Helper()
+		provider, err := NewProvider(cfg)
+		require.NoError(t, err)
+
+		r := chi.NewRouter()
+		r.Route(cfg.URL, provider.Routes)
+		server := httptest.NewServer(r)
+		t.Cleanup(server.Close)
+		return provider, server
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	postToken := func(t *testing.T, server *httptest.Server, form url.Values) (int, map[string]any) {
+		t.Helper()
+		resp, err := client.PostForm(server.URL+"/.oidc/token", form)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+
+	cfg := func() *config.OIDCConfig {
+		return &config.OIDCConfig{
+			Enabled: true,
+			Claims:  map[string]any{"tenant": "acme"},
+			Clients: map[string]string{"app": "secret"},
+			Users: map[string]*config.OIDCUser{
+				"jane": {Password: "pw", Claims: map[string]any{"email": "jane@example.com"}},
+			},
+		}
+	}
+
+	t.Run("discovery and JWKS", func(t *testing.T) {
+		provider, server := newServer(t, cfg())
+
+		resp, err := client.Get(server.URL + "/.oidc" + DiscoveryPath)
+		require.NoError(t, err)
+		var doc map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+		_ = resp.Body.Close()
+
+		issuer := server.URL + "/.oidc"
+		assert.Equal(t, issuer, doc["issuer"])
+		assert.Equal(t, issuer+"/token", doc["token_endpoint"])
+		assert.Equal(t, issuer+"/jwks", doc["jwks_uri"])
+
+		keys := NewRemoteKeySet(issuer + "/jwks")
+		public, err := keys.PublicKey(provider.Key().ID)
+		require.NoError(t, err)
+		assert.Equal(t, provider.Key().Private.N, public.N)
+	})
+
+	t.Run("client credentials", func(t *testing.T) {
+		provider, server := newServer(t, cfg())
+
+		status, body := postToken(t, server, url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {"app"},
+			"client_secret": {"secret"},
+			"scope":         {"pets:read"},
+		})
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "Bearer", body["token_type"])
+		assert.Equal(t, float64(3600), body["expires_in"])
+		assert.Nil(t, body["refresh_token"])
+
+		claims, err := Verify(body["access_token"].(string), provider.Key())
+		require.NoError(t, err)
+		assert.Equal(t, "app", claims["sub"])
+		assert.Equal(t, "app", claims["aud"])
+		assert.Equal(t, "pets:read", claims["scope"])
+		assert.Equal(t, "acme", claims["tenant"])
+		assert.Equal(t, server.URL+"/.oidc", claims["iss"])
+	})
+
+	t.Run("rejects unknown clients", func(t *testing.T) {
+		_, server := newServer(t, cfg())
+
+		status, body := postToken(t, server, url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {"app"},
+			"client_secret": {"wrong"},
+		})
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "invalid_client", body["error"])
+	})
+
+	t.Run("password and refresh token", func(t *testing.T) {
+		provider, server := newServer(t, cfg())
+
+		status, body := postToken(t, server, url.Values{
+			"grant_type": {"password"},
+			"client_id":  {"app"}, "client_secret": {"secret"},
+			"username": {"jane"}, "password": {"pw"},
+			"scope": {"openid"},
+		})
+		require.Equal(t, http.StatusOK, status)
+		require.NotEmpty(t, body["refresh_token"])
+
+		idClaims, err := Verify(body["id_token"].(string), provider.Key())
+		require.NoError(t, err)
+		assert.Equal(t, "jane", idClaims["sub"])
+		assert.Equal(t, "jane@example.com", idClaims["email"])
+
+		refreshToken := body["refresh_token"].(string)
+		status, body = postToken(t, server, url.Values{
+			"grant_type": {"refresh_token"},
+			"client_id":  {"app"}, "client_secret": {"secret"},
+			"refresh_token": {refreshToken},
+		})
+		require.Equal(t, http.StatusOK, status)
+		assert.NotEqual(t, refreshToken, body["refresh_token"])
+
+		status, body = postToken(t, server, url.Values{
+			"grant_type": {"refresh_token"},
+			"client_id":  {"app"}, "client_secret": {"secret"},
+			"refresh_token": {refreshToken},
+		})
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "invalid_grant", body["error"])
+	})
+
+	t.Run("expired grants are removed", func(t *testing.T) {
+		provider, server := newServer(t, cfg())
+		expired := time.Now().Add(-time.Second)
+		provider.refreshTokens["old"] = &grant{clientID: "app", subject: "jane", expiresAt: expired}
+		provider.codes["old"] = &grant{clientID: "app", subject: "jane", expiresAt: expired}
+
+		status, body := postToken(t, server, url.Values{
+			"grant_type": {"password"},
+			"client_id":  {"app"}, "client_secret": {"secret"},
+			"username": {"jane"}, "password": {"pw"},
+		})
+		require.Equal(t, http.StatusOK, status)
+		assert.NotContains(t, provider.refreshTokens, "old")
+		assert.Contains(t, provider.refreshTokens, body["refresh_token"])
+
+		provider.put(provider.codes, "new", &grant{expiresAt: time.Now().Add(authCodeTTL)})
+		assert.NotContains(t, provider.codes, "old")
+		assert.Contains(t, provider.codes, "new")
+	})
+
+	t.Run("rejects expired refresh tokens", func(t *testing.T) {
+		provider, server := newServer(t, cfg())
+		provider.refreshTokens["old"] = &grant{clientID: "app", subject: "jane", expiresAt: time.Now().Add(-time.Second)}
+
+		status, body := postToken(t, server, url.Values{
+			"grant_type": {"refresh_token"},
+			"client_id":  {"app"}, "client_secret": {"secret"},
+			"refresh_token": {"old"},
+		})
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "invalid_grant", body["error"])
+	})
+
+	t.Run("rejects wrong passwords", func(t *testing.T) {
+		_, server := newServer(t, cfg())
+
+		status, body := postToken(t, server, url.Values{
+			"grant_type": {"password"},
+			"client_id":  {"app"}, "client_secret": {"secret"},
+			"username": {"jane"}, "password": {"nope"},
+		})
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "invalid_grant", body["error"])
+	})
+
+	t.Run("authorization code with PKCE", func(t *testing.T) {
+		provider, server := newServer(t, cfg())
+
+		verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		sum := sha256.Sum256([]byte(verifier))
+		challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+		authorize := func() string {
+			resp, err := client.Get(server.URL + "/.oidc/authorize?" + url.Values{
+				"response_type":         {"code"},
+				"client_id":             {"app"},
+				"redirect_uri":          {"http://app.local/callback"},
+				"scope":                 {"openid"},
+				"state":                 {"xyz"},
+				"nonce":                 {"n-1"},
+				"code_challenge":        {challenge},
+				"code_challenge_method": {"S256"},
+			}.Encode())
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			require.Equal(t, http.StatusFound, resp.StatusCode)
+
+			location, err := url.Parse(resp.Header.Get("Location"))
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(location.String(), "http://app.local/callback?"))
+			assert.Equal(t, "xyz", location.Query().Get("state"))
+			return location.Query().Get("code")
+		}
+
+		code := authorize()
+		status, body := postToken(t, server, url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {"app"},
+			"code":          {code},
+			"redirect_uri":  {"http://app.local/callback"},
+			"code_verifier": {"wrong"},
+		})
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "invalid_grant", body["error"])
+
+		code = authorize()
+		status, body = postToken(t, server, url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {"app"},
+			"code":          {code},
+			"redirect_uri":  {"http://app.local/callback"},
+			"code_verifier": {verifier},
+		})
+		require.Equal(t, http.StatusOK, status)
+
+		idClaims, err := Verify(body["id_token"].(string), provider.Key())
+		require.NoError(t, err)
+		assert.Equal(t, "jane", idClaims["sub"])
+		assert.Equal(t, "n-1", idClaims["nonce"])
+
+		status, _ = postToken(t, server, url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {"app"},
+			"code":          {code},
+			"redirect_uri":  {"http://app.local/callback"},
+			"code_verifier": {verifier},
+		})
+		assert.Equal(t, http.StatusBadRequest, status, "codes are single use")
+	})
+
+	t.Run("authorize rejects unknown clients", func(t *testing.T) {
+		_, server := newServer(t, cfg())
+
+		resp, err := client.Get(server.URL + "/.oidc/authorize?response_type=code&client_id=other&redirect_uri=http://app.local/cb")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("unsupported grant type", func(t *testing.T) {
+		_, server := newServer(t, &config.OIDCConfig{Enabled: true})
+
+		status, body := postToken(t, server, url.Values{"grant_type": {"implicit"}})
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "unsupported_grant_type", body["error"])
+	})
+
+	t.Run("userinfo", func(t *testing.T) {
+		_, server := newServer(t, cfg())
+
+		_, body := postToken(t, server, url.Values{
+			"grant_type": {"password"},
+			"client_id":  {"app"}, "client_secret": {"secret"},
+			"username": {"jane"}, "password": {"pw"},
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/.oidc/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer "+body["access_token"].(string))
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		var info map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+		_ = resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, map[string]any{"sub": "jane", "email": "jane@example.com", "tenant": "acme"}, info)
+
+		req.Header.Set("Authorization", "Bearer nope")
+		resp, err = client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`)
+	})
+}
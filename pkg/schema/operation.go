@@ -15,6 +15,9 @@ type Operation struct {
 	Headers     *Schema         `json:"headers,omitempty"`
 	Body        *Schema         `json:"body,omitempty"`
 
+	// Whether the spec marks the request body as required
+	BodyRequired bool `json:"bodyRequired,omitempty"`
+
 	// Encoding metadata for form fields
 	BodyEncoding map[string]codegen.RequestBodyEncoding `json:"bodyEncoding,omitempty"`
 	Response     *Response                              `json:"response,omitempty"`
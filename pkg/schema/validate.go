@@ -0,0 +1,260 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ValidationError describes a value that doesn't match its schema.
+// Field is a JSON path to the value, e.g. "$.items[].name", with "[]" standing for any array element,
+// so errors of the same field in different elements share the path.
+// In tells where a request value came from: path, query, header or body.
+type ValidationError struct {
+	In      string `json:"in,omitempty"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.In != "" {
+		return e.In + " " + e.Field + ": " + e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// patterns caches the compiled schema patterns, nil for the ones that don't compile.
+var patterns sync.Map
+
+// compilePattern returns the compiled pattern, or nil if it's not a valid regular expression.
+// Patterns are compiled once, not for every validated value.
+func compilePattern(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = nil
+	}
+	patterns.Store(pattern, re)
+	return re
+}
+
+// Validate checks a decoded JSON value against the schema.
+// It reports type mismatches, missing required properties, enum, length, range and pattern violations
+// and malformed date, date-time and uuid strings.
+// Properties not described by the schema are allowed, so additions don't count as violations.
+func Validate(s *Schema, value any) []*ValidationError {
+	var errs []*ValidationError
+	validateValue(s, value, "$", &errs)
+	return errs
+}
+
+// ValidateResponse checks a response against the responses of an operation.
+// Only JSON bodies are validated, a status code or content type that isn't documented is reported
+// on the "status" and "content-type" fields.
+func ValidateResponse(resp *Response, statusCode int, contentType string, body []byte) []*ValidationError {
+	if resp == nil || len(resp.All) == 0 {
+		return nil
+	}
+
+	item := resp.GetResponse(statusCode)
+	if item == nil {
+		return []*ValidationError{{
+			Field:   "status",
+			Message: fmt.Sprintf("status code %d is not documented", statusCode),
+		}}
+	}
+	if item.Content == nil {
+		return nil
+	}
+
+	return ValidateBody(item.Content, item.ContentType, contentType, body)
+}
+
+// ValidateBody checks a body against a schema documented for the expected content type.
+// Bodies of content types other than JSON are not validated.
+func ValidateBody(s *Schema, expectedContentType, contentType string, body []byte) []*ValidationError {
+	actual, _, _ := mime.ParseMediaType(contentType)
+	expected, _, _ := mime.ParseMediaType(expectedContentType)
+	if expected != "" && actual != "" && isJSONMediaType(expected) != isJSONMediaType(actual) {
+		return []*ValidationError{{
+			Field:   "content-type",
+			Message: fmt.Sprintf("expected %s, got %s", expected, actual),
+		}}
+	}
+	if !isJSONMediaType(actual) && !(actual == "" && isJSONMediaType(expected)) {
+		return nil
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return []*ValidationError{{Field: "$", Message: "body is empty"}}
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []*ValidationError{{Field: "$", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	return Validate(s, value)
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func validateValue(s *Schema, value any, path string, errs *[]*ValidationError) {
+	if s == nil || s.Recursive {
+		return
+	}
+
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, &ValidationError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if value == nil {
+		if s.Type != "" && s.Type != "null" && !s.Nullable {
+			fail("expected %s, got null", s.Type)
+		}
+		return
+	}
+
+	if s.Type != "" && !matchesType(s.Type, value) {
+		fail("expected %s, got %s", s.Type, jsonType(value))
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(v any) bool { return equalJSON(v, value) }) {
+		fail("value %v is not one of %v", value, s.Enum)
+	}
+
+	switch v := value.(type) {
+	case string:
+		validateString(s, v, fail)
+
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("%v is less than minimum %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("%v is greater than maximum %v", v, *s.Maximum)
+		}
+
+	case []any:
+		if s.MinItems != nil && int64(len(v)) < *s.MinItems {
+			fail("has %d items, expected at least %d", len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && int64(len(v)) > *s.MaxItems {
+			fail("has %d items, expected at most %d", len(v), *s.MaxItems)
+		}
+		for _, item := range v {
+			validateValue(s.Items, item, path+"[]", errs)
+		}
+
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, &ValidationError{Field: path + "." + name, Message: "required property is missing"})
+			}
+		}
+		for name, prop := range v {
+			if ps, ok := s.Properties[name]; ok {
+				validateValue(ps, prop, path+"."+name, errs)
+			} else if s.AdditionalProperties != nil {
+				validateValue(s.AdditionalProperties, prop, path+"."+name, errs)
+			}
+		}
+	}
+}
+
+func validateString(s *Schema, v string, fail func(format string, args ...any)) {
+	length := int64(len([]rune(v)))
+	if s.MinLength != nil && length < *s.MinLength {
+		fail("length %d is less than %d", length, *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		fail("length %d is greater than %d", length, *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		if re := compilePattern(s.Pattern); re != nil && !re.MatchString(v) {
+			fail("does not match pattern %s", s.Pattern)
+		}
+	}
+
+	switch s.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			fail("%q is not a valid date-time", v)
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			fail("%q is not a valid date", v)
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(v) {
+			fail("%q is not a valid uuid", v)
+		}
+	}
+}
+
+func matchesType(typ string, value any) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// equalJSON compares an enum value from the spec with a decoded JSON value.
+// Spec values may be ints while JSON numbers are always float64.
+func equalJSON(expected, actual any) bool {
+	if expected == actual {
+		return true
+	}
+	return fmt.Sprint(expected) == fmt.Sprint(actual)
+}
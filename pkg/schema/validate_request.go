@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidateRequest checks a request against an operation:
+// path, query and header parameters against their schemas and required flags, and the body against the body schema.
+// pathValues holds the values of the path parameters by name.
+// Parameter values are converted to the type of their schema before validation,
+// so "abc" for an integer parameter is reported as a string.
+// Empty bodies are only reported when the operation requires a body.
+func ValidateRequest(op *Operation, pathValues map[string]string, query url.Values, header http.Header, body []byte) []*ValidationError {
+	if op == nil {
+		return nil
+	}
+
+	var errs []*ValidationError
+
+	if op.PathParams != nil {
+		for _, name := range slices.Sorted(maps.Keys(op.PathParams.Properties)) {
+			value, ok := pathValues[name]
+			if !ok {
+				continue
+			}
+			errs = append(errs, validateParam("path", name, op.PathParams.Properties[name], []string{value})...)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(op.Query)) {
+		param := op.Query[name]
+		values, ok := query[name]
+		if !ok {
+			if param.Required {
+				errs = append(errs, &ValidationError{In: "query", Field: name, Message: "required parameter is missing"})
+			}
+			continue
+		}
+		errs = append(errs, validateParam("query", name, param.Schema, values)...)
+	}
+
+	if op.Headers != nil {
+		for _, name := range slices.Sorted(maps.Keys(op.Headers.Properties)) {
+			values := header.Values(name)
+			if len(values) == 0 {
+				if slices.Contains(op.Headers.Required, name) {
+					errs = append(errs, &ValidationError{In: "header", Field: name, Message: "required header is missing"})
+				}
+				continue
+			}
+			errs = append(errs, validateParam("header", name, op.Headers.Properties[name], values)...)
+		}
+	}
+
+	hasBody := len(strings.TrimSpace(string(body))) > 0
+	if !hasBody && op.BodyRequired {
+		errs = append(errs, &ValidationError{In: "body", Field: "$", Message: "required body is missing"})
+	}
+
+	if op.Body != nil && hasBody {
+		for _, e := range ValidateBody(op.Body, op.ContentType, header.Get("Content-Type"), body) {
+			if e.Field == "content-type" {
+				e.In, e.Field = "header", "Content-Type"
+			} else {
+				e.In = "body"
+			}
+			errs = append(errs, e)
+		}
+	}
+
+	return errs
+}
+
+// validateParam validates the raw values of a parameter.
+// Arrays take every value, or a single comma-separated one.
+func validateParam(in, name string, s *Schema, values []string) []*ValidationError {
+	if s == nil {
+		return nil
+	}
+
+	var value any
+	if s.Type == "array" {
+		if len(values) == 1 && strings.Contains(values[0], ",") {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]any, 0, len(values))
+		for _, v := range values {
+			items = append(items, coerceParam(s.Items, v))
+		}
+		value = items
+	} else {
+		value = coerceParam(s, values[0])
+	}
+
+	var errs []*ValidationError
+	validateValue(s, value, name, &errs)
+	for _, e := range errs {
+		e.In = in
+	}
+	return errs
+}
+
+// coerceParam converts a raw parameter value to the type of its schema.
+// Values that don't convert are kept as strings, so validation reports the type mismatch.
+func coerceParam(s *Schema, raw string) any {
+	if s == nil {
+		return raw
+	}
+	switch s.Type {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
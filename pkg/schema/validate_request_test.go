@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRequest(t *testing.T) {
+	op := &Operation{
+		PathParams: &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"id": {Type: "integer"}},
+		},
+		Query: map[string]*QueryParameter{
+			"limit":  {Schema: &Schema{Type: "integer", Maximum: ptr(100.0)}, Required: true},
+			"status": {Schema: &Schema{Type: "string", Enum: []any{"active", "blocked"}}},
+			"ids":    {Schema: &Schema{Type: "array", Items: &Schema{Type: "string", Format: "uuid"}}},
+		},
+		Headers: &Schema{
+			Type:       "object",
+			Required:   []string{"x-tenant"},
+			Properties: map[string]*Schema{"x-tenant": {Type: "string", MinLength: ptr(int64(3))}},
+		},
+		ContentType: "application/json",
+		Body: &Schema{
+			Type:       "object",
+			Required:   []string{"name"},
+			Properties: map[string]*Schema{"name": {Type: "string"}},
+		},
+	}
+
+	header := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return h
+	}
+
+	t.Run("valid request", func(t *testing.T) {
+		query := url.Values{
+			"limit":  {"10"},
+			"status": {"active"},
+			"ids":    {"0b3c4e9a-3f6e-4f0e-9c1a-2b7d5f8e6a1c,1c4d5f0b-4a7f-4a1f-8d2b-3c8e6a9f7b2d"},
+		}
+		errs := ValidateRequest(op, map[string]string{"id": "42"}, query,
+			header("X-Tenant", "acme", "Content-Type", "application/json"), []byte(`{"name":"Jane"}`))
+		assert.Empty(t, errs)
+	})
+
+	t.Run("collects violations", func(t *testing.T) {
+		query := url.Values{
+			"status": {"deleted"},
+			"ids":    {"0b3c4e9a-3f6e-4f0e-9c1a-2b7d5f8e6a1c", "nope"},
+		}
+		errs := ValidateRequest(op, map[string]string{"id": "abc"}, query,
+			header("Content-Type", "application/json"), []byte(`{"name":1}`))
+
+		assert.ElementsMatch(t, []*ValidationError{
+			{In: "path", Field: "id", Message: "expected integer, got string"},
+			{In: "query", Field: "limit", Message: "required parameter is missing"},
+			{In: "query", Field: "status", Message: "value deleted is not one of [active blocked]"},
+			{In: "query", Field: "ids[]", Message: `"nope" is not a valid uuid`},
+			{In: "header", Field: "x-tenant", Message: "required header is missing"},
+			{In: "body", Field: "$.name", Message: "expected string, got integer"},
+		}, errs)
+	})
+
+	t.Run("parameter values are converted to their types", func(t *testing.T) {
+		errs := ValidateRequest(op, nil, url.Values{"limit": {"200"}}, header("X-Tenant", "acme"), nil)
+		assert.Equal(t, []*ValidationError{
+			{In: "query", Field: "limit", Message: "200 is greater than maximum 100"},
+		}, errs)
+	})
+
+	t.Run("body content type mismatch", func(t *testing.T) {
+		errs := ValidateRequest(op, nil, url.Values{"limit": {"1"}},
+			header("X-Tenant", "acme", "Content-Type", "text/plain"), []byte("hello"))
+		assert.Equal(t, []*ValidationError{
+			{In: "header", Field: "Content-Type", Message: "expected application/json, got text/plain"},
+		}, errs)
+	})
+
+	t.Run("invalid JSON body", func(t *testing.T) {
+		errs := ValidateRequest(op, nil, url.Values{"limit": {"1"}},
+			header("X-Tenant", "acme", "Content-Type", "application/json"), []byte(`{`))
+		assert.Len(t, errs, 1)
+		assert.Equal(t, "body $: invalid JSON: unexpected end of JSON input", errs[0].Error())
+	})
+
+	t.Run("missing required body", func(t *testing.T) {
+		required := &Operation{ContentType: "application/json", Body: op.Body, BodyRequired: true}
+		errs := ValidateRequest(required, nil, nil, header("Content-Type", "application/json"), []byte("  "))
+		assert.Equal(t, []*ValidationError{
+			{In: "body", Field: "$", Message: "required body is missing"},
+		}, errs)
+
+		assert.Empty(t, ValidateRequest(&Operation{Body: op.Body}, nil, nil, nil, nil))
+	})
+
+	t.Run("nil operation", func(t *testing.T) {
+		assert.Empty(t, ValidateRequest(nil, nil, nil, nil, nil))
+	})
+}
@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	user := &Schema{
+		Type:     "object",
+		Required: []string{"id", "name"},
+		Properties: map[string]*Schema{
+			"id":      {Type: "integer", Minimum: ptr(1.0)},
+			"name":    {Type: "string", MinLength: ptr(int64(2))},
+			"email":   {Type: "string", Nullable: true},
+			"status":  {Type: "string", Enum: []any{"active", "blocked"}},
+			"created": {Type: "string", Format: "date-time"},
+			"tags": {
+				Type:     "array",
+				MaxItems: ptr(int64(2)),
+				Items:    &Schema{Type: "object", Required: []string{"label"}, Properties: map[string]*Schema{"label": {Type: "string"}}},
+			},
+		},
+	}
+
+	t.Run("valid value", func(t *testing.T) {
+		errs := Validate(user, decode(t, `{"id":1,"name":"Jane","email":null,"status":"active",
+			"created":"2024-01-02T03:04:05Z","tags":[{"label":"a"}],"extra":true}`))
+		assert.Empty(t, errs)
+	})
+
+	t.Run("collects violations", func(t *testing.T) {
+		errs := Validate(user, decode(t, `{"id":1.5,"status":"deleted","created":"yesterday",
+			"tags":[{"label":1},{},{"label":"c"}]}`))
+
+		assert.ElementsMatch(t, []*ValidationError{
+			{Field: "$.id", Message: "expected integer, got number"},
+			{Field: "$.name", Message: "required property is missing"},
+			{Field: "$.status", Message: "value deleted is not one of [active blocked]"},
+			{Field: "$.created", Message: `"yesterday" is not a valid date-time`},
+			{Field: "$.tags", Message: "has 3 items, expected at most 2"},
+			{Field: "$.tags[].label", Message: "expected string, got integer"},
+			{Field: "$.tags[].label", Message: "required property is missing"},
+		}, errs)
+	})
+
+	t.Run("null for non-nullable", func(t *testing.T) {
+		errs := Validate(user, decode(t, `{"id":null,"name":"Jo"}`))
+		require.Len(t, errs, 1)
+		assert.Equal(t, "$.id", errs[0].Field)
+		assert.Equal(t, "expected integer, got null", errs[0].Message)
+	})
+
+	t.Run("root type", func(t *testing.T) {
+		errs := Validate(user, decode(t, `[1]`))
+		require.Len(t, errs, 1)
+		assert.Equal(t, "$: expected object, got array", errs[0].Error())
+	})
+
+	t.Run("patterns", func(t *testing.T) {
+		code := &Schema{Type: "string", Pattern: `^[A-Z]{3}$`}
+		for range 2 {
+			assert.Empty(t, Validate(code, "EUR"))
+			assert.Equal(t, []*ValidationError{
+				{Field: "$", Message: "does not match pattern ^[A-Z]{3}$"},
+			}, Validate(code, "euro"))
+		}
+		assert.Same(t, compilePattern(code.Pattern), compilePattern(code.Pattern))
+
+		// Invalid patterns are ignored
+		assert.Empty(t, Validate(&Schema{Type: "string", Pattern: `(`}, "x"))
+		assert.Nil(t, compilePattern(`(`))
+	})
+
+	t.Run("recursive and untyped schemas accept anything", func(t *testing.T) {
+		assert.Empty(t, Validate(&Schema{Recursive: true, Type: "object"}, "x"))
+		assert.Empty(t, Validate(&Schema{}, decode(t, `{"a":[1]}`)))
+		assert.Empty(t, Validate(nil, 1.0))
+	})
+}
+
+func TestValidateResponse(t *testing.T) {
+	resp := NewResponse(map[int]*ResponseItem{
+		200: {
+			StatusCode:  200,
+			ContentType: "application/json",
+			Content:     &Schema{Type: "object", Required: []string{"id"}, Properties: map[string]*Schema{"id": {Type: "integer"}}},
+		},
+		204: {StatusCode: 204},
+	}, 200)
+
+	t.Run("valid", func(t *testing.T) {
+		assert.Empty(t, ValidateResponse(resp, 200, "application/json; charset=utf-8", []byte(`{"id":1}`)))
+		assert.Empty(t, ValidateResponse(resp, 204, "", nil))
+	})
+
+	t.Run("body violation", func(t *testing.T) {
+		errs := ValidateResponse(resp, 200, "application/json", []byte(`{"id":"1"}`))
+		require.Len(t, errs, 1)
+		assert.Equal(t, "$.id", errs[0].Field)
+	})
+
+	t.Run("undocumented status", func(t *testing.T) {
+		errs := ValidateResponse(resp, 500, "application/json", []byte(`{}`))
+		require.Len(t, errs, 1)
+		assert.Equal(t, "status", errs[0].Field)
+		assert.Equal(t, "status code 500 is not documented", errs[0].Message)
+	})
+
+	t.Run("content type mismatch", func(t *testing.T) {
+		errs := ValidateResponse(resp, 200, "text/html", []byte(`<html></html>`))
+		require.Len(t, errs, 1)
+		assert.Equal(t, "content-type", errs[0].Field)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		errs := ValidateResponse(resp, 200, "application/json", []byte(`{`))
+		require.Len(t, errs, 1)
+		assert.Equal(t, "$", errs[0].Field)
+	})
+
+	t.Run("no responses documented", func(t *testing.T) {
+		assert.Empty(t, ValidateResponse(nil, 200, "application/json", []byte(`{}`)))
+	})
+}
@@ -0,0 +1,302 @@
+// Package security enforces the security requirements of OpenAPI specs on incoming requests.
+// It supports apiKey schemes in headers, query strings and cookies, HTTP basic and bearer schemes,
+// OAuth2 and OpenID Connect bearer tokens and mutual TLS.
+package security
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
+	"go.yaml.in/yaml/v4"
+)
+
+// Scheme is a security scheme from the components of a spec.
+// Name and In locate the key of apiKey schemes, Scheme is the HTTP authentication scheme of http ones.
+type Scheme struct {
+	Type   string `yaml:"type"`
+	Name   string `yaml:"name"`
+	In     string `yaml:"in"`
+	Scheme string `yaml:"scheme"`
+}
+
+// Requirement is a security requirement: every listed scheme has to be satisfied.
+// The scopes of OAuth2 and OpenID Connect schemes are not checked.
+type Requirement map[string][]string
+
+// Failure describes a rejected request.
+// Challenges are the WWW-Authenticate values of 401 responses.
+type Failure struct {
+	StatusCode int
+	Challenges []string
+	Message    string
+}
+
+// Enforcer checks requests against the security requirements of a spec.
+type Enforcer struct {
+	schemes    map[string]*Scheme
+	global     []Requirement
+	operations map[string][]Requirement
+	cfg        *config.SecurityConfig
+	realm      string
+	keys       oidc.KeySet
+}
+
+type specDocument struct {
+	Security   []Requirement `yaml:"security"`
+	Components struct {
+		SecuritySchemes map[string]*Scheme `yaml:"securitySchemes"`
+	} `yaml:"components"`
+	Paths map[string]map[string]yaml.Node `yaml:"paths"`
+}
+
+type operationDocument struct {
+	Security *[]Requirement `yaml:"security"`
+}
+
+var methods = []string{
+	http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+	http.MethodOptions, http.MethodHead, http.MethodPatch, http.MethodTrace,
+}
+
+// New creates an Enforcer from the raw bytes of an OpenAPI 3 spec.
+// realm names the protection space in WWW-Authenticate challenges, usually the service name.
+func New(specBytes []byte, cfg *config.SecurityConfig, realm string) (*Enforcer, error) {
+	var doc specDocument
+	if err := yaml.Unmarshal(specBytes, &doc); err != nil {
+		return nil, fmt.Errorf("parsing spec: %w", err)
+	}
+
+	operations := make(map[string][]Requirement)
+	for path, item := range doc.Paths {
+		for key, node := range item {
+			method := strings.ToUpper(key)
+			if !slices.Contains(methods, method) {
+				continue
+			}
+			var op operationDocument
+			if err := node.Decode(&op); err != nil {
+				return nil, fmt.Errorf("parsing %s %s: %w", method, path, err)
+			}
+			if op.Security != nil {
+				operations[method+" "+path] = *op.Security
+			}
+		}
+	}
+
+	e := &Enforcer{
+		schemes:    doc.Components.SecuritySchemes,
+		global:     doc.Security,
+		operations: operations,
+		cfg:        cfg,
+		realm:      realm,
+	}
+	if cfg.JWKSURL != "" {
+		e.keys = oidc.NewRemoteKeySet(cfg.JWKSURL)
+	}
+	return e, nil
+}
+
+// Check checks a request against the requirements of the operation at specPath.
+// requestPath is matched against the endpoint overrides of the config.
+// Returns nil if the request is allowed.
+func (e *Enforcer) Check(r *http.Request, specPath, requestPath string) *Failure {
+	if e == nil || !e.cfg.IsEnforced(requestPath, r.Method) {
+		return nil
+	}
+
+	reqs, ok := e.operations[r.Method+" "+specPath]
+	if !ok {
+		reqs = e.global
+	}
+	if ep := e.cfg.GetEndpoint(requestPath, r.Method); ep != nil && len(ep.Schemes) > 0 {
+		reqs = make([]Requirement, 0, len(ep.Schemes))
+		for _, name := range ep.Schemes {
+			reqs = append(reqs, Requirement{name: nil})
+		}
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	forbidden := false
+	for _, req := range reqs {
+		present, valid := e.satisfies(r, req)
+		if present && valid {
+			return nil
+		}
+		if present {
+			forbidden = true
+		}
+	}
+
+	if forbidden {
+		return &Failure{StatusCode: http.StatusForbidden, Message: "credentials are not accepted"}
+	}
+	return &Failure{
+		StatusCode: http.StatusUnauthorized,
+		Challenges: e.challenges(reqs),
+		Message:    "credentials are missing",
+	}
+}
+
+// satisfies tells whether a request carries the credentials of every scheme in a requirement
+// and whether they are all accepted. Schemes missing from the spec are ignored.
+func (e *Enforcer) satisfies(r *http.Request, req Requirement) (bool, bool) {
+	present, valid := true, true
+	for name := range req {
+		scheme := e.schemes[name]
+		if scheme == nil {
+			continue
+		}
+		value, ok := credential(r, scheme)
+		if !ok {
+			present = false
+			continue
+		}
+		if !e.accepts(scheme, value) {
+			valid = false
+		}
+	}
+	return present, valid
+}
+
+// credential extracts the credential of a scheme from a request.
+func credential(r *http.Request, s *Scheme) (string, bool) {
+	switch s.Type {
+	case "apiKey":
+		var value string
+		switch s.In {
+		case "header":
+			value = r.Header.Get(s.Name)
+		case "query":
+			value = r.URL.Query().Get(s.Name)
+		case "cookie":
+			if c, err := r.Cookie(s.Name); err == nil {
+				value = c.Value
+			}
+		}
+		return value, value != ""
+
+	case "http":
+		if strings.EqualFold(s.Scheme, "basic") {
+			user, password, ok := r.BasicAuth()
+			return user + ":" + password, ok
+		}
+		return authorization(r, s.Scheme)
+
+	case "oauth2", "openIdConnect":
+		return authorization(r, "bearer")
+
+	case "mutualTLS":
+		return "", r.TLS != nil && len(r.TLS.PeerCertificates) > 0
+	}
+	return "", true
+}
+
+// authorization returns the credentials of the Authorization header if it uses the given scheme.
+func authorization(r *http.Request, scheme string) (string, bool) {
+	prefix, value, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(prefix, scheme) {
+		return "", false
+	}
+	value = strings.TrimSpace(value)
+	return value, value != ""
+}
+
+// accepts checks a credential against the allow-lists of the config.
+// Empty allow-lists accept any credential.
+func (e *Enforcer) accepts(s *Scheme, value string) bool {
+	switch s.Type {
+	case "apiKey":
+		return len(e.cfg.APIKeys) == 0 || slices.Contains(e.cfg.APIKeys, value)
+
+	case "http":
+		if strings.EqualFold(s.Scheme, "basic") {
+			if len(e.cfg.Users) == 0 {
+				return true
+			}
+			user, password, _ := strings.Cut(value, ":")
+			expected, ok := e.cfg.Users[user]
+			return ok && expected == password
+		}
+		return e.acceptsToken(value)
+
+	case "oauth2", "openIdConnect":
+		return e.acceptsToken(value)
+	}
+	return true
+}
+
+// acceptsToken checks a bearer token against the allow-list and, with a JWKS URL configured,
+// verifies it as a JWT signed by a key of the set.
+func (e *Enforcer) acceptsToken(token string) bool {
+	if slices.Contains(e.cfg.Tokens, token) {
+		return true
+	}
+	if e.keys == nil {
+		return len(e.cfg.Tokens) == 0
+	}
+
+	claims, err := oidc.Verify(token, e.keys)
+	if err != nil {
+		return false
+	}
+	if e.cfg.Issuer != "" {
+		iss, _ := claims["iss"].(string)
+		return iss == e.cfg.Issuer
+	}
+	return true
+}
+
+// challenges builds the WWW-Authenticate values for the schemes of the requirements.
+// apiKey schemes have no registered HTTP authentication scheme,
+// so they are announced as ApiKey with the location of the key.
+func (e *Enforcer) challenges(reqs []Requirement) []string {
+	var res []string
+	for _, req := range reqs {
+		for _, name := range slices.Sorted(maps.Keys(req)) {
+			scheme := e.schemes[name]
+			if scheme == nil {
+				continue
+			}
+
+			var params []string
+			if e.realm != "" {
+				params = append(params, fmt.Sprintf("realm=%q", e.realm))
+			}
+
+			var challenge string
+			switch scheme.Type {
+			case "apiKey":
+				challenge = "ApiKey"
+				params = append(params, fmt.Sprintf("in=%q", scheme.In), fmt.Sprintf("name=%q", scheme.Name))
+			case "http":
+				challenge = capitalize(scheme.Scheme)
+			case "oauth2", "openIdConnect":
+				challenge = "Bearer"
+			default:
+				continue
+			}
+			if len(params) > 0 {
+				challenge += " " + strings.Join(params, ", ")
+			}
+
+			if !slices.Contains(res, challenge) {
+				res = append(res, challenge)
+			}
+		}
+	}
+	return res
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
@@ -0,0 +1,261 @@
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const spec = `
+openapi: 3.0.0
+info:
+  title: Secured
+  version: "1.0"
+security:
+  - apiKey: []
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: ok
+    parameters:
+      - name: limit
+        in: query
+  /pets/{id}:
+    get:
+      security:
+        - basicAuth: []
+        - bearerAuth: []
+      responses:
+        "200":
+          description: ok
+    delete:
+      security:
+        - oauth: [pets:write]
+          queryKey: []
+      responses:
+        "204":
+          description: ok
+  /health:
+    get:
+      security: []
+      responses:
+        "200":
+          description: ok
+  /session:
+    get:
+      security:
+        - cookieKey: []
+        - tls: []
+      responses:
+        "200":
+          description: ok
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-API-Key
+    queryKey:
+      type: apiKey
+      in: query
+      name: key
+    cookieKey:
+      type: apiKey
+      in: cookie
+      name: session
+    basicAuth:
+      type: http
+      scheme: basic
+    bearerAuth:
+      type: http
+      scheme: bearer
+    oauth:
+      type: oauth2
+      flows: {}
+    tls:
+      type: mutualTLS
+`
+
+func TestEnforcer_Check(t *testing.T) {
+	newEnforcer := func(t *testing.T, cfg *config.SecurityConfig) *Enforcer {
+		t.Helper()
+		e, err := New([]byte(spec), cfg, "pets")
+		require.NoError(t, err)
+		return e
+	}
+
+	t.Run("missing api key returns 401 with challenge", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true})
+
+		failure := e.Check(httptest.NewRequest(http.MethodGet, "/pets", nil), "/pets", "/pets")
+		require.NotNil(t, failure)
+		assert.Equal(t, http.StatusUnauthorized, failure.StatusCode)
+		assert.Equal(t, []string{`ApiKey realm="pets", in="header", name="X-API-Key"`}, failure.Challenges)
+	})
+
+	t.Run("present api key passes without allow-list", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true})
+
+		req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+		req.Header.Set("X-API-Key", "anything")
+		assert.Nil(t, e.Check(req, "/pets", "/pets"))
+	})
+
+	t.Run("unknown api key returns 403", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true, APIKeys: []string{"secret"}})
+
+		req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+		req.Header.Set("X-API-Key", "wrong")
+		failure := e.Check(req, "/pets", "/pets")
+		require.NotNil(t, failure)
+		assert.Equal(t, http.StatusForbidden, failure.StatusCode)
+		assert.Empty(t, failure.Challenges)
+
+		req.Header.Set("X-API-Key", "secret")
+		assert.Nil(t, e.Check(req, "/pets", "/pets"))
+	})
+
+	t.Run("alternatives announce every scheme", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true})
+
+		failure := e.Check(httptest.NewRequest(http.MethodGet, "/pets/1", nil), "/pets/{id}", "/pets/1")
+		require.NotNil(t, failure)
+		assert.Equal(t, []string{`Basic realm="pets"`, `Bearer realm="pets"`}, failure.Challenges)
+	})
+
+	t.Run("basic auth users", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true, Users: map[string]string{"admin": "secret"}})
+
+		req := httptest.NewRequest(http.MethodGet, "/pets/1", nil)
+		req.SetBasicAuth("admin", "wrong")
+		assert.Equal(t, http.StatusForbidden, e.Check(req, "/pets/{id}", "/pets/1").StatusCode)
+
+		req.SetBasicAuth("admin", "secret")
+		assert.Nil(t, e.Check(req, "/pets/{id}", "/pets/1"))
+	})
+
+	t.Run("bearer tokens", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true, Tokens: []string{"token"}})
+
+		req := httptest.NewRequest(http.MethodGet, "/pets/1", nil)
+		req.Header.Set("Authorization", "Bearer other")
+		assert.Equal(t, http.StatusForbidden, e.Check(req, "/pets/{id}", "/pets/1").StatusCode)
+
+		req.Header.Set("Authorization", "bearer token")
+		assert.Nil(t, e.Check(req, "/pets/{id}", "/pets/1"))
+	})
+
+	t.Run("every scheme of a requirement is needed", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true})
+
+		req := httptest.NewRequest(http.MethodDelete, "/pets/1", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		failure := e.Check(req, "/pets/{id}", "/pets/1")
+		require.NotNil(t, failure)
+		assert.Equal(t, http.StatusUnauthorized, failure.StatusCode)
+		assert.Equal(t, []string{`Bearer realm="pets"`, `ApiKey realm="pets", in="query", name="key"`}, failure.Challenges)
+
+		req = httptest.NewRequest(http.MethodDelete, "/pets/1?key=abc", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		assert.Nil(t, e.Check(req, "/pets/{id}", "/pets/1"))
+	})
+
+	t.Run("cookie key and mutual TLS", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true})
+
+		req := httptest.NewRequest(http.MethodGet, "/session", nil)
+		assert.NotNil(t, e.Check(req, "/session", "/session"))
+
+		req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+		assert.Nil(t, e.Check(req, "/session", "/session"))
+
+		req = httptest.NewRequest(http.MethodGet, "/session", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}
+		assert.Nil(t, e.Check(req, "/session", "/session"))
+	})
+
+	t.Run("operations can opt out", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true})
+		assert.Nil(t, e.Check(httptest.NewRequest(http.MethodGet, "/health", nil), "/health", "/health"))
+	})
+
+	t.Run("not enforced", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{})
+		assert.Nil(t, e.Check(httptest.NewRequest(http.MethodGet, "/pets", nil), "/pets", "/pets"))
+
+		var nilEnforcer *Enforcer
+		assert.Nil(t, nilEnforcer.Check(httptest.NewRequest(http.MethodGet, "/pets", nil), "/pets", "/pets"))
+	})
+
+	t.Run("endpoint overrides", func(t *testing.T) {
+		e := newEnforcer(t, &config.SecurityConfig{
+			Enforce: true,
+			Endpoints: map[string]*config.SecurityEndpointMethods{
+				"/pets": {Any: &config.SecurityEndpoint{Enforce: new(bool)}},
+				"/pets/{id}": {Methods: map[string]*config.SecurityEndpoint{
+					http.MethodGet: {Schemes: []string{"apiKey"}},
+				}},
+			},
+		})
+
+		assert.Nil(t, e.Check(httptest.NewRequest(http.MethodGet, "/pets", nil), "/pets", "/pets"))
+
+		failure := e.Check(httptest.NewRequest(http.MethodGet, "/pets/1", nil), "/pets/{id}", "/pets/1")
+		require.NotNil(t, failure)
+		assert.Equal(t, []string{`ApiKey realm="pets", in="header", name="X-API-Key"`}, failure.Challenges)
+	})
+
+	t.Run("verifies tokens against a JWKS URL", func(t *testing.T) {
+		key, err := oidc.GenerateKey()
+		require.NoError(t, err)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(&oidc.JWKS{Keys: []oidc.JWK{key.JWK()}})
+		}))
+		defer server.Close()
+
+		e := newEnforcer(t, &config.SecurityConfig{Enforce: true, JWKSURL: server.URL, Issuer: "http://idp"})
+		check := func(token string) *Failure {
+			req := httptest.NewRequest(http.MethodGet, "/pets/1", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			return e.Check(req, "/pets/{id}", "/pets/1")
+		}
+
+		token, err := key.Sign(map[string]any{"iss": "http://idp", "exp": time.Now().Add(time.Minute).Unix()})
+		require.NoError(t, err)
+		assert.Nil(t, check(token))
+
+		token, err = key.Sign(map[string]any{"iss": "http://other"})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, check(token).StatusCode)
+
+		assert.Equal(t, http.StatusForbidden, check("opaque").StatusCode)
+	})
+
+	t.Run("no realm", func(t *testing.T) {
+		e, err := New([]byte(spec), &config.SecurityConfig{Enforce: true}, "")
+		require.NoError(t, err)
+
+		failure := e.Check(httptest.NewRequest(http.MethodGet, "/pets/1", nil), "/pets/{id}", "/pets/1")
+		require.NotNil(t, failure)
+		assert.Equal(t, []string{"Basic", "Bearer"}, failure.Challenges)
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("returns error for invalid spec", func(t *testing.T) {
+		_, err := New([]byte("paths: ["), &config.SecurityConfig{}, "")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,164 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	nsEnvelope11 = "http://schemas.xmlsoap.org/soap/envelope/"
+	nsEnvelope12 = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+func (v version) envelopeNamespace() string {
+	if v == soap12 {
+		return nsEnvelope12
+	}
+	return nsEnvelope11
+}
+
+func (v version) contentType() string {
+	if v == soap12 {
+		return "application/soap+xml; charset=utf-8"
+	}
+	return "text/xml; charset=utf-8"
+}
+
+func (v version) String() string {
+	if v == soap12 {
+		return "1.2"
+	}
+	return "1.1"
+}
+
+// request is the routing information of an incoming SOAP request.
+type request struct {
+	version version
+	action  string
+
+	// body is the name of the first child of the SOAP Body.
+	body xml.Name
+}
+
+// parseRequest reads the SOAP version, action and body element of a request.
+// The version is taken from the envelope namespace, falling back to the content type.
+// The action comes from the SOAPAction header (1.1) or the action parameter
+// of the content type (1.2).
+func parseRequest(r *http.Request) (*request, error) {
+	req := &request{version: soap11}
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/soap+xml" {
+		req.version = soap12
+		req.action = params["action"]
+	}
+	if action := strings.Trim(r.Header.Get("SOAPAction"), `"`); action != "" {
+		req.action = action
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return req, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return req, errors.New("request body is empty")
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	inBody := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return req, errors.New("SOAP Body not found")
+			}
+			return req, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1:
+				if t.Name.Local != "Envelope" {
+					return req, errors.New("root element is not a SOAP Envelope")
+				}
+				switch t.Name.Space {
+				case nsEnvelope12:
+					req.version = soap12
+				case nsEnvelope11:
+					req.version = soap11
+				}
+			case depth == 2 && t.Name.Local == "Body":
+				inBody = true
+			case depth == 3 && inBody:
+				req.body = t.Name
+				return req, nil
+			}
+		case xml.EndElement:
+			depth--
+			if inBody && depth == 1 {
+				// Empty body, e.g. an operation without input parts.
+				return req, nil
+			}
+		}
+	}
+}
+
+// envelope wraps rendered body content in a SOAP envelope.
+func envelope(v version, body string) []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<soap:Envelope xmlns:soap="` + v.envelopeNamespace() + `"><soap:Body>`)
+	b.WriteString(body)
+	b.WriteString(`</soap:Body></soap:Envelope>`)
+	return b.Bytes()
+}
+
+// fault renders a SOAP Fault.
+// Client errors are Client (1.1) or Sender (1.2) faults, everything else
+// is a Server or Receiver fault. detail is rendered XML, it may be empty.
+func fault(v version, statusCode int, reason, detail string) string {
+	client := statusCode >= 400 && statusCode < 500
+
+	w := newXMLWriter()
+	w.raw("<soap:Fault>")
+	if v == soap12 {
+		code := "soap:Receiver"
+		if client {
+			code = "soap:Sender"
+		}
+		w.raw("<soap:Code><soap:Value>" + code + "</soap:Value></soap:Code>")
+		w.raw(`<soap:Reason><soap:Text xml:lang="en">`)
+		w.text(reason)
+		w.raw("</soap:Text></soap:Reason>")
+		if detail != "" {
+			w.raw("<soap:Detail>" + detail + "</soap:Detail>")
+		}
+	} else {
+		code := "soap:Server"
+		if client {
+			code = "soap:Client"
+		}
+		w.raw("<faultcode>" + code + "</faultcode><faultstring>")
+		w.text(reason)
+		w.raw("</faultstring>")
+		if detail != "" {
+			w.raw("<detail>" + detail + "</detail>")
+		}
+	}
+	w.raw("</soap:Fault>")
+	return w.String()
+}
+
+func writeEnvelope(w http.ResponseWriter, v version, statusCode int, body string) {
+	w.Header().Set("Content-Type", v.contentType())
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(envelope(v, body))
+}
@@ -0,0 +1,288 @@
+// Package soap serves mock responses for SOAP services described by WSDL 1.1.
+//
+// Message payloads are described by the XML Schemas inline in the WSDL types section.
+// Response envelopes are generated with the generator's value replacer, keyed by
+// element and attribute names, so service contexts, fake functions and formats
+// apply just as they do for OpenAPI services.
+// Requests are routed by SOAP action or by the first element of the SOAP Body.
+package soap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	"github.com/mockzilla/connexions/v2/pkg/generator"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+)
+
+// Handler implements api.Handler for a WSDL document.
+// It is also an http.Handler serving the SOAP endpoints from a catch-all route.
+type Handler struct {
+	wsdl       []byte
+	operations []*operation
+	endpoints  map[string][]*operation
+	gen        generator.Generate
+	generate   *config.GenerationConfig
+}
+
+type handlerConfig struct {
+	serviceContext []byte
+	generate       *config.GenerationConfig
+}
+
+// Option configures a Handler.
+type Option func(*handlerConfig)
+
+// WithServiceContext sets a service-specific context YAML for value replacements.
+func WithServiceContext(contextYAML []byte) Option {
+	return func(c *handlerConfig) {
+		c.serviceContext = contextYAML
+	}
+}
+
+// WithGenerationConfig sets the generation settings, e.g. the number of repeated elements.
+func WithGenerationConfig(cfg *config.GenerationConfig) Option {
+	return func(c *handlerConfig) {
+		c.generate = cfg
+	}
+}
+
+// NewHandler creates a Handler from raw WSDL 1.1 bytes.
+// Every operation of a SOAP 1.1 or 1.2 port is served at the path of the port address.
+// Default replacement contexts (common, fake, words) are loaded automatically.
+func NewHandler(wsdl []byte, opts ...Option) (*Handler, error) {
+	hc := &handlerConfig{}
+	for _, opt := range opts {
+		opt(hc)
+	}
+
+	defs, err := parseDefinitions(wsdl)
+	if err != nil {
+		return nil, fmt.Errorf("parsing WSDL: %w", err)
+	}
+
+	operations, err := buildOperations(defs)
+	if err != nil {
+		return nil, fmt.Errorf("loading WSDL: %w", err)
+	}
+
+	defaultContexts := generator.LoadDefaultContexts()
+	orderedCtx := generator.LoadServiceContext(hc.serviceContext, defaultContexts)
+	gen, err := generator.NewGenerator(orderedCtx, defaultContexts)
+	if err != nil {
+		return nil, fmt.Errorf("creating generator: %w", err)
+	}
+
+	endpoints := make(map[string][]*operation)
+	for _, op := range operations {
+		endpoints[op.path] = append(endpoints[op.path], op)
+	}
+
+	return &Handler{
+		wsdl:       wsdl,
+		operations: operations,
+		endpoints:  endpoints,
+		gen:        gen,
+		generate:   hc.generate,
+	}, nil
+}
+
+// Routes returns one route per operation.
+// Operations share the endpoint path, so the path carries the operation name as a fragment,
+// e.g. "/calculator#Add".
+func (h *Handler) Routes() api.RouteDescriptions {
+	routes := make(api.RouteDescriptions, 0, len(h.operations))
+	for _, op := range h.operations {
+		mediaType, _, _ := strings.Cut(op.version.contentType(), ";")
+		routes = append(routes, &api.RouteDescription{
+			ID:          op.name,
+			Method:      http.MethodPost,
+			Path:        op.path + "#" + op.name,
+			ContentType: mediaType,
+		})
+	}
+	return routes
+}
+
+// RegisterRoutes registers the SOAP endpoints.
+// GET requests to an endpoint with the "wsdl" query parameter return the WSDL.
+func (h *Handler) RegisterRoutes(router chi.Router) {
+	for path, ops := range h.endpoints {
+		serve := func(w http.ResponseWriter, r *http.Request) {
+			h.serveEndpoint(w, r, ops)
+		}
+		router.Post(path, serve)
+		router.Get(path, serve)
+	}
+}
+
+// Generate handles UI generate requests by returning a request envelope
+// for the operation in the path fragment.
+func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
+	var req api.GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var op *operation
+	for _, o := range h.operations {
+		if o.path+"#"+o.name == req.Path {
+			op = o
+			break
+		}
+	}
+	if op == nil {
+		http.Error(w, fmt.Sprintf("no matching operation: %s %s", req.Method, req.Path), http.StatusNotFound)
+		return
+	}
+
+	headers := map[string]string{}
+	if op.version == soap11 {
+		headers["SOAPAction"] = `"` + op.action + `"`
+	}
+
+	api.NewJSONResponse(w).Send(map[string]any{
+		"path":        op.path,
+		"method":      http.MethodPost,
+		"contentType": op.version.contentType(),
+		"headers":     headers,
+		"body":        string(envelope(op.version, h.render(op.input, req.Context))),
+	})
+}
+
+// ServeHTTP serves the SOAP endpoints from a catch-all route,
+// with chi's "*" param holding the path relative to the service root.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := "/" + strings.TrimSuffix(chi.URLParam(r, "*"), "/")
+	ops, ok := h.endpoints[path]
+	if !ok {
+		http.Error(w, fmt.Sprintf("no matching operation: %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		return
+	}
+	h.serveEndpoint(w, r, ops)
+}
+
+// WriteError writes a simulated error as a SOAP Fault, keeping the status code.
+// Client errors carry the first fault declared for the operation as detail.
+func (h *Handler) WriteError(w http.ResponseWriter, r *http.Request, statusCode int) bool {
+	req, _ := parseRequest(r)
+	op := match(h.operations, req)
+
+	detail := ""
+	if op != nil && op.fault != nil && statusCode >= 400 && statusCode < 500 {
+		detail = h.render([]*node{op.fault}, api.ExtractContextFromRequest(r))
+	}
+
+	reason := fmt.Sprintf("Simulated error: %d %s", statusCode, http.StatusText(statusCode))
+	writeEnvelope(w, req.version, statusCode, fault(req.version, statusCode, reason, detail))
+	return true
+}
+
+// serveEndpoint serves the operations bound to one endpoint.
+// Requests that can't be routed get a Client (1.1) or Sender (1.2) fault.
+func (h *Handler) serveEndpoint(w http.ResponseWriter, r *http.Request, ops []*operation) {
+	if r.Method == http.MethodGet {
+		if !r.URL.Query().Has("wsdl") {
+			http.Error(w, "use ?wsdl to get the service description", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		_, _ = w.Write(h.wsdl)
+		return
+	}
+
+	req, err := parseRequest(r)
+	if err != nil {
+		slog.Debug("Invalid SOAP request", "error", err)
+		writeClientFault(w, req.version, fmt.Sprintf("Invalid SOAP request: %v", err))
+		return
+	}
+
+	op := match(ops, req)
+	if op == nil {
+		writeClientFault(w, req.version, fmt.Sprintf("No matching operation for action %q and element %q", req.action, req.body.Local))
+		return
+	}
+
+	if len(op.output) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	writeEnvelope(w, op.version, http.StatusOK, h.render(op.output, api.ExtractContextFromRequest(r)))
+}
+
+// render generates values for the body children of a message and renders them as XML.
+func (h *Handler) render(nodes []*node, ctx map[string]any) string {
+	res := h.gen.Response(&schema.ResponseSchema{
+		ContentType: "application/json",
+		Body:        bodySchema(nodes, h.generate.GetListSize()),
+	}, ctx)
+
+	var value any
+	if !res.IsError && len(res.Body) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(res.Body))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			slog.Debug("Invalid generated SOAP payload", "error", err)
+		}
+	}
+
+	w := newXMLWriter()
+	w.nodes(nodes, value)
+	return w.String()
+}
+
+// match finds the operation for a request, preferring operations of the request's SOAP version.
+// The SOAP action is matched first, then the name of the first element of the Body.
+func match(ops []*operation, req *request) *operation {
+	var candidates []*operation
+	for _, op := range ops {
+		if op.version == req.version {
+			candidates = append(candidates, op)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = ops
+	}
+
+	if req.action != "" {
+		for _, op := range candidates {
+			if op.action == req.action {
+				return op
+			}
+		}
+	}
+
+	if req.body.Local != "" {
+		for _, op := range candidates {
+			if len(op.input) == 0 {
+				continue
+			}
+			in := op.input[0]
+			if in.name == req.body.Local && (req.body.Space == "" || in.namespace == req.body.Space) {
+				return op
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeClientFault writes a fault for a request the service can't process.
+// SOAP 1.1 faults use 500 as required by the WS-I Basic Profile, SOAP 1.2 Sender faults use 400.
+func writeClientFault(w http.ResponseWriter, v version, reason string) {
+	statusCode := http.StatusInternalServerError
+	if v == soap12 {
+		statusCode = http.StatusBadRequest
+	}
+	writeEnvelope(w, v, statusCode, fault(v, http.StatusBadRequest, reason, ""))
+}
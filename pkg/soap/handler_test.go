@@ -0,0 +1,377 @@
+package soap
+
+import (
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mockzilla/connexions/v2/pkg/api"
+	"github.com/mockzilla/connexions/v2/pkg/config"
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata/**
+var testDataFS embed.FS
+
+func loadTestWSDL(t *testing.T, fileName string) []byte {
+	t.Helper()
+	contents, err := testDataFS.ReadFile(filepath.Join("testdata", fileName))
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	return contents
+}
+
+func newTestHandler(t *testing.T, opts ...Option) *Handler {
+	t.Helper()
+	h, err := NewHandler(loadTestWSDL(t, "calculator.wsdl"), opts...)
+	require.NoError(t, err)
+	return h
+}
+
+func listSize(n int) *config.GenerationConfig {
+	return &config.GenerationConfig{ListSize: &config.ListSize{Min: n, Max: n}}
+}
+
+func encodeContext(t *testing.T, ctx map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(ctx)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// xmlElement is a generic XML tree for inspecting responses.
+type xmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Children []xmlElement `xml:",any"`
+	Text     string       `xml:",chardata"`
+}
+
+func (e *xmlElement) child(local string) *xmlElement {
+	for i := range e.Children {
+		if e.Children[i].XMLName.Local == local {
+			return &e.Children[i]
+		}
+	}
+	return nil
+}
+
+func (e *xmlElement) attr(local string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// soapBody parses a response envelope and returns its Body.
+func soapBody(t *testing.T, data []byte) (*xmlElement, string) {
+	t.Helper()
+	env := &xmlElement{}
+	require.NoError(t, xml.Unmarshal(data, env), string(data))
+	require.Equal(t, "Envelope", env.XMLName.Local)
+	body := env.child("Body")
+	require.NotNil(t, body, string(data))
+	return body, env.XMLName.Space
+}
+
+func soapRequest(path, contentType, action, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	if action != "" {
+		req.Header.Set("SOAPAction", action)
+	}
+	return req
+}
+
+const (
+	addRequest11 = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:c="urn:example:calculator">
+<soap:Body><c:Add><c:a>1</c:a><c:b>2</c:b></c:Add></soap:Body></soap:Envelope>`
+	addRequest12 = `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">
+<env:Body><Add xmlns="urn:example:calculator"><a>1</a><b>2</b></Add></env:Body></env:Envelope>`
+	describeRequest11 = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+<soap:Body><Describe xmlns="urn:example:calculator"><id>x</id></Describe></soap:Body></soap:Envelope>`
+)
+
+func TestNewHandler(t *testing.T) {
+	assert := assert2.New(t)
+
+	t.Run("loads operations", func(t *testing.T) {
+		h := newTestHandler(t)
+		assert.Len(h.operations, 5)
+		assert.Len(h.endpoints["/calculator"], 2)
+		assert.Len(h.endpoints["/calculator12"], 2)
+		assert.Len(h.endpoints["/legacy"], 1)
+	})
+
+	t.Run("invalid XML", func(t *testing.T) {
+		_, err := NewHandler([]byte("<definitions"))
+		assert.Error(err)
+		assert.Contains(err.Error(), "parsing WSDL")
+	})
+
+	t.Run("no operations", func(t *testing.T) {
+		_, err := NewHandler([]byte(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"/>`))
+		assert.Error(err)
+		assert.Contains(err.Error(), "no SOAP operations")
+	})
+
+	t.Run("missing element", func(t *testing.T) {
+		_, err := NewHandler([]byte(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/" xmlns:tns="urn:x">
+  <message name="In"><part name="p" element="tns:Missing"/></message>
+  <portType name="PT"><operation name="Op"><input message="tns:In"/></operation></portType>
+  <binding name="B" type="tns:PT"><soap:binding style="document"/><operation name="Op"/></binding>
+</definitions>`))
+		assert.Error(err)
+		assert.Contains(err.Error(), "element tns:Missing not found")
+	})
+}
+
+func TestHandler_Routes(t *testing.T) {
+	assert := assert2.New(t)
+	routes := newTestHandler(t).Routes()
+
+	paths := make(map[string]string)
+	for _, r := range routes {
+		assert.Equal(http.MethodPost, r.Method)
+		paths[r.Path] = r.ContentType
+	}
+	assert.Equal("text/xml", paths["/calculator#Add"])
+	assert.Equal("application/soap+xml", paths["/calculator12#Add"])
+	assert.Equal("text/xml", paths["/legacy#Multiply"])
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	assert := assert2.New(t)
+
+	newRouter := func(t *testing.T, opts ...Option) http.Handler {
+		router := chi.NewRouter()
+		newTestHandler(t, opts...).RegisterRoutes(router)
+		return router
+	}
+
+	t.Run("routes by SOAPAction", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t).ServeHTTP(w, soapRequest("/calculator", "text/xml", `"urn:example:calculator/Describe"`, addRequest11))
+
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("text/xml; charset=utf-8", w.Header().Get("Content-Type"))
+		body, ns := soapBody(t, w.Body.Bytes())
+		assert.Equal(nsEnvelope11, ns)
+		assert.NotNil(body.child("DescribeResponse"))
+	})
+
+	t.Run("routes by body element", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t).ServeHTTP(w, soapRequest("/calculator", "text/xml", "", addRequest11))
+
+		assert.Equal(http.StatusOK, w.Code)
+		body, _ := soapBody(t, w.Body.Bytes())
+		res := body.child("AddResponse")
+		require.NotNil(t, res, w.Body.String())
+		assert.Equal("urn:example:calculator", res.XMLName.Space)
+
+		result := res.child("result")
+		require.NotNil(t, result)
+		assert.Equal("urn:example:calculator", result.XMLName.Space, "elementFormDefault is qualified")
+		_, err := strconv.Atoi(result.Text)
+		assert.NoError(err)
+	})
+
+	t.Run("SOAP 1.2", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t).ServeHTTP(w, soapRequest("/calculator12",
+			`application/soap+xml; charset=utf-8; action="urn:example:calculator/Add"`, "", addRequest12))
+
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("application/soap+xml; charset=utf-8", w.Header().Get("Content-Type"))
+		body, ns := soapBody(t, w.Body.Bytes())
+		assert.Equal(nsEnvelope12, ns)
+		assert.NotNil(body.child("AddResponse"))
+	})
+
+	t.Run("renders XSD types in order", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t, WithGenerationConfig(listSize(3))).ServeHTTP(w, soapRequest("/calculator", "text/xml", "", describeRequest11))
+
+		assert.Equal(http.StatusOK, w.Code)
+		body, _ := soapBody(t, w.Body.Bytes())
+		res := body.child("DescribeResponse")
+		require.NotNil(t, res, w.Body.String())
+
+		var names []string
+		for _, c := range res.Children {
+			names = append(names, c.XMLName.Local)
+		}
+		assert.Equal([]string{"name", "mode", "tag", "tag", "tag", "price", "email"}, names,
+			"base type first, repeated elements, first choice, no recursion")
+
+		_, err := strconv.Atoi(res.attr("version"))
+		assert.NoError(err)
+		assert.Contains([]string{"BASIC", "SCIENTIFIC"}, res.child("mode").Text)
+
+		price := res.child("price")
+		assert.NotEmpty(price.attr("currency"))
+		_, err = strconv.ParseFloat(price.Text, 64)
+		assert.NoError(err)
+	})
+
+	t.Run("RPC style", func(t *testing.T) {
+		req := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+<soap:Body><l:Multiply xmlns:l="urn:example:legacy"><x>2</x><y>3</y></l:Multiply></soap:Body></soap:Envelope>`
+		w := httptest.NewRecorder()
+		newRouter(t).ServeHTTP(w, soapRequest("/legacy", "text/xml", `""`, req))
+
+		assert.Equal(http.StatusOK, w.Code)
+		body, _ := soapBody(t, w.Body.Bytes())
+		res := body.child("MultiplyResponse")
+		require.NotNil(t, res, w.Body.String())
+		assert.Equal("urn:example:legacy", res.XMLName.Space)
+
+		product := res.child("product")
+		require.NotNil(t, product)
+		assert.Empty(product.XMLName.Space, "RPC parts are unqualified")
+	})
+
+	t.Run("context replacements", func(t *testing.T) {
+		req := soapRequest("/calculator", "text/xml", "", addRequest11)
+		req.Header.Set(api.ContextHeaderName, encodeContext(t, map[string]any{"result": 42}))
+		w := httptest.NewRecorder()
+		newRouter(t).ServeHTTP(w, req)
+
+		body, _ := soapBody(t, w.Body.Bytes())
+		assert.Equal("42", body.child("AddResponse").child("result").Text)
+	})
+
+	t.Run("unknown operation", func(t *testing.T) {
+		req := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><Missing/></soap:Body></soap:Envelope>`
+		w := httptest.NewRecorder()
+		newRouter(t).ServeHTTP(w, soapRequest("/calculator", "text/xml", "", req))
+
+		assert.Equal(http.StatusInternalServerError, w.Code)
+		body, _ := soapBody(t, w.Body.Bytes())
+		f := body.child("Fault")
+		require.NotNil(t, f)
+		assert.Equal("soap:Client", f.child("faultcode").Text)
+		assert.Contains(f.child("faultstring").Text, "Missing")
+	})
+
+	t.Run("invalid envelope", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t).ServeHTTP(w, soapRequest("/calculator12", "application/soap+xml", "", "<not-soap/>"))
+
+		assert.Equal(http.StatusBadRequest, w.Code)
+		body, ns := soapBody(t, w.Body.Bytes())
+		assert.Equal(nsEnvelope12, ns)
+		assert.Equal("soap:Sender", body.child("Fault").child("Code").child("Value").Text)
+	})
+
+	t.Run("WSDL", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/calculator?wsdl", nil))
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Contains(w.Body.String(), "wsdl:definitions")
+
+		w = httptest.NewRecorder()
+		newRouter(t).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/calculator", nil))
+		assert.Equal(http.StatusMethodNotAllowed, w.Code)
+	})
+
+	t.Run("catch-all route", func(t *testing.T) {
+		h := newTestHandler(t)
+		router := chi.NewRouter()
+		router.HandleFunc("/*", h.ServeHTTP)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, soapRequest("/legacy/", "text/xml", "", `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><Multiply/></s:Body></s:Envelope>`))
+		assert.Equal(http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, soapRequest("/missing", "text/xml", "", addRequest11))
+		assert.Equal(http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandler_WriteError(t *testing.T) {
+	assert := assert2.New(t)
+	h := newTestHandler(t)
+
+	t.Run("client error with fault detail", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		assert.True(h.WriteError(w, soapRequest("/calculator", "text/xml", "", addRequest11), http.StatusBadRequest))
+
+		assert.Equal(http.StatusBadRequest, w.Code)
+		body, _ := soapBody(t, w.Body.Bytes())
+		f := body.child("Fault")
+		require.NotNil(t, f, w.Body.String())
+		assert.Equal("soap:Client", f.child("faultcode").Text)
+		assert.Equal("Simulated error: 400 Bad Request", f.child("faultstring").Text)
+
+		detail := f.child("detail").child("CalculatorFault")
+		require.NotNil(t, detail)
+		code, err := strconv.Atoi(detail.child("code").Text)
+		assert.NoError(err)
+		assert.GreaterOrEqual(code, 100)
+		assert.LessOrEqual(code, 199)
+	})
+
+	t.Run("server error in SOAP 1.2", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		assert.True(h.WriteError(w, soapRequest("/calculator12", "application/soap+xml", "", addRequest12), http.StatusServiceUnavailable))
+
+		assert.Equal(http.StatusServiceUnavailable, w.Code)
+		body, _ := soapBody(t, w.Body.Bytes())
+		f := body.child("Fault")
+		require.NotNil(t, f, w.Body.String())
+		assert.Equal("soap:Receiver", f.child("Code").child("Value").Text)
+		assert.Nil(f.child("Detail"))
+	})
+}
+
+func TestHandler_Generate(t *testing.T) {
+	assert := assert2.New(t)
+	h := newTestHandler(t)
+
+	generate := func(path string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(api.GenerateRequest{Path: path, Method: http.MethodPost})
+		w := httptest.NewRecorder()
+		h.Generate(w, httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(string(body))))
+		return w
+	}
+
+	t.Run("generates request envelope", func(t *testing.T) {
+		w := generate("/calculator#Add")
+		assert.Equal(http.StatusOK, w.Code)
+
+		var res struct {
+			Path    string            `json:"path"`
+			Headers map[string]string `json:"headers"`
+			Body    string            `json:"body"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal("/calculator", res.Path)
+		assert.Equal(`"urn:example:calculator/Add"`, res.Headers["SOAPAction"])
+
+		body, _ := soapBody(t, []byte(res.Body))
+		add := body.child("Add")
+		require.NotNil(t, add)
+		assert.NotNil(add.child("a"))
+		assert.NotNil(add.child("b"))
+	})
+
+	t.Run("unknown operation", func(t *testing.T) {
+		assert.Equal(http.StatusNotFound, generate("/calculator#Missing").Code)
+	})
+}
@@ -0,0 +1,226 @@
+package soap
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/internal/types"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+)
+
+// bodySchema builds the generator schema for the body children of a message.
+// Values are generated keyed by element and attribute local names.
+func bodySchema(nodes []*node, listSize int) *schema.Schema {
+	props := make(map[string]*schema.Schema, len(nodes))
+	for _, n := range nodes {
+		props[n.name] = nodeSchema(n, listSize)
+	}
+	return &schema.Schema{Type: types.TypeObject, Properties: props}
+}
+
+// nodeSchema builds the schema of an element or attribute.
+// Repeated elements are arrays of listSize items, or of one item if the element is required.
+func nodeSchema(n *node, listSize int) *schema.Schema {
+	var s *schema.Schema
+
+	if len(n.children) == 0 && n.simple != nil {
+		cp := *n.simple
+		s = &cp
+	} else {
+		props := make(map[string]*schema.Schema, len(n.children)+1)
+		for _, c := range n.children {
+			if c.repeated && c.optional && listSize == 0 {
+				continue
+			}
+			props[c.name] = nodeSchema(c, listSize)
+		}
+		if n.simple != nil {
+			props[textKey] = n.simple
+		}
+		s = &schema.Schema{Type: types.TypeObject, Properties: props}
+	}
+
+	if !n.repeated {
+		return s
+	}
+
+	size := int64(listSize)
+	if size == 0 {
+		size = 1
+	}
+	return &schema.Schema{
+		Type:     types.TypeArray,
+		Items:    s,
+		MinItems: &size,
+		MaxItems: &size,
+	}
+}
+
+// xmlWriter renders generated values as XML.
+// Namespaces are bound to generated prefixes, declared on each top-level element.
+type xmlWriter struct {
+	buf      strings.Builder
+	prefixes map[string]string
+}
+
+func newXMLWriter() *xmlWriter {
+	return &xmlWriter{prefixes: make(map[string]string)}
+}
+
+func (w *xmlWriter) String() string {
+	return w.buf.String()
+}
+
+func (w *xmlWriter) raw(s string) {
+	w.buf.WriteString(s)
+}
+
+func (w *xmlWriter) text(s string) {
+	_ = xml.EscapeText(&w.buf, []byte(s))
+}
+
+// prefix returns the prefix bound to a namespace, binding a new one if needed.
+// The second value reports whether the binding is new.
+func (w *xmlWriter) prefix(namespace string) (string, bool) {
+	if p, ok := w.prefixes[namespace]; ok {
+		return p, false
+	}
+	p := "ns" + strconv.Itoa(len(w.prefixes)+1)
+	w.prefixes[namespace] = p
+	return p, true
+}
+
+// nodes renders the top-level elements of a message from a value keyed by element name.
+func (w *xmlWriter) nodes(nodes []*node, value any) {
+	values, _ := value.(map[string]any)
+	for _, n := range nodes {
+		v, ok := values[n.name]
+		if (!ok || v == nil) && n.optional {
+			continue
+		}
+		w.node(n, v, true)
+	}
+}
+
+// node renders an element, once per item if the element is repeated.
+func (w *xmlWriter) node(n *node, value any, top bool) {
+	if items, ok := value.([]any); ok && n.repeated {
+		for _, item := range items {
+			w.element(n, item, top)
+		}
+		return
+	}
+	w.element(n, value, top)
+}
+
+func (w *xmlWriter) element(n *node, value any, top bool) {
+	name := n.name
+	var decls []string
+
+	// Declare the namespaces of the whole subtree on top-level elements,
+	// so nested elements don't repeat declarations.
+	if top {
+		for _, ns := range namespaces(n, nil) {
+			if p, isNew := w.prefix(ns); isNew {
+				decls = append(decls, fmt.Sprintf(` xmlns:%s="%s"`, p, escapeAttr(ns)))
+			}
+		}
+	}
+	if n.namespace != "" {
+		p, isNew := w.prefix(n.namespace)
+		if isNew {
+			decls = append(decls, fmt.Sprintf(` xmlns:%s="%s"`, p, escapeAttr(n.namespace)))
+		}
+		name = p + ":" + n.name
+	}
+
+	values, _ := value.(map[string]any)
+
+	w.raw("<" + name)
+	for _, d := range decls {
+		w.raw(d)
+	}
+	for _, c := range n.children {
+		if !c.attribute {
+			continue
+		}
+		v, ok := values[c.name]
+		if !ok || v == nil {
+			continue
+		}
+		w.raw(fmt.Sprintf(` %s="%s"`, c.name, escapeAttr(formatValue(v))))
+	}
+	w.raw(">")
+
+	switch {
+	case n.simple != nil && len(n.children) > 0:
+		w.text(formatValue(values[textKey]))
+	case n.simple != nil:
+		w.text(formatValue(value))
+	default:
+		for _, c := range n.children {
+			if c.attribute {
+				continue
+			}
+			v, ok := values[c.name]
+			if (!ok || v == nil) && c.optional {
+				continue
+			}
+			w.node(c, v, false)
+		}
+	}
+
+	w.raw("</" + name + ">")
+}
+
+// namespaces lists the namespaces used in a subtree, in document order.
+func namespaces(n *node, res []string) []string {
+	if n.namespace != "" && !contains(res, n.namespace) {
+		res = append(res, n.namespace)
+	}
+	for _, c := range n.children {
+		res = namespaces(c, res)
+	}
+	return res
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValue converts a generated value to its XML Schema lexical form.
+func formatValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+func escapeAttr(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,361 @@
+package soap
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	nsSOAP11Binding = "http://schemas.xmlsoap.org/wsdl/soap/"
+	nsSOAP12Binding = "http://schemas.xmlsoap.org/wsdl/soap12/"
+)
+
+// definitions is a WSDL 1.1 document.
+type definitions struct {
+	TargetNamespace string          `xml:"targetNamespace,attr"`
+	Schemas         []*xsdSchema    `xml:"types>schema"`
+	Messages        []*wsdlMessage  `xml:"message"`
+	PortTypes       []*wsdlPortType `xml:"portType"`
+	Bindings        []*wsdlBinding  `xml:"binding"`
+	Services        []*wsdlService  `xml:"service"`
+}
+
+type wsdlMessage struct {
+	Name  string      `xml:"name,attr"`
+	Parts []*wsdlPart `xml:"part"`
+}
+
+type wsdlPart struct {
+	Name    string `xml:"name,attr"`
+	Element string `xml:"element,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+type wsdlPortType struct {
+	Name       string           `xml:"name,attr"`
+	Operations []*wsdlOperation `xml:"operation"`
+}
+
+type wsdlOperation struct {
+	Name   string    `xml:"name,attr"`
+	Input  *wsdlIO   `xml:"input"`
+	Output *wsdlIO   `xml:"output"`
+	Faults []*wsdlIO `xml:"fault"`
+}
+
+// wsdlIO is an input, output or fault of an operation.
+// In a port type it references a message, in a binding it carries the soap:body.
+type wsdlIO struct {
+	Name    string        `xml:"name,attr"`
+	Message string        `xml:"message,attr"`
+	Body11  *soapBodyBind `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
+	Body12  *soapBodyBind `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ body"`
+}
+
+type soapBodyBind struct {
+	Namespace string `xml:"namespace,attr"`
+}
+
+type wsdlBinding struct {
+	Name       string                  `xml:"name,attr"`
+	Type       string                  `xml:"type,attr"`
+	SOAP11     *soapBindingStyle       `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
+	SOAP12     *soapBindingStyle       `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ binding"`
+	Operations []*wsdlBindingOperation `xml:"operation"`
+}
+
+type soapBindingStyle struct {
+	Style string `xml:"style,attr"`
+}
+
+type wsdlBindingOperation struct {
+	Name   string             `xml:"name,attr"`
+	SOAP11 *soapOperationBind `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	SOAP12 *soapOperationBind `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
+	Input  *wsdlIO            `xml:"input"`
+	Output *wsdlIO            `xml:"output"`
+}
+
+type soapOperationBind struct {
+	SOAPAction string `xml:"soapAction,attr"`
+	Style      string `xml:"style,attr"`
+}
+
+type wsdlService struct {
+	Name  string      `xml:"name,attr"`
+	Ports []*wsdlPort `xml:"port"`
+}
+
+type wsdlPort struct {
+	Name      string       `xml:"name,attr"`
+	Binding   string       `xml:"binding,attr"`
+	Address11 *soapAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+	Address12 *soapAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ address"`
+}
+
+type soapAddress struct {
+	Location string `xml:"location,attr"`
+}
+
+// version is the SOAP version of a binding.
+type version int
+
+const (
+	soap11 version = iota + 1
+	soap12
+)
+
+// operation is a bound operation served at an endpoint.
+type operation struct {
+	name    string
+	action  string
+	version version
+	path    string
+
+	// input and output are the children of the SOAP Body.
+	input  []*node
+	output []*node
+
+	// fault is the detail element of the first declared fault, if any.
+	fault *node
+}
+
+// parseDefinitions reads a WSDL 1.1 document.
+func parseDefinitions(data []byte) (*definitions, error) {
+	defs := &definitions{}
+	if err := xml.Unmarshal(data, defs); err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+// buildOperations resolves the operations of every SOAP port.
+// WSDLs without a service section are served from the root path for each SOAP binding.
+func buildOperations(defs *definitions) ([]*operation, error) {
+	ts := newTypeSystem(defs.Schemas)
+
+	type port struct {
+		binding *wsdlBinding
+		path    string
+	}
+
+	var ports []port
+	for _, svc := range defs.Services {
+		for _, p := range svc.Ports {
+			b := defs.binding(p.Binding)
+			if b == nil {
+				return nil, fmt.Errorf("port %s: binding %s not found", p.Name, p.Binding)
+			}
+			location := ""
+			switch {
+			case p.Address11 != nil:
+				location = p.Address11.Location
+			case p.Address12 != nil:
+				location = p.Address12.Location
+			default:
+				continue
+			}
+			ports = append(ports, port{binding: b, path: endpointPath(location)})
+		}
+	}
+	if len(ports) == 0 {
+		for _, b := range defs.Bindings {
+			ports = append(ports, port{binding: b, path: "/"})
+		}
+	}
+
+	seen := make(map[string]bool)
+	var res []*operation
+
+	for _, p := range ports {
+		b := p.binding
+		var v version
+		var style string
+		switch {
+		case b.SOAP11 != nil:
+			v, style = soap11, b.SOAP11.Style
+		case b.SOAP12 != nil:
+			v, style = soap12, b.SOAP12.Style
+		default:
+			continue
+		}
+
+		pt := defs.portType(b.Type)
+		if pt == nil {
+			return nil, fmt.Errorf("binding %s: port type %s not found", b.Name, b.Type)
+		}
+
+		for _, bop := range b.Operations {
+			key := fmt.Sprintf("%s %d %s", p.path, v, bop.Name)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			op, err := defs.buildOperation(ts, pt, bop, v, style)
+			if err != nil {
+				return nil, fmt.Errorf("operation %s: %w", bop.Name, err)
+			}
+			op.path = p.path
+			res = append(res, op)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, errors.New("no SOAP operations found")
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].path != res[j].path {
+			return res[i].path < res[j].path
+		}
+		return res[i].name < res[j].name
+	})
+	return res, nil
+}
+
+func (d *definitions) buildOperation(
+	ts *typeSystem,
+	pt *wsdlPortType,
+	bop *wsdlBindingOperation,
+	v version,
+	style string,
+) (*operation, error) {
+	var ptop *wsdlOperation
+	for _, o := range pt.Operations {
+		if o.Name == bop.Name {
+			ptop = o
+			break
+		}
+	}
+	if ptop == nil {
+		return nil, fmt.Errorf("not declared in port type %s", pt.Name)
+	}
+
+	op := &operation{name: bop.Name, version: v}
+	if ob := bop.soapOperation(); ob != nil {
+		op.action = ob.SOAPAction
+		if ob.Style != "" {
+			style = ob.Style
+		}
+	}
+	rpc := style == "rpc"
+
+	var err error
+	if ptop.Input != nil {
+		op.input, err = d.messageNodes(ts, ptop.Input.Message, rpc, bop.Name, bodyNamespace(bop.Input))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ptop.Output != nil {
+		op.output, err = d.messageNodes(ts, ptop.Output.Message, rpc, bop.Name+"Response", bodyNamespace(bop.Output))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(ptop.Faults) > 0 {
+		if nodes, err := d.messageNodes(ts, ptop.Faults[0].Message, false, "", ""); err == nil && len(nodes) > 0 {
+			op.fault = nodes[0]
+		}
+	}
+
+	return op, nil
+}
+
+// messageNodes resolves the body children of a message.
+// Document style parts are global elements; RPC style parts are wrapped
+// in an element named after the operation, in the soap:body namespace.
+func (d *definitions) messageNodes(ts *typeSystem, messageName string, rpc bool, wrapper, namespace string) ([]*node, error) {
+	msg := d.message(messageName)
+	if msg == nil {
+		return nil, fmt.Errorf("message %s not found", messageName)
+	}
+
+	var parts []*node
+	for _, part := range msg.Parts {
+		r := ts.newResolver()
+		var n *node
+		switch {
+		case part.Element != "":
+			n = r.globalElement(part.Element)
+			if n == nil {
+				return nil, fmt.Errorf("element %s not found", part.Element)
+			}
+		default:
+			n = r.typedElement(part.Name, "", part.Type)
+		}
+		parts = append(parts, n)
+	}
+
+	if !rpc {
+		return parts, nil
+	}
+	if namespace == "" {
+		namespace = d.TargetNamespace
+	}
+	return []*node{{name: wrapper, namespace: namespace, children: parts}}, nil
+}
+
+func (d *definitions) message(name string) *wsdlMessage {
+	for _, m := range d.Messages {
+		if m.Name == localName(name) {
+			return m
+		}
+	}
+	return nil
+}
+
+func (d *definitions) portType(name string) *wsdlPortType {
+	for _, pt := range d.PortTypes {
+		if pt.Name == localName(name) {
+			return pt
+		}
+	}
+	return nil
+}
+
+func (d *definitions) binding(name string) *wsdlBinding {
+	for _, b := range d.Bindings {
+		if b.Name == localName(name) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (o *wsdlBindingOperation) soapOperation() *soapOperationBind {
+	if o.SOAP11 != nil {
+		return o.SOAP11
+	}
+	return o.SOAP12
+}
+
+func bodyNamespace(io *wsdlIO) string {
+	switch {
+	case io == nil:
+		return ""
+	case io.Body11 != nil:
+		return io.Body11.Namespace
+	case io.Body12 != nil:
+		return io.Body12.Namespace
+	}
+	return ""
+}
+
+// endpointPath returns the path of a soap:address location.
+// Locations that are not URLs, e.g. placeholders, are served from the root.
+func endpointPath(location string) string {
+	u, err := url.Parse(location)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	if p := strings.TrimSuffix(u.Path, "/"); p != "" {
+		return p
+	}
+	return "/"
+}
@@ -0,0 +1,511 @@
+package soap
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/internal/types"
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+)
+
+// xsdSchema is an inline XML Schema from the WSDL types section.
+// Only the constructs needed to describe message payloads are read;
+// imports and includes of external schemas are not followed.
+type xsdSchema struct {
+	TargetNamespace    string            `xml:"targetNamespace,attr"`
+	ElementFormDefault string            `xml:"elementFormDefault,attr"`
+	Elements           []*xsdElement     `xml:"element"`
+	ComplexTypes       []*xsdComplexType `xml:"complexType"`
+	SimpleTypes        []*xsdSimpleType  `xml:"simpleType"`
+}
+
+type xsdElement struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr"`
+	Ref         string          `xml:"ref,attr"`
+	Form        string          `xml:"form,attr"`
+	MinOccurs   string          `xml:"minOccurs,attr"`
+	MaxOccurs   string          `xml:"maxOccurs,attr"`
+	ComplexType *xsdComplexType `xml:"complexType"`
+	SimpleType  *xsdSimpleType  `xml:"simpleType"`
+}
+
+type xsdComplexType struct {
+	Name           string          `xml:"name,attr"`
+	Sequence       *xsdGroup       `xml:"sequence"`
+	All            *xsdGroup       `xml:"all"`
+	Choice         *xsdGroup       `xml:"choice"`
+	Attributes     []*xsdAttribute `xml:"attribute"`
+	ComplexContent *xsdContent     `xml:"complexContent"`
+	SimpleContent  *xsdContent     `xml:"simpleContent"`
+}
+
+type xsdContent struct {
+	Extension   *xsdDerivation `xml:"extension"`
+	Restriction *xsdDerivation `xml:"restriction"`
+}
+
+// xsdDerivation is the extension or restriction of a base type.
+// Facets only apply to restrictions of simple types.
+type xsdDerivation struct {
+	Base         string          `xml:"base,attr"`
+	Sequence     *xsdGroup       `xml:"sequence"`
+	All          *xsdGroup       `xml:"all"`
+	Choice       *xsdGroup       `xml:"choice"`
+	Attributes   []*xsdAttribute `xml:"attribute"`
+	Enumerations []xsdFacet      `xml:"enumeration"`
+	Pattern      *xsdFacet       `xml:"pattern"`
+	MinInclusive *xsdFacet       `xml:"minInclusive"`
+	MaxInclusive *xsdFacet       `xml:"maxInclusive"`
+	MinLength    *xsdFacet       `xml:"minLength"`
+	MaxLength    *xsdFacet       `xml:"maxLength"`
+	Length       *xsdFacet       `xml:"length"`
+}
+
+type xsdFacet struct {
+	Value string `xml:"value,attr"`
+}
+
+type xsdSimpleType struct {
+	Name        string         `xml:"name,attr"`
+	Restriction *xsdDerivation `xml:"restriction"`
+}
+
+type xsdAttribute struct {
+	Name       string         `xml:"name,attr"`
+	Type       string         `xml:"type,attr"`
+	Ref        string         `xml:"ref,attr"`
+	Use        string         `xml:"use,attr"`
+	SimpleType *xsdSimpleType `xml:"simpleType"`
+}
+
+// xsdGroup is a sequence, all or choice model group.
+// Particles keep their document order, as it defines the order of child elements.
+type xsdGroup struct {
+	MinOccurs string
+	MaxOccurs string
+	Choice    bool
+	Particles []xsdParticle
+}
+
+// xsdParticle is either an element or a nested model group.
+type xsdParticle struct {
+	Element *xsdElement
+	Group   *xsdGroup
+}
+
+func (g *xsdGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	g.Choice = start.Name.Local == "choice"
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "minOccurs":
+			g.MinOccurs = attr.Value
+		case "maxOccurs":
+			g.MaxOccurs = attr.Value
+		}
+	}
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "element":
+				el := &xsdElement{}
+				if err := d.DecodeElement(el, &t); err != nil {
+					return err
+				}
+				g.Particles = append(g.Particles, xsdParticle{Element: el})
+			case "sequence", "choice", "all":
+				group := &xsdGroup{}
+				if err := d.DecodeElement(group, &t); err != nil {
+					return err
+				}
+				g.Particles = append(g.Particles, xsdParticle{Group: group})
+			default:
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// node is a resolved element or attribute of a message payload.
+// The tree is built once per message and used both to build the generator
+// schema and to render the generated values as XML in document order.
+type node struct {
+	name      string
+	namespace string
+	attribute bool
+	optional  bool
+	repeated  bool
+
+	// simple is set for nodes with text content.
+	simple *schema.Schema
+
+	// children holds attributes first, then child elements.
+	children []*node
+}
+
+// textKey is the key of the text content of elements that also have attributes.
+const textKey = "#text"
+
+// scoped is a schema component with the schema it is defined in.
+type scoped[T any] struct {
+	value  T
+	schema *xsdSchema
+}
+
+// typeSystem resolves XSD components across all inline schemas.
+// Components are looked up by local name, namespace prefixes are ignored.
+type typeSystem struct {
+	elements     map[string]scoped[*xsdElement]
+	complexTypes map[string]scoped[*xsdComplexType]
+	simpleTypes  map[string]scoped[*xsdSimpleType]
+}
+
+func newTypeSystem(schemas []*xsdSchema) *typeSystem {
+	ts := &typeSystem{
+		elements:     make(map[string]scoped[*xsdElement]),
+		complexTypes: make(map[string]scoped[*xsdComplexType]),
+		simpleTypes:  make(map[string]scoped[*xsdSimpleType]),
+	}
+
+	for _, s := range schemas {
+		for _, el := range s.Elements {
+			ts.elements[el.Name] = scoped[*xsdElement]{el, s}
+		}
+		for _, ct := range s.ComplexTypes {
+			ts.complexTypes[ct.Name] = scoped[*xsdComplexType]{ct, s}
+		}
+		for _, st := range s.SimpleTypes {
+			ts.simpleTypes[st.Name] = scoped[*xsdSimpleType]{st, s}
+		}
+	}
+
+	return ts
+}
+
+// resolver builds node trees, guarding against recursive types.
+type resolver struct {
+	types    *typeSystem
+	visiting map[string]bool
+}
+
+func (ts *typeSystem) newResolver() *resolver {
+	return &resolver{types: ts, visiting: make(map[string]bool)}
+}
+
+// globalElement resolves a top-level element by (prefixed) name.
+// Returns nil if the element is not defined.
+func (r *resolver) globalElement(name string) *node {
+	ge, ok := r.types.elements[localName(name)]
+	if !ok {
+		return nil
+	}
+	return r.element(ge.value, ge.schema, ge.schema.TargetNamespace)
+}
+
+// typedElement builds an element of a named type, e.g. an RPC message part.
+func (r *resolver) typedElement(name, namespace, typeName string) *node {
+	n := &node{name: name, namespace: namespace}
+	r.resolveType(n, typeName)
+	return n
+}
+
+// element resolves an element declaration. namespace is the namespace
+// the element is in, empty for unqualified local elements.
+// Returns nil for elements of types already being resolved higher up.
+func (r *resolver) element(el *xsdElement, s *xsdSchema, namespace string) *node {
+	if el.Ref != "" {
+		key := "element:" + localName(el.Ref)
+		ge, ok := r.types.elements[localName(el.Ref)]
+		if !ok || r.visiting[key] {
+			return nil
+		}
+		r.visiting[key] = true
+		defer delete(r.visiting, key)
+
+		n := r.element(ge.value, ge.schema, ge.schema.TargetNamespace)
+		if n != nil {
+			n.optional = el.MinOccurs == "0"
+			n.repeated = isRepeated(el.MaxOccurs)
+		}
+		return n
+	}
+
+	n := &node{
+		name:      el.Name,
+		namespace: namespace,
+		optional:  el.MinOccurs == "0",
+		repeated:  isRepeated(el.MaxOccurs),
+	}
+
+	switch {
+	case el.ComplexType != nil:
+		r.complexType(n, el.ComplexType, s)
+	case el.SimpleType != nil:
+		n.simple = r.simpleType(el.SimpleType)
+	case el.Type != "":
+		key := "type:" + localName(el.Type)
+		if r.visiting[key] {
+			return nil
+		}
+		r.visiting[key] = true
+		defer delete(r.visiting, key)
+		r.resolveType(n, el.Type)
+	default:
+		n.simple = &schema.Schema{Type: types.TypeString}
+	}
+
+	return n
+}
+
+// resolveType fills a node from a named type: a built-in, simple or complex type.
+func (r *resolver) resolveType(n *node, typeName string) {
+	name := localName(typeName)
+	if ct, ok := r.types.complexTypes[name]; ok {
+		r.complexType(n, ct.value, ct.schema)
+		return
+	}
+	n.simple = r.simpleTypeByName(typeName)
+}
+
+func (r *resolver) complexType(n *node, ct *xsdComplexType, s *xsdSchema) {
+	n.children = append(n.children, r.attributes(ct.Attributes)...)
+
+	switch {
+	case ct.SimpleContent != nil:
+		if d := derivation(ct.SimpleContent); d != nil {
+			n.simple = r.simpleTypeByName(d.Base)
+			n.children = append(n.children, r.attributes(d.Attributes)...)
+		}
+
+	case ct.ComplexContent != nil:
+		d := derivation(ct.ComplexContent)
+		if d == nil {
+			return
+		}
+		if ct.ComplexContent.Extension != nil {
+			if base, ok := r.types.complexTypes[localName(d.Base)]; ok {
+				key := "type:" + base.value.Name
+				if !r.visiting[key] {
+					r.visiting[key] = true
+					r.complexType(n, base.value, base.schema)
+					delete(r.visiting, key)
+				}
+			}
+		}
+		n.children = append(n.children, r.attributes(d.Attributes)...)
+		n.children = append(n.children, r.group(firstGroup(d.Sequence, d.All, d.Choice), s)...)
+
+	default:
+		n.children = append(n.children, r.group(firstGroup(ct.Sequence, ct.All, ct.Choice), s)...)
+	}
+}
+
+// group resolves the elements of a model group.
+// Only the first alternative of a choice is generated.
+func (r *resolver) group(g *xsdGroup, s *xsdSchema) []*node {
+	if g == nil {
+		return nil
+	}
+
+	particles := g.Particles
+	if g.Choice && len(particles) > 1 {
+		particles = particles[:1]
+	}
+
+	var res []*node
+	for _, p := range particles {
+		if p.Group != nil {
+			res = append(res, r.group(p.Group, s)...)
+			continue
+		}
+
+		namespace := ""
+		if p.Element.Form == "qualified" || (p.Element.Form == "" && s.ElementFormDefault == "qualified") {
+			namespace = s.TargetNamespace
+		}
+		if n := r.element(p.Element, s, namespace); n != nil {
+			if g.Choice || g.MinOccurs == "0" {
+				n.optional = true
+			}
+			if isRepeated(g.MaxOccurs) {
+				n.repeated = true
+			}
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+func (r *resolver) attributes(attrs []*xsdAttribute) []*node {
+	res := make([]*node, 0, len(attrs))
+	for _, a := range attrs {
+		name := a.Name
+		if name == "" {
+			name = localName(a.Ref)
+		}
+		if name == "" || a.Use == "prohibited" {
+			continue
+		}
+
+		n := &node{name: name, attribute: true, optional: a.Use != "required"}
+		switch {
+		case a.SimpleType != nil:
+			n.simple = r.simpleType(a.SimpleType)
+		case a.Type != "":
+			n.simple = r.simpleTypeByName(a.Type)
+		default:
+			n.simple = &schema.Schema{Type: types.TypeString}
+		}
+		res = append(res, n)
+	}
+	return res
+}
+
+// simpleTypeByName maps a built-in or user-defined simple type to a generator schema.
+func (r *resolver) simpleTypeByName(typeName string) *schema.Schema {
+	name := localName(typeName)
+	if st, ok := r.types.simpleTypes[name]; ok {
+		key := "simple:" + name
+		if r.visiting[key] {
+			return &schema.Schema{Type: types.TypeString}
+		}
+		r.visiting[key] = true
+		defer delete(r.visiting, key)
+		return r.simpleType(st.value)
+	}
+	return builtinSchema(name)
+}
+
+// simpleType applies the facets of a restriction to its base type.
+// Lists and unions are generated as plain strings.
+func (r *resolver) simpleType(st *xsdSimpleType) *schema.Schema {
+	d := st.Restriction
+	if d == nil {
+		return &schema.Schema{Type: types.TypeString}
+	}
+
+	base := builtinSchema("string")
+	if d.Base != "" {
+		base = r.simpleTypeByName(d.Base)
+	}
+	res := *base
+
+	if len(d.Enumerations) > 0 {
+		res.Enum = make([]any, 0, len(d.Enumerations))
+		for _, e := range d.Enumerations {
+			res.Enum = append(res.Enum, e.Value)
+		}
+		res.Type = types.TypeString
+		res.Format = ""
+	}
+	if d.Pattern != nil {
+		res.Pattern = d.Pattern.Value
+	}
+	if v, ok := facetFloat(d.MinInclusive); ok {
+		res.Minimum = &v
+	}
+	if v, ok := facetFloat(d.MaxInclusive); ok {
+		res.Maximum = &v
+	}
+	if v, ok := facetInt(d.MinLength); ok {
+		res.MinLength = &v
+	}
+	if v, ok := facetInt(d.MaxLength); ok {
+		res.MaxLength = &v
+	}
+	if v, ok := facetInt(d.Length); ok {
+		res.MinLength, res.MaxLength = &v, &v
+	}
+
+	return &res
+}
+
+// builtinSchema maps XML Schema built-in types to generator schemas.
+func builtinSchema(name string) *schema.Schema {
+	zero, one := 0.0, 1.0
+
+	switch name {
+	case "boolean":
+		return &schema.Schema{Type: types.TypeBoolean}
+	case "int":
+		return &schema.Schema{Type: types.TypeInteger, Format: "int32"}
+	case "integer", "long", "short", "byte":
+		return &schema.Schema{Type: types.TypeInteger}
+	case "unsignedInt", "unsignedLong", "unsignedShort", "unsignedByte", "nonNegativeInteger":
+		return &schema.Schema{Type: types.TypeInteger, Minimum: &zero}
+	case "positiveInteger":
+		return &schema.Schema{Type: types.TypeInteger, Minimum: &one}
+	case "decimal", "float", "double":
+		return &schema.Schema{Type: types.TypeNumber}
+	case "dateTime":
+		return &schema.Schema{Type: types.TypeString, Format: "date-time"}
+	case "date":
+		return &schema.Schema{Type: types.TypeString, Format: "date"}
+	case "anyURI":
+		return &schema.Schema{Type: types.TypeString, Format: "uri"}
+	case "base64Binary":
+		return &schema.Schema{Type: types.TypeString, Format: "byte"}
+	default:
+		return &schema.Schema{Type: types.TypeString}
+	}
+}
+
+func derivation(c *xsdContent) *xsdDerivation {
+	if c.Extension != nil {
+		return c.Extension
+	}
+	return c.Restriction
+}
+
+func firstGroup(groups ...*xsdGroup) *xsdGroup {
+	for _, g := range groups {
+		if g != nil {
+			return g
+		}
+	}
+	return nil
+}
+
+func facetFloat(f *xsdFacet) (float64, bool) {
+	if f == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(f.Value, 64)
+	return v, err == nil
+}
+
+func facetInt(f *xsdFacet) (int64, bool) {
+	if f == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(f.Value, 10, 64)
+	return v, err == nil
+}
+
+func isRepeated(maxOccurs string) bool {
+	if maxOccurs == "unbounded" {
+		return true
+	}
+	n, err := strconv.Atoi(maxOccurs)
+	return err == nil && n > 1
+}
+
+// localName strips the namespace prefix of a qualified name.
+func localName(qname string) string {
+	if i := strings.LastIndex(qname, ":"); i >= 0 {
+		return qname[i+1:]
+	}
+	return qname
+}
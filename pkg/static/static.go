@@ -0,0 +1,455 @@
+// Package static reads and writes the static service layout: one response file per
+// method and path, <method>/<path>/index.<ext>, with an optional sidecar for
+// the status code and headers. OpenAPI specs are generated from it with GenerateSpec.
+package static
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mockzilla/connexions/v2/pkg/schema"
+	"go.yaml.in/yaml/v4"
+)
+
+// MetaSuffix is the suffix of the sidecar file holding the status code and headers
+// of a static response, e.g. index.meta.yml next to index.json.
+const MetaSuffix = ".meta.yml"
+
+// Route represents a static route with its content.
+type Route struct {
+	Method      string
+	Path        string
+	ContentType string
+	Content     string
+	StatusCode  int
+	Headers     map[string]string
+}
+
+// Meta is the content of a static response sidecar file.
+type Meta struct {
+	Status  int               `yaml:"status,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// scanStaticFiles scans a static directory and returns all routes.
+// Directory structure: <staticDir>/<method>/<path>/index.<ext>
+// An optional <name>.meta.yml sidecar sets the status code and headers of <name>.<ext>.
+func scanStaticFiles(staticDir string) ([]Route, error) {
+	var routes []Route
+
+	// Walk through method directories (get, post, etc.)
+	methodDirs, err := os.ReadDir(staticDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading static directory: %w", err)
+	}
+
+	for _, methodDir := range methodDirs {
+		if !methodDir.IsDir() {
+			continue
+		}
+
+		method := strings.ToUpper(methodDir.Name())
+		methodPath := filepath.Join(staticDir, methodDir.Name())
+
+		// Walk through all subdirectories to find response files
+		err := filepath.Walk(methodPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() || strings.HasSuffix(info.Name(), MetaSuffix) {
+				return nil
+			}
+
+			// Get extension and check if it's a supported content type
+			ext := filepath.Ext(info.Name())
+			contentType := getContentType(ext)
+			if contentType == "" {
+				return nil // Skip unsupported files
+			}
+
+			// Get the path relative to method directory
+			relPath, err := filepath.Rel(methodPath, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+
+			// Convert directory path to URL path
+			urlPath := "/" + strings.ReplaceAll(relPath, string(filepath.Separator), "/")
+			if relPath == "." {
+				urlPath = "/"
+			}
+
+			// Get filename without extension
+			filename := strings.TrimSuffix(info.Name(), ext)
+
+			// If filename is not "index", append it to the URL path
+			if filename != "index" {
+				if urlPath == "/" {
+					urlPath = "/" + info.Name()
+				} else {
+					urlPath = urlPath + "/" + info.Name()
+				}
+			}
+
+			// Read file content
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("reading file %s: %w", path, err)
+			}
+
+			// Trim trailing whitespace
+			contentStr := strings.TrimRight(string(content), "\n\r\t ")
+
+			meta, err := readMeta(filepath.Join(filepath.Dir(path), filename+MetaSuffix))
+			if err != nil {
+				return err
+			}
+
+			routes = append(routes, Route{
+				Method:      method,
+				Path:        urlPath,
+				ContentType: contentType,
+				Content:     contentStr,
+				StatusCode:  meta.Status,
+				Headers:     meta.Headers,
+			})
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("walking method directory %s: %w", method, err)
+		}
+	}
+
+	return routes, nil
+}
+
+// readMeta reads a sidecar file, returning an empty meta if it doesn't exist.
+func readMeta(path string) (*Meta, error) {
+	meta := &Meta{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return meta, nil
+		}
+		return nil, fmt.Errorf("reading file %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("parsing file %s: %w", path, err)
+	}
+	return meta, nil
+}
+
+// WriteResponse writes a response into the static layout under serviceDir,
+// as <method>/<path>/index.<ext> plus a sidecar when the status isn't 200 or headers are set.
+// The extension is derived from the content type, unsupported content types are rejected.
+func WriteResponse(serviceDir, method, path, contentType string, content []byte, meta *Meta) error {
+	ext := Extension(contentType)
+	if ext == "" {
+		return fmt.Errorf("unsupported content type: %s", contentType)
+	}
+
+	dir := filepath.Join(serviceDir, strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		if segment == "." || segment == ".." || strings.ContainsAny(segment, `\`) {
+			return fmt.Errorf("invalid path segment %q in %s", segment, path)
+		}
+		dir = filepath.Join(dir, segment)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating directory %s: %w", dir, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "index"+ext), content, 0o644); err != nil {
+		return fmt.Errorf("writing static response: %w", err)
+	}
+
+	metaPath := filepath.Join(dir, "index"+MetaSuffix)
+	if meta == nil || ((meta.Status == 0 || meta.Status == 200) && len(meta.Headers) == 0) {
+		if err := os.Remove(metaPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing stale sidecar: %w", err)
+		}
+		return nil
+	}
+
+	data, err := yaml.Dump(meta, yaml.WithIndent(2))
+	if err != nil {
+		return fmt.Errorf("marshalling sidecar: %w", err)
+	}
+	if err := os.WriteFile(metaPath, data, 0o644); err != nil {
+		return fmt.Errorf("writing sidecar: %w", err)
+	}
+	return nil
+}
+
+// Extension returns the static file extension for a content type.
+// JSON-based types such as application/vnd.api+json map to .json.
+// Returns empty string for unsupported content types.
+func Extension(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return ".json"
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return ".xml"
+	case mediaType == "text/html":
+		return ".html"
+	case mediaType == "text/plain":
+		return ".txt"
+	case mediaType == "application/yaml" || mediaType == "application/x-yaml" || mediaType == "text/yaml":
+		return ".yml"
+	default:
+		return ""
+	}
+}
+
+// getContentType returns the content type for a file extension.
+// Returns empty string for unsupported extensions.
+func getContentType(ext string) string {
+	switch ext {
+	case ".json":
+		return "application/json"
+	case ".xml":
+		return "application/xml"
+	case ".html", ".htm":
+		return "text/html"
+	case ".txt":
+		return "text/plain"
+	case ".yaml", ".yml":
+		return "application/yaml"
+	default:
+		return ""
+	}
+}
+
+// generateOpenAPIFromStatic generates an OpenAPI spec from static routes.
+func generateOpenAPIFromStatic(routes []Route, serviceName string) ([]byte, error) {
+	spec := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   serviceName,
+			"version": "1.0.0",
+		},
+		"paths": make(map[string]any),
+	}
+
+	paths := spec["paths"].(map[string]any)
+
+	for _, route := range routes {
+		path := route.Path
+		method := strings.ToLower(route.Method)
+
+		// Get or create path item
+		var pathItem map[string]any
+		if existing, ok := paths[path]; ok {
+			pathItem = existing.(map[string]any)
+		} else {
+			pathItem = make(map[string]any)
+			paths[path] = pathItem
+		}
+
+		// Infer schema from content
+		responseSchema, err := schema.BuildSchemaFromContent([]byte(route.Content), route.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build schema for %s %s: %w", route.Method, route.Path, err)
+		}
+
+		// Convert our schema to OpenAPI schema map
+		schemaMap := schemaToMap(responseSchema)
+
+		// Generate operation ID from method and path
+		operationId := generateOperationId(method, path)
+
+		statusCode := route.StatusCode
+		if statusCode == 0 {
+			statusCode = 200
+		}
+
+		description := "Success"
+		if statusCode != 200 {
+			description = http.StatusText(statusCode)
+		}
+
+		response := map[string]any{
+			"description": description,
+			"content": map[string]any{
+				route.ContentType: map[string]any{
+					"schema":            schemaMap,
+					"x-static-response": route.Content,
+				},
+			},
+		}
+
+		if len(route.Headers) > 0 {
+			headers := make(map[string]any, len(route.Headers))
+			for name, value := range route.Headers {
+				headers[name] = map[string]any{
+					"schema": map[string]any{
+						"type":    "string",
+						"example": value,
+					},
+				}
+			}
+			response["headers"] = headers
+		}
+
+		// Create operation
+		operation := map[string]any{
+			"operationId": operationId,
+			"responses": map[string]any{
+				strconv.Itoa(statusCode): response,
+			},
+		}
+
+		pathItem[method] = operation
+	}
+
+	// Marshal to YAML with 2-space indent
+	yamlBytes, err := yaml.Dump(spec, yaml.WithIndent(2))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OpenAPI spec: %w", err)
+	}
+
+	return yamlBytes, nil
+}
+
+// schemaToMap converts our schema.Schema to a map for OpenAPI spec.
+func schemaToMap(s *schema.Schema) map[string]any {
+	m := make(map[string]any)
+
+	if s.Type != "" {
+		m["type"] = s.Type
+	}
+
+	if s.Format != "" {
+		m["format"] = s.Format
+	}
+
+	if s.Items != nil {
+		m["items"] = schemaToMap(s.Items)
+	}
+
+	if len(s.Properties) > 0 {
+		props := make(map[string]any)
+		for k, v := range s.Properties {
+			props[k] = schemaToMap(v)
+		}
+		m["properties"] = props
+	}
+
+	if len(s.Required) > 0 {
+		m["required"] = s.Required
+	}
+
+	if s.AdditionalProperties != nil {
+		m["additionalProperties"] = schemaToMap(s.AdditionalProperties)
+	}
+
+	if len(s.Enum) > 0 {
+		m["enum"] = s.Enum
+	}
+
+	if s.Example != nil {
+		m["example"] = s.Example
+	}
+
+	if s.Default != nil {
+		m["default"] = s.Default
+	}
+
+	if s.Nullable {
+		m["nullable"] = true
+	}
+
+	if s.Pattern != "" {
+		m["pattern"] = s.Pattern
+	}
+
+	if s.MinLength != nil {
+		m["minLength"] = *s.MinLength
+	}
+
+	if s.MaxLength != nil {
+		m["maxLength"] = *s.MaxLength
+	}
+
+	if s.Minimum != nil {
+		m["minimum"] = *s.Minimum
+	}
+
+	if s.Maximum != nil {
+		m["maximum"] = *s.Maximum
+	}
+
+	return m
+}
+
+// generateOperationId creates an operation ID from method and path.
+// Example: "get", "/users/{id}" -> "getUsers"
+func generateOperationId(method, path string) string {
+	// Remove leading slash and split by /
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	// Filter out path parameters and build camelCase name
+	var nameParts []string
+	for _, part := range parts {
+		// Skip path parameters like {id}
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			continue
+		}
+		// Skip file extensions
+		if strings.Contains(part, ".") {
+			part = strings.Split(part, ".")[0]
+		}
+		if part != "" {
+			nameParts = append(nameParts, part)
+		}
+	}
+
+	// Build operation ID: method + CamelCasePath
+	if len(nameParts) == 0 {
+		return method + "Root"
+	}
+
+	// Capitalize first letter of each part
+	for i, part := range nameParts {
+		if len(part) > 0 {
+			nameParts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+
+	return method + strings.Join(nameParts, "")
+}
+
+// GenerateSpec generates an OpenAPI spec from a static service directory.
+func GenerateSpec(staticDir, serviceName string) ([]byte, error) {
+	// Scan static files
+	routes, err := scanStaticFiles(staticDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan static files: %w", err)
+	}
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no static files found in directory: %s", staticDir)
+	}
+
+	// Generate OpenAPI spec from routes
+	specBytes, err := generateOpenAPIFromStatic(routes, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OpenAPI spec: %w", err)
+	}
+
+	return specBytes, nil
+}
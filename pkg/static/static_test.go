@@ -0,0 +1,54 @@
+package static
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+)
+
+func TestGenerateSpec_Sidecar(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, WriteResponse(dir, "POST", "/orders", "application/json", []byte(`{"id":1}`), &Meta{
+		Status:  201,
+		Headers: map[string]string{"Location": "/orders/1"},
+	}))
+	require.NoError(t, WriteResponse(dir, "GET", "/orders", "application/json", []byte(`[]`), nil))
+
+	specBytes, err := GenerateSpec(dir, "shop")
+	require.NoError(t, err)
+
+	var spec map[string]any
+	require.NoError(t, yaml.Unmarshal(specBytes, &spec))
+
+	paths := spec["paths"].(map[string]any)
+	orders := paths["/orders"].(map[string]any)
+
+	created := orders["post"].(map[string]any)["responses"].(map[string]any)["201"].(map[string]any)
+	assert.Equal(t, "Created", created["description"])
+	location := created["headers"].(map[string]any)["Location"].(map[string]any)
+	assert.Equal(t, "/orders/1", location["schema"].(map[string]any)["example"])
+
+	assert.Contains(t, orders["get"].(map[string]any)["responses"], "200")
+}
+
+func TestWriteResponse(t *testing.T) {
+	t.Run("rejects unsupported content type", func(t *testing.T) {
+		err := WriteResponse(t.TempDir(), "GET", "/logo", "image/png", []byte("x"), nil)
+		assert.ErrorContains(t, err, "unsupported content type")
+	})
+
+	t.Run("rejects path traversal", func(t *testing.T) {
+		err := WriteResponse(t.TempDir(), "GET", "/../etc", "application/json", []byte("{}"), nil)
+		assert.ErrorContains(t, err, "invalid path segment")
+	})
+
+	t.Run("removes stale sidecar", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, WriteResponse(dir, "GET", "/a", "application/json", []byte("{}"), &Meta{Status: 404}))
+		require.NoError(t, WriteResponse(dir, "GET", "/a", "application/json", []byte("{}"), nil))
+		assert.NoFileExists(t, filepath.Join(dir, "get", "a", "index"+MetaSuffix))
+	})
+}
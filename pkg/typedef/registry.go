@@ -65,14 +65,17 @@ func (td *TypeDefinitionRegistry) GetResponseSchema(path, method string) *schema
 }
 
 // NewTypeDefinitionRegistry creates a new TypeDefinitionRegistry instance.
-// It extracts x-static-response extensions from the spec if specBytes is provided.
+// It extracts x-static-response extensions and required request bodies from the spec if specBytes is provided.
 func NewTypeDefinitionRegistry(parseCtx *codegen.ParseContext, maxRecursionDepth int, specBytes []byte) *TypeDefinitionRegistry {
-	// Extract static responses if spec bytes are provided
-	var staticResponses map[StaticResponseKey]string
+	// Extract static responses and required bodies if spec bytes are provided
+	var (
+		staticResponses map[StaticResponseKey]string
+		requiredBodies  map[string]bool
+	)
 	if specBytes != nil {
-		var err error
-		staticResponses, err = ExtractStaticResponses(specBytes)
-		if err != nil {
+		if details, err := extractSpecDetails(specBytes); err == nil {
+			staticResponses, requiredBodies = details.staticResponses, details.requiredBodies
+		} else {
 			// Log error but continue - static responses are optional
 			staticResponses = make(map[StaticResponseKey]string)
 		}
@@ -198,6 +201,7 @@ func NewTypeDefinitionRegistry(parseCtx *codegen.ParseContext, maxRecursionDepth
 			Query:        queryParams,
 			Response:     response,
 			Body:         reqSchema,
+			BodyRequired: requiredBodies[op.Method+" "+op.Path],
 			BodyEncoding: bodyEncoding,
 		}
 		operations = append(operations, enhanced)
@@ -215,6 +219,11 @@ func NewTypeDefinitionRegistry(parseCtx *codegen.ParseContext, maxRecursionDepth
 // This is a convenience function that handles parsing and registry creation.
 // If specOptions.LazyLoad is true, operations are parsed on-demand for faster startup.
 func NewRegistryFromSpec(specBytes []byte, codegenCfg codegen.Configuration, specOptions *config.SpecOptions) OperationRegistry {
+	specBytes, err := ConvertSwagger2(specBytes)
+	if err != nil {
+		panic("failed to convert Swagger 2.0 spec: " + err.Error())
+	}
+
 	if specOptions != nil && specOptions.LazyLoad {
 		registry, err := NewLazyTypeDefinitionRegistry(specBytes, codegenCfg, specOptions)
 		if err != nil {
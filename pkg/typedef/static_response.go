@@ -19,9 +19,26 @@ func NewStaticResponseKey(method, path string, statusCode int) StaticResponseKey
 	return StaticResponseKey(fmt.Sprintf("%s %s %d", method, path, statusCode))
 }
 
+// specDetails holds what the registry reads from the spec itself rather than from the codegen parse context.
+type specDetails struct {
+	staticResponses map[StaticResponseKey]string
+
+	// requiredBodies is keyed by "METHOD /path"
+	requiredBodies map[string]bool
+}
+
 // ExtractStaticResponses extracts all x-static-response values from an OpenAPI spec.
 // Returns a map keyed by "METHOD /path statusCode" -> static response content.
 func ExtractStaticResponses(specBytes []byte) (map[StaticResponseKey]string, error) {
+	details, err := extractSpecDetails(specBytes)
+	if err != nil {
+		return nil, err
+	}
+	return details.staticResponses, nil
+}
+
+// extractSpecDetails reads static responses and required request bodies in one pass over the spec.
+func extractSpecDetails(specBytes []byte) (*specDetails, error) {
 	doc, err := codegen.LoadDocumentFromContents(specBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load OpenAPI document: %w", err)
@@ -32,15 +49,22 @@ func ExtractStaticResponses(specBytes []byte) (map[StaticResponseKey]string, err
 		return nil, fmt.Errorf("failed to build OpenAPI model: %w", err)
 	}
 
+	details := &specDetails{
+		staticResponses: make(map[StaticResponseKey]string),
+		requiredBodies:  make(map[string]bool),
+	}
+
 	model := &builtModel.Model
 	if model == nil || model.Paths == nil || model.Paths.PathItems == nil {
-		return make(map[StaticResponseKey]string), nil
+		return details, nil
 	}
 
-	staticResponses := make(map[StaticResponseKey]string)
-
 	for path, pathItem := range model.Paths.PathItems.FromOldest() {
 		for method, operation := range pathItem.GetOperations().FromOldest() {
+			if body := operation.RequestBody; body != nil && body.Required != nil && *body.Required {
+				details.requiredBodies[strings.ToUpper(method)+" "+path] = true
+			}
+
 			if operation.Responses == nil || operation.Responses.Codes == nil {
 				continue
 			}
@@ -76,11 +100,11 @@ func ExtractStaticResponses(specBytes []byte) (map[StaticResponseKey]string, err
 
 					// Create key and store (method must be uppercase to match OperationDefinition)
 					key := NewStaticResponseKey(strings.ToUpper(method), path, statusCode)
-					staticResponses[key] = staticResponse
+					details.staticResponses[key] = staticResponse
 				}
 			}
 		}
 	}
 
-	return staticResponses, nil
+	return details, nil
 }
@@ -200,3 +200,35 @@ paths:
 		assert.Empty(t, result)
 	})
 }
+
+func TestExtractSpecDetails(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: Test
+  version: 1.0.0
+paths:
+  /pets:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+      responses:
+        '201':
+          description: Created
+    put:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+      responses:
+        '200':
+          description: OK
+`)
+	details, err := extractSpecDetails(spec)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"POST /pets": true}, details.requiredBodies)
+}
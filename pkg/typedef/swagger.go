@@ -0,0 +1,742 @@
+package typedef
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// IsSwagger2 reports whether the spec is a Swagger 2.0 document.
+func IsSwagger2(specBytes []byte) bool {
+	if !bytes.Contains(specBytes, []byte("swagger")) {
+		return false
+	}
+
+	var head struct {
+		Swagger string `yaml:"swagger"`
+	}
+	if err := yaml.Unmarshal(specBytes, &head); err != nil {
+		return false
+	}
+	return strings.HasPrefix(head.Swagger, "2.")
+}
+
+// ConvertSwagger2 converts a Swagger 2.0 spec to an OpenAPI 3.0 YAML document.
+// Specs that are not Swagger 2.0 are returned unchanged.
+//
+// Body and formData parameters become request bodies, produces and consumes become
+// media types, securityDefinitions become security schemes and definitions,
+// parameters and responses move to components with their references rewritten.
+// The order of keys in the original spec is preserved.
+func ConvertSwagger2(specBytes []byte) ([]byte, error) {
+	if !IsSwagger2(specBytes) {
+		return specBytes, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(specBytes, &doc); err != nil {
+		return nil, fmt.Errorf("parsing swagger spec: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("swagger spec is not a mapping")
+	}
+
+	res := newSwaggerConverter(doc.Content[0]).convert()
+	blockStyle(res)
+
+	return yaml.Dump(res, yaml.WithIndent(2))
+}
+
+// swaggerConverter holds the document-wide settings operations inherit.
+type swaggerConverter struct {
+	root     *yaml.Node
+	consumes []string
+	produces []string
+
+	// bodyParams and formParams are the global body and formData parameters by name.
+	// Body parameters become request bodies in components,
+	// formData parameters are inlined where they are referenced.
+	bodyParams map[string]*yaml.Node
+	formParams map[string]*yaml.Node
+}
+
+func newSwaggerConverter(root *yaml.Node) *swaggerConverter {
+	c := &swaggerConverter{
+		root:       root,
+		consumes:   stringList(mapGet(root, "consumes")),
+		produces:   stringList(mapGet(root, "produces")),
+		bodyParams: make(map[string]*yaml.Node),
+		formParams: make(map[string]*yaml.Node),
+	}
+
+	if params := mapGet(root, "parameters"); params != nil {
+		for name, p := range mapPairs(params) {
+			switch scalar(mapGet(p, "in")) {
+			case "body":
+				c.bodyParams[name] = p
+			case "formData":
+				c.formParams[name] = p
+			}
+		}
+	}
+
+	return c
+}
+
+func (c *swaggerConverter) convert() *yaml.Node {
+	res := newMapping()
+	mapSet(res, "openapi", newScalar("3.0.3"))
+
+	servers := c.servers()
+	components := newMapping()
+
+	for key, value := range mapPairs(c.root) {
+		switch key {
+		case "swagger", "host", "basePath", "schemes", "consumes", "produces":
+			continue
+		case "info":
+			mapSet(res, key, value)
+			if servers != nil {
+				mapSet(res, "servers", servers)
+				servers = nil
+			}
+		case "paths":
+			mapSet(res, key, c.paths(value))
+		case "definitions":
+			mapSet(components, "schemas", mapValues(value, convertSchema))
+		case "parameters":
+			params, bodies := newMapping(), newMapping()
+			for name, p := range mapPairs(value) {
+				switch scalar(mapGet(p, "in")) {
+				case "body":
+					mapSet(bodies, name, c.requestBody(p, c.consumes))
+				case "formData":
+					// Inlined into the request bodies of the operations referencing them.
+				default:
+					mapSet(params, name, convertParameter(p))
+				}
+			}
+			if len(params.Content) > 0 {
+				mapSet(components, "parameters", params)
+			}
+			if len(bodies.Content) > 0 {
+				mapSet(components, "requestBodies", bodies)
+			}
+		case "responses":
+			mapSet(components, "responses", mapValues(value, func(r *yaml.Node) *yaml.Node {
+				return convertResponse(r, c.produces)
+			}))
+		case "securityDefinitions":
+			mapSet(components, "securitySchemes", mapValues(value, convertSecurityScheme))
+		default:
+			mapSet(res, key, value)
+		}
+	}
+
+	if servers != nil {
+		mapSet(res, "servers", servers)
+	}
+	if len(components.Content) > 0 {
+		mapSet(res, "components", components)
+	}
+	return res
+}
+
+// servers builds the server list from host, basePath and schemes.
+func (c *swaggerConverter) servers() *yaml.Node {
+	host := scalar(mapGet(c.root, "host"))
+	basePath := scalar(mapGet(c.root, "basePath"))
+	if host == "" && basePath == "" {
+		return nil
+	}
+
+	schemes := stringList(mapGet(c.root, "schemes"))
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+
+	res := newSequence()
+	for _, scheme := range schemes {
+		url := basePath
+		if host != "" {
+			url = scheme + "://" + host + basePath
+		}
+		if url == "" {
+			url = "/"
+		}
+		server := newMapping()
+		mapSet(server, "url", newScalar(url))
+		res.Content = append(res.Content, server)
+		if host == "" {
+			break
+		}
+	}
+	return res
+}
+
+var operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+func (c *swaggerConverter) paths(paths *yaml.Node) *yaml.Node {
+	res := newMapping()
+
+	for path, item := range mapPairs(paths) {
+		if item.Kind != yaml.MappingNode {
+			mapSet(res, path, item)
+			continue
+		}
+
+		// Path-level body and formData parameters apply to every operation.
+		var shared []*yaml.Node
+		params := newSequence()
+		if v := mapGet(item, "parameters"); v != nil {
+			for _, p := range v.Content {
+				if c.isBodyOrForm(p) {
+					shared = append(shared, p)
+					continue
+				}
+				params.Content = append(params.Content, c.parameterRef(p))
+			}
+		}
+
+		converted := newMapping()
+		for key, value := range mapPairs(item) {
+			switch {
+			case key == "parameters":
+				if len(params.Content) > 0 {
+					mapSet(converted, key, params)
+				}
+			case key == "$ref":
+				mapSet(converted, key, newScalar(rewriteRef(scalar(value))))
+			case slices.Contains(operationMethods, key):
+				mapSet(converted, key, c.operation(value, shared))
+			default:
+				mapSet(converted, key, value)
+			}
+		}
+
+		mapSet(res, path, converted)
+	}
+
+	return res
+}
+
+func (c *swaggerConverter) operation(op *yaml.Node, shared []*yaml.Node) *yaml.Node {
+	consumes := c.consumes
+	if v := mapGet(op, "consumes"); v != nil {
+		consumes = stringList(v)
+	}
+	produces := c.produces
+	if v := mapGet(op, "produces"); v != nil {
+		produces = stringList(v)
+	}
+
+	var params []*yaml.Node
+	if v := mapGet(op, "parameters"); v != nil {
+		params = v.Content
+	}
+	params = append(slices.Clone(shared), params...)
+
+	var (
+		requestBody *yaml.Node
+		formParams  []*yaml.Node
+		converted   = newSequence()
+	)
+	for _, p := range params {
+		if ref := scalar(mapGet(p, "$ref")); ref != "" {
+			name := strings.TrimPrefix(ref, "#/parameters/")
+			if _, ok := c.bodyParams[name]; ok {
+				requestBody = newMapping()
+				mapSet(requestBody, "$ref", newScalar("#/components/requestBodies/"+name))
+				continue
+			}
+			if fp, ok := c.formParams[name]; ok {
+				formParams = append(formParams, fp)
+				continue
+			}
+		}
+
+		switch scalar(mapGet(p, "in")) {
+		case "body":
+			requestBody = c.requestBody(p, consumes)
+		case "formData":
+			formParams = append(formParams, p)
+		default:
+			converted.Content = append(converted.Content, c.parameterRef(p))
+		}
+	}
+	if len(formParams) > 0 {
+		requestBody = formRequestBody(formParams, consumes)
+	}
+
+	res := newMapping()
+	for key, value := range mapPairs(op) {
+		switch key {
+		case "consumes", "produces", "schemes":
+			continue
+		case "parameters":
+			if len(converted.Content) > 0 {
+				mapSet(res, key, converted)
+			}
+			if requestBody != nil {
+				mapSet(res, "requestBody", requestBody)
+				requestBody = nil
+			}
+		case "responses":
+			if requestBody != nil {
+				mapSet(res, "requestBody", requestBody)
+				requestBody = nil
+			}
+			mapSet(res, key, mapValues(value, func(r *yaml.Node) *yaml.Node {
+				return convertResponse(r, produces)
+			}))
+		default:
+			mapSet(res, key, value)
+		}
+	}
+	if requestBody != nil {
+		mapSet(res, "requestBody", requestBody)
+	}
+
+	return res
+}
+
+func (c *swaggerConverter) isBodyOrForm(p *yaml.Node) bool {
+	if ref := scalar(mapGet(p, "$ref")); ref != "" {
+		name := strings.TrimPrefix(ref, "#/parameters/")
+		_, body := c.bodyParams[name]
+		_, form := c.formParams[name]
+		return body || form
+	}
+	in := scalar(mapGet(p, "in"))
+	return in == "body" || in == "formData"
+}
+
+// parameterRef converts a non-body parameter or a reference to one.
+func (c *swaggerConverter) parameterRef(p *yaml.Node) *yaml.Node {
+	if ref := scalar(mapGet(p, "$ref")); ref != "" {
+		res := newMapping()
+		mapSet(res, "$ref", newScalar(rewriteRef(ref)))
+		return res
+	}
+	return convertParameter(p)
+}
+
+// requestBody converts a body parameter, with one media type per consumed content type.
+func (c *swaggerConverter) requestBody(p *yaml.Node, consumes []string) *yaml.Node {
+	if len(consumes) == 0 {
+		consumes = []string{"application/json"}
+	}
+
+	res := newMapping()
+	if v := mapGet(p, "description"); v != nil {
+		mapSet(res, "description", v)
+	}
+
+	content := newMapping()
+	schema := convertSchema(mapGet(p, "schema"))
+	for _, ct := range consumes {
+		media := newMapping()
+		if schema != nil {
+			mapSet(media, "schema", schema)
+		}
+		mapSet(content, ct, media)
+	}
+	mapSet(res, "content", content)
+
+	if v := mapGet(p, "required"); v != nil {
+		mapSet(res, "required", v)
+	}
+	return res
+}
+
+// formRequestBody merges formData parameters into an object schema.
+// Files are sent as multipart/form-data, other forms as application/x-www-form-urlencoded
+// unless the operation consumes a form content type explicitly.
+func formRequestBody(params []*yaml.Node, consumes []string) *yaml.Node {
+	properties := newMapping()
+	required := newSequence()
+	hasFile := false
+
+	for _, p := range params {
+		name := scalar(mapGet(p, "name"))
+		if scalar(mapGet(p, "type")) == "file" {
+			hasFile = true
+		}
+		prop := parameterSchema(p)
+		if v := mapGet(p, "description"); v != nil {
+			mapSet(prop, "description", v)
+		}
+		mapSet(properties, name, prop)
+		if scalar(mapGet(p, "required")) == "true" {
+			required.Content = append(required.Content, newScalar(name))
+		}
+	}
+
+	schema := newMapping()
+	mapSet(schema, "type", newScalar("object"))
+	mapSet(schema, "properties", properties)
+	if len(required.Content) > 0 {
+		mapSet(schema, "required", required)
+	}
+
+	var contentTypes []string
+	for _, ct := range consumes {
+		if ct == "multipart/form-data" || ct == "application/x-www-form-urlencoded" {
+			contentTypes = append(contentTypes, ct)
+		}
+	}
+	if len(contentTypes) == 0 {
+		contentTypes = []string{"application/x-www-form-urlencoded"}
+		if hasFile {
+			contentTypes = []string{"multipart/form-data"}
+		}
+	}
+
+	content := newMapping()
+	for _, ct := range contentTypes {
+		media := newMapping()
+		mapSet(media, "schema", schema)
+		mapSet(content, ct, media)
+	}
+
+	res := newMapping()
+	mapSet(res, "content", content)
+	if len(required.Content) > 0 {
+		mapSet(res, "required", newBool(true))
+	}
+	return res
+}
+
+// parameterSchemaKeys are the keys of a Swagger 2.0 parameter that describe its value.
+var parameterSchemaKeys = []string{
+	"type", "format", "items", "enum", "default",
+	"maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+	"maxLength", "minLength", "pattern",
+	"maxItems", "minItems", "uniqueItems", "multipleOf",
+}
+
+// convertParameter moves the value keywords of a query, path, header or cookie parameter
+// into a schema and maps collectionFormat to style and explode.
+func convertParameter(p *yaml.Node) *yaml.Node {
+	res := newMapping()
+	for key, value := range mapPairs(p) {
+		if slices.Contains(parameterSchemaKeys, key) || key == "collectionFormat" {
+			continue
+		}
+		mapSet(res, key, value)
+	}
+
+	if scalar(mapGet(p, "in")) == "path" {
+		mapSet(res, "required", newBool(true))
+	}
+
+	if scalar(mapGet(p, "type")) == "array" {
+		in := scalar(mapGet(p, "in"))
+		switch scalar(mapGet(p, "collectionFormat")) {
+		case "multi":
+			mapSet(res, "style", newScalar("form"))
+			mapSet(res, "explode", newBool(true))
+		case "ssv":
+			mapSet(res, "style", newScalar("spaceDelimited"))
+			mapSet(res, "explode", newBool(false))
+		case "pipes":
+			mapSet(res, "style", newScalar("pipeDelimited"))
+			mapSet(res, "explode", newBool(false))
+		default:
+			if in == "query" || in == "formData" {
+				mapSet(res, "style", newScalar("form"))
+				mapSet(res, "explode", newBool(false))
+			}
+		}
+	}
+
+	mapSet(res, "schema", parameterSchema(p))
+	return res
+}
+
+// parameterSchema builds the schema of a non-body parameter, header or items object.
+func parameterSchema(p *yaml.Node) *yaml.Node {
+	res := newMapping()
+	for key, value := range mapPairs(p) {
+		if !slices.Contains(parameterSchemaKeys, key) {
+			continue
+		}
+		switch {
+		case key == "items":
+			mapSet(res, key, parameterSchema(value))
+		case key == "type" && scalar(value) == "file":
+			mapSet(res, "type", newScalar("string"))
+			mapSet(res, "format", newScalar("binary"))
+		default:
+			mapSet(res, key, value)
+		}
+	}
+	return res
+}
+
+// convertResponse moves the response schema and examples into one media type per produced content type.
+func convertResponse(r *yaml.Node, produces []string) *yaml.Node {
+	if ref := scalar(mapGet(r, "$ref")); ref != "" {
+		res := newMapping()
+		mapSet(res, "$ref", newScalar(rewriteRef(ref)))
+		return res
+	}
+	if len(produces) == 0 {
+		produces = []string{"application/json"}
+	}
+
+	schema := convertSchema(mapGet(r, "schema"))
+	examples := mapGet(r, "examples")
+
+	var content *yaml.Node
+	if schema != nil || examples != nil {
+		content = newMapping()
+		if schema != nil {
+			for _, ct := range produces {
+				media := newMapping()
+				mapSet(media, "schema", schema)
+				mapSet(content, ct, media)
+			}
+		}
+		for ct, example := range mapPairs(examples) {
+			media := mapGet(content, ct)
+			if media == nil {
+				media = newMapping()
+				mapSet(content, ct, media)
+			}
+			mapSet(media, "example", example)
+		}
+	}
+
+	res := newMapping()
+	for key, value := range mapPairs(r) {
+		switch key {
+		case "schema", "examples":
+			if content != nil {
+				mapSet(res, "content", content)
+				content = nil
+			}
+		case "headers":
+			mapSet(res, key, mapValues(value, func(h *yaml.Node) *yaml.Node {
+				header := newMapping()
+				if v := mapGet(h, "description"); v != nil {
+					mapSet(header, "description", v)
+				}
+				mapSet(header, "schema", parameterSchema(h))
+				return header
+			}))
+		default:
+			mapSet(res, key, value)
+		}
+	}
+	if mapGet(res, "description") == nil {
+		mapSet(res, "description", newScalar(""))
+	}
+	return res
+}
+
+// convertSchema rewrites references and the Swagger-only keywords of a schema:
+// x-nullable, file types and string discriminators.
+func convertSchema(s *yaml.Node) *yaml.Node {
+	if s == nil || s.Kind != yaml.MappingNode {
+		return s
+	}
+
+	res := newMapping()
+	for key, value := range mapPairs(s) {
+		switch key {
+		case "$ref":
+			mapSet(res, key, newScalar(rewriteRef(scalar(value))))
+		case "x-nullable":
+			mapSet(res, "nullable", value)
+		case "type":
+			if scalar(value) == "file" {
+				mapSet(res, "type", newScalar("string"))
+				mapSet(res, "format", newScalar("binary"))
+				continue
+			}
+			mapSet(res, key, value)
+		case "discriminator":
+			if value.Kind == yaml.ScalarNode {
+				d := newMapping()
+				mapSet(d, "propertyName", value)
+				mapSet(res, key, d)
+				continue
+			}
+			mapSet(res, key, value)
+		case "properties", "patternProperties":
+			mapSet(res, key, mapValues(value, convertSchema))
+		case "items", "additionalProperties", "not":
+			mapSet(res, key, convertSchema(value))
+		case "allOf", "anyOf", "oneOf":
+			seq := newSequence()
+			for _, item := range value.Content {
+				seq.Content = append(seq.Content, convertSchema(item))
+			}
+			mapSet(res, key, seq)
+		default:
+			mapSet(res, key, value)
+		}
+	}
+	return res
+}
+
+// convertSecurityScheme maps basic auth to HTTP auth and oauth2 flows to their OpenAPI 3 names.
+func convertSecurityScheme(d *yaml.Node) *yaml.Node {
+	res := newMapping()
+
+	switch scalar(mapGet(d, "type")) {
+	case "basic":
+		mapSet(res, "type", newScalar("http"))
+		mapSet(res, "scheme", newScalar("basic"))
+		if v := mapGet(d, "description"); v != nil {
+			mapSet(res, "description", v)
+		}
+
+	case "oauth2":
+		flowNames := map[string]string{
+			"implicit":    "implicit",
+			"password":    "password",
+			"application": "clientCredentials",
+			"accessCode":  "authorizationCode",
+		}
+		flow := newMapping()
+		for _, key := range []string{"authorizationUrl", "tokenUrl"} {
+			if v := mapGet(d, key); v != nil {
+				mapSet(flow, key, v)
+			}
+		}
+		scopes := mapGet(d, "scopes")
+		if scopes == nil {
+			scopes = newMapping()
+		}
+		mapSet(flow, "scopes", scopes)
+
+		flows := newMapping()
+		mapSet(flows, flowNames[scalar(mapGet(d, "flow"))], flow)
+
+		mapSet(res, "type", newScalar("oauth2"))
+		if v := mapGet(d, "description"); v != nil {
+			mapSet(res, "description", v)
+		}
+		mapSet(res, "flows", flows)
+
+	default:
+		for key, value := range mapPairs(d) {
+			mapSet(res, key, value)
+		}
+	}
+
+	return res
+}
+
+// rewriteRef points Swagger 2.0 references to their OpenAPI 3 components.
+// References into other files keep their file part.
+func rewriteRef(ref string) string {
+	for from, to := range map[string]string{
+		"#/definitions/": "#/components/schemas/",
+		"#/parameters/":  "#/components/parameters/",
+		"#/responses/":   "#/components/responses/",
+	} {
+		if strings.Contains(ref, from) {
+			return strings.Replace(ref, from, to, 1)
+		}
+	}
+	return ref
+}
+
+// mapPairs iterates the key/value pairs of a mapping node in order.
+func mapPairs(n *yaml.Node) func(yield func(string, *yaml.Node) bool) {
+	return func(yield func(string, *yaml.Node) bool) {
+		if n == nil || n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if !yield(n.Content[i].Value, n.Content[i+1]) {
+				return
+			}
+		}
+	}
+}
+
+// mapValues converts every value of a mapping node, keeping the keys.
+func mapValues(n *yaml.Node, fn func(*yaml.Node) *yaml.Node) *yaml.Node {
+	res := newMapping()
+	for key, value := range mapPairs(n) {
+		mapSet(res, key, fn(value))
+	}
+	return res
+}
+
+func mapGet(n *yaml.Node, key string) *yaml.Node {
+	for k, v := range mapPairs(n) {
+		if k == key {
+			return v
+		}
+	}
+	return nil
+}
+
+// mapSet replaces the value of a key or appends it.
+func mapSet(n *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content[i+1] = value
+			return
+		}
+	}
+	n.Content = append(n.Content, newScalar(key), value)
+}
+
+func newMapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func newSequence() *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+}
+
+func newScalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func newBool(value bool) *yaml.Node {
+	v := "false"
+	if value {
+		v = "true"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: v}
+}
+
+func scalar(n *yaml.Node) string {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+func stringList(n *yaml.Node) []string {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	res := make([]string, 0, len(n.Content))
+	for _, item := range n.Content {
+		res = append(res, item.Value)
+	}
+	return res
+}
+
+// blockStyle clears the flow style of JSON input, so the result is plain block YAML.
+func blockStyle(n *yaml.Node) {
+	if n == nil {
+		return
+	}
+	n.Style &^= yaml.FlowStyle
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
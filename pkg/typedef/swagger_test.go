@@ -0,0 +1,171 @@
+package typedef
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+)
+
+func convertTestSwagger(t *testing.T, fileName string) ([]byte, map[string]any) {
+	t.Helper()
+
+	specContents, err := testDataFS.ReadFile(filepath.Join("testdata", fileName))
+	require.NoError(t, err)
+
+	out, err := ConvertSwagger2(specContents)
+	require.NoError(t, err)
+
+	var doc map[string]any
+	require.NoError(t, yaml.Unmarshal(out, &doc))
+	return out, doc
+}
+
+func dig(t *testing.T, v any, keys ...string) any {
+	t.Helper()
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		require.True(t, ok, "expected mapping at %q", key)
+		v, ok = m[key]
+		require.True(t, ok, "missing key %q", key)
+	}
+	return v
+}
+
+func TestIsSwagger2(t *testing.T) {
+	t.Run("swagger 2.0 yaml", func(t *testing.T) {
+		assert.True(t, IsSwagger2([]byte("swagger: \"2.0\"\ninfo:\n  title: x\n")))
+	})
+
+	t.Run("swagger 2.0 json", func(t *testing.T) {
+		assert.True(t, IsSwagger2([]byte(`{"swagger": "2.0", "info": {"title": "x"}}`)))
+	})
+
+	t.Run("openapi 3", func(t *testing.T) {
+		assert.False(t, IsSwagger2([]byte("openapi: 3.0.0\ninfo:\n  title: x\n")))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.False(t, IsSwagger2([]byte("swagger: [")))
+	})
+}
+
+func TestConvertSwagger2(t *testing.T) {
+	out, doc := convertTestSwagger(t, "swagger-petstore.yml")
+
+	t.Run("produces a valid OpenAPI 3 document", func(t *testing.T) {
+		assert.Equal(t, "3.0.3", doc["openapi"])
+		assert.NotContains(t, doc, "swagger")
+		assert.NotContains(t, doc, "definitions")
+
+		document, err := libopenapi.NewDocument(out)
+		require.NoError(t, err)
+		model, err := document.BuildV3Model()
+		require.NoError(t, err)
+		assert.NotNil(t, model.Model.Paths)
+	})
+
+	t.Run("servers", func(t *testing.T) {
+		assert.Equal(t, []any{
+			map[string]any{"url": "https://petstore.example.com/v1"},
+			map[string]any{"url": "http://petstore.example.com/v1"},
+		}, doc["servers"])
+	})
+
+	t.Run("definitions", func(t *testing.T) {
+		pet := dig(t, doc, "components", "schemas", "Pet", "properties")
+		assert.Equal(t, "#/components/schemas/Owner", dig(t, pet, "owner", "$ref"))
+		assert.Equal(t, true, dig(t, pet, "tag", "nullable"))
+		assert.NotContains(t, dig(t, pet, "tag"), "x-nullable")
+
+		assert.Equal(t, map[string]any{"propertyName": "kind"},
+			dig(t, doc, "components", "schemas", "Owner", "discriminator"))
+	})
+
+	t.Run("global parameters", func(t *testing.T) {
+		limit := dig(t, doc, "components", "parameters", "limit")
+		assert.Equal(t, map[string]any{"type": "integer", "format": "int32", "maximum": 100}, dig(t, limit, "schema"))
+		assert.NotContains(t, dig(t, doc, "components", "parameters"), "petBody")
+
+		body := dig(t, doc, "components", "requestBodies", "petBody")
+		assert.Equal(t, true, dig(t, body, "required"))
+		assert.Equal(t, "#/components/schemas/Pet", dig(t, body, "content", "application/json", "schema", "$ref"))
+	})
+
+	t.Run("query parameters", func(t *testing.T) {
+		params := dig(t, doc, "paths", "/pets", "get", "parameters").([]any)
+		require.Len(t, params, 2)
+		assert.Equal(t, "#/components/parameters/limit", dig(t, params[0], "$ref"))
+		assert.Equal(t, "form", dig(t, params[1], "style"))
+		assert.Equal(t, true, dig(t, params[1], "explode"))
+		assert.Equal(t, "array", dig(t, params[1], "schema", "type"))
+	})
+
+	t.Run("body parameter", func(t *testing.T) {
+		op := dig(t, doc, "paths", "/pets", "post")
+		assert.NotContains(t, op, "parameters")
+		assert.Equal(t, "#/components/requestBodies/petBody", dig(t, op, "requestBody", "$ref"))
+	})
+
+	t.Run("produces", func(t *testing.T) {
+		content := dig(t, doc, "paths", "/pets", "get", "responses", "200", "content").(map[string]any)
+		assert.Len(t, content, 2)
+		assert.Equal(t, "#/components/schemas/Pet", dig(t, content, "application/xml", "schema", "items", "$ref"))
+
+		header := dig(t, doc, "paths", "/pets", "get", "responses", "200", "headers", "X-Total")
+		assert.Equal(t, "integer", dig(t, header, "schema", "type"))
+	})
+
+	t.Run("per-operation produces and examples", func(t *testing.T) {
+		content := dig(t, doc, "paths", "/pets/{petId}", "get", "responses", "200", "content").(map[string]any)
+		assert.Len(t, content, 1)
+		assert.Equal(t, map[string]any{"id": 1, "name": "Rex"}, dig(t, content, "application/json", "example"))
+		assert.Equal(t, "#/components/responses/NotFound",
+			dig(t, doc, "paths", "/pets/{petId}", "get", "responses", "404", "$ref"))
+	})
+
+	t.Run("path-level parameters are required", func(t *testing.T) {
+		params := dig(t, doc, "paths", "/pets/{petId}", "parameters").([]any)
+		require.Len(t, params, 1)
+		assert.Equal(t, true, dig(t, params[0], "required"))
+	})
+
+	t.Run("form data", func(t *testing.T) {
+		op := dig(t, doc, "paths", "/pets/{petId}/photo", "post")
+		params := dig(t, op, "parameters").([]any)
+		assert.Len(t, params, 1)
+
+		form := dig(t, op, "requestBody", "content", "multipart/form-data", "schema")
+		assert.Equal(t, []any{"file"}, dig(t, form, "required"))
+		assert.Equal(t, map[string]any{"type": "string", "format": "binary"}, dig(t, form, "properties", "file"))
+		assert.Equal(t, "string", dig(t, form, "properties", "caption", "type"))
+	})
+
+	t.Run("security definitions", func(t *testing.T) {
+		schemes := dig(t, doc, "components", "securitySchemes")
+		assert.Equal(t, map[string]any{"type": "http", "scheme": "basic"}, dig(t, schemes, "basicAuth"))
+		assert.Equal(t, map[string]any{"type": "apiKey", "name": "X-API-Key", "in": "header"}, dig(t, schemes, "apiKey"))
+
+		flow := dig(t, schemes, "petstoreAuth", "flows", "authorizationCode")
+		assert.Equal(t, "https://auth.example.com/authorize", dig(t, flow, "authorizationUrl"))
+		assert.Equal(t, "https://auth.example.com/token", dig(t, flow, "tokenUrl"))
+		assert.Equal(t, map[string]any{"read:pets": "Read pets"}, dig(t, flow, "scopes"))
+	})
+
+	t.Run("openapi 3 is returned unchanged", func(t *testing.T) {
+		spec := []byte("openapi: 3.0.0\ninfo:\n  title: x\n  version: 1.0.0\npaths: {}\n")
+		res, err := ConvertSwagger2(spec)
+		assert.NoError(t, err)
+		assert.Equal(t, spec, res)
+	})
+
+	t.Run("invalid yaml is left to the spec parser", func(t *testing.T) {
+		spec := []byte("swagger: \"2.0\"\npaths: [\n")
+		res, err := ConvertSwagger2(spec)
+		assert.NoError(t, err)
+		assert.Equal(t, spec, res)
+	})
+}